@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/service/backup"
+	"log"
+	"os"
+)
+
+// backupCommand writes a backup of the SQLite database, the server can keep running meanwhile.
+// api backup [-db production.db] [-to FILE | -dir backups -keep 7]
+func backupCommand(args []string) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	dbFile := flags.String("db", databaseFile, "SQLite database to back up")
+	to := flags.String("to", "", "file the backup is written to, instead of a new file in -dir")
+	dir := flags.String("dir", "backups", "directory the backup is written to, the oldest backups beyond -keep are removed")
+	keep := flags.Int("keep", 7, "backups kept in -dir")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *keep < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid -keep, it must be at least 1.")
+		return 2
+	}
+
+	// * Opening a database that does not exist would create an empty one
+	if _, err := os.Stat(*dbFile); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not back up the database:", err)
+		return 1
+	}
+	db, err := SQLite.NewSqlite(*dbFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not open the database:", err)
+		return 1
+	}
+	defer db.Close()
+
+	file := *to
+	if file != "" {
+		err = SQLite.Backup(db, file, context.Background())
+	} else {
+		var b *models.Backup
+		b, err = backup.NewSQLiteBackupService(db, backup.Config{Dir: *dir, Keep: *keep}, log.New(os.Stderr, "", 0)).Backup(context.Background())
+		if err == nil {
+			file = b.File
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not back up the database:", err)
+		return 1
+	}
+	fmt.Println("Backed up", *dbFile, "to", file)
+	return 0
+}
+
+// restoreCommand replaces the SQLite database by a backup, after checking the backup can be opened by this version.
+// The server must be stopped first. api restore -from FILE [-db production.db]
+func restoreCommand(args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	from := flags.String("from", "", "backup to restore")
+	dbFile := flags.String("db", databaseFile, "SQLite database that is replaced, it is kept with the suffix .before-restore")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" {
+		fmt.Fprintln(os.Stderr, "Invalid -from, the backup to restore must be given.")
+		return 2
+	}
+
+	if err := SQLite.Restore(*from, *dbFile, context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not restore the database:", err)
+		return 1
+	}
+	fmt.Println("Restored", *dbFile, "from", *from)
+	return 0
+}
@@ -2,15 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/PostgreSQL"
 	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
 	"goapi/internal/api/server"
 	"goapi/internal/api/service"
+	"goapi/internal/api/service/backup"
+	dataService "goapi/internal/api/service/data"
+	"goapi/internal/api/service/dht22"
+	"goapi/internal/api/service/sensor"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 )
 
 // NewSimpleLogger creates a new log.Logger that writes to a file.
@@ -33,29 +46,164 @@ func NewSimpleLogger(logFile string) *log.Logger {
 	return log.New(io.MultiWriter(file, os.Stdout), "", log.Ldate|log.Ltime|log.Lshortfile)
 }
 
+// databaseFile is the SQLite database of -storage sqlite.
+const databaseFile = "production.db"
+
 func main() {
 
+	// * api backup and api restore work on production.db, the server does not start *
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			os.Exit(backupCommand(os.Args[2:]))
+		case "restore":
+			os.Exit(restoreCommand(os.Args[2:]))
+		}
+	}
+
+	// * Deleted records stay in the trash, and can be restored, for this many days *
+	trashRetentionDays := flag.Int("trash-retention-days", 30, "days deleted records are kept in the trash before they are purged")
+	// * In strict mode every change must send the ETag of the version it is based on in If-Match *
+	requireIfMatch := flag.Bool("require-if-match", false, "reject PUT, PATCH and DELETE of records and readings without an If-Match header")
+	// * Responses of the cacheable GET routes can be kept longer, e.g. -cache-control 'GET /dht22=private, max-age=5' *
+	cacheControl := cacheControlFlag{}
+	flag.Var(cacheControl, "cache-control", "Cache-Control of a route as 'ROUTE=VALUE', repeatable, routes: "+strings.Join(server.CacheableRoutes, ", "))
+	// * Files attached to records are stored on disk, the metadata in the database *
+	// * With -storage postgres the files are in the database too, every server that shares it can read them *
+	attachmentsDir := flag.String("attachments-dir", "attachments", "directory the files attached to records are stored in, not used with -storage postgres")
+	attachmentMaxMB := flag.Int("attachment-max-mb", int(dataService.DefaultAttachmentLimits.MaxSize>>20), "largest file that can be attached to a record, in MiB")
+	attachmentTypes := flag.String("attachment-types", strings.Join(dataService.DefaultAttachmentLimits.ContentTypes, ","), "comma separated MIME types of the files that can be attached to records")
+	// * In memory nothing is written to disk and everything is gone on shutdown, for demos *
+	storage := flag.String("storage", "sqlite", "where records and readings are stored: sqlite (production.db), postgres or memory")
+	// * PostgreSQL is for servers that share one database, the connection string is best kept out of the command line in DATABASE_URL *
+	postgresDSN := flag.String("postgres-dsn", os.Getenv("DATABASE_URL"), "connection string of the PostgreSQL database with -storage postgres, defaults to $DATABASE_URL")
+	// * By default each reading of POST /dht22 is written before the answer, 201 Created with its id *
+	// * With a -dht22-batch-size above 1 the readings are queued and answered 202 Accepted without an id, *
+	// * they are written in batches when full or after the flush interval *
+	dht22BatchSize := flag.Int("dht22-batch-size", 1, fmt.Sprintf("most DHT22 readings written in one transaction, 1 writes each reading on its own, e.g. %d to queue them", dht22.DefaultIngestConfig.BatchSize))
+	dht22FlushInterval := flag.Duration("dht22-flush-interval", dht22.DefaultIngestConfig.FlushInterval, "longest a DHT22 reading waits for its batch to fill up")
+	dht22QueueSize := flag.Int("dht22-queue-size", dht22.DefaultIngestConfig.QueueSize, "DHT22 readings that can wait to be written before POST /dht22 answers 429 Too Many Requests")
+	dht22DeadLetter := flag.String("dht22-dead-letter", dht22.DefaultIngestConfig.DeadLetterFile, "file the queued DHT22 readings that can not be written are kept in, they are written again when the server starts")
+	// * Backups of production.db are written to the backup dir, by POST /admin/backup and every backup interval, the newest are kept *
+	backupDir := flag.String("backup-dir", "backups", "directory the backups of the SQLite database are written to")
+	backupKeep := flag.Int("backup-keep", 7, "backups kept in the backup directory, the oldest ones are removed")
+	backupInterval := flag.Duration("backup-interval", 0, "time between scheduled backups of the SQLite database, e.g. 24h, 0 disables them")
+	// * Readings older than -archive-after are moved once an hour to gzip compressed NDJSON files, a directory per device and month *
+	// * GET /dht22?include_archive=true reads them back together with the live ones, GET /dht22/{id} reads one of them *
+	archiveAfter := flag.Duration("archive-after", 0, "age of the DHT22 readings moved out of the database into the archive, e.g. 8760h, 0 disables archiving")
+	archiveDir := flag.String("archive-dir", dht22.DefaultArchiveConfig.Dir, "directory the archived DHT22 readings are written to")
+	// * Sensor types besides the builtin BME280, DS18B20 and SCD30, their readings are served under /sensors/{type} *
+	sensorTypesFile := flag.String("sensor-types", "", "JSON file declaring more sensor types with their fields, units and ranges, see sensor.LoadTypes")
+	flag.Parse()
+
 	// * Timeout is used to gracefully shutdown the server *
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// * Create a logger and database connection *
+	// * Full-text search (/data/search) uses the SQLite FTS5 index with go build -tags sqlite_fts5, without it the table is scanned *
 	logger := NewSimpleLogger("production.log")
-	db, err := SQLite.NewSqlite("production.db")
-	if err != nil {
-		logger.Println("Error setting up database:", err)
+	if *trashRetentionDays < 1 {
+		logger.Println("Invalid -trash-retention-days, it must be at least 1.")
+		return
+	}
+	if *attachmentMaxMB < 1 {
+		logger.Println("Invalid -attachment-max-mb, it must be at least 1.")
+		return
+	}
+	if *dht22BatchSize < 1 || *dht22QueueSize < 1 || *dht22FlushInterval <= 0 {
+		logger.Println("Invalid -dht22-batch-size, -dht22-queue-size or -dht22-flush-interval, they must be positive.")
+		return
+	}
+	if *backupKeep < 1 || *backupInterval < 0 {
+		logger.Println("Invalid -backup-keep or -backup-interval, the backups kept must be at least 1 and the interval can not be negative.")
+		return
+	}
+	if *backupInterval > 0 && *storage != "sqlite" {
+		logger.Println("Invalid -backup-interval, scheduled backups are only made with -storage sqlite.")
+		return
+	}
+	if *archiveAfter < 0 {
+		logger.Println("Invalid -archive-after, it can not be negative.")
+		return
+	}
+	var sensorTypes []models.SensorType
+	if *sensorTypesFile != "" {
+		var err error
+		sensorTypes, err = sensor.LoadTypes(*sensorTypesFile)
+		if err != nil {
+			logger.Println("Invalid -sensor-types:", err)
+			return
+		}
+	}
+	attachmentLimits := dataService.AttachmentLimits{MaxSize: int64(*attachmentMaxMB) << 20}
+	for _, contentType := range strings.Split(*attachmentTypes, ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			attachmentLimits.ContentTypes = append(attachmentLimits.ContentTypes, contentType)
+		}
+	}
+	config := server.Config{
+		RequireIfMatch: *requireIfMatch,
+		CacheControl:   cacheControl,
+		DHT22Ingest:    dht22.IngestConfig{BatchSize: *dht22BatchSize, FlushInterval: *dht22FlushInterval, QueueSize: *dht22QueueSize, DeadLetterFile: *dht22DeadLetter},
+	}
+	var db DAL.SQLDatabase
+	switch *storage {
+	case "sqlite":
+		var err error
+		db, err = SQLite.NewSqlite(databaseFile)
+		if err != nil {
+			logger.Println("Error setting up database:", err)
+			return
+		}
+		defer db.Close()
+	case "postgres":
+		if *postgresDSN == "" {
+			logger.Println("Invalid -postgres-dsn, it must be set with -storage postgres.")
+			return
+		}
+		var err error
+		db, err = PostgreSQL.NewPostgreSQL(*postgresDSN)
+		if err != nil {
+			logger.Println("Error setting up database:", err)
+			return
+		}
+		defer db.Close()
+		config.DataService, config.DHT22Service = service.PostgreSQLDataService, service.PostgreSQLDHT22Service
+	case "memory":
+		config.DataService, config.DHT22Service = service.MemoryDataService, service.MemoryDHT22Service
+	default:
+		logger.Println("Invalid -storage, it must be sqlite, postgres or memory.")
 		return
 	}
-	defer db.Close()
 
 	// * Create a service factory and API server *
-	sf := service.NewServiceFactory(db, logger, ctx)
+	sf := service.NewServiceFactory(db, logger, ctx, service.Config{
+		AttachmentsDir:   *attachmentsDir,
+		AttachmentLimits: attachmentLimits,
+		Backup:           backup.Config{Dir: *backupDir, Keep: *backupKeep},
+		Archive:          dht22.ArchiveConfig{Dir: *archiveDir, ChunkSize: dht22.DefaultArchiveConfig.ChunkSize, FileSize: dht22.DefaultArchiveConfig.FileSize},
+		SensorTypes:      sensorTypes,
+	})
 
 	// * Create the API server *
-	server := server.NewServer(ctx, sf, logger)
+	server := server.NewServer(ctx, sf, logger, config)
+
+	// * Purge the trash in the background *
+	server.StartTrashPurge(time.Duration(*trashRetentionDays) * 24 * time.Hour)
+
+	// * Back up the database in the background *
+	if *backupInterval > 0 {
+		server.StartBackups(*backupInterval)
+	}
+
+	// * Archive the old readings in the background *
+	if *archiveAfter > 0 {
+		server.StartArchive(*archiveAfter)
+	}
 
 	// * Setup graceful shutdown *
-	gracefullShutdown(server, cancel, logger)
+	shutdown := gracefullShutdown(server, cancel, logger)
 
 	// * Start the server *
 	logger.Println("Starting server on :8080...")
@@ -63,23 +211,56 @@ func main() {
 		// If the server was shutdown gracefully, don't log a startup error
 		if err != http.ErrServerClosed {
 			logger.Println("Server startup error:", err)
+			return
 		}
+		// * ListenAndServe returns as soon as shutdown starts, the queued readings are still being written
+		<-shutdown
 		logger.Println("Server gracefully shutdown complete.")
 		return
 	}
 }
 
-func gracefullShutdown(server *server.Server, cancel context.CancelFunc, logger *log.Logger) {
+// cacheControlFlag collects the -cache-control flags by route.
+type cacheControlFlag map[string]string
+
+func (f cacheControlFlag) String() string {
+	routes := make([]string, 0, len(f))
+	for route, value := range f {
+		routes = append(routes, route+"="+value)
+	}
+	slices.Sort(routes)
+	return strings.Join(routes, "; ")
+}
+
+func (f cacheControlFlag) Set(s string) error {
+	route, value, ok := strings.Cut(s, "=")
+	route, value = strings.TrimSpace(route), strings.TrimSpace(value)
+	if !ok || value == "" {
+		return errors.New("must be ROUTE=VALUE, e.g. 'GET /dht22=private, max-age=5'")
+	}
+	if !slices.Contains(server.CacheableRoutes, route) {
+		return fmt.Errorf("unknown route %q, it must be one of: %s", route, strings.Join(server.CacheableRoutes, ", "))
+	}
+	f[route] = value
+	return nil
+}
+
+// gracefullShutdown shuts the server down on SIGINT or SIGTERM, the returned channel is closed once it is done.
+func gracefullShutdown(server *server.Server, cancel context.CancelFunc, logger *log.Logger) <-chan struct{} {
 
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
 
 	// * Listen for signals to shutdown the server gracefully *
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		<-signalCh
-		cancel()
+		// * The databases are closed with the context, after the last requests and readings are written
 		if err := server.Shutdown(); err != nil {
 			logger.Println("Error shutting down API Server:", err)
 		}
+		cancel()
 	}()
+	return done
 }
@@ -2,4 +2,7 @@ module goapi
 
 go 1.22.2
 
-require github.com/mattn/go-sqlite3 v1.14.22
+require (
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.22
+)
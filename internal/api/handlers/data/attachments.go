@@ -0,0 +1,203 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// * Attachments are files of a record: photos, datasheets, invoices. They are not part of the record and do not change its version *
+// * The file is uploaded as the "file" part of a multipart/form-data request, its type is detected from its content *
+// * curl -X POST http://127.0.0.1:8080/data/1/attachments -i -u admin:password -F "file=@datasheet.pdf"
+func PostAttachmentHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "The file must be sent as multipart/form-data."}`))
+		return
+	}
+
+	// * The file is streamed from the request to the store, other parts before it are skipped
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Missing file, send it as the file part of the form."}`))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment := &models.Attachment{DataID: id, FileName: part.FileName(), ContentType: part.Header.Get("Content-Type")}
+
+		// * Uploads take longer than the other requests
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		created, err := ds.CreateAttachment(attachment, part, ctx)
+		if err != nil {
+			writeAttachmentError(w, logger, err, id)
+			return
+		}
+		if created == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Resource not found."}`))
+			return
+		}
+
+		w.Header().Set("Location", "/data/"+strconv.Itoa(id)+"/attachments/"+strconv.Itoa(attachment.ID))
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(attachment); err != nil {
+			logger.Println("Error encoding attachment:", err, attachment)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		}
+		return
+	}
+}
+
+// * The GET method lists the files of a record, without their content *
+// * curl -X GET http://127.0.0.1:8080/data/1/attachments -i -u admin:password -H "Content-Type: application/json"
+func GetAttachmentsHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	attachments, err := ds.Attachments(id, ctx)
+	if err != nil {
+		logger.Println("Could not get attachments:", err, id)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if attachments == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(attachments); err != nil {
+		logger.Println("Error encoding attachments:", err, attachments)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The GET method of an attachment downloads the file, with Range requests and If-None-Match on its SHA-256 *
+// * Any Content-Type, or none, is accepted so the file can be opened from a browser *
+// * curl -X GET http://127.0.0.1:8080/data/1/attachments/1 -u admin:password -o datasheet.pdf
+func GetAttachmentHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+	attachmentID, err := strconv.Atoi(r.PathValue("attachment"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured attachment ID."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	attachment, content, err := ds.Attachment(id, attachmentID, ctx)
+	if err != nil {
+		logger.Println("Could not get attachment:", err, id, attachmentID)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if attachment == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+	defer content.Close()
+
+	// * The file is always downloaded, never shown inline, and browsers must not guess another type
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	createdAt, _ := time.Parse(time.RFC3339, attachment.CreatedAt)
+	http.ServeContent(w, r, "", createdAt, content)
+}
+
+// * curl -X DELETE http://127.0.0.1:8080/data/1/attachments/1 -i -u admin:password -H "Content-Type: application/json"
+func DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+	attachmentID, err := strconv.Atoi(r.PathValue("attachment"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured attachment ID."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	deleted, err := ds.DeleteAttachment(id, attachmentID, ctx)
+	if err != nil {
+		logger.Println("Could not delete attachment:", err, id, attachmentID)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAttachmentError(w http.ResponseWriter, logger *log.Logger, err error, id int) {
+	if errors.Is(err, models.ErrAttachmentTooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"error": "The file is too large."}`))
+		return
+	}
+	switch err := err.(type) {
+	case service.DataError:
+		// * The file name or type is not valid, response in JSON and with a 400 status code
+		w.WriteHeader(http.StatusBadRequest)
+		errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(errJSON)
+	default:
+		logger.Println("Could not create attachment:", err, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,209 @@
+package data_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// * newUploadRequest sends the content as the file part of a form *
+func newUploadRequest(t *testing.T, field string, content string) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(field, "notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	form.Close()
+
+	req, err := http.NewRequest("POST", "/data/1/attachments", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetPathValue("id", "1") // * Required for routing *
+	return req
+}
+
+func TestPostAttachmentSuccessful(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PostAttachmentHandler(rr, newUploadRequest(t, "file", "hello world"), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	if location := rr.Header().Get("Location"); location != "/data/1/attachments/1" {
+		t.Errorf("handler returned wrong Location: got %v want %v", location, "/data/1/attachments/1")
+	}
+
+	var attachment models.Attachment
+	if err := json.NewDecoder(rr.Body).Decode(&attachment); err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName != "notes.txt" || attachment.Size != 11 || attachment.DataID != 1 {
+		t.Errorf("handler returned unexpected attachment: got %+v", attachment)
+	}
+}
+
+func TestPostAttachmentMissingFile(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PostAttachmentHandler(rr, newUploadRequest(t, "photo", "hello world"), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPostAttachmentNotMultipart(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/attachments", strings.NewReader(`{"file": "hello world"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.PostAttachmentHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPostAttachmentNotFound(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PostAttachmentHandler(rr, newUploadRequest(t, "file", "hello world"), log.Default(), &service.MockDataServiceNotFound{})
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestPostAttachmentDataError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PostAttachmentHandler(rr, newUploadRequest(t, "file", "hello world"), log.Default(), &service.MockDataServiceError{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	expected := `{"error":"Error attaching file."}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestGetAttachmentsSuccessful(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1/attachments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetAttachmentsHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var attachments []models.Attachment
+	if err := json.NewDecoder(rr.Body).Decode(&attachments); err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 1 || attachments[0].FileName != "notes.txt" {
+		t.Errorf("handler returned unexpected attachments: got %+v", attachments)
+	}
+}
+
+func TestGetAttachmentsNotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1/attachments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetAttachmentsHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestGetAttachmentDownload(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1/attachments/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1")         // * Required for routing *
+	req.SetPathValue("attachment", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetAttachmentHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if rr.Body.String() != "hello world" {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), "hello world")
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("handler returned wrong Content-Type: got %v want %v", ct, "text/plain")
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != "attachment; filename=notes.txt" {
+		t.Errorf("handler returned wrong Content-Disposition: got %v want %v", cd, "attachment; filename=notes.txt")
+	}
+
+	// * The SHA-256 is the ETag, a client that has the file gets 304 Not Modified
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	data.GetAttachmentHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusNotModified {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotModified)
+	}
+}
+
+func TestGetAttachmentInvalidID(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1/attachments/first", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1")             // * Required for routing *
+	req.SetPathValue("attachment", "first") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetAttachmentHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	tests := []struct {
+		ds     service.DataService
+		status int
+	}{
+		{&service.MockDataServiceSuccessful{}, http.StatusNoContent},
+		{&service.MockDataServiceNotFound{}, http.StatusNotFound},
+		{&service.MockDataServiceError{}, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("DELETE", "/data/1/attachments/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetPathValue("id", "1")         // * Required for routing *
+		req.SetPathValue("attachment", "1") // * Required for routing *
+		rr := httptest.NewRecorder()
+
+		data.DeleteAttachmentHandler(rr, req, log.Default(), test.ds)
+		if status := rr.Code; status != test.status {
+			t.Errorf("handler returned wrong status code with %T: got %v want %v", test.ds, status, test.status)
+		}
+	}
+}
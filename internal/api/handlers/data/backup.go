@@ -0,0 +1,31 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/service/backup"
+	"log"
+	"net/http"
+)
+
+// * BackupHandler writes a consistent snapshot of the database while the server keeps serving, 201 with the file it was written to *
+// * 501 Not Implemented when the database is not backed up by the server (PostgreSQL, in memory) *
+// * curl -X POST http://127.0.0.1:8080/admin/backup -i -u admin:password -H "Content-Type: application/json"
+func BackupHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, backupService backup.BackupService) {
+	b, err := backupService.Backup(r.Context())
+	if err != nil {
+		if errors.Is(err, backup.ErrNotSupported) {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+		logger.Println("Could not back up the database:", err)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		logger.Println("Could not encode the backup:", err)
+	}
+}
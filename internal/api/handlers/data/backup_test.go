@@ -0,0 +1,45 @@
+package data
+
+import (
+	"encoding/json"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/service/backup"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBackupHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		service backup.BackupService
+		want    int
+	}{
+		{"Success", &backup.MockBackupServiceSuccessful{}, http.StatusCreated},
+		{"Error", &backup.MockBackupServiceError{}, http.StatusInternalServerError},
+		{"NotSupported", backup.NewUnsupportedBackupService(), http.StatusNotImplemented},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/backup", nil)
+			w := httptest.NewRecorder()
+
+			BackupHandler(w, req, log.Default(), test.service)
+
+			if w.Code != test.want {
+				t.Fatalf("Expected status code %d, got %d", test.want, w.Code)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			var b models.Backup
+			if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if b.File == "" || b.Size == 0 || b.CreatedAt == "" {
+				t.Errorf("Expected the file, size and time of the backup, got %+v", b)
+			}
+		})
+	}
+}
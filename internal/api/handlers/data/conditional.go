@@ -0,0 +1,94 @@
+package data
+
+import (
+	"goapi/internal/api/repository/models"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// * validators are the ETag and Last-Modified of a response, a client sends them back to only get a changed response *
+type validators struct {
+	etag         string
+	lastModified time.Time
+}
+
+// * itemValidators of a resource, its strong ETag is the version and it was last modified when it was updated *
+func itemValidators(version int, updatedAt string) validators {
+	v := validators{}
+	if version > 0 {
+		v.etag = models.ETag(version)
+	}
+	v.lastModified, _ = time.Parse(time.RFC3339, updatedAt)
+	return v
+}
+
+// * collectionValidators of a list, the version of the collection plus the query string, since each query is its own response *
+// * The ETag is weak: the same version can be encoded differently, e.g. prices converted with other rates of the same day *
+func collectionValidators(marker *models.ChangeMarker, r *http.Request) validators {
+	h := fnv.New32a()
+	h.Write([]byte(r.URL.RawQuery))
+	v := validators{etag: `W/"` + strconv.FormatInt(marker.Version, 10) + "-" + strconv.FormatUint(uint64(h.Sum32()), 16) + `"`}
+	v.lastModified, _ = time.Parse(time.RFC3339, marker.ChangedAt)
+	return v
+}
+
+// * notModified tells if the client already has this response, If-None-Match wins over If-Modified-Since *
+func (v validators) notModified(r *http.Request) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if v.etag == "" {
+			return false
+		}
+		for _, etag := range strings.Split(inm, ",") {
+			etag = strings.TrimSpace(etag)
+			// * GET compares weakly: W/"1" matches "1"
+			if etag == "*" || strings.TrimPrefix(etag, "W/") == strings.TrimPrefix(v.etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !v.lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// * Last-Modified has whole seconds only
+		return !v.lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// * set writes the validators to the response headers *
+func (v validators) set(w http.ResponseWriter) {
+	if v.etag != "" {
+		w.Header().Set("ETag", v.etag)
+	}
+	if !v.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// * writeNotModified responds with 304 Not Modified, without a body *
+func (v validators) writeNotModified(w http.ResponseWriter) {
+	v.set(w)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// * CacheControl sets the Cache-Control header of a route *
+// * The default, private, no-cache, lets clients keep responses but revalidate them with If-None-Match every time *
+func CacheControl(value string, next http.HandlerFunc) http.HandlerFunc {
+	if value == "" {
+		value = DefaultCacheControl
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", value)
+		next(w, r)
+	}
+}
+
+// * DefaultCacheControl of the cacheable routes *
+const DefaultCacheControl = "private, no-cache"
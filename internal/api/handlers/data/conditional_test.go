@@ -0,0 +1,171 @@
+package data_test
+
+import (
+	"goapi/internal/api/handlers/data"
+	service "goapi/internal/api/service/data"
+	"goapi/internal/api/service/dht22"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetValidators(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data?page=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.GetHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if etag := rr.Header().Get("ETag"); len(etag) < 3 || etag[:3] != `W/"` {
+		t.Errorf("handler returned wrong ETag: got %v want a weak ETag", etag)
+	}
+	if lastModified := rr.Header().Get("Last-Modified"); lastModified != "Fri, 01 Jan 2021 00:00:00 GMT" {
+		t.Errorf("handler returned wrong Last-Modified: got %v want %v", lastModified, "Fri, 01 Jan 2021 00:00:00 GMT")
+	}
+}
+
+func TestGetIfNoneMatch(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data?page=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	etag := rr.Header().Get("ETag")
+
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusNotModified {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotModified)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("handler returned a body with 304: got %v", rr.Body.String())
+	}
+
+	// * Another query is another response, with its own ETag
+	req, err = http.NewRequest("GET", "/data?page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestGetIfModifiedSince(t *testing.T) {
+	tests := []struct {
+		since  string
+		status int
+	}{
+		{"Fri, 01 Jan 2021 00:00:00 GMT", http.StatusNotModified},
+		{"Sat, 02 Jan 2021 00:00:00 GMT", http.StatusNotModified},
+		{"Thu, 31 Dec 2020 23:59:59 GMT", http.StatusOK},
+		{"yesterday", http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", "/data", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-Modified-Since", test.since)
+		rr := httptest.NewRecorder()
+
+		data.GetHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+		if status := rr.Code; status != test.status {
+			t.Errorf("handler returned wrong status code for %v: got %v want %v", test.since, status, test.status)
+		}
+	}
+}
+
+func TestGetChangeMarkerError(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.GetHandler(rr, req, log.Default(), &service.MockDataServiceError{})
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	}
+}
+
+func TestGetByIDIfNoneMatch(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	req.Header.Set("If-None-Match", `W/"1"`)
+	rr := httptest.NewRecorder()
+
+	data.GetByIDHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusNotModified {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotModified)
+	}
+	if etag := rr.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("handler returned wrong ETag: got %v want %v", etag, `"1"`)
+	}
+
+	req.Header.Set("If-None-Match", `"2", "3"`)
+	rr = httptest.NewRecorder()
+	data.GetByIDHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestGetDHT22IfNoneMatch(t *testing.T) {
+	req, err := http.NewRequest("GET", "/dht22", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetDHT22Handler(rr, req, log.Default(), &dht22.MockDHT22ServiceSuccessful{}, nil)
+
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	data.GetDHT22Handler(rr, req, log.Default(), &dht22.MockDHT22ServiceSuccessful{}, nil)
+	if status := rr.Code; status != http.StatusNotModified {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotModified)
+	}
+}
+
+func TestGetDHT22ByIDIfModifiedSince(t *testing.T) {
+	req, err := http.NewRequest("GET", "/dht22/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-Modified-Since", "Sun, 22 Dec 2024 12:00:00 GMT")
+	rr := httptest.NewRecorder()
+
+	data.GetDHT22ByIDHandler(rr, req, log.Default(), &dht22.MockDHT22ServiceSuccessful{}, nil)
+	if status := rr.Code; status != http.StatusNotModified {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotModified)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	rr := httptest.NewRecorder()
+	data.CacheControl("", ok)(rr, httptest.NewRequest("GET", "/data", nil))
+	if cc := rr.Header().Get("Cache-Control"); cc != data.DefaultCacheControl {
+		t.Errorf("handler returned wrong Cache-Control: got %v want %v", cc, data.DefaultCacheControl)
+	}
+
+	rr = httptest.NewRecorder()
+	data.CacheControl("private, max-age=5", ok)(rr, httptest.NewRequest("GET", "/dht22", nil))
+	if cc := rr.Header().Get("Cache-Control"); cc != "private, max-age=5" {
+		t.Errorf("handler returned wrong Cache-Control: got %v want %v", cc, "private, max-age=5")
+	}
+}
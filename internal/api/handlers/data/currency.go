@@ -0,0 +1,46 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// * requestedCurrency returns the ISO 4217 code of the currency= query parameter, empty when prices are returned as stored *
+func requestedCurrency(r *http.Request) string {
+	return strings.ToUpper(r.URL.Query().Get("currency"))
+}
+
+// * convertPrices converts the prices of the records to the requested currency, on failure the error response is written and false returned *
+func convertPrices(w http.ResponseWriter, logger *log.Logger, ds service.DataService, data []*models.Data, currency string, ctx context.Context) bool {
+	if currency == "" {
+		return true
+	}
+	err := ds.ConvertPrices(data, currency, ctx)
+	if err == nil {
+		return true
+	}
+
+	var noRate models.NoExchangeRateError
+	switch {
+	case errors.As(err, &noRate):
+		// * The currency is known but its rate was never set
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		errJSON, _ := json.Marshal(map[string]string{"error": "No exchange rate is set for " + noRate.Currency + ", set it with PUT /exchange-rates/" + noRate.Currency + "."})
+		w.Write(errJSON)
+	case errors.As(err, &service.DataError{}):
+		// * The message has the currency of the query, it is escaped
+		w.WriteHeader(http.StatusBadRequest)
+		errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(errJSON)
+	default:
+		logger.Println("Could not convert prices:", err, currency)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+	}
+	return false
+}
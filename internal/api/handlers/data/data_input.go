@@ -0,0 +1,79 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// * dataInput is a record sent by a client. For one release it also reads the serial number sent with SerialNumber, *
+// * the key before serial_number. It was a number, it is read as a number or a string and replaces serial_number when sent *
+type dataInput struct {
+	*models.Data
+	OldSerialNumber json.RawMessage `json:"SerialNumber"`
+}
+
+// * errPriceWithoutCurrency is returned for a price sent without its currency. price was a decimal amount in euros, *
+// * it is in minor units of currency now: {"price": 12} of a client that has not moved is 12 euros, not 12 cents *
+var errPriceWithoutCurrency = errors.New(`price is in minor units of its currency, send the currency with it, e.g. {"price": 1205, "currency": "EUR"} for 12.05 EUR.`)
+
+// * decodeData decodes a record sent by a client into data *
+func decodeData(body io.Reader, data *models.Data) error {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if err := checkPriceCurrency(raw); err != nil {
+		return err
+	}
+	input := dataInput{Data: data}
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return err
+	}
+	return input.applyOldSerialNumber()
+}
+
+// * checkPriceCurrency rejects a document with a price but no currency, the price could be meant in euros or in cents *
+func checkPriceCurrency(document []byte) error {
+	var sent struct {
+		Price    json.RawMessage `json:"price"`
+		Currency *string         `json:"currency"`
+	}
+	if err := json.Unmarshal(document, &sent); err != nil {
+		return err
+	}
+	if len(sent.Price) > 0 && string(sent.Price) != "null" && sent.Currency == nil {
+		return errPriceWithoutCurrency
+	}
+	return nil
+}
+
+// * writeDataInputError responds with a 400 status code to a record that could not be decoded *
+func writeDataInputError(w http.ResponseWriter, err error) {
+	w.WriteHeader(http.StatusBadRequest)
+	if errors.Is(err, errPriceWithoutCurrency) {
+		errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(errJSON)
+		return
+	}
+	w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+}
+
+// * applyOldSerialNumber sets the serial number sent with the old key, 0 stood for none like the migration of the stored ones takes it *
+func (input dataInput) applyOldSerialNumber() error {
+	if len(input.OldSerialNumber) == 0 || string(input.OldSerialNumber) == "null" {
+		return nil
+	}
+	var number float64
+	if err := json.Unmarshal(input.OldSerialNumber, &number); err != nil {
+		return json.Unmarshal(input.OldSerialNumber, &input.SerialNumber)
+	}
+	input.SerialNumber = ""
+	if number != 0 {
+		input.SerialNumber = strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return nil
+}
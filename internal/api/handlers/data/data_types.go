@@ -0,0 +1,161 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"time"
+)
+
+// * The GET method lists the registered record types with the JSON Schema of their attributes *
+// * curl -X GET http://127.0.0.1:8080/data/types -i -u admin:password -H "Content-Type: application/json"
+func GetDataTypesHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	dataTypes, err := ds.DataTypes(ctx)
+	if err != nil {
+		logger.Println("Could not get data types:", err)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if dataTypes == nil {
+		dataTypes = []*models.DataType{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(dataTypes); err != nil {
+		logger.Println("Error encoding data types:", err, dataTypes)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * curl -X GET http://127.0.0.1:8080/data/types/sensor -i -u admin:password -H "Content-Type: application/json"
+func GetDataTypeHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	dataType, err := ds.DataType(r.PathValue("name"), ctx)
+	if err != nil {
+		logger.Println("Could not get data type:", err, r.PathValue("name"))
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if dataType == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(dataType); err != nil {
+		logger.Println("Error encoding data type:", err, dataType)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The POST method registers a record type, records of the type must then have attributes matching the schema *
+// * curl -X POST http://127.0.0.1:8080/data/types -i -u admin:password -H "Content-Type: application/json" -d '{"name": "sensor", "description": "I2C sensors", "schema": {"type": "object", "required": ["voltage"], "properties": {"voltage": {"type": "number", "minimum": 1.8, "maximum": 5.5}}}}'
+func PostDataTypeHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	var dataType models.DataType
+	if err := json.NewDecoder(r.Body).Decode(&dataType); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := ds.CreateDataType(&dataType, ctx); err != nil {
+		if errors.Is(err, models.ErrDuplicateDataType) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Data type is already registered."}`))
+			return
+		}
+		writeDataTypeError(w, logger, err, dataType)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(dataType); err != nil {
+		logger.Println("Error encoding data type:", err, dataType)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The PUT method replaces the description and schema of a record type *
+// * curl -X PUT http://127.0.0.1:8080/data/types/sensor -i -u admin:password -H "Content-Type: application/json" -d '{"description": "I2C sensors", "schema": {"type": "object"}}'
+func PutDataTypeHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	var dataType models.DataType
+	if err := json.NewDecoder(r.Body).Decode(&dataType); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+	// * The name in the URI always wins
+	dataType.Name = r.PathValue("name")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if aff, err := ds.UpdateDataType(&dataType, ctx); err != nil {
+		writeDataTypeError(w, logger, err, dataType)
+		return
+	} else if aff == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(dataType); err != nil {
+		logger.Println("Error encoding data type:", err, dataType)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The DELETE method removes a record type, types that records still use can not be removed *
+// * curl -X DELETE http://127.0.0.1:8080/data/types/sensor -i -u admin:password -H "Content-Type: application/json"
+func DeleteDataTypeHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if aff, err := ds.DeleteDataType(r.PathValue("name"), ctx); err != nil {
+		if errors.Is(err, models.ErrDataTypeInUse) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Data type is used by records."}`))
+			return
+		}
+		logger.Println("Could not delete data type:", err, r.PathValue("name"))
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	} else if aff == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// * writeDataTypeError responds with a 400 status code to an invalid type and with a 500 to any other error *
+func writeDataTypeError(w http.ResponseWriter, logger *log.Logger, err error, dataType models.DataType) {
+	switch err.(type) {
+	case service.DataError:
+		w.WriteHeader(http.StatusBadRequest)
+		errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(errJSON)
+	default:
+		logger.Println("Error saving data type:", err, dataType)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,132 @@
+package data_test
+
+import (
+	"context"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"goapi/internal/api/service/jsonschema"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetDataTypes(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/types", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetDataTypesHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expected := `[{"name":"sensor","description":"Sensors on the I2C bus","schema":{"type":"object","required":["voltage"],"properties":{"voltage":{"type":"number","minimum":1.8,"maximum":5.5}}}}]`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestGetDataTypeNotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/types/unknown", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("name", "unknown") // * Required for routing *
+	rr := httptest.NewRecorder()
+	data.GetDataTypeHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestPostDataType(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/types", strings.NewReader(`{"name": "sensor", "schema": {"type": "object"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.PostDataTypeHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	expected := `{"name":"sensor","description":"","schema":{"type":"object"}}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestPostDataTypeInvalid(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/types", strings.NewReader(`{"name": "sensor", "schema": {"type": "object"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.PostDataTypeHandler(rr, req, log.Default(), &service.MockDataServiceError{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPutDataTypeNotFound(t *testing.T) {
+	req, err := http.NewRequest("PUT", "/data/types/unknown", strings.NewReader(`{"schema": {"type": "object"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("name", "unknown") // * Required for routing *
+	rr := httptest.NewRecorder()
+	data.PutDataTypeHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestDeleteDataType(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/data/types/sensor", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("name", "sensor") // * Required for routing *
+	rr := httptest.NewRecorder()
+	data.DeleteDataTypeHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+}
+
+// * invalidAttributesService rejects every record as if its attributes did not match the schema of its type *
+type invalidAttributesService struct {
+	service.MockDataServiceSuccessful
+}
+
+func (m *invalidAttributesService) Create(data *models.Data, ctx context.Context) error {
+	return service.ValidationError{Errors: []jsonschema.FieldError{
+		{Field: "date_time", Message: "must be in the format: 2021-01-01T12:00:00Z"},
+		{Field: "attributes.voltage", Message: "is required"},
+	}}
+}
+
+func TestPostFieldErrors(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data", strings.NewReader(`{"device_id": "device1", "type": "sensor", "date_time": "yesterday"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.PostHandler(rr, req, log.Default(), &invalidAttributesService{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	expected := `{"error":"Invalid data.","fields":[{"field":"date_time","message":"must be in the format: 2021-01-01T12:00:00Z"},{"field":"attributes.voltage","message":"is required"}]}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"errors"
 	"goapi/internal/api/repository/models"
 	service "goapi/internal/api/service/data"
 	"log"
@@ -11,7 +12,7 @@ import (
 )
 
 // * The DELETE method removes a resource identified by a URI *
-// * curl -X DELETE http://127.0.0.1:8080/data/1 -i -u admin:password -H "Content-Type: application/json"
+// * curl -X DELETE http://127.0.0.1:8080/data/1 -i -u admin:password -H "Content-Type: application/json" -H 'If-Match: "3"'
 func DeleteHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
@@ -21,11 +22,22 @@ func DeleteHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, d
 		return
 	}
 
+	// * The version the change is based on
+	version, err := ifMatchVersion(r, dataVersion(ds, id))
+	if err != nil {
+		writeIfMatchError(w, logger, err)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
-	aff, err := ds.Delete(&models.Data{ID: id}, ctx)
+	aff, err := ds.Delete(&models.Data{ID: id, Version: version}, ctx)
 	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			writeVersionMismatch(w)
+			return
+		}
 		logger.Println("Could not delete data:", err, id)
 		http.Error(w, "Internal Server error", http.StatusInternalServerError)
 		return
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goapi/internal/api/service/dht22"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultForecastHorizon = time.Hour
+	maxForecastHorizon     = 24 * time.Hour
+)
+
+// ForecastDHT22Handler - Predicts temperature and humidity of a device after the given horizon
+// * curl -X GET "http://127.0.0.1:8080/dht22/forecast?device=greenhouse-1&horizon=30m" -i -u admin:password -H "Content-Type: application/json"
+func ForecastDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service) {
+	device := r.URL.Query().Get("device")
+	if device == "" {
+		http.Error(w, "Missing device parameter", http.StatusBadRequest)
+		return
+	}
+
+	horizon := defaultForecastHorizon
+	if h := r.URL.Query().Get("horizon"); h != "" {
+		var err error
+		horizon, err = time.ParseDuration(h)
+		if err != nil || horizon <= 0 || horizon > maxForecastHorizon {
+			http.Error(w, fmt.Sprintf("Invalid horizon, expected a duration between 0 and %v", maxForecastHorizon), http.StatusBadRequest)
+			return
+		}
+	}
+
+	forecast, err := dht22Service.Forecast(device, horizon, r.Context())
+	if err != nil {
+		// * Too few readings, or readings all at one time, are a state of the data the client can fix, not a server error
+		if errors.Is(err, dht22.ErrNotEnoughReadings) || errors.Is(err, dht22.ErrSingleTimestamp) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to forecast DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the forecast
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(forecast); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
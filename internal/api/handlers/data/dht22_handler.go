@@ -1,16 +1,24 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
 	"goapi/internal/api/service/dht22"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // PostHandler - Creates a new DHT22 record
+// Responds 202 Accepted when the reading is queued to be written in a batch, and 429 Too Many Requests
+// with Retry-After when more readings are waiting to be written than the server queues,
+// 503 Service Unavailable with Retry-After while the server shuts down
 func CreateDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service) {
 	var data models.DHT22Data
 	// Decode the incoming request body to the DHT22Data struct
@@ -22,41 +30,275 @@ func CreateDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logg
 	// Call the service to create the record
 	err := dht22Service.Create(&data, r.Context())
 	if err != nil {
+		if _, ok := err.(dht22.DHT22ValidationError); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if full, ok := err.(dht22.IngestQueueFullError); ok {
+			w.Header().Set("Retry-After", retryAfterSeconds(full.RetryAfter))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, dht22.ErrIngestQueueClosed) {
+			// * The server is shutting down, another one or the restarted one takes the reading
+			w.Header().Set("Retry-After", retryAfterSeconds(shutdownRetryAfter))
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to create DHT22 data: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Respond with the created data in JSON format
+	// * A reading queued by the ingest queue is written later and has no id yet, it is only accepted
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	if data.ID == 0 {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
 	}
 }
 
-// GetHandler - Fetches all DHT22 records with pagination
-func GetDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service) {
-	// For now, let's just assume page and rowsPerPage are passed as query parameters
-	page := 1
-	rowsPerPage := 10
+// shutdownRetryAfter is when a server that is shutting down should be back, or another one have taken over
+const shutdownRetryAfter = 10 * time.Second
+
+// retryAfterSeconds is the Retry-After header value of a wait, in whole seconds and at least one
+func retryAfterSeconds(wait time.Duration) string {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	return strconv.Itoa(max(seconds, 1))
+}
+
+// GetHandler - Fetches all DHT22 records with pagination, either by page number or by cursor
+// Responds 304 Not Modified when the readings have not changed since the ETag or Last-Modified the client has
+// With ?include_archive=true the archived readings are merged in, by cursor and optionally in a date_time range:
+// curl -u user:pass "http://localhost:8080/dht22?include_archive=true&per_page=100"
+// curl -u user:pass -G "http://localhost:8080/dht22?include_archive=true" --data-urlencode 'filter=date_time >= "2020-01-01T00:00:00Z" and date_time < "2020-02-01T00:00:00Z"'
+func GetDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service, archive dht22.ArchiveService) {
+	includeArchive, err := parseIncludeArchive(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	marker, err := dht22Service.ChangeMarker(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read DHT22 changes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// * Archiving removes the readings from the database, so the marker also changes when the archive does
+	v := collectionValidators(marker, r)
+	if v.notModified(r) {
+		v.writeNotModified(w)
+		return
+	}
+
+	if includeArchive {
+		dateTimes, err := parseArchiveRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if archive == nil {
+			http.Error(w, "Archived readings are not available.", http.StatusNotImplemented)
+			return
+		}
+		getDHT22WithCursor(w, r, archiveRange{archive: archive, dateTimes: dateTimes}, v)
+		return
+	}
+
+	if isCursorPagination(r) {
+		if hasListQuery(r) {
+			http.Error(w, "filter, sort and fields can not be combined with cursor", http.StatusBadRequest)
+			return
+		}
+		getDHT22WithCursor(w, r, dht22Service, v)
+		return
+	}
+
+	page, rowsPerPage, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if hasListQuery(r) {
+		getDHT22WithQuery(w, r, dht22Service, page, rowsPerPage, v)
+		return
+	}
 
 	data, err := dht22Service.ReadMany(page, rowsPerPage, r.Context())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
 		return
 	}
+	total, err := dht22Service.Count(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := models.NewPage(data, page, rowsPerPage, total)
+	setLinkHeader(w, r, response.Meta)
 
-	// Respond with the fetched data
+	// Respond with the fetched page
 	w.Header().Set("Content-Type", "application/json")
+	v.set(w)
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// getDHT22WithQuery - Fetches DHT22 records matching ?filter=, ordered by ?sort= and with only the ?fields= selected
+func getDHT22WithQuery(w http.ResponseWriter, r *http.Request, dht22Service dht22.DHT22Service, page, rowsPerPage int, v validators) {
+	q, err := parseListQuery(r, models.DHT22QuerySchema)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := dht22Service.Query(q, page, rowsPerPage, r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	total, err := dht22Service.CountQuery(q, r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	selected, err := selectFields(data, q.Fields)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to select fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := models.NewPage(selected, page, rowsPerPage, total)
+	setLinkHeader(w, r, response.Meta)
+
+	// Respond with the matching page
+	w.Header().Set("Content-Type", "application/json")
+	v.set(w)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
 	}
 }
 
-// GetByIDHandler - Fetches a DHT22 record by ID
-func GetDHT22ByIDHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service) {
+// dht22CursorReader reads DHT22 records ordered by (date_time, id), the live ones or the live and the archived ones
+type dht22CursorReader interface {
+	ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error)
+}
+
+// parseIncludeArchive reads ?include_archive=, archived readings are only read when it is true
+func parseIncludeArchive(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("include_archive")
+	if value == "" {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("Invalid include_archive specified, it must be true or false.")
+	}
+	return include, nil
+}
+
+// archiveRange reads the live and the archived readings of a date_time range
+type archiveRange struct {
+	archive   dht22.ArchiveService
+	dateTimes dht22.DateTimeRange
+}
+
+func (a archiveRange) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	return a.archive.ReadRange(a.dateTimes, cursor, limit, ctx)
+}
+
+var errArchiveFilter = errors.New(`With include_archive the filter can only be a date_time range in RFC 3339, e.g. date_time >= "2020-01-01T00:00:00Z" and date_time < "2020-02-01T00:00:00Z".`)
+
+// parseArchiveRange reads the date_time range of ?filter= that can be read with include_archive: comparisons of date_time
+// with an RFC 3339 time joined by "and", at most one lower and one upper bound. Sort and fields can not be combined with it
+func parseArchiveRange(r *http.Request) (dht22.DateTimeRange, error) {
+	var dateTimes dht22.DateTimeRange
+	values := r.URL.Query()
+	if values.Has("sort") || values.Has("fields") {
+		return dateTimes, errors.New("sort and fields can not be combined with include_archive")
+	}
+	if !values.Has("filter") {
+		return dateTimes, nil
+	}
+	q, err := query.Parse(values.Get("filter"), "", "", models.DHT22QuerySchema)
+	if err != nil {
+		return dateTimes, err
+	}
+	return dateTimes, addArchiveRange(&dateTimes, q.Filter)
+}
+
+// addArchiveRange narrows the range by a filter expression. The times are compared in UTC, like the positions are
+func addArchiveRange(dateTimes *dht22.DateTimeRange, e query.Expr) error {
+	switch e := e.(type) {
+	case query.Logical:
+		if e.Op != "and" {
+			return errArchiveFilter
+		}
+		if err := addArchiveRange(dateTimes, e.Left); err != nil {
+			return err
+		}
+		return addArchiveRange(dateTimes, e.Right)
+	case query.Comparison:
+		value, _ := e.Value.(string)
+		t, err := time.Parse(time.RFC3339, value)
+		if e.Field != "date_time" || err != nil {
+			return errArchiveFilter
+		}
+		bound := t.UTC().Format(time.RFC3339Nano)
+		lower, upper := strings.HasPrefix(e.Op, ">") || e.Op == "==", strings.HasPrefix(e.Op, "<") || e.Op == "=="
+		if !lower && !upper || lower && dateTimes.From != "" || upper && dateTimes.To != "" {
+			return errArchiveFilter
+		}
+		if lower {
+			dateTimes.From, dateTimes.FromExcluded = bound, e.Op == ">"
+		}
+		if upper {
+			dateTimes.To, dateTimes.ToIncluded = bound, e.Op != "<"
+		}
+		return nil
+	default:
+		return errArchiveFilter
+	}
+}
+
+// getDHT22WithCursor - Fetches DHT22 records ordered by (date_time, id) after the given cursor
+func getDHT22WithCursor(w http.ResponseWriter, r *http.Request, reader dht22CursorReader, v validators) {
+	cursor, rowsPerPage, err := parseCursorPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, next, err := reader.ReadAfter(cursor, rowsPerPage, r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := models.NewCursorPage(data, rowsPerPage, next)
+	setCursorLinkHeader(w, r, response.NextCursor, rowsPerPage)
+
+	// Respond with the fetched records and the cursor of the next page
+	w.Header().Set("Content-Type", "application/json")
+	v.set(w)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// GetByIDHandler - Fetches a DHT22 record by ID, a reading moved to the archive is read from its file
+func GetDHT22ByIDHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service, archive dht22.ArchiveService) {
 	// Extract the ID from the URL
 	idStr := strings.TrimPrefix(r.URL.Path, "/dht22/")
 	var id int
@@ -71,9 +313,26 @@ func GetDHT22ByIDHandler(w http.ResponseWriter, r *http.Request, logger *log.Log
 		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
 		return
 	}
+	// * Archived readings can still be read, they can not be changed anymore
+	if data == nil && archive != nil {
+		if data, err = archive.ReadOne(id, r.Context()); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to fetch archived DHT22 data: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if data == nil {
+		http.Error(w, "DHT22 data not found", http.StatusNotFound)
+		return
+	}
 
-	// Respond with the fetched data
+	// Respond with the fetched data, its ETag is the version to send in If-Match or If-None-Match
+	v := itemValidators(data.Version, data.UpdatedAt)
+	if v.notModified(r) {
+		v.writeNotModified(w)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
+	v.set(w)
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
@@ -97,20 +356,124 @@ func UpdateDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logg
 		return
 	}
 
-	// Set the ID on the data (for update)
+	// Read the version the change is based on from If-Match, the version in the body is ignored
+	version, err := ifMatchVersion(r, dht22Version(dht22Service, id))
+	if err != nil {
+		if errors.As(err, new(preconditionError)) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Set the ID and the expected version on the data (for update)
 	data.ID = id
+	data.Version = version
 
 	// Call the service to update the record
 	if err := dht22Service.Update(&data, r.Context()); err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			http.Error(w, "DHT22 data has been changed since it was read", http.StatusPreconditionFailed)
+			return
+		}
+		if _, ok := err.(dht22.DHT22ValidationError); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to update DHT22 data: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Respond with a success message
+	setETag(w, data.Version)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("DHT22 data updated successfully"))
 }
 
+// PatchHandler - Applies a JSON Merge Patch to a DHT22 record by ID
+func PatchDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service) {
+	// Extract the ID from the URL
+	idStr := strings.TrimPrefix(r.URL.Path, "/dht22/")
+	var id int
+	if _, err := fmt.Sscanf(idStr, "%d", &id); err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !isMergePatch(r) {
+		http.Error(w, "Content-Type must be "+MergePatchContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Read the version the change is based on from If-Match
+	version, err := ifMatchVersion(r, dht22Version(dht22Service, id))
+	if err != nil {
+		if errors.As(err, new(preconditionError)) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	patch, err := readMergePatch(w, r)
+	if maxBytesErr := new(http.MaxBytesError); errors.As(err, &maxBytesErr) {
+		http.Error(w, fmt.Sprintf("Request body is larger than %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the stored record the patch applies to
+	current, err := dht22Service.ReadOne(id, r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		http.Error(w, "DHT22 data not found", http.StatusNotFound)
+		return
+	}
+	if version != 0 && version != current.Version {
+		http.Error(w, "DHT22 data has been changed since it was read", http.StatusPreconditionFailed)
+		return
+	}
+
+	var data models.DHT22Data
+	if err := applyMergePatch(current, patch, &data); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	data.ID = id
+	// The patch was merged onto the version read above, the update fails if the reading changed since
+	data.Version = current.Version
+
+	// Update validates the merged record before it is stored
+	if err := dht22Service.Update(&data, r.Context()); err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			http.Error(w, "DHT22 data has been changed since it was read", http.StatusPreconditionFailed)
+			return
+		}
+		if _, ok := err.(dht22.DHT22ValidationError); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to update DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the updated record
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, data.Version)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
 // DeleteHandler - Deletes a DHT22 record by ID
 func DeleteDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service) {
 	// Extract the ID from the URL
@@ -121,13 +484,29 @@ func DeleteDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logg
 		return
 	}
 
+	// Read the version the change is based on from If-Match
+	version, err := ifMatchVersion(r, dht22Version(dht22Service, id))
+	if err != nil {
+		if errors.As(err, new(preconditionError)) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to fetch DHT22 data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
 	// Create a DHT22Data object with the ID to pass to the service
 	data := &models.DHT22Data{
-		ID: id,
+		ID:      id,
+		Version: version,
 	}
 
 	// Call the service to delete the record using the DHT22Data object
 	if err := dht22Service.Delete(data, r.Context()); err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			http.Error(w, "DHT22 data has been changed since it was read", http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to delete DHT22 data: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -2,12 +2,17 @@ package data
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"goapi/internal/api/repository/DAL/memory"
 	"goapi/internal/api/repository/models"
 	"goapi/internal/api/service/dht22"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreateDHT22Handler_Success(t *testing.T) {
@@ -78,7 +83,7 @@ func TestCreateDHT22Handler_Error(t *testing.T) {
 func TestGetDHT22Handler_Success(t *testing.T) {
 	mockService := &dht22.MockDHT22ServiceSuccessful{}
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		GetDHT22Handler(w, r, nil, mockService)
+		GetDHT22Handler(w, r, nil, mockService, nil)
 	})
 
 	req := httptest.NewRequest("GET", "/dht22", nil)
@@ -92,21 +97,21 @@ func TestGetDHT22Handler_Success(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
 	}
 
-	// Check that the response body contains the expected data
-	var respData []*models.DHT22Data
+	// Check that the response body contains the expected page
+	var respData models.Page[*models.DHT22Data]
 	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
 		t.Fatalf("Failed to decode response body: %v", err)
 	}
 
-	if len(respData) != 2 {
-		t.Errorf("Expected 2 records, got %d", len(respData))
+	if len(respData.Data) != 2 || respData.Meta.Total != 2 {
+		t.Errorf("Expected 2 records, got %d of %d", len(respData.Data), respData.Meta.Total)
 	}
 }
 
 func TestGetDHT22ByIDHandler_NotFound(t *testing.T) {
 	mockService := &dht22.MockDHT22ServiceNotFound{}
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		GetDHT22ByIDHandler(w, r, nil, mockService)
+		GetDHT22ByIDHandler(w, r, nil, mockService, nil)
 	})
 
 	req := httptest.NewRequest("GET", "/dht22/999", nil) // Non-existent ID
@@ -121,10 +126,40 @@ func TestGetDHT22ByIDHandler_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetDHT22ByIDHandler_Archived(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceNotFound{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetDHT22ByIDHandler(w, r, nil, mockService, &dht22.MockArchiveService{})
+	})
+
+	// Check that a reading that is no longer live is read from the archive
+	req := httptest.NewRequest("GET", "/dht22/2", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var respData models.DHT22Data
+	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if respData.ID != 2 || respData.DateTime != "2019-01-01T11:00:00Z" {
+		t.Errorf("Expected the archived reading 2, got %+v", respData)
+	}
+
+	// Check that a reading that is neither live nor archived is not found
+	req = httptest.NewRequest("GET", "/dht22/999", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
 func TestGetDHT22ByIDHandler_Success(t *testing.T) {
 	mockService := &dht22.MockDHT22ServiceSuccessful{}
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		GetDHT22ByIDHandler(w, r, nil, mockService)
+		GetDHT22ByIDHandler(w, r, nil, mockService, nil)
 	})
 
 	req := httptest.NewRequest("GET", "/dht22/1", nil)
@@ -203,3 +238,392 @@ func TestDeleteDHT22Handler_Success(t *testing.T) {
 		t.Errorf("Expected success message, got %s", w.Body.String())
 	}
 }
+
+func TestForecastDHT22Handler_Success(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ForecastDHT22Handler(w, r, nil, mockService)
+	})
+
+	req := httptest.NewRequest("GET", "/dht22/forecast?device=greenhouse-1&horizon=30m", nil)
+	w := httptest.NewRecorder()
+
+	// Call the handler
+	handler.ServeHTTP(w, req)
+
+	// Check the response code
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Check that the forecast is for the requested device and horizon
+	var respData models.DHT22Forecast
+	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	if respData.DeviceName != "greenhouse-1" || respData.Horizon != "30m0s" {
+		t.Errorf("Expected forecast for greenhouse-1 in 30m0s, got %+v", respData)
+	}
+}
+
+func TestForecastDHT22Handler_InvalidParameters(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ForecastDHT22Handler(w, r, nil, mockService)
+	})
+
+	for _, url := range []string{"/dht22/forecast", "/dht22/forecast?device=a&horizon=soon", "/dht22/forecast?device=a&horizon=-1h", "/dht22/forecast?device=a&horizon=48h"} {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: Expected status code %d, got %d", url, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestForecastDHT22Handler_NotEnoughReadings(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceNotFound{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ForecastDHT22Handler(w, r, nil, mockService)
+	})
+
+	req := httptest.NewRequest("GET", "/dht22/forecast?device=unknown", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestForecastDHT22Handler_SingleTimestamp(t *testing.T) {
+	// * The readings of the device are all at one time, the service finds no trend in them
+	service := dht22.NewDHT22Service(memory.NewDHT22Repository(memory.NewDatabase()))
+	for i := 0; i < 3; i++ {
+		reading := &models.DHT22Data{DeviceName: "greenhouse-1", Temperature: 20 + float64(i), Humidity: 50, DateTime: "2024-12-22T12:00:00Z"}
+		if err := service.Create(reading, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ForecastDHT22Handler(w, r, nil, service)
+	})
+
+	req := httptest.NewRequest("GET", "/dht22/forecast?device=greenhouse-1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), dht22.ErrSingleTimestamp.Error()) {
+		t.Errorf("Expected status code %d with %q, got %d %q", http.StatusUnprocessableEntity, dht22.ErrSingleTimestamp, w.Code, w.Body.String())
+	}
+}
+
+func TestForecastDHT22Handler_Error(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceError{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ForecastDHT22Handler(w, r, nil, mockService)
+	})
+
+	req := httptest.NewRequest("GET", "/dht22/forecast?device=greenhouse-1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestPatchDHT22Handler_Success(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PatchDHT22Handler(w, r, nil, mockService)
+	})
+
+	req := httptest.NewRequest("PATCH", "/dht22/1", bytes.NewReader([]byte(`{"humidity": 41.5}`)))
+	req.Header.Set("Content-Type", MergePatchContentType)
+	w := httptest.NewRecorder()
+
+	// Call the handler
+	handler.ServeHTTP(w, req)
+
+	// Check the response code
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Check that only the humidity was changed
+	var respData models.DHT22Data
+	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	if respData.Humidity != 41.5 || respData.Temperature != 10 || respData.DeviceName != "DHT22 Sensor" {
+		t.Errorf("Expected only humidity to change, got %+v", respData)
+	}
+}
+
+func TestPatchDHT22Handler_NotFound(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceNotFound{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PatchDHT22Handler(w, r, nil, mockService)
+	})
+
+	req := httptest.NewRequest("PATCH", "/dht22/999", bytes.NewReader([]byte(`{"humidity": 41.5}`)))
+	req.Header.Set("Content-Type", MergePatchContentType)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestPatchDHT22Handler_UnsupportedMediaType(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PatchDHT22Handler(w, r, nil, mockService)
+	})
+
+	req := httptest.NewRequest("PATCH", "/dht22/1", bytes.NewReader([]byte(`{"humidity": 41.5}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+}
+
+func TestPatchDHT22Handler_TooLarge(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PatchDHT22Handler(w, r, nil, mockService)
+	})
+
+	body := `{"device_name": "` + strings.Repeat("x", maxMergePatchSize) + `"}`
+	req := httptest.NewRequest("PATCH", "/dht22/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", MergePatchContentType)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestGetDHT22Handler_Cursor(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetDHT22Handler(w, r, nil, mockService, nil)
+	})
+
+	cursor := models.Cursor{DateTime: "2024-12-22T09:00:00Z", ID: 7}.Encode()
+	req := httptest.NewRequest("GET", "/dht22?cursor="+cursor, nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Check that the page carries a cursor for the next request
+	var respData models.CursorPage[*models.DHT22Data]
+	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	if len(respData.Data) != 2 || respData.NextCursor == "" {
+		t.Errorf("Expected 2 records and a next cursor, got %+v", respData)
+	}
+}
+
+func TestGetDHT22Handler_Query(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetDHT22Handler(w, r, nil, mockService, nil)
+	})
+
+	req := httptest.NewRequest("GET", `/dht22?filter=temperature%3E20&fields=temperature`, nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Check that only the selected field is returned
+	var respData models.Page[map[string]any]
+	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(respData.Data) != 2 || len(respData.Data[0]) != 1 || respData.Data[0]["temperature"] != 22.5 {
+		t.Errorf("Expected only temperature to be selected, got %+v", respData.Data)
+	}
+
+	// Unknown fields are rejected
+	req = httptest.NewRequest("GET", `/dht22?filter=pressure%3E1000`, nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// queuingDHT22Service accepts readings without writing them yet, like the ingest queue
+type queuingDHT22Service struct {
+	dht22.MockDHT22ServiceSuccessful
+	err error
+}
+
+func (m *queuingDHT22Service) Create(data *models.DHT22Data, ctx context.Context) error {
+	return m.err
+}
+
+func TestCreateDHT22Handler_Queued(t *testing.T) {
+	reqBody, _ := json.Marshal(&models.DHT22Data{DeviceName: "Test Sensor", Temperature: 25.5, Humidity: 60.0, DateTime: "2024-12-22T12:00:00Z"})
+	req := httptest.NewRequest("POST", "/dht22", bytes.NewReader(reqBody))
+	w := httptest.NewRecorder()
+
+	CreateDHT22Handler(w, req, nil, &queuingDHT22Service{})
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status code %d, got %d", http.StatusAccepted, w.Code)
+	}
+}
+
+func TestCreateDHT22Handler_QueueFull(t *testing.T) {
+	tests := []struct {
+		retryAfter time.Duration
+		want       string
+	}{
+		{100 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+	}
+	for _, test := range tests {
+		reqBody, _ := json.Marshal(&models.DHT22Data{DeviceName: "Test Sensor", Temperature: 25.5, Humidity: 60.0, DateTime: "2024-12-22T12:00:00Z"})
+		req := httptest.NewRequest("POST", "/dht22", bytes.NewReader(reqBody))
+		w := httptest.NewRecorder()
+
+		CreateDHT22Handler(w, req, nil, &queuingDHT22Service{err: dht22.IngestQueueFullError{RetryAfter: test.retryAfter}})
+
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected status code %d, got %d", http.StatusTooManyRequests, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != test.want {
+			t.Errorf("Expected Retry-After %q for %v, got %q", test.want, test.retryAfter, got)
+		}
+	}
+}
+
+func TestCreateDHT22Handler_ShuttingDown(t *testing.T) {
+	reqBody, _ := json.Marshal(&models.DHT22Data{DeviceName: "Test Sensor", Temperature: 25.5, Humidity: 60.0, DateTime: "2024-12-22T12:00:00Z"})
+	req := httptest.NewRequest("POST", "/dht22", bytes.NewReader(reqBody))
+	w := httptest.NewRecorder()
+
+	CreateDHT22Handler(w, req, nil, &queuingDHT22Service{err: dht22.ErrIngestQueueClosed})
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Expected Retry-After 10, got %q", got)
+	}
+}
+
+func TestGetDHT22Handler_IncludeArchive(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetDHT22Handler(w, r, nil, mockService, &dht22.MockArchiveService{})
+	})
+
+	req := httptest.NewRequest("GET", "/dht22?include_archive=true&per_page=1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Check that the archived readings are read by cursor, and the next page keeps reading them
+	var respData models.CursorPage[*models.DHT22Data]
+	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(respData.Data) != 1 || respData.Data[0].DateTime != "2019-01-01T10:00:00Z" || respData.NextCursor == "" {
+		t.Errorf("Expected the first archived reading and a next cursor, got %+v", respData)
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, "include_archive=true") {
+		t.Errorf("Expected the next link to include the archive, got %q", link)
+	}
+}
+
+func TestGetDHT22Handler_IncludeArchiveRange(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetDHT22Handler(w, r, nil, mockService, &dht22.MockArchiveService{})
+	})
+
+	filter := url.QueryEscape(`date_time > "2019-01-01T10:00:00Z" and date_time <= "2019-01-01T12:00:00+01:00"`)
+	req := httptest.NewRequest("GET", "/dht22?include_archive=true&filter="+filter, nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Check that only the archived readings of the range are read, the upper bound is compared in UTC
+	var respData models.CursorPage[*models.DHT22Data]
+	if err := json.NewDecoder(w.Body).Decode(&respData); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(respData.Data) != 1 || respData.Data[0].DateTime != "2019-01-01T11:00:00Z" {
+		t.Errorf("Expected the second archived reading, got %+v", respData)
+	}
+}
+
+func TestGetDHT22Handler_IncludeArchiveInvalid(t *testing.T) {
+	mockService := &dht22.MockDHT22ServiceSuccessful{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetDHT22Handler(w, r, nil, mockService, &dht22.MockArchiveService{})
+	})
+
+	queries := []string{"include_archive=maybe", "include_archive=true&page=2", "include_archive=true&sort=-temperature", "include_archive=true&fields=id"}
+	// Only a date_time range in RFC 3339 can be read from the archive
+	for _, filter := range []string{
+		`temperature > 20`,
+		`date_time > "2019-01"`,
+		`date_time != "2019-01-01T10:00:00Z"`,
+		`date_time > "2019-01-01T10:00:00Z" or date_time < "2018-01-01T10:00:00Z"`,
+		`not date_time > "2019-01-01T10:00:00Z"`,
+		`date_time > "2019-01-01T10:00:00Z" and date_time >= "2019-01-01T11:00:00Z"`,
+		`date_time == "2019-01-01T10:00:00Z" and date_time < "2019-01-02T00:00:00Z"`,
+	} {
+		queries = append(queries, "include_archive=true&filter="+url.QueryEscape(filter))
+	}
+	for _, query := range queries {
+		req := httptest.NewRequest("GET", "/dht22?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
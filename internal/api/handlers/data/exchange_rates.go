@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// * The GET method lists the exchange rates used by GET /data?currency=, as units of each currency per one EUR *
+// * curl -X GET http://127.0.0.1:8080/exchange-rates -i -u admin:password -H "Content-Type: application/json"
+func GetExchangeRatesHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	rates, err := ds.ExchangeRates(ctx)
+	if err != nil {
+		logger.Println("Could not get exchange rates:", err)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if rates == nil {
+		rates = []*models.ExchangeRate{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(rates); err != nil {
+		logger.Println("Error encoding exchange rates:", err, rates)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The PUT method sets the exchange rate of one currency *
+// * curl -X PUT http://127.0.0.1:8080/exchange-rates/USD -i -u admin:password -H "Content-Type: application/json" -d '{"rate": 1.0842}'
+func PutExchangeRateHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	var rate models.ExchangeRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+	// * The currency in the URI always wins
+	rate.Currency = strings.ToUpper(r.PathValue("currency"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := ds.SetExchangeRate(&rate, ctx); err != nil {
+		switch err.(type) {
+		case service.DataError:
+			// * The message has the currency of the URI, it is escaped
+			w.WriteHeader(http.StatusBadRequest)
+			errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+			w.Write(errJSON)
+			return
+		default:
+			logger.Println("Error saving exchange rate:", err, rate)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(rate); err != nil {
+		logger.Println("Error encoding exchange rate:", err, rate)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,87 @@
+package data_test
+
+import (
+	"context"
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	services "goapi/internal/api/service"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetExchangeRates(t *testing.T) {
+	req, err := http.NewRequest("GET", "/exchange-rates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetExchangeRatesHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expected := `[{"currency":"EUR","rate":1,"updated_at":"2021-01-01T00:00:00Z"},{"currency":"USD","rate":1.25,"updated_at":"2021-01-01T00:00:00Z"}]`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestPutExchangeRate(t *testing.T) {
+	req, err := http.NewRequest("PUT", "/exchange-rates/usd", strings.NewReader(`{"currency": "GBP", "rate": 1.0842}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("currency", "usd") // * Required for routing *
+	rr := httptest.NewRecorder()
+	data.PutExchangeRateHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	// * The currency in the URI wins over the body
+	expected := `{"currency":"USD","rate":1.0842,"updated_at":"2021-01-01T00:00:00Z"}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestPutExchangeRateInvalid(t *testing.T) {
+	req, err := http.NewRequest("PUT", "/exchange-rates/USD", strings.NewReader(`{"rate": 1.0842}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("currency", "USD") // * Required for routing *
+	rr := httptest.NewRecorder()
+	data.PutExchangeRateHandler(rr, req, log.Default(), &service.MockDataServiceError{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPutExchangeRateUnknownCurrency(t *testing.T) {
+	ds, err := services.NewServiceFactory(nil, log.Default(), context.Background(), services.Config{}).CreateDataService(services.MemoryDataService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("PUT", "/exchange-rates/x%22y", strings.NewReader(`{"rate": 1.0842}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("currency", `x"y`) // * Required for routing *
+	rr := httptest.NewRecorder()
+	data.PutExchangeRateHandler(rr, req, log.Default(), ds)
+
+	// * The currency of the URI is in the message, escaped
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	var response map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || !strings.Contains(response["error"], `X"Y`) {
+		t.Errorf("handler returned unexpected body: got %v (%v) want an error about X\"Y", rr.Body.String(), err)
+	}
+}
@@ -3,47 +3,181 @@ package data
 import (
 	"context"
 	"encoding/json"
+	"goapi/internal/api/repository/models"
 	service "goapi/internal/api/service/data"
 	"log"
 	"net/http"
-	"strconv"
+	"slices"
 	"time"
 )
 
-// * The GET method retrieves all resources identified by a URI *
-// * curl -X GET http://127.0.0.1:8080/data -i -u admin:password -H "Content-Type: application/json"
+// * The GET method retrieves all resources identified by a URI, one page at a time *
+// * curl -X GET "http://127.0.0.1:8080/data?page=2&per_page=20" -i -u admin:password -H "Content-Type: application/json"
+// * Filter, sort and select fields with a small query language:
+// * curl -X GET "http://127.0.0.1:8080/data?filter=price>100%20and%20type==%22sensor%22&sort=-date_time&fields=id,device_name" -i -u admin:password -H "Content-Type: application/json"
+// * Prices are returned as stored, or converted to one currency with the exchange rates:
+// * curl -X GET "http://127.0.0.1:8080/data?currency=USD" -i -u admin:password -H "Content-Type: application/json"
+// * Records with all of the tags, or any of them with tag_match=any:
+// * curl -X GET "http://127.0.0.1:8080/data?tag=project:apollo&tag=location:lab-2&tag_match=any" -i -u admin:password -H "Content-Type: application/json"
+// * Large tables can be walked with a cursor instead, up to 5000 records a page, follow next_cursor until it is missing:
+// * curl -X GET "http://127.0.0.1:8080/data?cursor=&per_page=5000" -i -u admin:password -H "Content-Type: application/json"
+// * A list that has not changed since the ETag or Last-Modified of the last response is not sent again (304 Not Modified):
+// * curl -X GET "http://127.0.0.1:8080/data?page=2" -i -u admin:password -H 'If-None-Match: W/"42-9f2c1a3b"'
 func GetHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	// * The change marker is read before the list, so a change in between only makes the next request a full one
+	marker, err := ds.ChangeMarker(ctx)
 	if err != nil {
-		if err == err.(*strconv.NumError) {
-			// * There was no page specified, so we default to 0 *
-			page = 0
-		} else {
-			// * Invalid page specified, return a 400 status code *
+		logger.Println("Could not read data changes:", err)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	v := collectionValidators(marker, r)
+	if v.notModified(r) {
+		v.writeNotModified(w)
+		return
+	}
+
+	if isCursorPagination(r) {
+		if hasListQuery(r) || hasTagQuery(r) {
 			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"error": "Invalid page specified."}`))
+			w.Write([]byte(`{"error": "filter, sort, fields and tag can not be combined with cursor."}`))
 			return
 		}
+		getWithCursor(w, r, logger, ds, v)
+		return
+	}
+
+	page, perPage, err := parsePagination(r)
+	if err != nil {
+		// * Invalid page or per_page specified, return a 400 status code *
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	if hasListQuery(r) || hasTagQuery(r) {
+		getWithQuery(w, r, logger, ds, page, perPage, v)
+		return
+	}
+
+	data, err := ds.ReadMany(page, perPage, ctx)
+	if err != nil {
+		logger.Println("Could not get data:", err, data)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	total, err := ds.Count(ctx)
+	if err != nil {
+		logger.Println("Could not count data:", err)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if !convertPrices(w, logger, ds, data, requestedCurrency(r), ctx) {
+		return
+	}
+
+	// * A page past the end is an empty list, not a missing resource
+	response := models.NewPage(data, page, perPage, total)
+	setLinkHeader(w, r, response.Meta)
+	v.set(w)
+
+	// * Return the page to the user as JSON with a 200 OK status code
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Println("Error encoding data:", err, data)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * getWithQuery returns one page of the resources matching the filter, in the requested order and with the selected fields *
+func getWithQuery(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService, page int, perPage int, v validators) {
+	q, err := parseListQuery(r, models.DataQuerySchema)
+	if err == nil {
+		err = parseTagQuery(r, q)
+	}
+	if err != nil {
+		// * The query could not be parsed or uses fields that are not allowed, return a 400 status code *
+		w.WriteHeader(http.StatusBadRequest)
+		errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(errJSON)
+		return
+	}
+
+	// * A converted price is only meaningful with its currency
+	currency := requestedCurrency(r)
+	if currency != "" && slices.Contains(q.Fields, "price") && !slices.Contains(q.Fields, "currency") {
+		q.Fields = append(q.Fields, "currency")
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
-	data, err := ds.ReadMany(page, 10, ctx)
+	data, err := ds.Query(q, page, perPage, ctx)
+	if err != nil {
+		logger.Println("Could not query data:", err, data)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	total, err := ds.CountQuery(q, ctx)
+	if err != nil {
+		logger.Println("Could not count data:", err)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	if !convertPrices(w, logger, ds, data, currency, ctx) {
+		return
+	}
+	selected, err := selectFields(data, q.Fields)
+	if err != nil {
+		logger.Println("Error selecting fields:", err, data)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.NewPage(selected, page, perPage, total)
+	setLinkHeader(w, r, response.Meta)
+	v.set(w)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Println("Error encoding data:", err, data)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * getWithCursor returns one page of a keyset paginated list ordered by (date_time, id) *
+func getWithCursor(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService, v validators) {
+	cursor, perPage, err := parseCursorPagination(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	data, next, err := ds.ReadAfter(cursor, perPage, ctx)
 	if err != nil {
 		logger.Println("Could not get data:", err, data)
 		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
 		return
 	}
-	if len(data) == 0 {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(`{"error": "Resource not found."}`))
+	if !convertPrices(w, logger, ds, data, requestedCurrency(r), ctx) {
 		return
 	}
 
-	// * Return the data to the user as JSON with a 200 OK status code
+	response := models.NewCursorPage(data, perPage, next)
+	setCursorLinkHeader(w, r, response.NextCursor, perPage)
+	v.set(w)
+
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		logger.Println("Error encoding data:", err, data)
 		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
 		return
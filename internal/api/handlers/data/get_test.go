@@ -1,14 +1,21 @@
 package data_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	services "goapi/internal/api/service"
 	service "goapi/internal/api/service/data"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // * This ONLY test that the GetHandler returns the expected response code and body in case of succesfull (200) multiple resource retrieval without the use of a database and the page parameter *
@@ -28,15 +35,15 @@ func TestGetHandlerSuccessful(t *testing.T) {
 	}
 
 	// * We know what the MockDataService will return, so we can compare the response body to the expected value *
-	data, _ := mockDataService.ReadMany(0, 10, nil)
-	expected, _ := json.Marshal(data)
+	data, _ := mockDataService.ReadMany(1, 10, nil)
+	expected, _ := json.Marshal(models.NewPage(data, 1, 10, 2))
 	if strings.TrimSpace(rr.Body.String()) != string(expected) {
 		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), string(expected))
 	}
 }
 
-// * This ONLY test that the GetHandler returns the expected response code and body in case of an unsuccesfull (404) multiple resource retrieval without the use of a database and the page parameter *
-func TestGetHandlerNotFound(t *testing.T) {
+// * This ONLY test that the GetHandler returns an empty page (200) when there is nothing to list, instead of a 404 *
+func TestGetHandlerEmptyPage(t *testing.T) {
 	mockDataService := &service.MockDataServiceNotFound{}
 	req, err := http.NewRequest("GET", "/data", nil)
 	if err != nil {
@@ -45,13 +52,45 @@ func TestGetHandlerNotFound(t *testing.T) {
 	rr := httptest.NewRecorder()
 	// * GetHanler should call the ReadMany method of the DataService *
 	data.GetHandler(rr, req, log.Default(), mockDataService)
-	// * Response code should be 404 Not Found *
-	if status := rr.Code; status != http.StatusNotFound {
-		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	// * Response code should be 200 OK *
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
-	// * We know what the MockDataService will return, so we can compare the response body to the expected value *
-	if strings.TrimSpace(rr.Body.String()) != `{"error": "Resource not found."}` {
-		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), `{"error": "Resource not found."}`)
+	expected := `{"data":[],"meta":{"page":1,"per_page":10,"total":0,"total_pages":0}}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+// * This ONLY test that the GetHandler rejects out of bounds page and per_page parameters with a 400 *
+func TestGetHandlerInvalidPagination(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	for _, query := range []string{"page=abc", "page=0", "per_page=0", "per_page=101", "per_page=x"} {
+		req, err := http.NewRequest("GET", "/data?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.GetHandler(rr, req, log.Default(), mockDataService)
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+// * This ONLY test that the GetHandler sets the RFC 5988 Link header and keeps other query parameters *
+func TestGetHandlerLinkHeader(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	req, err := http.NewRequest("GET", "/data?page=2&per_page=1&sort=id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), mockDataService)
+
+	expected := `</data?page=1&per_page=1&sort=id>; rel="first", </data?page=1&per_page=1&sort=id>; rel="prev", </data?page=2&per_page=1&sort=id>; rel="last"`
+	if link := rr.Header().Get("Link"); link != expected {
+		t.Errorf("handler returned unexpected Link header: got %v want %v", link, expected)
 	}
 }
 
@@ -77,3 +116,239 @@ func TestGetHandlerError(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), `Internal Server error.`)
 	}
 }
+
+// * This ONLY test that the GetHandler switches to cursor pagination and returns the next cursor and Link header *
+func TestGetHandlerCursor(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	req, err := http.NewRequest("GET", "/data?cursor=&per_page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), mockDataService)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var page models.CursorPage[*models.Data]
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := models.DecodeCursor(page.NextCursor)
+	if err != nil || cursor == nil || cursor.ID != 2 {
+		t.Errorf("handler returned unexpected next_cursor: got %v (%v)", page.NextCursor, err)
+	}
+	expected := `</data?cursor=` + page.NextCursor + `&per_page=2>; rel="next"`
+	if link := rr.Header().Get("Link"); link != expected {
+		t.Errorf("handler returned unexpected Link header: got %v want %v", link, expected)
+	}
+}
+
+// * This ONLY test that the GetHandler omits next_cursor on the last page *
+func TestGetHandlerCursorLastPage(t *testing.T) {
+	mockDataService := &service.MockDataServiceNotFound{}
+	req, err := http.NewRequest("GET", "/data?cursor=", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), mockDataService)
+
+	expected := `{"data":[],"per_page":10}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+	if link := rr.Header().Get("Link"); link != "" {
+		t.Errorf("handler returned unexpected Link header: got %v want none", link)
+	}
+}
+
+// * This ONLY test that the GetHandler rejects malformed cursors and mixing page with cursor *
+func TestGetHandlerInvalidCursor(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	for _, query := range []string{"cursor=not-a-cursor", "cursor=&page=2", "cursor=&per_page=0", "cursor=&per_page=5001"} {
+		req, err := http.NewRequest("GET", "/data?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.GetHandler(rr, req, log.Default(), mockDataService)
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+// * This ONLY test that a cursor page can be larger than a numbered page, for exports of whole tables *
+func TestGetHandlerCursorPerPage(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	for query, want := range map[string]int{"cursor=&per_page=5000": http.StatusOK, "page=1&per_page=5000": http.StatusBadRequest} {
+		req, err := http.NewRequest("GET", "/data?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.GetHandler(rr, req, log.Default(), mockDataService)
+		if status := rr.Code; status != want {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, status, want)
+		}
+	}
+}
+
+// * This ONLY test that the GetHandler returns only the selected fields when ?fields= is used *
+func TestGetHandlerSelectFields(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	req, err := http.NewRequest("GET", `/data?filter=price>100&sort=-date_time&fields=id,device_name`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), mockDataService)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expected := `{"data":[{"device_name":"device1","id":1},{"device_name":"device2","id":2}],"meta":{"page":1,"per_page":10,"total":2,"total_pages":1}}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+// * This ONLY test that the GetHandler rejects filters on fields outside of the whitelist *
+func TestGetHandlerInvalidFilter(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	for _, query := range []string{"filter=password==%22x%22", "sort=secret", "fields=id,secret", "filter=price>&cursor="} {
+		req, err := http.NewRequest("GET", "/data?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.GetHandler(rr, req, log.Default(), mockDataService)
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetHandlerConvertCurrency(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	req, err := http.NewRequest("GET", "/data?currency=eur&fields=id,price", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), mockDataService)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	// * 523.00 USD is 418.40 EUR at the mock rate of 1.25, the currency is returned with the converted price
+	expected := `{"data":[{"currency":"EUR","id":1,"price":1000},{"currency":"EUR","id":2,"price":41840}],"meta":{"page":1,"per_page":10,"total":2,"total_pages":1}}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestGetHandlerUnknownCurrency(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	req, err := http.NewRequest("GET", "/data?currency=XYZ", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), mockDataService)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	expected := `{"error":"Unknown currency: XYZ."}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+
+	// * The currency is the client's, a quote in it is escaped in the JSON of the error
+	req, err = http.NewRequest("GET", "/data?currency=x%22y", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	data.GetHandler(rr, req, log.Default(), mockDataService)
+
+	var response map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || response["error"] != `Unknown currency: X"Y.` {
+		t.Errorf("handler returned unexpected body: got %v (%v) want the error of currency X\"Y", rr.Body.String(), err)
+	}
+}
+
+// * This test filters and sorts on the server-managed timestamps against a SQLite database, they are set by the repository *
+func TestGetHandlerFilterTimestamps(t *testing.T) {
+	db, err := SQLite.NewSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds, err := services.NewServiceFactory(db, log.Default(), ctx, services.Config{AttachmentsDir: t.TempDir()}).CreateDataService(services.SQLiteDataService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(serial string) *models.Data {
+		t.Helper()
+		record := &models.Data{DeviceID: "d1", SerialNumber: serial, DateTime: "2024-01-01T10:00:00Z"}
+		if err := ds.Create(record, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return record
+	}
+	// * A boundary is a time between two changes, in the format the timestamps are stored in
+	boundary := func() string {
+		time.Sleep(2 * time.Millisecond)
+		defer time.Sleep(2 * time.Millisecond)
+		return time.Now().UTC().Format(models.HistoryTimeFormat)
+	}
+
+	a, b := create("SN-A"), create("SN-B")
+	created := boundary()
+	c := create("SN-C")
+	updated := boundary()
+	a.Description = "updated last"
+	if _, err := ds.Update(a, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{`filter=created_at>"` + created + `"`, []int{c.ID}},
+		{`filter=created_at<"` + created + `"&sort=-updated_at`, []int{a.ID, b.ID}},
+		{`filter=created_at<"` + created + `" and updated_at<"` + updated + `"`, []int{b.ID}},
+		{`filter=updated_at>"` + updated + `" or created_at>"` + created + `"&sort=-created_at`, []int{c.ID, a.ID}},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", "/data?"+strings.ReplaceAll(strings.ReplaceAll(tt.query, `"`, "%22"), " ", "%20"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.GetHandler(rr, req, log.Default(), ds)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("%s: handler returned wrong status code: got %v want %v: %s", tt.query, status, http.StatusOK, rr.Body.String())
+		}
+		var page struct {
+			Data []models.Data `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, d := range page.Data {
+			ids = append(ids, d.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: handler returned the records %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"encoding/json"
+	"goapi/internal/api/repository/models"
 	service "goapi/internal/api/service/data"
 	"log"
 	"net/http"
@@ -11,7 +12,11 @@ import (
 )
 
 // * The GET method retrieves a resource identified by a URI *
+// * The ETag header is the version of the resource, send it back in If-Match to change this version only *
+// * or in If-None-Match to only get the resource when it has changed (304 Not Modified otherwise) *
 // * curl -X GET http://127.0.0.1:8080/data/1 -i -u admin:password -H "Content-Type: application/json"
+// * The resource as it was at a point in time can be read with as_of:
+// * curl -X GET "http://127.0.0.1:8080/data/1?as_of=2024-03-31T23:59:59Z" -i -u admin:password -H "Content-Type: application/json"
 func GetByIDHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
 
 	id, err := strconv.Atoi(r.PathValue("id"))
@@ -22,10 +27,25 @@ func GetByIDHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger,
 		return
 	}
 
+	var asOf time.Time
+	if a := r.URL.Query().Get("as_of"); a != "" {
+		asOf, err = time.Parse(time.RFC3339, a)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "as_of must be in the format: 2021-01-01T12:00:00Z."}`))
+			return
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
-	data, err := ds.ReadOne(id, ctx)
+	var data *models.Data
+	if asOf.IsZero() {
+		data, err = ds.ReadOne(id, ctx)
+	} else {
+		data, err = ds.ReadAsOf(id, asOf, ctx)
+	}
 	if err != nil {
 		logger.Println("Could not read one:", err, id)
 		http.Error(w, "Internal server error.", http.StatusInternalServerError)
@@ -38,6 +58,15 @@ func GetByIDHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger,
 		return
 	}
 
+	// * Past versions from the history are not the current version, they have no ETag
+	if asOf.IsZero() {
+		v := itemValidators(data.Version, data.UpdatedAt)
+		if v.notModified(r) {
+			v.writeNotModified(w)
+			return
+		}
+		v.set(w)
+	}
 	w.WriteHeader(http.StatusOK)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
@@ -92,3 +92,22 @@ func TestGetByIdSuccessful(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), string(expected))
 	}
 }
+
+func TestGetByIdTimestamps(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	req, err := http.NewRequest("GET", "/data/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetByIDHandler(rr, req, log.Default(), mockDataService)
+	var body map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["created_at"] != "2021-01-01T00:00:00.000000Z" || body["updated_at"] != "2021-01-01T00:00:00.000000Z" {
+		t.Errorf("handler returned unexpected timestamps: got %v and %v", body["created_at"], body["updated_at"])
+	}
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"time"
+)
+
+// * The GET method retrieves the resource with an exact serial number, e.g. scanned from a barcode *
+// * curl -X GET http://127.0.0.1:8080/data/by-serial/0042-AB -i -u admin:password -H "Content-Type: application/json"
+func GetBySerialHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+
+	serialNumber := r.PathValue("serial")
+	if serialNumber == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured serial number."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	data, err := ds.ReadBySerialNumber(serialNumber, ctx)
+	if err != nil {
+		logger.Println("Could not read by serial number:", err, serialNumber)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		// * This is a User Error, response in JSON and with a 404 status code
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Println("Error encoding data:", err, data)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
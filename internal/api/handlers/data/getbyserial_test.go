@@ -0,0 +1,108 @@
+package data_test
+
+import (
+	"context"
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetBySerialSuccessful(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/by-serial/0006225965", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("serial", "0006225965") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetBySerialHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var got models.Data
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 {
+		t.Errorf("handler returned unexpected record: got id %v want %v", got.ID, 1)
+	}
+}
+
+func TestGetBySerialNotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/by-serial/unknown", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("serial", "unknown") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetBySerialHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestGetBySerialInternalError(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/by-serial/12689", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("serial", "12689") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetBySerialHandler(rr, req, log.Default(), &service.MockDataServiceError{})
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	}
+}
+
+// * duplicateSerialService fails every write as if the serial number was already used by another record *
+type duplicateSerialService struct {
+	service.MockDataServiceSuccessful
+}
+
+func (m *duplicateSerialService) Create(data *models.Data, ctx context.Context) error {
+	return models.ErrDuplicateSerialNumber
+}
+
+func (m *duplicateSerialService) Update(data *models.Data, ctx context.Context) (int64, error) {
+	return 0, models.ErrDuplicateSerialNumber
+}
+
+func TestPostDuplicateSerialNumber(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data", strings.NewReader(`{"device_id": "device1", "device_name": "device1", "price": 10, "currency": "EUR", "serial_number": "12689", "type": "type1", "date_time": "2021-01-01T00:00:00Z", "description": "description1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.PostHandler(rr, req, log.Default(), &duplicateSerialService{})
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+	}
+
+	expected := `{"error": "Serial number is already used by another record."}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestPutDuplicateSerialNumber(t *testing.T) {
+	req, err := http.NewRequest("PUT", "/data", strings.NewReader(`{"id": 1, "device_id": "device1", "device_name": "device1", "price": 10, "currency": "EUR", "serial_number": "12689", "type": "type1", "date_time": "2021-01-01T00:00:00Z", "description": "description1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.PutHandler(rr, req, log.Default(), &duplicateSerialService{})
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+	}
+}
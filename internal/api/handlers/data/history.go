@@ -0,0 +1,46 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// * The history of a resource lists every version of it: who changed it, when and which fields *
+// * curl -X GET http://127.0.0.1:8080/data/1/history -i -u admin:password -H "Content-Type: application/json"
+func HistoryHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		// * This is a User Error: format of id is invalid, response in JSON and with a 400 status code
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	versions, err := ds.History(id, ctx)
+	if err != nil {
+		logger.Println("Could not read history:", err, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	if len(versions) == 0 {
+		// * A resource that never existed has no history
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(versions); err != nil {
+		logger.Println("Error encoding data:", err, versions)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,95 @@
+package data_test
+
+import (
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHistoryInvalidID(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/invalid/history", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "invalid") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.HistoryHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestHistoryNotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1/history", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.HistoryHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestHistorySuccessful(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1/history", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.HistoryHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var versions []models.DataVersion
+	if err := json.NewDecoder(rr.Body).Decode(&versions); err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[1].Operation != models.OperationUpdate || versions[1].ChangedFields[0] != "price" {
+		t.Errorf("handler returned unexpected history: got %+v", versions)
+	}
+}
+
+func TestGetByIDAsOf(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1?as_of=2021-03-31T23:59:59Z", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetByIDHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestGetByIDInvalidAsOf(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1?as_of=last-quarter", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetByIDHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	expected := `{"error": "as_of must be in the format: 2021-01-01T12:00:00Z."}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
@@ -0,0 +1,74 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// * maxMergePatchSize limits the body of a PATCH, a patch of a record or a reading is a few hundred bytes *
+const maxMergePatchSize = 64 << 10
+
+var errInvalidMergePatch = errors.New("merge patch must be a JSON object")
+
+// * isMergePatch reports whether the request body is declared as a JSON Merge Patch (RFC 7396) *
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == MergePatchContentType
+}
+
+// * readMergePatch reads the body of a PATCH, a body larger than maxMergePatchSize is an *http.MaxBytesError *
+func readMergePatch(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return io.ReadAll(http.MaxBytesReader(w, r.Body, maxMergePatchSize))
+}
+
+// * applyMergePatch applies a JSON Merge Patch to current and decodes the merged document into target *
+// * Fields missing from the patch are kept, fields set to null are reset to their zero value *
+func applyMergePatch(current any, patch []byte, target any) error {
+	var patchDoc any
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return err
+	}
+	patchObject, ok := patchDoc.(map[string]any)
+	if !ok {
+		return errInvalidMergePatch
+	}
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var currentObject map[string]any
+	if err := json.Unmarshal(currentJSON, &currentObject); err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergeObjects(currentObject, patchObject))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(merged, target)
+}
+
+func mergeObjects(target, patch map[string]any) map[string]any {
+	if target == nil {
+		target = map[string]any{}
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchChild, ok := value.(map[string]any); ok {
+			targetChild, _ := target[key].(map[string]any)
+			target[key] = mergeObjects(targetChild, patchChild)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
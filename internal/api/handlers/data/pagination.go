@@ -0,0 +1,114 @@
+package data
+
+import (
+	"goapi/internal/api/repository/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPerPage = 10
+	maxPerPage     = 100
+	// * A cursor page costs the same whatever the position, exports of whole tables read larger pages *
+	maxCursorPerPage = 5000
+)
+
+type paginationError string
+
+func (e paginationError) Error() string {
+	return string(e)
+}
+
+// * parsePagination reads the page and per_page query parameters, both are optional *
+func parsePagination(r *http.Request) (page int, perPage int, err error) {
+	page = 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		page, err = strconv.Atoi(p)
+		if err != nil || page < 1 {
+			return 0, 0, paginationError("Invalid page specified.")
+		}
+	}
+	perPage, err = parsePerPage(r, maxPerPage)
+	if err != nil {
+		return 0, 0, err
+	}
+	return page, perPage, nil
+}
+
+// * isCursorPagination reports whether the client asked for keyset pagination, ?cursor= (empty) starts at the first row *
+func isCursorPagination(r *http.Request) bool {
+	return r.URL.Query().Has("cursor")
+}
+
+// * parseCursorPagination reads the cursor and per_page query parameters, per_page can be up to maxCursorPerPage *
+func parseCursorPagination(r *http.Request) (cursor *models.Cursor, perPage int, err error) {
+	if r.URL.Query().Has("page") {
+		return nil, 0, paginationError("Use either page or cursor, not both.")
+	}
+	cursor, err = models.DecodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		return nil, 0, paginationError("Invalid cursor specified.")
+	}
+	perPage, err = parsePerPage(r, maxCursorPerPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cursor, perPage, nil
+}
+
+func parsePerPage(r *http.Request, limit int) (int, error) {
+	pp := r.URL.Query().Get("per_page")
+	if pp == "" {
+		return defaultPerPage, nil
+	}
+	perPage, err := strconv.Atoi(pp)
+	if err != nil || perPage < 1 || perPage > limit {
+		return 0, paginationError("Invalid per_page specified, it must be between 1 and " + strconv.Itoa(limit) + ".")
+	}
+	return perPage, nil
+}
+
+// * setLinkHeader sets the RFC 5988 Link header with the first, prev, next and last pages *
+func setLinkHeader(w http.ResponseWriter, r *http.Request, meta models.PageMeta) {
+	lastPage := meta.TotalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []string{pageLink(r, 1, meta.PerPage, "first")}
+	if meta.Page > 1 {
+		prev := meta.Page - 1
+		if prev > lastPage {
+			prev = lastPage
+		}
+		links = append(links, pageLink(r, prev, meta.PerPage, "prev"))
+	}
+	if meta.Page < lastPage {
+		links = append(links, pageLink(r, meta.Page+1, meta.PerPage, "next"))
+	}
+	links = append(links, pageLink(r, lastPage, meta.PerPage, "last"))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func pageLink(r *http.Request, page int, perPage int, rel string) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return `<` + u.String() + `>; rel="` + rel + `"`
+}
+
+// * setCursorLinkHeader sets the Link header to the next page of a cursor paginated list *
+func setCursorLinkHeader(w http.ResponseWriter, r *http.Request, nextCursor string, perPage int) {
+	if nextCursor == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	query.Set("per_page", strconv.Itoa(perPage))
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", `<`+u.String()+`>; rel="next"`)
+}
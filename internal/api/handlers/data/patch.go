@@ -0,0 +1,127 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// * When using PATCH, the client sends only the fields to change as a JSON Merge Patch (RFC 7396): Partial Resource Update. *
+// * With If-Match the patch is only applied if the resource is still the version of the ETag, otherwise 412 Precondition Failed *
+// * curl -X PATCH http://127.0.0.1:8080/data/1 -i -u admin:password -H "Content-Type: application/merge-patch+json" -H 'If-Match: "3"' -d '{"price": 12050, "description": "updated"}'
+func PatchHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		// * This is a User Error: format of id is invalid, response in JSON and with a 400 status code
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	if !isMergePatch(r) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		w.Write([]byte(`{"error": "Content-Type header should be set to: application/merge-patch+json."}`))
+		return
+	}
+
+	// * The version the change is based on
+	version, err := ifMatchVersion(r, dataVersion(ds, id))
+	if err != nil {
+		writeIfMatchError(w, logger, err)
+		return
+	}
+
+	patch, err := readMergePatch(w, r)
+	if maxBytesErr := new(http.MaxBytesError); errors.As(err, &maxBytesErr) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"error": "Request body is too large."}`))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	current, err := ds.ReadOne(id, ctx)
+	if err != nil {
+		logger.Println("Could not read one:", err, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+	if version != 0 && version != current.Version {
+		writeVersionMismatch(w)
+		return
+	}
+
+	// * Merge the patch onto the stored resource, the id in the URI always wins
+	var data models.Data
+	input := dataInput{Data: &data}
+	if err := checkPriceCurrency(patch); err != nil {
+		writeDataInputError(w, err)
+		return
+	}
+	if err := applyMergePatch(current, patch, &input); err != nil || input.applyOldSerialNumber() != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+	data.ID = id
+	// * The patch was merged onto the version read above, the update fails if the resource changed since
+	data.Version = current.Version
+
+	// * Update validates the merged result before it is stored
+	if aff, err := ds.Update(&data, ctx); err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			writeVersionMismatch(w)
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateSerialNumber) {
+			// * Another record already has this serial number, response in JSON and with a 409 status code
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Serial number is already used by another record."}`))
+			return
+		}
+		switch err := err.(type) {
+		case service.ValidationError:
+			// * The record is not valid, every invalid field is listed with a 400 status code
+			writeValidationError(w, err)
+			return
+		case service.DataError:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "` + err.Error() + `"}`))
+			return
+		default:
+			logger.Println("Error patching data:", err, data)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+	} else if aff == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	// * Return the updated resource with a 200 OK status code
+	setETag(w, data.Version)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Println("Error encoding data:", err, data)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,136 @@
+package data_test
+
+import (
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newPatchRequest(t *testing.T, id string, body string) *http.Request {
+	req, err := http.NewRequest("PATCH", "/data/"+id, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", id) // * Required for routing *
+	req.Header.Set("Content-Type", data.MergePatchContentType)
+	return req
+}
+
+func TestPatchInvalidID(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "invalid", `{"price": 1}`), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPatchWrongContentType(t *testing.T) {
+	req := newPatchRequest(t, "1", `{"price": 1}`)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusUnsupportedMediaType {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestPatchNotAnObject(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "1", `[1, 2]`), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPatchTooLarge(t *testing.T) {
+	// * The body is read up to the limit, the rest of a larger one is not read
+	body := `{"description": "` + strings.Repeat("x", 64<<10) + `"}`
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "1", body), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestPatchNotFound(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "1", `{"price": 1}`), log.Default(), &service.MockDataServiceNotFound{})
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+	expected := `{"error": "Resource not found."}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestPatchError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "1", `{"price": 1}`), log.Default(), &service.MockDataServiceError{})
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	}
+}
+
+func TestPatchSuccessful(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "1", `{"id": 99, "price": 125050, "currency": "EUR", "description": null}`), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	// * Patched fields change, null resets a field, everything else is kept from the stored resource
+	var patched models.Data
+	if err := json.NewDecoder(rr.Body).Decode(&patched); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := (&service.MockDataServiceSuccessful{}).ReadOne(1, nil)
+	expected := *stored
+	expected.Price = 125050
+	expected.Description = ""
+	if !reflect.DeepEqual(patched, expected) {
+		t.Errorf("handler returned unexpected body: got %+v want %+v", patched, expected)
+	}
+}
+
+func TestPatchOldSerialNumberKey(t *testing.T) {
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "1", `{"SerialNumber": 555}`), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	// * The old key replaces the stored serial number like serial_number does
+	var patched models.Data
+	if err := json.NewDecoder(rr.Body).Decode(&patched); err != nil {
+		t.Fatal(err)
+	}
+	if patched.SerialNumber != "555" {
+		t.Errorf("handler returned serial number %q, want 555", patched.SerialNumber)
+	}
+}
+
+func TestPatchLegacyPrice(t *testing.T) {
+	// * A patch of the price alone could be meant in euros, the currency must be sent with it
+	rr := httptest.NewRecorder()
+	data.PatchHandler(rr, newPatchRequest(t, "1", `{"price": 12.5}`), log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "minor units") {
+		t.Errorf("handler returned %v %v, want %v with the error about minor units", status, rr.Body.String(), http.StatusBadRequest)
+	}
+}
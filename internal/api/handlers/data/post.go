@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"goapi/internal/api/repository/models"
 	service "goapi/internal/api/service/data"
 	"log"
@@ -11,16 +12,14 @@ import (
 )
 
 // * User sends a POST request to /data with a JSON payload in the request body *
-// * curl -X POST http://127.0.0.1:8080/data -i -u admin:password -H "Content-Type: application/json" -d '{"device_id": "device1", "device_name": "device1", "value": 1.0, "type": "type1", "date_time": "2021-01-01T00:00:00Z", "description": "description1"}'
+// * curl -X POST http://127.0.0.1:8080/data -i -u admin:password -H "Content-Type: application/json" -d '{"device_id": "device1", "device_name": "device1", "price": 1205, "currency": "EUR", "type": "type1", "date_time": "2021-01-01T00:00:00Z", "description": "description1"}'
 func PostHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
 	var data models.Data
 
 	// * Decode the JSON payload from the request body into the data struct
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-
+	if err := decodeData(r.Body, &data); err != nil {
 		// * This is a User Error: format of body is invalid, response in JSON and with a 400 status code
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		writeDataInputError(w, err)
 		return
 	}
 
@@ -29,7 +28,17 @@ func PostHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds
 
 	// * Try to create the data in the database
 	if err := ds.Create(&data, ctx); err != nil {
-		switch err.(type) {
+		if errors.Is(err, models.ErrDuplicateSerialNumber) {
+			// * Another record already has this serial number, response in JSON and with a 409 status code
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Serial number is already used by another record."}`))
+			return
+		}
+		switch err := err.(type) {
+		case service.ValidationError:
+			// * The record is not valid, every invalid field is listed with a 400 status code
+			writeValidationError(w, err)
+			return
 		case service.DataError:
 			// * If the error is a DataError, handle it as a client error
 			w.WriteHeader(http.StatusBadRequest)
@@ -45,8 +45,8 @@ func TestPostErrorCreatingData(t *testing.T) {
 		ID:           1,
 		DeviceID:     "device1",
 		DeviceName:   "device1",
-		Price:        1000,  //Added price
-		SerialNumber: 12689, //Added SerialNumber
+		Price:        1000,    //Added price
+		SerialNumber: "12689", //Added SerialNumber
 		Type:         "type1",
 		DateTime:     "2021-01-01 00:00:00",
 		Description:  "description1",
@@ -79,7 +79,8 @@ func TestPostSuccessful(t *testing.T) {
 		DeviceID:     "device1",
 		DeviceName:   "device1",
 		Price:        1000, //Added price
-		SerialNumber: 12689,
+		Currency:     "EUR",
+		SerialNumber: "12689",
 		Type:         "type1",
 		DateTime:     "2021-01-01 00:00:00",
 		Description:  "description1",
@@ -98,8 +99,87 @@ func TestPostSuccessful(t *testing.T) {
 	}
 
 	// * Check the response body
-	expected := `{"id":1,"device_id":"device1","device_name":"device1","value":1,"type":"type1","date_time":"2021-01-01 00:00:00","description":"description1"}`
+	expected := `{"id":1,"device_id":"device1","device_name":"device1","price":1000,"currency":"EUR","serial_number":"12689","type":"type1","date_time":"2021-01-01 00:00:00","description":"description1"}`
 	if strings.TrimSpace(rr.Body.String()) != expected {
 		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
 	}
 }
+
+func TestPostOldSerialNumberKey(t *testing.T) {
+	tests := map[string]string{
+		// * The old key was a number, clients that have not moved to serial_number yet still send it as one
+		`"SerialNumber": 12689`:                          `"12689"`,
+		`"SerialNumber": 12.5`:                           `"12.5"`,
+		`"SerialNumber": 0`:                              `""`,
+		`"SerialNumber": "SN-1"`:                         `"SN-1"`,
+		`"SerialNumber": null, "serial_number": "SN-2"`:  `"SN-2"`,
+		`"serial_number": "SN-3", "SerialNumber": 12689`: `"12689"`,
+	}
+	for serialNumber, expected := range tests {
+		req, err := http.NewRequest("POST", "/data", strings.NewReader(`{"device_id": "device1", "currency": "EUR", `+serialNumber+`}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.PostHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+		if status := rr.Code; status != http.StatusCreated {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", serialNumber, status, http.StatusCreated)
+		}
+		if !strings.Contains(rr.Body.String(), `"serial_number":`+expected) {
+			t.Errorf("%s: handler returned unexpected body: got %v want serial_number %v", serialNumber, rr.Body.String(), expected)
+		}
+	}
+
+	req, err := http.NewRequest("POST", "/data", strings.NewReader(`{"device_id": "device1", "SerialNumber": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.PostHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code for a serial number that is not a number or a string: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPostLegacyPrice(t *testing.T) {
+	// * Clients that have not moved to minor units send price as a decimal amount in euros, without a currency
+	for _, body := range []string{
+		`{"device_id": "device1", "price": 12}`,
+		`{"device_id": "device1", "price": 12.5}`,
+		`{"device_id": "device1", "price": 12, "currency": null}`,
+	} {
+		req, err := http.NewRequest("POST", "/data", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.PostHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", body, status, http.StatusBadRequest)
+		}
+		var response map[string]string
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || !strings.Contains(response["error"], "minor units") {
+			t.Errorf("%s: handler returned unexpected body: got %v want the error about minor units", body, rr.Body.String())
+		}
+	}
+
+	// * With the currency the price is in its minor units, without a price the record has none
+	for _, body := range []string{
+		`{"device_id": "device1", "price": 1205, "currency": "EUR"}`,
+		`{"device_id": "device1"}`,
+		`{"device_id": "device1", "price": null}`,
+	} {
+		req, err := http.NewRequest("POST", "/data", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.PostHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+		if status := rr.Code; status != http.StatusCreated {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", body, status, http.StatusCreated)
+		}
+	}
+}
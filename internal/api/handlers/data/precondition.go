@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"goapi/internal/api/service/dht22"
+	"goapi/internal/api/service/sensor"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type preconditionError string
+
+func (e preconditionError) Error() string {
+	return string(e)
+}
+
+// * noVersion is a version no resource has, a change based on it fails with 412, or 404 when the resource does not exist *
+const noVersion = -1
+
+// * ifMatchVersion returns the version a change is based on, from the If-Match header *
+// * 0 when there is no header or it is "*", a change then applies to whatever version is stored *
+// * The tags are compared like RFC 9110 says: weak and foreign tags never match, a list matches when one of its tags does *
+// * current reads the stored version, 0 when the resource does not exist, it is only called for a list of versions *
+func ifMatchVersion(r *http.Request, current func(ctx context.Context) (int, error)) (int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+	tags, ok := parseETags(ifMatch)
+	if !ok {
+		return 0, preconditionError("If-Match must be * or a list of ETags returned by a GET of the resource.")
+	}
+
+	var versions []int
+	for _, tag := range tags {
+		if version, ok := models.ParseETag(tag); ok && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return noVersion, nil
+	case 1:
+		return versions[0], nil
+	}
+
+	// * The change is based on the stored version if it is one of the list, it still fails if that changes meanwhile
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	stored, err := current(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(versions, stored) {
+		return noVersion, nil
+	}
+	return stored, nil
+}
+
+// * parseETags splits a list of entity tags, weak ones keep their W/ prefix, false if the header is not such a list *
+func parseETags(header string) ([]string, bool) {
+	var tags []string
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			return tags, len(tags) > 0
+		}
+		start := 0
+		if strings.HasPrefix(rest, "W/") {
+			start = len("W/")
+		}
+		if len(rest) <= start || rest[start] != '"' {
+			return nil, false
+		}
+		end := strings.IndexByte(rest[start+1:], '"')
+		if end < 0 {
+			return nil, false
+		}
+		end += start + 2
+		tags = append(tags, rest[:end])
+
+		// * A tag ends the list or is followed by a comma
+		rest = strings.TrimLeft(rest[end:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, false
+		}
+	}
+}
+
+// * writeIfMatchError responds with 400 to an If-Match header that is not a list of ETags, and with 500 when the stored version could not be read *
+func writeIfMatchError(w http.ResponseWriter, logger *log.Logger, err error) {
+	if errors.As(err, new(preconditionError)) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+	logger.Println("Could not read the version for If-Match:", err)
+	http.Error(w, "Internal server error.", http.StatusInternalServerError)
+}
+
+// * dataVersion reads the stored version of a record for ifMatchVersion *
+func dataVersion(ds service.DataService, id int) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		data, err := ds.ReadOne(id, ctx)
+		if err != nil || data == nil {
+			return 0, err
+		}
+		return data.Version, nil
+	}
+}
+
+// * dht22Version reads the stored version of a reading for ifMatchVersion *
+func dht22Version(dht22Service dht22.DHT22Service, id int) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		data, err := dht22Service.ReadOne(id, ctx)
+		if err != nil || data == nil {
+			return 0, err
+		}
+		return data.Version, nil
+	}
+}
+
+// * sensorReadingVersion reads the stored version of a reading of a sensor type for ifMatchVersion *
+func sensorReadingVersion(ss sensor.SensorService, sensorType string, id int) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		reading, err := ss.ReadOne(sensorType, id, ctx)
+		if err != nil || reading == nil {
+			return 0, err
+		}
+		return reading.Version, nil
+	}
+}
+
+// * setETag sets the ETag header of a version of a resource, resources read without their version have none *
+func setETag(w http.ResponseWriter, version int) {
+	if version > 0 {
+		w.Header().Set("ETag", models.ETag(version))
+	}
+}
+
+// * RequireIfMatch rejects changes without an If-Match header with 428 Precondition Required, *
+// * so clients can not overwrite changes they have not seen (strict mode). If-Match: * matches any version, it is rejected too *
+func RequireIfMatch(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ifMatch := strings.TrimSpace(r.Header.Get("If-Match")); ifMatch == "" || ifMatch == "*" {
+			w.WriteHeader(http.StatusPreconditionRequired)
+			w.Write([]byte(`{"error": "If-Match header is required, use the ETag of the resource."}`))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// * writeVersionMismatch responds with 412 Precondition Failed when the resource has changed since the ETag in If-Match *
+func writeVersionMismatch(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusPreconditionFailed)
+	w.Write([]byte(`{"error": "The resource has been changed since it was read, get it again and retry."}`))
+}
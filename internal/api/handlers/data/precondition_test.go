@@ -0,0 +1,194 @@
+package data_test
+
+import (
+	"context"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetByIDETag(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.GetByIDHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if etag := rr.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("handler returned wrong ETag: got %v want %v", etag, `"1"`)
+	}
+}
+
+func TestPatchIfMatchMismatch(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/data/1", strings.NewReader(`{"description": "updated"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	req.Header.Set("Content-Type", data.MergePatchContentType)
+	req.Header.Set("If-Match", `"2"`)
+	rr := httptest.NewRecorder()
+
+	data.PatchHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusPreconditionFailed {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusPreconditionFailed)
+	}
+}
+
+func TestPatchIfMatch(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/data/1", strings.NewReader(`{"description": "updated"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	req.Header.Set("Content-Type", data.MergePatchContentType)
+	req.Header.Set("If-Match", `"1"`)
+	rr := httptest.NewRecorder()
+
+	data.PatchHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+// * storedVersionService keeps version 1 of every record, a change based on another version fails like in the repositories *
+type storedVersionService struct {
+	service.MockDataServiceSuccessful
+}
+
+func (s *storedVersionService) Update(data *models.Data, ctx context.Context) (int64, error) {
+	if data.Version != 0 && data.Version != 1 {
+		return 0, models.ErrVersionMismatch
+	}
+	return 1, nil
+}
+
+func TestPutIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifMatch  string
+		expected int
+	}{
+		{"current version", `"1"`, http.StatusOK},
+		{"any version", `*`, http.StatusOK},
+		{"list with the current version", `"2", "1"`, http.StatusOK},
+		// * Weak and foreign tags never match, like an old version
+		{"weak tag", `W/"1"`, http.StatusPreconditionFailed},
+		{"foreign tag", `"abc"`, http.StatusPreconditionFailed},
+		{"list without the current version", `"3","2"`, http.StatusPreconditionFailed},
+		{"list with a weak current version", `"2", W/"1"`, http.StatusPreconditionFailed},
+		{"not a tag", `1`, http.StatusBadRequest},
+		{"unterminated tag", `"1`, http.StatusBadRequest},
+		{"not a list", `"1" "2"`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", "/data", strings.NewReader(`{"id": 1, "device_id": "device1", "date_time": "2021-01-01T00:00:00Z"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("If-Match", tt.ifMatch)
+			rr := httptest.NewRecorder()
+
+			data.PutHandler(rr, req, log.Default(), &storedVersionService{})
+			if status := rr.Code; status != tt.expected {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", status, tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestPatchIfMatchList(t *testing.T) {
+	for ifMatch, expected := range map[string]int{`"1", "2"`: http.StatusOK, `W/"1", "2"`: http.StatusPreconditionFailed} {
+		req, err := http.NewRequest("PATCH", "/data/1", strings.NewReader(`{"description": "updated"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetPathValue("id", "1") // * Required for routing *
+		req.Header.Set("Content-Type", data.MergePatchContentType)
+		req.Header.Set("If-Match", ifMatch)
+		rr := httptest.NewRecorder()
+
+		data.PatchHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+		if status := rr.Code; status != expected {
+			t.Errorf("If-Match %s: handler returned wrong status code: got %v want %v", ifMatch, status, expected)
+		}
+	}
+}
+
+// * staleVersionService fails every change because the record was changed by someone else *
+type staleVersionService struct {
+	service.MockDataServiceSuccessful
+}
+
+func (s *staleVersionService) Update(data *models.Data, ctx context.Context) (int64, error) {
+	return 0, models.ErrVersionMismatch
+}
+
+func (s *staleVersionService) Delete(data *models.Data, ctx context.Context) (int64, error) {
+	return 0, models.ErrVersionMismatch
+}
+
+func TestPutVersionMismatch(t *testing.T) {
+	req, err := http.NewRequest("PUT", "/data", strings.NewReader(`{"id": 1, "device_id": "device1", "date_time": "2021-01-01T00:00:00Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-Match", `"1"`)
+	rr := httptest.NewRecorder()
+
+	data.PutHandler(rr, req, log.Default(), &staleVersionService{})
+	if status := rr.Code; status != http.StatusPreconditionFailed {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusPreconditionFailed)
+	}
+}
+
+func TestDeleteVersionMismatch(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/data/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	req.Header.Set("If-Match", `"1"`)
+	rr := httptest.NewRecorder()
+
+	data.DeleteHandler(rr, req, log.Default(), &staleVersionService{})
+	if status := rr.Code; status != http.StatusPreconditionFailed {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusPreconditionFailed)
+	}
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	handler := data.RequireIfMatch(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest("DELETE", "/data/1", nil)
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	if status := rr.Code; status != http.StatusPreconditionRequired {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusPreconditionRequired)
+	}
+
+	// * * matches any version, it does not say which one the change is based on
+	req.Header.Set("If-Match", `*`)
+	rr = httptest.NewRecorder()
+	handler(rr, req)
+	if status := rr.Code; status != http.StatusPreconditionRequired {
+		t.Errorf("handler returned wrong status code for If-Match *: got %v want %v", status, http.StatusPreconditionRequired)
+	}
+
+	req.Header.Set("If-Match", `"1"`)
+	rr = httptest.NewRecorder()
+	handler(rr, req)
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+}
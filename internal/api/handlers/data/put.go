@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"goapi/internal/api/repository/models"
 	service "goapi/internal/api/service/data"
 	"log"
@@ -11,24 +12,47 @@ import (
 )
 
 // * When using PUT, the client sends a complete representation of a resource to replace the current version: Whole Resource Replacement. *
-// * curl -X PUT http://127.0.0.1:8080/data -i -u admin:password -H "Content-Type: application/json" -d '{"id": 1, "content": "updated data"}'
+// * With If-Match the resource is only replaced if it is still the version of the ETag, otherwise 412 Precondition Failed *
+// * curl -X PUT http://127.0.0.1:8080/data -i -u admin:password -H "Content-Type: application/json" -H 'If-Match: "3"' -d '{"id": 1, "content": "updated data"}'
 func PutHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
 	var data models.Data
 
 	// * Decode the JSON payload from the request body into the data struct
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+	if err := decodeData(r.Body, &data); err != nil {
 		// * This is a User Error: format of body is invalid, response in JSON and with a 400 status code
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		writeDataInputError(w, err)
 		return
 	}
 
+	// * The version the change is based on, the version in the body is ignored
+	version, err := ifMatchVersion(r, dataVersion(ds, data.ID))
+	if err != nil {
+		writeIfMatchError(w, logger, err)
+		return
+	}
+
+	data.Version = version
+
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
 	// * Try to update the data in the database
 	if aff, err := ds.Update(&data, ctx); err != nil {
-		switch err.(type) {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			writeVersionMismatch(w)
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateSerialNumber) {
+			// * Another record already has this serial number, response in JSON and with a 409 status code
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Serial number is already used by another record."}`))
+			return
+		}
+		switch err := err.(type) {
+		case service.ValidationError:
+			// * The record is not valid, every invalid field is listed with a 400 status code
+			writeValidationError(w, err)
+			return
 		case service.DataError:
 			// * If the error is a DataError, handle it as a client error
 			w.WriteHeader(http.StatusBadRequest)
@@ -48,6 +72,7 @@ func PutHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds s
 	}
 
 	// * Return the data to the user as JSON with a 200 OK status code
+	setETag(w, data.Version)
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		logger.Println("Error encoding data:", err, data)
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"encoding/json"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// * hasListQuery reports whether any of the filter, sort or fields query parameters were sent *
+func hasListQuery(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Has("filter") || q.Has("sort") || q.Has("fields")
+}
+
+// * parseListQuery parses the filter, sort and fields query parameters against the whitelist of the model *
+// * e.g. ?filter=price>100 and type=="sensor"&sort=-date_time&fields=id,device_name
+func parseListQuery(r *http.Request, schema query.Schema) (*query.Query, error) {
+	q := r.URL.Query()
+	return query.Parse(q.Get("filter"), q.Get("sort"), q.Get("fields"), schema)
+}
+
+// * hasTagQuery reports whether records are filtered by tag *
+func hasTagQuery(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Has("tag") || q.Has("tag_match")
+}
+
+// * maxTagFilters limits the number of tags a list can be filtered on *
+const maxTagFilters = 20
+
+// * parseTagQuery adds the tag filter to the query of a list of records *
+// * ?tag=a&tag=b lists the records with both tags, ?tag=a&tag=b&tag_match=any the records with either
+func parseTagQuery(r *http.Request, q *query.Query) error {
+	params := r.URL.Query()
+	switch params.Get("tag_match") {
+	case "", "all":
+	case "any":
+		q.AnyTag = true
+	default:
+		return query.Error{Message: "tag_match must be all or any."}
+	}
+	for _, tag := range params["tag"] {
+		normalized, ok := models.NormalizeTag(tag)
+		if !ok {
+			return query.Error{Message: "Invalid tag: " + tag + "."}
+		}
+		if !slices.Contains(q.Tags, normalized) {
+			q.Tags = append(q.Tags, normalized)
+		}
+	}
+	if len(q.Tags) > maxTagFilters {
+		return query.Error{Message: "At most " + strconv.Itoa(maxTagFilters) + " tags can be filtered on."}
+	}
+	return nil
+}
+
+// * selectFields keeps only the selected JSON fields of every item, all fields are kept when none are selected *
+func selectFields[T any](items []T, fields []string) ([]any, error) {
+	selected := make([]any, 0, len(items))
+	for _, item := range items {
+		if len(fields) == 0 {
+			selected = append(selected, item)
+			continue
+		}
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(b, &all); err != nil {
+			return nil, err
+		}
+		projected := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			projected[f] = all[f]
+		}
+		selected = append(selected, projected)
+	}
+	return selected, nil
+}
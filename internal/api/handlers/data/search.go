@@ -0,0 +1,56 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"time"
+)
+
+// * The search method returns the resources matching a full-text query, best match first, with highlighted snippets *
+// * curl -X GET "http://127.0.0.1:8080/data/search?q=bosch%20cracked" -i -u admin:password -H "Content-Type: application/json"
+func SearchHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	text := r.URL.Query().Get("q")
+	if text == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missing search text, use ?q=."}`))
+		return
+	}
+
+	page, perPage, err := parsePagination(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	results, total, err := ds.Search(text, page, perPage, ctx)
+	if err != nil {
+		switch {
+		case errors.As(err, &service.DataError{}):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		default:
+			logger.Println("Could not search data:", err, text)
+			http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.NewPage(results, page, perPage, total)
+	setLinkHeader(w, r, response.Meta)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Println("Error encoding data:", err, results)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
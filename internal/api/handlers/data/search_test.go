@@ -0,0 +1,78 @@
+package data_test
+
+import (
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearchMissingText(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/search", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.SearchHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestSearchSuccessful(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/search?q=device", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.SearchHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var page models.Page[*models.DataSearchResult]
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Meta.Total != 2 || page.Data[0].ID != 1 || page.Data[0].Snippet != "<mark>device1</mark>" {
+		t.Errorf("handler returned unexpected results: got %+v", page)
+	}
+}
+
+func TestSearchNoMatches(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/search?q=nothing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.SearchHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expected := `{"data":[],"meta":{"page":1,"per_page":10,"total":0,"total_pages":0}}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestSearchError(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/search?q=device", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.SearchHandler(rr, req, log.Default(), &service.MockDataServiceError{})
+
+	// * The mock returns a DataError, which is a client error *
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"goapi/internal/api/service/sensor"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// * The GET method lists the registered sensor types with their fields, units and valid ranges *
+// * curl -X GET http://127.0.0.1:8080/sensors -i -u admin:password -H "Content-Type: application/json"
+func GetSensorTypesHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ss sensor.SensorService) {
+	sensorTypes := ss.Types()
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sensorTypes); err != nil {
+		logger.Println("Error encoding sensor types:", err, sensorTypes)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The GET method retrieves the readings of a sensor type, one page at a time *
+// * curl -X GET "http://127.0.0.1:8080/sensors/bme280?page=2&per_page=20" -i -u admin:password -H "Content-Type: application/json"
+func GetSensorReadingsHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ss sensor.SensorService) {
+	sensorType := r.PathValue("type")
+	if ss.Type(sensorType) == nil {
+		writeUnknownSensorType(w)
+		return
+	}
+
+	page, perPage, err := parsePagination(r)
+	if err != nil {
+		// * Invalid page or per_page specified, return a 400 status code *
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "` + err.Error() + `"}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	readings, err := ss.ReadMany(sensorType, page, perPage, ctx)
+	if err != nil {
+		logger.Println("Could not get sensor readings:", err, sensorType)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	total, err := ss.Count(sensorType, ctx)
+	if err != nil {
+		logger.Println("Could not count sensor readings:", err, sensorType)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+
+	// * A page past the end is an empty list, not a missing resource
+	response := models.NewPage(readings, page, perPage, total)
+	setLinkHeader(w, r, response.Meta)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Println("Error encoding sensor readings:", err, readings)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The GET method retrieves a reading of a sensor type, the ETag header is its version *
+// * curl -X GET http://127.0.0.1:8080/sensors/bme280/1 -i -u admin:password -H "Content-Type: application/json"
+func GetSensorReadingHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ss sensor.SensorService) {
+	sensorType := r.PathValue("type")
+	if ss.Type(sensorType) == nil {
+		writeUnknownSensorType(w)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		// * This is a User Error: format of id is invalid, response in JSON and with a 400 status code
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	reading, err := ss.ReadOne(sensorType, id, ctx)
+	if err != nil {
+		logger.Println("Could not read sensor reading:", err, sensorType, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	if reading == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	v := itemValidators(reading.Version, reading.UpdatedAt)
+	if v.notModified(r) {
+		v.writeNotModified(w)
+		return
+	}
+	v.set(w)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(reading); err != nil {
+		logger.Println("Error encoding sensor reading:", err, reading)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The POST method stores a reading of a sensor type, its values are checked against the fields of the type *
+// * curl -X POST http://127.0.0.1:8080/sensors/bme280 -i -u admin:password -H "Content-Type: application/json" -d '{"device_name": "greenhouse", "date_time": "2024-12-22T12:00:00Z", "values": {"temperature": 21.5, "humidity": 48, "pressure": 1013.25}}'
+func PostSensorReadingHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ss sensor.SensorService) {
+	sensorType := r.PathValue("type")
+	if ss.Type(sensorType) == nil {
+		writeUnknownSensorType(w)
+		return
+	}
+
+	var reading models.SensorReading
+	if err := json.NewDecoder(r.Body).Decode(&reading); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+	// * The type in the URI always wins
+	reading.Type = sensorType
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := ss.Create(&reading, ctx); err != nil {
+		writeSensorReadingError(w, logger, err, reading)
+		return
+	}
+
+	setETag(w, reading.Version)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(reading); err != nil {
+		logger.Println("Error encoding sensor reading:", err, reading)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The PUT method replaces a reading of a sensor type, with If-Match only if it is still the version of the ETag *
+// * curl -X PUT http://127.0.0.1:8080/sensors/ds18b20/1 -i -u admin:password -H "Content-Type: application/json" -H 'If-Match: "1"' -d '{"device_name": "boiler", "date_time": "2024-12-22T12:00:00Z", "values": {"temperature": 61.5}}'
+func PutSensorReadingHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ss sensor.SensorService) {
+	sensorType := r.PathValue("type")
+	if ss.Type(sensorType) == nil {
+		writeUnknownSensorType(w)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	var reading models.SensorReading
+	if err := json.NewDecoder(r.Body).Decode(&reading); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+
+	// * The version the change is based on, the version in the body is ignored
+	version, err := ifMatchVersion(r, sensorReadingVersion(ss, sensorType, id))
+	if err != nil {
+		writeIfMatchError(w, logger, err)
+		return
+	}
+	// * The type and id in the URI always win
+	reading.Type = sensorType
+	reading.ID = id
+	reading.Version = version
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if aff, err := ss.Update(&reading, ctx); err != nil {
+		writeSensorReadingError(w, logger, err, reading)
+		return
+	} else if aff == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	setETag(w, reading.Version)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(reading); err != nil {
+		logger.Println("Error encoding sensor reading:", err, reading)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * The DELETE method moves a reading of a sensor type to the trash, POST /sensors/{type}/{id}/restore takes it out again *
+// * curl -X DELETE http://127.0.0.1:8080/sensors/scd30/1 -i -u admin:password -H "Content-Type: application/json" -H 'If-Match: "1"'
+func DeleteSensorReadingHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ss sensor.SensorService) {
+	sensorType := r.PathValue("type")
+	if ss.Type(sensorType) == nil {
+		writeUnknownSensorType(w)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	// * The version the change is based on
+	version, err := ifMatchVersion(r, sensorReadingVersion(ss, sensorType, id))
+	if err != nil {
+		writeIfMatchError(w, logger, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	aff, err := ss.Delete(sensorType, id, version, ctx)
+	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			writeVersionMismatch(w)
+			return
+		}
+		logger.Println("Could not delete sensor reading:", err, sensorType, id)
+		http.Error(w, "Internal Server error", http.StatusInternalServerError)
+		return
+	}
+	if aff == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// * writeUnknownSensorType responds with a 404 status code to a sensor type that is not registered *
+func writeUnknownSensorType(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(`{"error": "Unknown sensor type, GET /sensors lists the registered ones."}`))
+}
+
+// * writeSensorReadingError responds with the invalid fields of a reading, 412 to a stale If-Match and 500 to any other error *
+func writeSensorReadingError(w http.ResponseWriter, logger *log.Logger, err error, reading models.SensorReading) {
+	var validationErr sensor.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		// * The reading is not valid, every invalid field is listed with a 400 status code
+		writeValidationError(w, service.ValidationError{Errors: validationErr.Errors})
+	case errors.Is(err, models.ErrVersionMismatch):
+		writeVersionMismatch(w)
+	case errors.Is(err, sensor.ErrUnknownSensorType):
+		writeUnknownSensorType(w)
+	default:
+		logger.Println("Error saving sensor reading:", err, reading)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,204 @@
+package data_test
+
+import (
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/service/sensor"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetSensorTypes(t *testing.T) {
+	req, err := http.NewRequest("GET", "/sensors", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.GetSensorTypesHandler(rr, req, log.Default(), &sensor.MockSensorServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	var types []models.SensorType
+	if err := json.Unmarshal(rr.Body.Bytes(), &types); err != nil || len(types) != 3 || types[0].Name != "bme280" {
+		t.Errorf("handler returned unexpected body: %v", rr.Body.String())
+	}
+	if f := types[0].Field("pressure"); f == nil || f.Unit != "hPa" || f.Min != 300 || f.Max != 1100 {
+		t.Errorf("Expected the pressure field of the BME280, got %+v", f)
+	}
+}
+
+func TestGetSensorReadings(t *testing.T) {
+	req, err := http.NewRequest("GET", "/sensors/ds18b20?page=1&per_page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("type", "ds18b20") // * Required for routing *
+	rr := httptest.NewRecorder()
+	data.GetSensorReadingsHandler(rr, req, log.Default(), &sensor.MockSensorServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	var response models.Page[*models.SensorReading]
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || len(response.Data) != 2 || response.Meta.Total != 2 {
+		t.Errorf("handler returned unexpected body: %v", rr.Body.String())
+	}
+}
+
+func TestGetSensorReadingsUnknownType(t *testing.T) {
+	req, err := http.NewRequest("GET", "/sensors/dht11", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("type", "dht11")
+	rr := httptest.NewRecorder()
+	data.GetSensorReadingsHandler(rr, req, log.Default(), &sensor.MockSensorServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestGetSensorReading(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected int
+	}{
+		{"found", "1", http.StatusOK},
+		{"not found", "2", http.StatusNotFound},
+		{"invalid id", "one", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/sensors/bme280/"+tt.id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetPathValue("type", "bme280")
+			req.SetPathValue("id", tt.id)
+			rr := httptest.NewRecorder()
+			data.GetSensorReadingHandler(rr, req, log.Default(), &sensor.MockSensorServiceSuccessful{})
+
+			if status := rr.Code; status != tt.expected {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expected)
+			}
+			if tt.expected == http.StatusOK && rr.Header().Get("ETag") != `"1"` {
+				t.Errorf("Expected the ETag of version 1, got %q", rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestPostSensorReading(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		fields   string
+	}{
+		{"valid", `{"device_name": "greenhouse", "date_time": "2024-12-22T12:00:00Z", "values": {"temperature": 21.5, "humidity": 48, "pressure": 1013.25}}`, http.StatusCreated, ""},
+		{"out of range and missing", `{"device_name": "greenhouse", "date_time": "2024-12-22T12:00:00Z", "values": {"temperature": 90, "humidity": 48}}`, http.StatusBadRequest, "values.temperature,values.pressure"},
+		{"unknown field", `{"device_name": "greenhouse", "date_time": "2024-12-22T12:00:00Z", "values": {"temperature": 21.5, "humidity": 48, "pressure": 1013.25, "co2": 400}}`, http.StatusBadRequest, "values.co2"},
+		{"invalid JSON", `{"values": {"temperature": "warm"}}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/sensors/bme280", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetPathValue("type", "bme280")
+			rr := httptest.NewRecorder()
+			data.PostSensorReadingHandler(rr, req, log.Default(), &sensor.MockSensorServiceSuccessful{})
+
+			if status := rr.Code; status != tt.expected {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", status, tt.expected, rr.Body.String())
+			}
+			if tt.fields == "" {
+				return
+			}
+			var response struct {
+				Fields []struct{ Field string } `json:"fields"`
+			}
+			json.Unmarshal(rr.Body.Bytes(), &response)
+			var fields []string
+			for _, f := range response.Fields {
+				fields = append(fields, f.Field)
+			}
+			if strings.Join(fields, ",") != tt.fields {
+				t.Errorf("Expected the invalid fields %s, got %s", tt.fields, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestPutSensorReading(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		ifMatch  string
+		expected int
+	}{
+		{"current version", "1", `"1"`, http.StatusOK},
+		{"stale version", "1", `"3"`, http.StatusPreconditionFailed},
+		{"not found", "2", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"device_name": "boiler", "date_time": "2024-12-22T12:00:00Z", "values": {"temperature": 61.5}}`
+			req, err := http.NewRequest("PUT", "/sensors/ds18b20/"+tt.id, strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetPathValue("type", "ds18b20")
+			req.SetPathValue("id", tt.id)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			data.PutSensorReadingHandler(rr, req, log.Default(), &sensor.MockSensorServiceSuccessful{})
+
+			if status := rr.Code; status != tt.expected {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expected)
+			}
+			if tt.expected == http.StatusOK && rr.Header().Get("ETag") != `"2"` {
+				t.Errorf("Expected the ETag of version 2, got %q", rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestDeleteSensorReading(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  sensor.SensorService
+		id       string
+		expected int
+	}{
+		{"deleted", &sensor.MockSensorServiceSuccessful{}, "1", http.StatusNoContent},
+		{"not found", &sensor.MockSensorServiceSuccessful{}, "2", http.StatusNotFound},
+		{"error", &sensor.MockSensorServiceError{}, "1", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("DELETE", "/sensors/scd30/"+tt.id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetPathValue("type", "scd30")
+			req.SetPathValue("id", tt.id)
+			rr := httptest.NewRecorder()
+			data.DeleteSensorReadingHandler(rr, req, log.Default(), tt.service)
+
+			if status := rr.Code; status != tt.expected {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"time"
+)
+
+// * The stats method returns the inventory in numbers: records per type and device with their price totals and averages, and records per month *
+// * Prices are in minor units and summed per currency, they are not converted *
+// * curl -X GET http://127.0.0.1:8080/data/stats -i -u admin:password -H "Content-Type: application/json"
+func StatsHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// * The numbers only change with the records, so they share the validators of the collection
+	marker, err := ds.ChangeMarker(ctx)
+	if err != nil {
+		logger.Println("Could not read data changes:", err)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+	v := collectionValidators(marker, r)
+	if v.notModified(r) {
+		v.writeNotModified(w)
+		return
+	}
+
+	stats, err := ds.Stats(ctx)
+	if err != nil {
+		logger.Println("Could not get data statistics:", err)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+
+	v.set(w)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logger.Println("Error encoding data statistics:", err, stats)
+		http.Error(w, "Internal Server error.", http.StatusInternalServerError)
+		return
+	}
+}
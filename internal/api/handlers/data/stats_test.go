@@ -0,0 +1,80 @@
+package data_test
+
+import (
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatsSuccessful(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.StatsHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var stats models.DataStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 2 || len(stats.ByType) != 2 || stats.ByType[1].Prices[0].Total != 52300 || stats.ByMonth[0].Month != "2021-01" {
+		t.Errorf("handler returned unexpected stats: got %+v", stats)
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.StatsHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expected := `{"total":0,"by_type":[],"by_device":[],"by_month":[]}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestStatsIfNoneMatch(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.StatsHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	data.StatsHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusNotModified {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotModified)
+	}
+}
+
+func TestStatsError(t *testing.T) {
+	req, err := http.NewRequest("GET", "/data/stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	data.StatsHandler(rr, req, log.Default(), &service.MockDataServiceError{})
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,107 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// * tagsRequest is the body of POST and DELETE /data/{id}/tags *
+type tagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// * Tags group records by project, location or anything else the type can not express *
+// * Adding a tag the record already has changes nothing, the record is returned with all of its tags *
+// * curl -X POST http://127.0.0.1:8080/data/1/tags -i -u admin:password -H "Content-Type: application/json" -d '{"tags": ["project:apollo", "location:lab-2"]}'
+func PostTagsHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	tagsHandler(w, r, logger, ds, ds.AddTags)
+}
+
+// * Removing a tag the record does not have changes nothing, the tags can also be sent as ?tag=a&tag=b *
+// * curl -X DELETE http://127.0.0.1:8080/data/1/tags -i -u admin:password -H "Content-Type: application/json" -d '{"tags": ["location:lab-2"]}'
+func DeleteTagsHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	tagsHandler(w, r, logger, ds, ds.RemoveTags)
+}
+
+func tagsHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService, change func(id int, tags []string, version int, ctx context.Context) (int64, error)) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		// * This is a User Error: format of id is invalid, response in JSON and with a 400 status code
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	// * The tags are part of the record, a change can be made conditional with If-Match like any other
+	version, err := ifMatchVersion(r, dataVersion(ds, id))
+	if err != nil {
+		writeIfMatchError(w, logger, err)
+		return
+	}
+
+	var body tagsRequest
+	if tags := r.URL.Query()["tag"]; r.Method == http.MethodDelete && len(tags) > 0 {
+		body.Tags = tags
+	} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid request data. Please check your input."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	changed, err := change(id, body.Tags, version, ctx)
+	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			writeVersionMismatch(w)
+			return
+		}
+		switch err := err.(type) {
+		case service.DataError:
+			// * The tags are not valid, response in JSON and with a 400 status code
+			w.WriteHeader(http.StatusBadRequest)
+			errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+			w.Write(errJSON)
+			return
+		default:
+			logger.Println("Could not change tags:", err, id, body.Tags)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+	}
+	if changed == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	data, err := ds.ReadOne(id, ctx)
+	if err != nil {
+		logger.Println("Could not read tagged data:", err, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		// * The record was deleted in the meantime
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found."}`))
+		return
+	}
+
+	// * Return the record with its tags and new ETag with a 200 OK status code
+	itemValidators(data.Version, data.UpdatedAt).set(w)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Println("Error encoding data:", err, data)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,145 @@
+package data_test
+
+import (
+	"context"
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostTagsSuccessful(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/tags", strings.NewReader(`{"tags": ["project:apollo"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.PostTagsHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if etag := rr.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("handler returned wrong ETag: got %v want %v", etag, `"1"`)
+	}
+
+	var tagged models.Data
+	if err := json.NewDecoder(rr.Body).Decode(&tagged); err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged.Tags) != 1 || tagged.Tags[0] != "project:apollo" {
+		t.Errorf("handler returned unexpected tags: got %v", tagged.Tags)
+	}
+}
+
+func TestPostTagsInvalidBody(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/tags", strings.NewReader(`{"tags": "project:apollo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.PostTagsHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestPostTagsNotFound(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/tags", strings.NewReader(`{"tags": ["project:apollo"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.PostTagsHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestPostTagsDataError(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/tags", strings.NewReader(`{"tags": ["project:apollo"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.PostTagsHandler(rr, req, log.Default(), &service.MockDataServiceError{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	expected := `{"error":"Error tagging data."}`
+	if strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestDeleteTagsFromQuery(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/data/1/tags?tag=project:apollo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.DeleteTagsHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestDeleteTagsVersionMismatch(t *testing.T) {
+	req, err := http.NewRequest("DELETE", "/data/1/tags", strings.NewReader(`{"tags": ["project:apollo"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	req.Header.Set("If-Match", `"1"`)
+	rr := httptest.NewRecorder()
+
+	data.DeleteTagsHandler(rr, req, log.Default(), &staleTagsService{})
+	if status := rr.Code; status != http.StatusPreconditionFailed {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusPreconditionFailed)
+	}
+}
+
+// * staleTagsService fails every change of tags because the record was changed by someone else *
+type staleTagsService struct {
+	service.MockDataServiceSuccessful
+}
+
+func (s *staleTagsService) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return 0, models.ErrVersionMismatch
+}
+
+func TestGetHandlerTagFilter(t *testing.T) {
+	mockDataService := &service.MockDataServiceSuccessful{}
+	for query, status := range map[string]int{
+		"tag=project:apollo&tag=location:lab-2":              http.StatusOK,
+		"tag=project:apollo&tag_match=any":                   http.StatusOK,
+		"tag=project:apollo&tag_match=none":                  http.StatusBadRequest,
+		"tag=two%20words":                                    http.StatusBadRequest,
+		"tag=project:apollo&cursor=":                         http.StatusBadRequest,
+		"tag=project:apollo&filter=price>100&fields=id,tags": http.StatusBadRequest,
+	} {
+		req, err := http.NewRequest("GET", "/data?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		data.GetHandler(rr, req, log.Default(), mockDataService)
+		if rr.Code != status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, rr.Code, status)
+		}
+	}
+}
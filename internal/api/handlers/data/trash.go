@@ -0,0 +1,245 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"goapi/internal/api/service/dht22"
+	"goapi/internal/api/service/sensor"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// * The trash lists deleted resources, most recently deleted first, they can be restored until they are purged *
+// * ?type=data (default) lists records, ?type=dht22 lists DHT22 readings, ?type=sensors lists the readings of every sensor type *
+// * curl -X GET "http://127.0.0.1:8080/trash?type=data&page=1&per_page=10" -i -u admin:password -H "Content-Type: application/json"
+func TrashHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService, dht22Service dht22.DHT22Service, ss sensor.SensorService) {
+	page, perPage, err := parsePagination(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(errJSON)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	var response any
+	switch r.URL.Query().Get("type") {
+	case "", "data":
+		trash, total, err := readDataTrash(ds, page, perPage, ctx)
+		if err != nil {
+			logger.Println("Could not read data trash:", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		p := models.NewPage(trash, page, perPage, total)
+		setLinkHeader(w, r, p.Meta)
+		response = p
+	case "dht22":
+		trash, total, err := readDHT22Trash(dht22Service, page, perPage, ctx)
+		if err != nil {
+			logger.Println("Could not read DHT22 trash:", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		p := models.NewPage(trash, page, perPage, total)
+		setLinkHeader(w, r, p.Meta)
+		response = p
+	case "sensors":
+		trash, total, err := readSensorTrash(ss, page, perPage, ctx)
+		if err != nil {
+			logger.Println("Could not read sensor trash:", err)
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		p := models.NewPage(trash, page, perPage, total)
+		setLinkHeader(w, r, p.Meta)
+		response = p
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid type specified, it must be data, dht22 or sensors."}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Println("Error encoding trash:", err, response)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+func readDataTrash(ds service.DataService, page int, perPage int, ctx context.Context) ([]*models.TrashedData, int, error) {
+	trash, err := ds.ReadTrash(page, perPage, ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := ds.CountTrash(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if trash == nil {
+		trash = []*models.TrashedData{}
+	}
+	return trash, total, nil
+}
+
+func readDHT22Trash(dht22Service dht22.DHT22Service, page int, perPage int, ctx context.Context) ([]*models.TrashedDHT22Data, int, error) {
+	trash, err := dht22Service.ReadTrash(page, perPage, ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := dht22Service.CountTrash(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if trash == nil {
+		trash = []*models.TrashedDHT22Data{}
+	}
+	return trash, total, nil
+}
+
+func readSensorTrash(ss sensor.SensorService, page int, perPage int, ctx context.Context) ([]*models.TrashedSensorReading, int, error) {
+	trash, err := ss.ReadTrash(page, perPage, ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := ss.CountTrash(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if trash == nil {
+		trash = []*models.TrashedSensorReading{}
+	}
+	return trash, total, nil
+}
+
+// * Restoring takes a deleted resource out of the trash, it is then returned by every read again *
+// * curl -X POST http://127.0.0.1:8080/data/1/restore -i -u admin:password -H "Content-Type: application/json"
+func RestoreHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ds service.DataService) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		// * This is a User Error: format of id is invalid, response in JSON and with a 400 status code
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	restored, err := ds.Restore(id, ctx)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateSerialNumber) {
+			// * Another record got the serial number while this one was in the trash
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "Serial number is already used by another record."}`))
+			return
+		}
+		logger.Println("Could not restore data:", err, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	if restored == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found in the trash."}`))
+		return
+	}
+
+	data, err := ds.ReadOne(id, ctx)
+	if err != nil {
+		logger.Println("Could not read restored data:", err, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Println("Error encoding data:", err, data)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// * curl -X POST http://127.0.0.1:8080/dht22/1/restore -i -u admin:password -H "Content-Type: application/json"
+func RestoreDHT22Handler(w http.ResponseWriter, r *http.Request, logger *log.Logger, dht22Service dht22.DHT22Service) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := dht22Service.Restore(id, r.Context())
+	if err != nil {
+		logger.Println("Could not restore DHT22 data:", err, id)
+		http.Error(w, "Failed to restore DHT22 data", http.StatusInternalServerError)
+		return
+	}
+	if restored == 0 {
+		http.Error(w, "DHT22 data not found in the trash", http.StatusNotFound)
+		return
+	}
+
+	data, err := dht22Service.ReadOne(id, r.Context())
+	if err != nil {
+		logger.Println("Could not read restored DHT22 data:", err, id)
+		http.Error(w, "Failed to fetch DHT22 data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// * curl -X POST http://127.0.0.1:8080/sensors/scd30/1/restore -i -u admin:password -H "Content-Type: application/json"
+func RestoreSensorReadingHandler(w http.ResponseWriter, r *http.Request, logger *log.Logger, ss sensor.SensorService) {
+	sensorType := r.PathValue("type")
+	if ss.Type(sensorType) == nil {
+		writeUnknownSensorType(w)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Missconfigured ID."}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	restored, err := ss.Restore(sensorType, id, ctx)
+	if err != nil {
+		logger.Println("Could not restore sensor reading:", err, sensorType, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	if restored == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Resource not found in the trash."}`))
+		return
+	}
+
+	reading, err := ss.ReadOne(sensorType, id, ctx)
+	if err != nil {
+		logger.Println("Could not read restored sensor reading:", err, sensorType, id)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(reading); err != nil {
+		logger.Println("Error encoding sensor reading:", err, reading)
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,242 @@
+package data_test
+
+import (
+	"context"
+	"encoding/json"
+	"goapi/internal/api/handlers/data"
+	"goapi/internal/api/repository/models"
+	service "goapi/internal/api/service/data"
+	"goapi/internal/api/service/dht22"
+	"goapi/internal/api/service/sensor"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrashData(t *testing.T) {
+	req, err := http.NewRequest("GET", "/trash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.TrashHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{}, &dht22.MockDHT22ServiceSuccessful{}, &sensor.MockSensorServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var page models.Page[models.TrashedData]
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 1 || page.Data[0].ID != 1 || page.Data[0].DeletedBy != "prakash" || page.Meta.Total != 1 {
+		t.Errorf("handler returned unexpected trash: got %+v", page)
+	}
+}
+
+func TestTrashDHT22(t *testing.T) {
+	req, err := http.NewRequest("GET", "/trash?type=dht22", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.TrashHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{}, &dht22.MockDHT22ServiceSuccessful{}, &sensor.MockSensorServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var page models.Page[models.TrashedDHT22Data]
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 1 || page.Data[0].DeviceName != "DHT22 Sensor" || page.Data[0].DeletedAt == "" {
+		t.Errorf("handler returned unexpected trash: got %+v", page)
+	}
+}
+
+func TestTrashSensors(t *testing.T) {
+	req, err := http.NewRequest("GET", "/trash?type=sensors", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.TrashHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{}, &dht22.MockDHT22ServiceSuccessful{}, &sensor.MockSensorServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var page models.Page[models.TrashedSensorReading]
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 1 || page.Data[0].Type != "ds18b20" || page.Data[0].DeletedAt == "" || page.Meta.Total != 1 {
+		t.Errorf("handler returned unexpected trash: got %+v", page)
+	}
+}
+
+func TestTrashEmpty(t *testing.T) {
+	req, err := http.NewRequest("GET", "/trash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.TrashHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{}, &dht22.MockDHT22ServiceNotFound{}, &sensor.MockSensorServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var page models.Page[models.TrashedData]
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Data == nil || len(page.Data) != 0 {
+		t.Errorf("handler returned unexpected trash: got %+v", page)
+	}
+}
+
+func TestTrashInvalidType(t *testing.T) {
+	req, err := http.NewRequest("GET", "/trash?type=attachments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.TrashHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{}, &dht22.MockDHT22ServiceSuccessful{}, &sensor.MockSensorServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestTrashError(t *testing.T) {
+	req, err := http.NewRequest("GET", "/trash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	data.TrashHandler(rr, req, log.Default(), &service.MockDataServiceError{}, &dht22.MockDHT22ServiceError{}, &sensor.MockSensorServiceError{})
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	}
+}
+
+func TestRestoreSuccessful(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/restore", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.RestoreHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestRestoreNotInTrash(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/restore", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.RestoreHandler(rr, req, log.Default(), &service.MockDataServiceNotFound{})
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestRestoreInvalidID(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/invalid/restore", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "invalid") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.RestoreHandler(rr, req, log.Default(), &service.MockDataServiceSuccessful{})
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+// * restoreConflictService fails the restore because the serial number was reused *
+type restoreConflictService struct {
+	service.MockDataServiceSuccessful
+}
+
+func (s *restoreConflictService) Restore(id int, ctx context.Context) (int64, error) {
+	return 0, models.ErrDuplicateSerialNumber
+}
+
+func TestRestoreDuplicateSerialNumber(t *testing.T) {
+	req, err := http.NewRequest("POST", "/data/1/restore", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.RestoreHandler(rr, req, log.Default(), &restoreConflictService{})
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+	}
+}
+
+func TestRestoreDHT22(t *testing.T) {
+	req, err := http.NewRequest("POST", "/dht22/1/restore", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetPathValue("id", "1") // * Required for routing *
+	rr := httptest.NewRecorder()
+
+	data.RestoreDHT22Handler(rr, req, log.Default(), &dht22.MockDHT22ServiceSuccessful{})
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	rr = httptest.NewRecorder()
+	data.RestoreDHT22Handler(rr, req, log.Default(), &dht22.MockDHT22ServiceNotFound{})
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestRestoreSensorReading(t *testing.T) {
+	tests := []struct {
+		name       string
+		service    sensor.SensorService
+		sensorType string
+		id         string
+		expected   int
+	}{
+		{"restored", &sensor.MockSensorServiceSuccessful{}, "scd30", "1", http.StatusOK},
+		{"not in the trash", &sensor.MockSensorServiceSuccessful{}, "scd30", "2", http.StatusNotFound},
+		{"unknown type", &sensor.MockSensorServiceSuccessful{}, "unknown", "1", http.StatusNotFound},
+		{"invalid id", &sensor.MockSensorServiceSuccessful{}, "scd30", "invalid", http.StatusBadRequest},
+		{"error", &sensor.MockSensorServiceError{}, "scd30", "1", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/sensors/"+tt.sensorType+"/"+tt.id+"/restore", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetPathValue("type", tt.sensorType)
+			req.SetPathValue("id", tt.id)
+			rr := httptest.NewRecorder()
+			data.RestoreSensorReadingHandler(rr, req, log.Default(), tt.service)
+
+			if status := rr.Code; status != tt.expected {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package data
+
+import (
+	"encoding/json"
+	service "goapi/internal/api/service/data"
+	"net/http"
+)
+
+// * writeValidationError responds with every invalid field of a record, e.g. *
+// * {"error": "Invalid data.", "fields": [{"field": "attributes.voltage", "message": "is required"}]} *
+func writeValidationError(w http.ResponseWriter, err service.ValidationError) {
+	w.WriteHeader(http.StatusBadRequest)
+	errJSON, _ := json.Marshal(map[string]any{
+		"error":  "Invalid data.",
+		"fields": err.Errors,
+	})
+	w.Write(errJSON)
+}
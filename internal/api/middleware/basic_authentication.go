@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"encoding/base64"
+	"goapi/internal/api/repository/models"
 	"net/http"
 	"strings"
 )
@@ -56,6 +57,9 @@ func BasicAuthenticationMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// * Remember who is making the request, e.g. for the audit trail of changes
+		r = r.WithContext(models.WithActor(r.Context(), username))
+
 		// Call the next handler in the chain
 		next.ServeHTTP(w, r)
 	})
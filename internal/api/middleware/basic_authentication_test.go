@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"encoding/base64"
+	"goapi/internal/api/repository/models"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -132,3 +134,23 @@ func TestBasicAuthInvalidCredentials(t *testing.T) {
 	}
 
 }
+
+// * Test: Valid credentials make the user available to the handler as the actor of the request
+func TestBasicAuthSetsActor(t *testing.T) {
+
+	req := httptest.NewRequest(http.MethodGet, "/data/0", nil)
+	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("prakash:12345678")))
+
+	rr := httptest.NewRecorder()
+
+	var actor string
+	handler := BasicAuthenticationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = models.ActorFromContext(r.Context())
+	}),
+	)
+	handler.ServeHTTP(rr, req)
+
+	if actor != "prakash" {
+		t.Errorf("Expected actor prakash, got %s", actor)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -14,27 +15,59 @@ func ChainMiddleware(h http.Handler, middlewares ...Middleware) http.Handler {
 	return h
 }
 
+// * ContentTypeRules are the request Content-Types of the routes that do not take JSON, keyed by ServeMux pattern *
+// * An empty list allows any Content-Type or none, e.g. for downloads *
+type ContentTypeRules map[string][]string
+
+// * JSONContentTypes are accepted by every route without a rule, PATCH requests may send a JSON Merge Patch *
+var JSONContentTypes = []string{"application/json", "application/merge-patch+json"}
+
+// * ExposedHeaders are the response headers browsers let scripts read, besides the few they always do: *
+// * conditional requests, pagination, created resources and when to retry *
+const ExposedHeaders = "ETag, Last-Modified, Link, Location, Retry-After"
+
 func CommonMiddleware(next http.Handler) http.Handler {
+	return NewCommonMiddleware(nil)(next)
+}
+
+// * NewCommonMiddleware with the Content-Type rules of the routes, the other routes only take JSON *
+func NewCommonMiddleware(rules ContentTypeRules) Middleware {
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	// * The routes are matched like the API's ServeMux does, a rule applies to exactly the requests of its route
+	routes := http.NewServeMux()
+	for pattern := range rules {
+		routes.Handle(pattern, http.NotFoundHandler())
+	}
 
-		// * If type is Option return, no need for authentication or content type validation *
-		if r.Method == http.MethodOptions {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			// * If type is Option return, no need for authentication or content type validation *
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed := JSONContentTypes
+			if _, pattern := routes.Handler(r); pattern != "" {
+				allowed = rules[pattern]
+			}
+
+			// * The request body should be JSON, and the Content-Type header must start with: application/json *
+			contentType := r.Header.Get("Content-Type")
+			if len(allowed) > 0 && !slices.ContainsFunc(allowed, func(prefix string) bool { return strings.HasPrefix(contentType, prefix) }) {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				w.Write([]byte(`{"error": "Content-Type header should be set to: ` + allowed[0] + `."}`))
+				return
+			}
+
+			// * Set the Content-Type header of the response to application/json for all responses
+			// * On http.Error("..."), the Content-Type header will be set to text/plain; charset=utf-8
+			// * Handlers of files set their own Content-Type
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Expose-Headers", ExposedHeaders)
 			next.ServeHTTP(w, r)
-			return
-		}
-
-		// * The request body should be JSON, and the Content-Type header must start with: application/json *
-		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-			w.WriteHeader(http.StatusUnsupportedMediaType)
-			w.Write([]byte(`{"error": "Content-Type header should be set to: application/json."}`))
-			return
-		}
-
-		// * Set the Content-Type header of the response to application/json for all responses
-		// * On http.Error("..."), the Content-Type header will be set to text/plain; charset=utf-8
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		next.ServeHTTP(w, r)
-	})
+		})
+	}
 }
@@ -51,4 +51,74 @@ func TestCommonCorrectContentType(t *testing.T) {
 	if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
 		t.Fatalf("Expected Access-Control-Allow-Origin: *, got: %s", rr.Header().Get("Access-Control-Allow-Origin"))
 	}
+	if rr.Header().Get("Access-Control-Expose-Headers") != "ETag, Last-Modified, Link, Location, Retry-After" {
+		t.Fatalf("Expected the ETag, Last-Modified, Link, Location and Retry-After headers to be exposed, got: %s", rr.Header().Get("Access-Control-Expose-Headers"))
+	}
+}
+
+func TestCommonMergePatchContentType(t *testing.T) {
+
+	req, err := http.NewRequest("PATCH", "/data/0", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rr := httptest.NewRecorder()
+
+	handler := CommonMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code == http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected application/merge-patch+json to be accepted, got: %d", rr.Code)
+	}
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected Content-Type: application/json, got: %s", rr.Header().Get("Content-Type"))
+	}
+}
+
+func TestCommonContentTypeRules(t *testing.T) {
+
+	handler := NewCommonMiddleware(ContentTypeRules{
+		"POST /data/{id}/attachments":             {"multipart/form-data"},
+		"GET /data/{id}/attachments/{attachment}": {},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		method      string
+		target      string
+		contentType string
+		status      int
+	}{
+		{"POST", "/data/1/attachments", "multipart/form-data; boundary=x", http.StatusOK},
+		{"POST", "/data/1/attachments", "application/json", http.StatusUnsupportedMediaType},
+		{"GET", "/data/1/attachments/2", "", http.StatusOK},
+		{"POST", "/data", "multipart/form-data; boundary=x", http.StatusUnsupportedMediaType},
+		{"POST", "/data", "application/json", http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.target, nil)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != test.status {
+			t.Errorf("%s %s with %q: expected status code %d, got: %d", test.method, test.target, test.contentType, test.status, rr.Code)
+		}
+	}
+
+	// * The error names the Content-Type of the route
+	req, _ := http.NewRequest("POST", "/data/1/attachments", nil)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	expected := `{"error": "Content-Type header should be set to: multipart/form-data."}`
+	if rr.Body.String() != expected {
+		t.Fatalf("Expected response body: %s, got: %s", expected, rr.Body.String())
+	}
 }
@@ -0,0 +1,223 @@
+package PostgreSQL
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"io"
+	"io/fs"
+	"time"
+)
+
+type AttachmentRepository struct {
+	sqlDB *sql.DB
+	createStmt,
+	readStmt,
+	readAllStmt *sql.Stmt
+	ctx context.Context
+}
+
+// attachmentColumns are the columns of an attachment in the order they are scanned.
+var attachmentColumns = "id, data_id, file_name, content_type, size, sha256, blob_key, " + timestamp("created_at") + ", created_by"
+
+// NewAttachmentRepository initializes the metadata of the files attached to records, the files themselves are in a blob store.
+func NewAttachmentRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.AttachmentRepository, error) {
+
+	repo := &AttachmentRepository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	// * Attachments are kept while their record is in the trash, they are deleted with the orphans when it is purged
+	if err := createSchema(repo.sqlDB,
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			data_id BIGINT NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			content_type VARCHAR(100) NOT NULL,
+			size BIGINT NOT NULL,
+			sha256 CHAR(64) NOT NULL,
+			blob_key VARCHAR(64) NOT NULL UNIQUE,
+			created_at TIMESTAMPTZ NOT NULL,
+			created_by VARCHAR(50) NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_attachments_data_id ON attachments (data_id, id)`,
+	); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO attachments (data_id, file_name, content_type, size, sha256, blob_key, created_at, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.createStmt = createStmt
+
+	readStmt, err := repo.sqlDB.Prepare("SELECT " + attachmentColumns + " FROM attachments WHERE data_id = $1 AND id = $2")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readStmt = readStmt
+
+	readAllStmt, err := repo.sqlDB.Prepare("SELECT " + attachmentColumns + " FROM attachments WHERE data_id = $1 ORDER BY id")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAllStmt = readAllStmt
+
+	go CloseAttachments(ctx, repo)
+
+	return repo, nil
+}
+
+func CloseAttachments(ctx context.Context, r *AttachmentRepository) {
+	<-ctx.Done()
+	r.createStmt.Close()
+	r.readStmt.Close()
+	r.readAllStmt.Close()
+	r.sqlDB.Close()
+}
+
+// Create stores the metadata of an attachment whose content is already in the blob store.
+func (r *AttachmentRepository) Create(attachment *models.Attachment, ctx context.Context) error {
+	attachment.CreatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	attachment.CreatedBy = models.ActorFromContext(ctx)
+	return DAL.Stmt(ctx, r.sqlDB, r.createStmt).QueryRowContext(ctx, attachment.DataID, attachment.FileName, attachment.ContentType, attachment.Size,
+		attachment.SHA256, attachment.BlobKey, attachment.CreatedAt, attachment.CreatedBy).Scan(&attachment.ID)
+}
+
+// ReadOne returns an attachment of the record, nil if the record has no attachment with the id.
+func (r *AttachmentRepository) ReadOne(dataID int, id int, ctx context.Context) (*models.Attachment, error) {
+	attachment, err := scanAttachment(DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, dataID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return attachment, err
+}
+
+func (r *AttachmentRepository) ReadAll(dataID int, ctx context.Context) ([]*models.Attachment, error) {
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readAllStmt).QueryContext(ctx, dataID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// Delete removes the metadata of an attachment and returns it, so its blob can be deleted. nil if there is none.
+func (r *AttachmentRepository) Delete(dataID int, id int, ctx context.Context) (*models.Attachment, error) {
+	attachment, err := scanAttachment(DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `DELETE FROM attachments WHERE data_id = $1 AND id = $2
+		RETURNING `+attachmentColumns, dataID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return attachment, err
+}
+
+// DeleteOrphans removes the metadata of the attachments of purged records and returns them, so their blobs can be deleted.
+func (r *AttachmentRepository) DeleteOrphans(ctx context.Context) ([]*models.Attachment, error) {
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `DELETE FROM attachments a WHERE NOT EXISTS (SELECT 1 FROM data d WHERE d.id = a.data_id)
+		RETURNING `+attachmentColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func scanAttachment(row scanner) (*models.Attachment, error) {
+	var a models.Attachment
+	if err := row.Scan(&a.ID, &a.DataID, &a.FileName, &a.ContentType, &a.Size, &a.SHA256, &a.BlobKey, &a.CreatedAt, &a.CreatedBy); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// BlobStore keeps blobs in the database, so every server that shares it can read the files attached through the others.
+type BlobStore struct {
+	sqlDB *sql.DB
+	ctx   context.Context
+}
+
+// NewBlobStore initializes the table of the blobs, the content of an attachment is read into memory to be stored.
+func NewBlobStore(sqlDB DAL.SQLDatabase, ctx context.Context) (*BlobStore, error) {
+	store := &BlobStore{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+	if err := createSchema(store.sqlDB,
+		`CREATE TABLE IF NOT EXISTS attachment_blobs (
+			blob_key VARCHAR(64) PRIMARY KEY,
+			content BYTEA NOT NULL
+		)`,
+	); err != nil {
+		store.sqlDB.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *BlobStore) Create(key string, content io.Reader) (int64, string, error) {
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := s.sqlDB.ExecContext(s.ctx, `INSERT INTO attachment_blobs (blob_key, content) VALUES ($1, $2)
+		ON CONFLICT (blob_key) DO UPDATE SET content = EXCLUDED.content`, key, b); err != nil {
+		return 0, "", err
+	}
+	sum := sha256.Sum256(b)
+	return int64(len(b)), hex.EncodeToString(sum[:]), nil
+}
+
+// Open returns a reader of the blob, like os.Open it fails with fs.ErrNotExist for unknown keys.
+func (s *BlobStore) Open(key string) (io.ReadSeekCloser, error) {
+	var b []byte
+	err := s.sqlDB.QueryRowContext(s.ctx, `SELECT content FROM attachment_blobs WHERE blob_key = $1`, key).Scan(&b)
+	if err == sql.ErrNoRows {
+		return nil, &fs.PathError{Op: "open", Path: key, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(b)}, nil
+}
+
+// Delete removes a blob, a blob that does not exist is already deleted.
+func (s *BlobStore) Delete(key string) error {
+	_, err := s.sqlDB.ExecContext(s.ctx, `DELETE FROM attachment_blobs WHERE blob_key = $1`, key)
+	return err
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+)
+
+// changeMarkerFormat is the time of a change with milliseconds, like SQLite writes it in a trigger.
+const changeMarkerFormat = `'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"'`
+
+// watchChanges keeps the change marker of a collection, its version goes up with every insert, update and
+// delete of the tables the collection is read from. The triggers also see changes that bypass the repositories.
+func watchChanges(sqlDB *sql.DB, collection string, tables ...string) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS change_markers (
+			collection VARCHAR(20) PRIMARY KEY,
+			version BIGINT NOT NULL,
+			changed_at TIMESTAMPTZ NOT NULL
+		)`,
+		`INSERT INTO change_markers (collection, version, changed_at) VALUES ('` + collection + `', 1, now()) ON CONFLICT (collection) DO NOTHING`,
+		// * The collection is the argument of the trigger
+		`CREATE OR REPLACE FUNCTION change_marker_bump() RETURNS trigger LANGUAGE plpgsql AS $$
+		BEGIN
+			UPDATE change_markers SET version = version + 1, changed_at = clock_timestamp() WHERE collection = TG_ARGV[0];
+			RETURN NULL;
+		END
+		$$`,
+	}
+	for _, table := range tables {
+		statements = append(statements, `CREATE OR REPLACE TRIGGER `+table+`_changes_`+collection+` AFTER INSERT OR UPDATE OR DELETE ON `+table+`
+			FOR EACH ROW EXECUTE FUNCTION change_marker_bump('`+collection+`')`)
+	}
+	return createSchema(sqlDB, statements...)
+}
+
+// readChangeMarker returns the change marker of a collection.
+func readChangeMarker(sqlDB DAL.Querier, collection string, ctx context.Context) (*models.ChangeMarker, error) {
+	var marker models.ChangeMarker
+	err := sqlDB.QueryRowContext(ctx, `SELECT version, to_char(changed_at AT TIME ZONE 'UTC', `+changeMarkerFormat+`) FROM change_markers WHERE collection = $1`, collection).Scan(&marker.Version, &marker.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+func (r *DataRepository) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return readChangeMarker(DAL.Conn(ctx, r.sqlDB), "data", ctx)
+}
+
+func (r *DHT22Repository) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return readChangeMarker(DAL.Conn(ctx, r.sqlDB), "dht22", ctx)
+}
@@ -0,0 +1,237 @@
+package PostgreSQL_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/PostgreSQL"
+	"goapi/internal/api/repository/DAL/contract"
+	"goapi/internal/api/repository/models"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testDSN is the server the tests run against: POSTGRES_TEST_DSN, or a server started for the tests from
+// the initdb and postgres binaries on the PATH. The tests are skipped when there is neither.
+var testDSN string
+
+func TestMain(m *testing.M) {
+	testDSN = os.Getenv("POSTGRES_TEST_DSN")
+	var stop func()
+	if testDSN == "" {
+		var err error
+		testDSN, stop, err = startPostgres()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "PostgreSQL tests skipped:", err)
+		}
+	}
+	code := m.Run()
+	if stop != nil {
+		stop()
+	}
+	os.Exit(code)
+}
+
+// startPostgres starts a throwaway server in a temporary directory, it listens on a unix socket only.
+func startPostgres() (string, func(), error) {
+	initdb, err := findBinary("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+	postgres, err := findBinary("postgres")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "goapi-postgres")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust", "--no-sync").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %v: %s", err, out)
+	}
+	server := exec.Command(postgres, "-D", data, "-k", dir, "-c", "listen_addresses=", "-c", "fsync=off")
+	if err := server.Start(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	stop := func() {
+		server.Process.Signal(os.Interrupt)
+		server.Wait()
+		os.RemoveAll(dir)
+	}
+
+	dsn := "host=" + dir + " user=postgres dbname=postgres sslmode=disable"
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		stop()
+		return "", nil, err
+	}
+	defer db.Close()
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		if err = db.Ping(); err == nil {
+			return dsn, stop, nil
+		}
+		if time.Since(start) > 30*time.Second {
+			stop()
+			return "", nil, fmt.Errorf("server did not start: %v", err)
+		}
+	}
+}
+
+// findBinary looks for a PostgreSQL binary on the PATH and where Debian and Ubuntu install it.
+func findBinary(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/" + name)
+	if len(matches) > 0 {
+		return matches[len(matches)-1], nil
+	}
+	return "", fmt.Errorf("%s not found, set POSTGRES_TEST_DSN to run them against a server", name)
+}
+
+var schemas atomic.Int64
+
+// openDatabase returns a connection whose tables are in a schema of their own, it is dropped when the test ends.
+func openDatabase(t *testing.T) (*PostgreSQL.PostgreSQL, context.Context) {
+	t.Helper()
+	if testDSN == "" {
+		t.Skip("no PostgreSQL server, set POSTGRES_TEST_DSN")
+	}
+
+	schema := fmt.Sprintf("contract_%d_%d", os.Getpid(), schemas.Add(1))
+	admin, err := sql.Open("postgres", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	db, err := PostgreSQL.NewPostgreSQL(withSearchPath(testDSN, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db.(*PostgreSQL.PostgreSQL), ctx
+}
+
+// withSearchPath sets the schema of the connections, in a URL or a key=value connection string.
+func withSearchPath(dsn string, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+func TestDataRepositoryContract(t *testing.T) {
+	contract.TestDataRepository(t, func(t *testing.T) models.DataRepository {
+		db, ctx := openDatabase(t)
+		repo, err := PostgreSQL.NewDataRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+func TestDHT22RepositoryContract(t *testing.T) {
+	contract.TestDHT22Repository(t, func(t *testing.T) models.DHT22Repository {
+		db, ctx := openDatabase(t)
+		repo, err := PostgreSQL.NewDHT22Repository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+func TestUnitOfWorkContract(t *testing.T) {
+	contract.TestUnitOfWork(t, func(t *testing.T) contract.UnitOfWorkRepositories {
+		db, ctx := openDatabase(t)
+		data, err := PostgreSQL.NewDataRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dht22, err := PostgreSQL.NewDHT22Repository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contract.UnitOfWorkRepositories{Unit: DAL.NewUnitOfWork(db), Data: data, DHT22: dht22}
+	})
+}
+
+func TestDHT22ArchiveRepositoryContract(t *testing.T) {
+	contract.TestDHT22ArchiveRepository(t, func(t *testing.T) contract.DHT22ArchiveRepositories {
+		db, ctx := openDatabase(t)
+		dht22, err := PostgreSQL.NewDHT22Repository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive, err := PostgreSQL.NewDHT22ArchiveRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contract.DHT22ArchiveRepositories{Archive: archive, DHT22: dht22}
+	})
+}
+
+func TestSensorRepositoryContract(t *testing.T) {
+	contract.TestSensorRepository(t, func(t *testing.T) models.SensorRepository {
+		db, ctx := openDatabase(t)
+		repo, err := PostgreSQL.NewSensorRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+func TestDataTrashContract(t *testing.T) {
+	contract.TestDataTrash(t, func(t *testing.T) contract.DataTrashRepositories {
+		db, ctx := openDatabase(t)
+		data, err := PostgreSQL.NewDataRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attachments, err := PostgreSQL.NewAttachmentRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contract.DataTrashRepositories{Data: data, Attachments: attachments}
+	})
+}
+
+func TestBlobStoreContract(t *testing.T) {
+	contract.TestBlobStore(t, func(t *testing.T) models.BlobStore {
+		db, ctx := openDatabase(t)
+		store, err := PostgreSQL.NewBlobStore(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
@@ -0,0 +1,520 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type DataRepository struct {
+	sqlDB *sql.DB
+	createStmt,
+	readStmt,
+	lockStmt,
+	readBySerialStmt,
+	readManyStmt,
+	readFirstStmt,
+	readAfterStmt,
+	countStmt,
+	updateStmt,
+	deleteStmt,
+	historyStmt *sql.Stmt
+	ctx context.Context
+}
+
+const createDataTable = `CREATE TABLE IF NOT EXISTS data (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		device_id VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50) NOT NULL DEFAULT '',
+		price BIGINT NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+		serial_number VARCHAR(50) NOT NULL DEFAULT '',
+		data_type VARCHAR(20) NOT NULL DEFAULT '',
+		date_time TIMESTAMPTZ NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		attributes JSONB NOT NULL DEFAULT '{}',
+		version INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		deleted_at TIMESTAMPTZ,
+		deleted_by VARCHAR(50),
+		search TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', device_name || ' ' || data_type || ' ' || description)) STORED
+	)`
+
+// dataColumns are the columns of a record in the order they are scanned, of the table or its alias.
+func dataColumns(alias string) string {
+	return alias + "id, " + alias + "device_id, " + alias + "device_name, " + alias + "price, " + alias + "currency, " + alias + "serial_number, " +
+		alias + "data_type, " + dateTime(alias+"date_time") + ", " + alias + "description, " + alias + "attributes, " + alias + "version, " +
+		timestamp(alias+"created_at") + ", " + timestamp(alias+"updated_at")
+}
+
+func scanData(row scanner, data *models.Data, extra ...any) error {
+	dest := []any{&data.ID, &data.DeviceID, &data.DeviceName, &data.Price, &data.Currency, &data.SerialNumber, &data.Type, &data.DateTime, &data.Description, &data.Attributes, &data.Version, &data.CreatedAt, &data.UpdatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// * Fields of Data that list endpoints can filter, sort and select on, the columns of models.DataQuerySchema in PostgreSQL
+// * The times are the TIMESTAMPTZ columns, dataQueryTimes reads them as text and compares them like SQLite: date_time>"2024-03" works
+var dataQuerySchema = query.Schema{
+	"id":            {Column: "id", Kind: query.Number},
+	"device_id":     {Column: "device_id", Kind: query.String},
+	"device_name":   {Column: "device_name", Kind: query.String},
+	"price":         {Column: "price", Kind: query.Number},
+	"currency":      {Column: "currency", Kind: query.String},
+	"serial_number": {Column: "serial_number", Kind: query.String},
+	"type":          {Column: "data_type", Kind: query.String},
+	"date_time":     {Column: "date_time", Kind: query.String},
+	"description":   {Column: "description", Kind: query.String},
+	"attributes":    {Column: "attributes::text", Kind: query.String},
+	"version":       {Column: "version", Kind: query.Number},
+	"created_at":    {Column: "created_at", Kind: query.String},
+	"updated_at":    {Column: "updated_at", Kind: query.String},
+}
+
+var dataQueryTimes = queryTimes{"date_time": dateTimeLayout, "created_at": models.HistoryTimeFormat, "updated_at": models.HistoryTimeFormat}
+
+func NewDataRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.DataRepository, error) {
+
+	repo := &DataRepository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	err := createSchema(repo.sqlDB,
+		createDataTable,
+		// * Audit trail of every change to the data table
+		createDataHistoryTable,
+		`CREATE INDEX IF NOT EXISTS idx_data_history_data_id ON data_history (data_id, changed_at)`,
+		// * Serial numbers are unique among the records that are not deleted, records without one are stored with an empty serial number
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_data_serial_number_active ON data (serial_number) WHERE serial_number <> '' AND deleted_at IS NULL`,
+		// * Index used by the keyset (cursor) pagination
+		`CREATE INDEX IF NOT EXISTS idx_data_date_time_id ON data (date_time, id)`,
+		// * Records are mostly added in time order, a BRIN index keeps time range scans cheap at a fraction of the size of a B-tree
+		`CREATE INDEX IF NOT EXISTS idx_data_date_time_brin ON data USING BRIN (date_time)`,
+		// * Full-text search index
+		`CREATE INDEX IF NOT EXISTS idx_data_search ON data USING GIN (search)`,
+	)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Tags of the records, many-to-many
+	if err := createDataTagTables(repo.sqlDB); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * The change marker of GET /data, for conditional requests
+	if err := watchChanges(repo.sqlDB, "data", "data"); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Create needed Prepared SQL statements, this is more efficient than running each query individually
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO data (device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.createStmt = createStmt
+
+	readStmt, err := repo.sqlDB.Prepare("SELECT " + dataColumns("") + " FROM data WHERE id = $1 AND deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readStmt = readStmt
+
+	// * Changes read the record they change with a row lock, so concurrent changes of one record take turns
+	lockStmt, err := repo.sqlDB.Prepare("SELECT " + dataColumns("") + " FROM data WHERE id = $1 AND deleted_at IS NULL FOR UPDATE")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.lockStmt = lockStmt
+
+	readBySerialStmt, err := repo.sqlDB.Prepare("SELECT " + dataColumns("") + " FROM data WHERE serial_number = $1 AND serial_number <> '' AND deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readBySerialStmt = readBySerialStmt
+
+	readManyStmt, err := repo.sqlDB.Prepare("SELECT " + dataColumns("") + " FROM data WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readManyStmt = readManyStmt
+
+	readFirstStmt, err := repo.sqlDB.Prepare("SELECT " + dataColumns("") + " FROM data WHERE deleted_at IS NULL ORDER BY date_time, id LIMIT $1")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readFirstStmt = readFirstStmt
+
+	readAfterStmt, err := repo.sqlDB.Prepare("SELECT " + dataColumns("") + " FROM data WHERE deleted_at IS NULL AND (date_time, id) > ($1::timestamptz, $2) ORDER BY date_time, id LIMIT $3")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAfterStmt = readAfterStmt
+
+	countStmt, err := repo.sqlDB.Prepare("SELECT COUNT(*) FROM data WHERE deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.countStmt = countStmt
+
+	updateStmt, err := repo.sqlDB.Prepare("UPDATE data SET device_id = $1, device_name = $2, price = $3, currency = $4, serial_number = $5, data_type = $6, date_time = $7, description = $8, attributes = $9, version = version + 1, updated_at = $10 WHERE id = $11 AND deleted_at IS NULL AND ($12 = 0 OR version = $12) RETURNING version")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.updateStmt = updateStmt
+
+	deleteStmt, err := repo.sqlDB.Prepare("UPDATE data SET deleted_at = $1, deleted_by = $2, updated_at = $3 WHERE id = $4 AND deleted_at IS NULL AND ($5 = 0 OR version = $5)")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.deleteStmt = deleteStmt
+
+	historyStmt, err := repo.sqlDB.Prepare(`INSERT INTO data_history (data_id, operation, changed_at, changed_by, changed_fields, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.historyStmt = historyStmt
+
+	go Close(ctx, repo)
+
+	return repo, nil
+}
+
+func Close(ctx context.Context, r *DataRepository) {
+
+	<-ctx.Done()
+	r.createStmt.Close()
+	r.readStmt.Close()
+	r.lockStmt.Close()
+	r.readBySerialStmt.Close()
+	r.updateStmt.Close()
+	r.deleteStmt.Close()
+	r.historyStmt.Close()
+	r.readManyStmt.Close()
+	r.readFirstStmt.Close()
+	r.readAfterStmt.Close()
+	r.countStmt.Close()
+	r.sqlDB.Close()
+}
+
+// * Create, Update and Delete write the change and its history entry in one transaction *
+func (r *DataRepository) Create(data *models.Data, ctx context.Context) error {
+
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// * The timestamps are the server's, whatever the client sent, tags are added with AddTags
+	data.Tags = nil
+	data.CreatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	data.UpdatedAt = data.CreatedAt
+	var id int
+	err = tx.StmtContext(ctx, r.createStmt).QueryRowContext(ctx, data.DeviceID, data.DeviceName, data.Price, data.Currency, data.SerialNumber, data.Type, data.DateTime, data.Description, data.Attributes, data.CreatedAt, data.UpdatedAt).Scan(&id)
+	if err != nil {
+		return uniqueSerialNumberError(err)
+	}
+	data.ID = id
+	data.Version = 1
+
+	if err := r.recordHistory(tx, models.OperationCreate, data, models.ChangedDataFields(&models.Data{}, data), ctx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *DataRepository) ReadOne(id int, ctx context.Context) (*models.Data, error) {
+	return r.readOne(DAL.Conn(ctx, r.sqlDB), DAL.Stmt(ctx, r.sqlDB, r.readStmt), id, ctx)
+}
+
+// ReadBySerialNumber returns the record with the exact serial number, nil if there is none.
+func (r *DataRepository) ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error) {
+	var data models.Data
+	err := scanData(DAL.Stmt(ctx, r.sqlDB, r.readBySerialStmt).QueryRowContext(ctx, serialNumber), &data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := readTags(DAL.Conn(ctx, r.sqlDB), []*models.Data{&data}, ctx); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// readOne reads a record with its tags, through the statement of the connection or of a transaction. nil if it does not exist.
+func (r *DataRepository) readOne(q queryer, stmt *sql.Stmt, id int, ctx context.Context) (*models.Data, error) {
+	var data models.Data
+	err := scanData(stmt.QueryRowContext(ctx, id), &data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := readTags(q, []*models.Data{&data}, ctx); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// versionMismatch tells why a conditional change did not affect a record read in the same transaction: it was
+// changed by someone else, or it no longer exists.
+func (r *DataRepository) versionMismatch(tx *DAL.Tx, id int, version int, ctx context.Context) error {
+	if version == 0 {
+		return nil
+	}
+	current, err := r.readOneTx(tx, id, ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return models.ErrVersionMismatch
+	}
+	return nil
+}
+
+// readOneTx reads and locks a record inside a transaction, nil if it does not exist.
+func (r *DataRepository) readOneTx(tx *DAL.Tx, id int, ctx context.Context) (*models.Data, error) {
+	return r.readOne(tx, tx.StmtContext(ctx, r.lockStmt), id, ctx)
+}
+
+func (r *DataRepository) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+
+	offset := rowsPerPage * (page - 1)
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readManyStmt).QueryContext(ctx, rowsPerPage, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.Data
+	for rows.Next() {
+		var d models.Data
+		if err := scanData(rows, &d); err != nil {
+			return nil, err
+		}
+		data = append(data, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return data, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+// ReadAfter returns up to limit rows ordered by (date_time, id) that come after the cursor.
+// A nil cursor starts from the beginning, the returned cursor is nil on the last page.
+func (r *DataRepository) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error) {
+	var rows *sql.Rows
+	var err error
+	// * One extra row is read to know whether there is a next page
+	if cursor == nil {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readFirstStmt).QueryContext(ctx, limit+1)
+	} else {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readAfterStmt).QueryContext(ctx, cursor.DateTime, cursor.ID, limit+1)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.Data
+	var next *models.Cursor
+	for rows.Next() {
+		if len(data) == limit {
+			next = &models.Cursor{DateTime: data[limit-1].DateTime, ID: data[limit-1].ID}
+			break
+		}
+		var d models.Data
+		if err := scanData(rows, &d); err != nil {
+			return nil, nil, err
+		}
+		data = append(data, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+	return data, next, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+func (r *DataRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Stmt(ctx, r.sqlDB, r.countStmt).QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Query returns one page of the rows matching the filter, in the requested order and with only the selected fields set.
+// The query is built from the whitelisted schema, every value is passed as a parameter.
+func (r *DataRepository) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	fields := q.Selected(models.DataQueryFields)
+	where, args := dataQueryTimes.where(q, dataQuerySchema)
+	where, args = withTagFilter(q, where, args)
+	sqlQuery := "SELECT " + dataQueryTimes.columns(dataQuerySchema, fields) + " FROM data WHERE " + notDeleted(where)
+	sqlQuery += " ORDER BY " + q.OrderBy(dataQuerySchema, "id") + " LIMIT ? OFFSET ?"
+	args = append(args, rowsPerPage, rowsPerPage*(page-1))
+
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, rebind(sqlQuery, args), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.Data
+	for rows.Next() {
+		var d models.Data
+		targets := dataQueryTargets(&d)
+		dest := make([]any, len(fields))
+		for i, f := range fields {
+			dest[i] = targets[f]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		data = append(data, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	// * Tags are not a column, records have them when no fields are selected
+	if len(q.Fields) > 0 {
+		return data, nil
+	}
+	return data, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+// CountQuery counts the rows matching the filter of the query.
+func (r *DataRepository) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	where, args := dataQueryTimes.where(q, dataQuerySchema)
+	where, args = withTagFilter(q, where, args)
+	sqlQuery := "SELECT COUNT(*) FROM data WHERE " + notDeleted(where)
+
+	var count int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, rebind(sqlQuery, args), args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func dataQueryTargets(d *models.Data) map[string]any {
+	return map[string]any{
+		"id":            &d.ID,
+		"device_id":     &d.DeviceID,
+		"device_name":   &d.DeviceName,
+		"price":         &d.Price,
+		"currency":      &d.Currency,
+		"serial_number": &d.SerialNumber,
+		"type":          &d.Type,
+		"date_time":     &d.DateTime,
+		"description":   &d.Description,
+		"attributes":    &d.Attributes,
+		"version":       &d.Version,
+		"created_at":    &d.CreatedAt,
+		"updated_at":    &d.UpdatedAt,
+	}
+}
+
+func (r *DataRepository) Update(data *models.Data, ctx context.Context) (int64, error) {
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := r.readOneTx(tx, data.ID, ctx)
+	if err != nil || before == nil {
+		return 0, err
+	}
+	data.UpdatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	// * A version of 0 updates whatever version is stored, otherwise the statement only matches the stored one
+	var version int
+	err = tx.StmtContext(ctx, r.updateStmt).QueryRowContext(ctx, data.DeviceID, data.DeviceName, data.Price, data.Currency, data.SerialNumber, data.Type, data.DateTime, data.Description, data.Attributes, data.UpdatedAt, data.ID, data.Version).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, r.versionMismatch(tx, data.ID, data.Version, ctx)
+	}
+	if err != nil {
+		return 0, uniqueSerialNumberError(err)
+	}
+	data.Version = version
+	data.CreatedAt = before.CreatedAt
+	data.Tags = before.Tags
+
+	// * Only real changes are recorded in the history
+	if changed := models.ChangedDataFields(before, data); len(changed) > 0 {
+		if err := r.recordHistory(tx, models.OperationUpdate, data, changed, ctx); err != nil {
+			return 0, err
+		}
+	}
+	return 1, tx.Commit()
+}
+
+func (r *DataRepository) Delete(data *models.Data, ctx context.Context) (int64, error) {
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := r.readOneTx(tx, data.ID, ctx)
+	if err != nil || before == nil {
+		return 0, err
+	}
+
+	// * The record is only marked as deleted, it stays in the trash until it is restored or purged
+	deletedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	res, err := tx.StmtContext(ctx, r.deleteStmt).ExecContext(ctx, deletedAt, models.ActorFromContext(ctx), deletedAt, data.ID, data.Version)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, r.versionMismatch(tx, data.ID, data.Version, ctx)
+	}
+
+	if err := r.recordHistory(tx, models.OperationDelete, before, []string{}, ctx); err != nil {
+		return 0, err
+	}
+	return rowsAffected, tx.Commit()
+}
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// uniqueSerialNumberError turns a violation of the unique serial number index into models.ErrDuplicateSerialNumber.
+func uniqueSerialNumberError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "idx_data_serial_number_active" {
+		return models.ErrDuplicateSerialNumber
+	}
+	return err
+}
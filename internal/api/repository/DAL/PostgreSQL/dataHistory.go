@@ -0,0 +1,89 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"strings"
+	"time"
+)
+
+// createDataHistoryTable is the audit trail of the data table.
+// Every create, update and delete stores the resulting version of the record with who changed it, when and which fields.
+const createDataHistoryTable = `CREATE TABLE IF NOT EXISTS data_history (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		data_id BIGINT NOT NULL,
+		operation VARCHAR(10) NOT NULL,
+		changed_at TIMESTAMPTZ NOT NULL,
+		changed_by VARCHAR(50) NOT NULL,
+		changed_fields TEXT NOT NULL,
+		device_id VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50) NOT NULL DEFAULT '',
+		price BIGINT NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+		serial_number VARCHAR(50) NOT NULL DEFAULT '',
+		data_type VARCHAR(20) NOT NULL DEFAULT '',
+		date_time TIMESTAMPTZ NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		attributes JSONB NOT NULL DEFAULT '{}'
+	)`
+
+// historyColumns are the columns of a version of a record, without the operation.
+var historyColumns = "data_id, device_id, device_name, price, currency, serial_number, data_type, " + dateTime("date_time") + ", description, attributes"
+
+// recordHistory stores a version of the record in the history table, as part of the transaction of the change.
+func (r *DataRepository) recordHistory(tx *DAL.Tx, operation string, data *models.Data, changedFields []string, ctx context.Context) error {
+	_, err := tx.StmtContext(ctx, r.historyStmt).ExecContext(ctx,
+		data.ID, operation, time.Now().UTC().Format(models.HistoryTimeFormat), models.ActorFromContext(ctx), strings.Join(changedFields, ","),
+		data.DeviceID, data.DeviceName, data.Price, data.Currency, data.SerialNumber, data.Type, data.DateTime, data.Description, data.Attributes)
+	return err
+}
+
+// ReadHistory returns every recorded version of a record, oldest first.
+func (r *DataRepository) ReadHistory(id int, ctx context.Context) ([]*models.DataVersion, error) {
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT id, operation, `+timestamp("changed_at")+`, changed_by, changed_fields, `+historyColumns+`
+		FROM data_history WHERE data_id = $1 ORDER BY changed_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*models.DataVersion
+	for rows.Next() {
+		var v models.DataVersion
+		var changedFields string
+		err := rows.Scan(&v.HistoryID, &v.Operation, &v.ChangedAt, &v.ChangedBy, &changedFields,
+			&v.Data.ID, &v.Data.DeviceID, &v.Data.DeviceName, &v.Data.Price, &v.Data.Currency, &v.Data.SerialNumber, &v.Data.Type, &v.Data.DateTime, &v.Data.Description, &v.Data.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		v.ChangedFields = []string{}
+		if changedFields != "" {
+			v.ChangedFields = strings.Split(changedFields, ",")
+		}
+		versions = append(versions, &v)
+	}
+	return versions, rows.Err()
+}
+
+// ReadAsOf returns the record as it was at the given time, nil if it did not exist or was deleted at that time.
+func (r *DataRepository) ReadAsOf(id int, asOf time.Time, ctx context.Context) (*models.Data, error) {
+	row := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `SELECT operation, `+historyColumns+`
+		FROM data_history WHERE data_id = $1 AND changed_at <= $2 ORDER BY changed_at DESC, id DESC LIMIT 1`,
+		id, asOf.UTC().Format(models.HistoryTimeFormat))
+
+	var operation string
+	var data models.Data
+	err := row.Scan(&operation, &data.ID, &data.DeviceID, &data.DeviceName, &data.Price, &data.Currency, &data.SerialNumber, &data.Type, &data.DateTime, &data.Description, &data.Attributes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if operation == models.OperationDelete {
+		return nil, nil
+	}
+	return &data, nil
+}
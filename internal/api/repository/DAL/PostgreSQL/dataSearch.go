@@ -0,0 +1,66 @@
+package PostgreSQL
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"strings"
+	"unicode"
+)
+
+// searchHeadline marks the matched words in the part of the record that matches best, like the FTS5 snippet of SQLite.
+const searchHeadline = `'StartSel=<mark>, StopSel=</mark>, MaxWords=12, MinWords=4, MaxFragments=1, FragmentDelimiter=…'`
+
+// Search returns one page of the records matching the text, best match first, with a highlighted snippet.
+// Every word of the text must match, the last word also matches as a prefix.
+// The rank is minus the ts_rank of the record, lower is better like the bm25 rank of SQLite.
+func (r *DataRepository) Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	match := searchQuery(text)
+	if match == "" {
+		return []*models.DataSearchResult{}, 0, nil
+	}
+
+	var total int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `SELECT COUNT(*) FROM data WHERE search @@ to_tsquery('simple', $1) AND deleted_at IS NULL`, match).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT `+dataColumns("d.")+`, -ts_rank(d.search, q), ts_headline('simple', d.device_name || ' ' || d.data_type || ' ' || d.description, q, `+searchHeadline+`)
+		FROM data d, to_tsquery('simple', $1) q
+		WHERE d.search @@ q AND d.deleted_at IS NULL
+		ORDER BY ts_rank(d.search, q) DESC, d.id
+		LIMIT $2 OFFSET $3`, match, rowsPerPage, rowsPerPage*(page-1))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []*models.DataSearchResult
+	for rows.Next() {
+		var res models.DataSearchResult
+		if err := scanData(rows, &res.Data, &res.Rank, &res.Snippet); err != nil {
+			return nil, 0, err
+		}
+		results = append(results, &res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	data := make([]*models.Data, len(results))
+	for i, res := range results {
+		data[i] = &res.Data
+	}
+	return results, total, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+// searchQuery turns the user's text into a tsquery that needs every word, the last one as a prefix.
+// The text is split into letters and digits like the words of the index, so tsquery syntax in it is never interpreted.
+func searchQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+	if len(words) > 0 {
+		words[len(words)-1] += ":*"
+	}
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,87 @@
+package PostgreSQL
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+)
+
+// Stats counts the records that are not in the trash by type, device and month of their DateTime.
+// Prices are summed and averaged per currency, one row per group and currency, and put together here.
+func (r *DataRepository) Stats(ctx context.Context) (*models.DataStats, error) {
+	stats := &models.DataStats{ByType: []*models.TypeStats{}, ByDevice: []*models.DeviceStats{}, ByMonth: []*models.MonthCount{}}
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `SELECT COUNT(*) FROM data WHERE deleted_at IS NULL`).Scan(&stats.Total); err != nil {
+		return nil, err
+	}
+
+	// * SUM of a BIGINT is NUMERIC and AVG rounds half away from zero, like SQLite
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT data_type, currency, COUNT(*), SUM(price)::bigint, ROUND(AVG(price))::bigint
+		FROM data WHERE deleted_at IS NULL
+		GROUP BY data_type, currency ORDER BY data_type, currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dataType string
+		var price models.PriceStats
+		if err := rows.Scan(&dataType, &price.Currency, &price.Count, &price.Total, &price.Average); err != nil {
+			return nil, err
+		}
+		if n := len(stats.ByType); n == 0 || stats.ByType[n-1].Type != dataType {
+			stats.ByType = append(stats.ByType, &models.TypeStats{Type: dataType})
+		}
+		t := stats.ByType[len(stats.ByType)-1]
+		t.Count += price.Count
+		t.Prices = append(t.Prices, &price)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// * A device can be renamed, the name is the one of its latest record
+	rows, err = DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT d.device_id,
+			(SELECT n.device_name FROM data n WHERE n.device_id = d.device_id AND n.deleted_at IS NULL ORDER BY n.date_time DESC, n.id DESC LIMIT 1),
+			d.currency, COUNT(*), SUM(d.price)::bigint, ROUND(AVG(d.price))::bigint
+		FROM data d WHERE d.deleted_at IS NULL
+		GROUP BY d.device_id, d.currency ORDER BY d.device_id, d.currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var deviceID, deviceName string
+		var price models.PriceStats
+		if err := rows.Scan(&deviceID, &deviceName, &price.Currency, &price.Count, &price.Total, &price.Average); err != nil {
+			return nil, err
+		}
+		if n := len(stats.ByDevice); n == 0 || stats.ByDevice[n-1].DeviceID != deviceID {
+			stats.ByDevice = append(stats.ByDevice, &models.DeviceStats{DeviceID: deviceID, DeviceName: deviceName})
+		}
+		d := stats.ByDevice[len(stats.ByDevice)-1]
+		d.Count += price.Count
+		d.Prices = append(d.Prices, &price)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// * Months are of the UTC time, like the RFC 3339 text SQLite stores
+	rows, err = DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT to_char(date_time AT TIME ZONE 'UTC', 'YYYY-MM') AS month, COUNT(*)
+		FROM data WHERE deleted_at IS NULL
+		GROUP BY month ORDER BY month`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var month models.MonthCount
+		if err := rows.Scan(&month.Month, &month.Count); err != nil {
+			return nil, err
+		}
+		stats.ByMonth = append(stats.ByMonth, &month)
+	}
+	return stats, rows.Err()
+}
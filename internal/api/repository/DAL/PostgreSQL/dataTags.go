@@ -0,0 +1,143 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// createDataTagTables creates the tags and the many-to-many table that tags the records.
+// Tags are kept while a record is in the trash, they are removed with the record when it is purged.
+func createDataTagTables(sqlDB *sql.DB) error {
+	return createSchema(sqlDB,
+		`CREATE TABLE IF NOT EXISTS tags (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			name VARCHAR(50) NOT NULL UNIQUE
+		)`,
+		`CREATE TABLE IF NOT EXISTS data_tags (
+			data_id BIGINT NOT NULL REFERENCES data (id) ON DELETE CASCADE,
+			tag_id BIGINT NOT NULL REFERENCES tags (id),
+			PRIMARY KEY (data_id, tag_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_data_tags_tag_id ON data_tags (tag_id, data_id)`,
+	)
+}
+
+// readTags sets the tags of the records, in alphabetical order, with one query for all of them.
+func readTags(q queryer, data []*models.Data, ctx context.Context) error {
+	if len(data) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Data, len(data))
+	ids := make([]int64, 0, len(data))
+	for _, d := range data {
+		byID[d.ID] = d
+		ids = append(ids, int64(d.ID))
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT dt.data_id, t.name FROM data_tags dt JOIN tags t ON t.id = dt.tag_id
+		WHERE dt.data_id = ANY($1) ORDER BY dt.data_id, t.name`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		if d, ok := byID[id]; ok {
+			d.Tags = append(d.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// withTagFilter adds the tags of the query to the WHERE clause: records with all of the tags, or any of them.
+// Like the filter of the query package it uses ? placeholders, the query is rebound before it runs.
+func withTagFilter(q *query.Query, where string, args []any) (string, []any) {
+	if q == nil || len(q.Tags) == 0 {
+		return where, args
+	}
+	clause := `id IN (SELECT dt.data_id FROM data_tags dt JOIN tags t ON t.id = dt.tag_id WHERE t.name = ANY(?)`
+	args = append(args, pq.Array(q.Tags))
+	if q.AnyTag {
+		clause += `)`
+	} else {
+		clause += ` GROUP BY dt.data_id HAVING COUNT(*) = ?)`
+		args = append(args, len(q.Tags))
+	}
+
+	if where == "" {
+		return clause, args
+	}
+	return "(" + where + ") AND " + clause, args
+}
+
+// AddTags tags a record, tags it already has are kept. 0 if the record does not exist.
+func (r *DataRepository) AddTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return r.changeTags(id, version, ctx, func(tx *DAL.Tx) (int64, error) {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`, pq.Array(tags)); err != nil {
+			return 0, err
+		}
+		res, err := tx.ExecContext(ctx, `INSERT INTO data_tags (data_id, tag_id) SELECT $1::bigint, id FROM tags WHERE name = ANY($2)
+			ON CONFLICT (data_id, tag_id) DO NOTHING`, id, pq.Array(tags))
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	})
+}
+
+// RemoveTags removes tags from a record, tags it does not have are ignored. 0 if the record does not exist.
+func (r *DataRepository) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return r.changeTags(id, version, ctx, func(tx *DAL.Tx) (int64, error) {
+		res, err := tx.ExecContext(ctx, `DELETE FROM data_tags WHERE data_id = $1 AND tag_id IN (SELECT id FROM tags WHERE name = ANY($2))`, id, pq.Array(tags))
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	})
+}
+
+// changeTags runs a change of the tags of a record in a transaction. The tags are part of the record,
+// so a change makes a new version with a history entry, a change that adds or removes nothing does not.
+func (r *DataRepository) changeTags(id int, version int, ctx context.Context, change func(tx *DAL.Tx) (int64, error)) (int64, error) {
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := r.readOneTx(tx, id, ctx)
+	if err != nil || before == nil {
+		return 0, err
+	}
+	if version != 0 && version != before.Version {
+		return 0, models.ErrVersionMismatch
+	}
+
+	changed, err := change(tx)
+	if err != nil {
+		return 0, err
+	}
+	if changed == 0 {
+		return 1, tx.Commit()
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE data SET version = version + 1, updated_at = $1 WHERE id = $2`,
+		time.Now().UTC().Format(models.HistoryTimeFormat), id); err != nil {
+		return 0, err
+	}
+	if err := r.recordHistory(tx, models.OperationUpdate, before, []string{"tags"}, ctx); err != nil {
+		return 0, err
+	}
+	return 1, tx.Commit()
+}
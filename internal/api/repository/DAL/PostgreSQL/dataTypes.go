@@ -0,0 +1,171 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+
+	"github.com/lib/pq"
+)
+
+type DataTypeRepository struct {
+	sqlDB *sql.DB
+	createStmt,
+	readStmt,
+	readAllStmt,
+	updateStmt,
+	deleteStmt,
+	usedStmt *sql.Stmt
+	ctx context.Context
+}
+
+// NewDataTypeRepository initializes the registry of record types and the JSON Schemas of their attributes.
+func NewDataTypeRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.DataTypeRepository, error) {
+
+	repo := &DataTypeRepository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	// * The name is the Type of the records, it has the length of the data_type column.
+	// * The schema is kept as sent, JSONB would reorder its keys
+	if err := createSchema(repo.sqlDB, `CREATE TABLE IF NOT EXISTS data_types (
+		name VARCHAR(20) PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		schema TEXT NOT NULL
+	)`); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO data_types (name, description, schema) VALUES ($1, $2, $3)`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.createStmt = createStmt
+
+	readStmt, err := repo.sqlDB.Prepare("SELECT name, description, schema FROM data_types WHERE name = $1")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readStmt = readStmt
+
+	readAllStmt, err := repo.sqlDB.Prepare("SELECT name, description, schema FROM data_types ORDER BY name")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAllStmt = readAllStmt
+
+	updateStmt, err := repo.sqlDB.Prepare("UPDATE data_types SET description = $1, schema = $2 WHERE name = $3")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.updateStmt = updateStmt
+
+	deleteStmt, err := repo.sqlDB.Prepare("DELETE FROM data_types WHERE name = $1")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.deleteStmt = deleteStmt
+
+	usedStmt, err := repo.sqlDB.Prepare("SELECT EXISTS (SELECT 1 FROM data WHERE data_type = $1)")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.usedStmt = usedStmt
+
+	go CloseDataTypes(ctx, repo)
+
+	return repo, nil
+}
+
+func CloseDataTypes(ctx context.Context, r *DataTypeRepository) {
+	<-ctx.Done()
+	r.createStmt.Close()
+	r.readStmt.Close()
+	r.readAllStmt.Close()
+	r.updateStmt.Close()
+	r.deleteStmt.Close()
+	r.usedStmt.Close()
+	r.sqlDB.Close()
+}
+
+func (r *DataTypeRepository) Create(dataType *models.DataType, ctx context.Context) error {
+	_, err := DAL.Stmt(ctx, r.sqlDB, r.createStmt).ExecContext(ctx, dataType.Name, dataType.Description, string(dataType.Schema))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return models.ErrDuplicateDataType
+	}
+	return err
+}
+
+// ReadOne returns the type with the name, nil if it is not registered.
+func (r *DataTypeRepository) ReadOne(name string, ctx context.Context) (*models.DataType, error) {
+	dataType, err := scanDataType(DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return dataType, err
+}
+
+func (r *DataTypeRepository) ReadAll(ctx context.Context) ([]*models.DataType, error) {
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readAllStmt).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dataTypes []*models.DataType
+	for rows.Next() {
+		dataType, err := scanDataType(rows)
+		if err != nil {
+			return nil, err
+		}
+		dataTypes = append(dataTypes, dataType)
+	}
+	return dataTypes, rows.Err()
+}
+
+func (r *DataTypeRepository) Update(dataType *models.DataType, ctx context.Context) (int64, error) {
+	res, err := DAL.Stmt(ctx, r.sqlDB, r.updateStmt).ExecContext(ctx, dataType.Description, string(dataType.Schema), dataType.Name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Delete removes a type, types that records still use can not be deleted.
+func (r *DataTypeRepository) Delete(name string, ctx context.Context) (int64, error) {
+	var used bool
+	if err := DAL.Stmt(ctx, r.sqlDB, r.usedStmt).QueryRowContext(ctx, name).Scan(&used); err != nil {
+		return 0, err
+	}
+	if used {
+		return 0, models.ErrDataTypeInUse
+	}
+
+	res, err := DAL.Stmt(ctx, r.sqlDB, r.deleteStmt).ExecContext(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanDataType(row scanner) (*models.DataType, error) {
+	var dataType models.DataType
+	var schema string
+	if err := row.Scan(&dataType.Name, &dataType.Description, &schema); err != nil {
+		return nil, err
+	}
+	dataType.Schema = json.RawMessage(schema)
+	return &dataType, nil
+}
@@ -0,0 +1,187 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"strings"
+	"time"
+)
+
+type DHT22ArchiveRepository struct {
+	sqlDB *sql.DB
+	createStmt,
+	deleteStmt *sql.Stmt
+	ctx context.Context
+}
+
+// dht22ArchiveColumns are the columns of a manifest entry in the order they are scanned.
+var dht22ArchiveColumns = "id, device_name, month, file, readings, first_date_time, first_id, last_date_time, last_id, min_id, max_id, size, sha256, " + timestamp("created_at")
+
+// NewDHT22ArchiveRepository initializes the manifest of the archived readings, the archive files themselves are on disk.
+// The readings are moved out of the dht22_data table of NewDHT22Repository.
+func NewDHT22ArchiveRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.DHT22ArchiveRepository, error) {
+
+	repo := &DHT22ArchiveRepository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	// * The positions are kept as the text the cursors compare, in the C collation so they sort byte by byte
+	if err := createSchema(repo.sqlDB,
+		`CREATE TABLE IF NOT EXISTS dht22_archive (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			device_name VARCHAR(50) NOT NULL,
+			month CHAR(7) NOT NULL,
+			file VARCHAR(255) NOT NULL UNIQUE,
+			readings INTEGER NOT NULL,
+			first_date_time TEXT COLLATE "C" NOT NULL,
+			first_id BIGINT NOT NULL,
+			last_date_time TEXT COLLATE "C" NOT NULL,
+			last_id BIGINT NOT NULL,
+			min_id BIGINT NOT NULL,
+			max_id BIGINT NOT NULL,
+			size BIGINT NOT NULL,
+			sha256 CHAR(64) NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_dht22_archive_first ON dht22_archive (first_date_time, first_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_dht22_archive_ids ON dht22_archive (min_id, max_id)`,
+	); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO dht22_archive (device_name, month, file, readings, first_date_time, first_id, last_date_time, last_id, min_id, max_id, size, sha256, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.createStmt = createStmt
+
+	// * Archived readings are removed for good, they do not go through the trash
+	deleteStmt, err := repo.sqlDB.Prepare(`DELETE FROM dht22_data WHERE id = $1 AND deleted_at IS NULL`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.deleteStmt = deleteStmt
+
+	go CloseDHT22Archive(ctx, repo)
+
+	return repo, nil
+}
+
+func CloseDHT22Archive(ctx context.Context, r *DHT22ArchiveRepository) {
+	<-ctx.Done()
+	r.createStmt.Close()
+	r.deleteStmt.Close()
+	r.sqlDB.Close()
+}
+
+// ReadLive returns live readings in the order of ReadAfter of the DHT22Repository, the position of a reading is its DateTime.
+func (r *DHT22ArchiveRepository) ReadLive(after *models.Cursor, before time.Time, limit int, ctx context.Context) ([]*models.DHT22ArchiveReading, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []any
+	if after != nil {
+		args = append(args, after.DateTime, after.ID)
+		conditions = append(conditions, fmt.Sprintf("(date_time, id) > ($%d::timestamptz, $%d)", len(args)-1, len(args)))
+	}
+	if !before.IsZero() {
+		args = append(args, before)
+		conditions = append(conditions, fmt.Sprintf("date_time < $%d", len(args)))
+	}
+	args = append(args, limit)
+
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, "SELECT "+dht22Columns+" FROM dht22_data WHERE "+strings.Join(conditions, " AND ")+
+		fmt.Sprintf(" ORDER BY date_time, id LIMIT $%d", len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []*models.DHT22ArchiveReading
+	for rows.Next() {
+		var d models.DHT22ArchiveReading
+		if err := scanDHT22(rows, &d.DHT22Data); err != nil {
+			return nil, err
+		}
+		d.Position = models.Cursor{DateTime: d.DateTime, ID: d.ID}
+		readings = append(readings, &d)
+	}
+	return readings, rows.Err()
+}
+
+func (r *DHT22ArchiveRepository) Archive(archives []*models.DHT22Archive, ids []int, ctx context.Context) error {
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	createdAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	createStmt := tx.StmtContext(ctx, r.createStmt)
+	archiveIDs := make([]int, len(archives))
+	for i, a := range archives {
+		if err := createStmt.QueryRowContext(ctx, a.DeviceName, a.Month, a.File, a.Readings, a.First.DateTime, a.First.ID, a.Last.DateTime, a.Last.ID,
+			a.MinID, a.MaxID, a.Size, a.SHA256, createdAt).Scan(&archiveIDs[i]); err != nil {
+			return err
+		}
+	}
+
+	deleteStmt := tx.StmtContext(ctx, r.deleteStmt)
+	for _, id := range ids {
+		res, err := deleteStmt.ExecContext(ctx, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return models.ErrArchiveChanged
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for i, a := range archives {
+		a.ID = archiveIDs[i]
+		a.CreatedAt = createdAt
+	}
+	return nil
+}
+
+func (r *DHT22ArchiveRepository) ReadArchives(after *models.Cursor, ctx context.Context) ([]*models.DHT22Archive, error) {
+	where, args := "", []any{}
+	if after != nil {
+		where, args = " WHERE (last_date_time, last_id) > ($1, $2)", []any{after.DateTime, after.ID}
+	}
+	return r.readArchives(where, args, ctx)
+}
+
+func (r *DHT22ArchiveRepository) ReadArchivesOf(id int, ctx context.Context) ([]*models.DHT22Archive, error) {
+	return r.readArchives(" WHERE min_id <= $1 AND max_id >= $1", []any{id}, ctx)
+}
+
+// readArchives returns the manifest entries matching the condition, ordered by their first reading.
+func (r *DHT22ArchiveRepository) readArchives(where string, args []any, ctx context.Context) ([]*models.DHT22Archive, error) {
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, "SELECT "+dht22ArchiveColumns+" FROM dht22_archive"+where+" ORDER BY first_date_time, first_id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	archives := []*models.DHT22Archive{}
+	for rows.Next() {
+		var a models.DHT22Archive
+		if err := rows.Scan(&a.ID, &a.DeviceName, &a.Month, &a.File, &a.Readings, &a.First.DateTime, &a.First.ID, &a.Last.DateTime, &a.Last.ID, &a.MinID, &a.MaxID, &a.Size, &a.SHA256, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		archives = append(archives, &a)
+	}
+	return archives, rows.Err()
+}
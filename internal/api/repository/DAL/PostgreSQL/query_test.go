@@ -0,0 +1,61 @@
+package PostgreSQL
+
+import (
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"testing"
+	"time"
+)
+
+// * The SQL of the filters is checked without a server, the contract tests run it against one
+func TestQueryTimesWhere(t *testing.T) {
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		filter string
+		where  string
+		args   []any
+	}{
+		// * The columns are compared as times, the start of a time stands for the first time it starts
+		{`date_time > "2024-03"`, `date_time >= ?`, []any{march}},
+		{`date_time <= "2024-03-01"`, `date_time < ?`, []any{march}},
+		{`date_time >= "2024-03-01T10:30"`, `date_time >= ?`, []any{march.Add(10*time.Hour + 30*time.Minute)}},
+		{`date_time == "2024-03"`, `(date_time < ? AND date_time >= ?)`, []any{march, march}},
+		{`date_time != "2024"`, `(date_time < ? OR date_time >= ?)`, []any{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{`date_time == "2024-03-01T00:00:00Z"`, `date_time = ?`, []any{march}},
+		{`created_at < "2024-03-01T00:00:00.5"`, `created_at < ?`, []any{march.Add(500 * time.Millisecond)}},
+		{`updated_at > "2024-03-01T00:00:00.000001Z" and price > 1`, `(updated_at > ? AND price > ?)`, []any{march.Add(time.Microsecond), 1.0}},
+		// * Anything else is compared with the text of the column
+		{`date_time > "2024-3"`, dateTime("date_time") + ` > ?`, []any{"2024-3"}},
+		{`not date_time < "2024-02-30"`, `NOT (` + dateTime("date_time") + ` < ?)`, []any{"2024-02-30"}},
+		{`created_at > "2024-03-01T00:00:00Z"`, `to_char(created_at AT TIME ZONE 'UTC', ` + timestampFormat + `) > ?`, []any{"2024-03-01T00:00:00Z"}},
+	}
+	for _, tt := range tests {
+		q, err := query.Parse(tt.filter, "", "", models.DataQuerySchema)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.filter, err)
+		}
+		where, args := dataQueryTimes.where(q, dataQuerySchema)
+		if where != tt.where || fmt.Sprint(args) != fmt.Sprint(tt.args) {
+			t.Errorf("%s: got %q %v, want %q %v", tt.filter, where, args, tt.where, tt.args)
+		}
+	}
+}
+
+func TestQueryTimesColumns(t *testing.T) {
+	q, err := query.Parse("", "-date_time", "id,date_time,created_at", models.DataQuerySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// * Only the SELECT list reads the times as text, the order is the one of the column
+	columns := dataQueryTimes.columns(dataQuerySchema, q.Selected(models.DataQueryFields))
+	if want := "id, " + dateTime("date_time") + ", " + timestamp("created_at"); columns != want {
+		t.Errorf("got columns %q, want %q", columns, want)
+	}
+	if orderBy := q.OrderBy(dataQuerySchema, "id"); orderBy != "date_time DESC, id" {
+		t.Errorf("got order %q, want date_time DESC, id", orderBy)
+	}
+	if where, _ := dataQueryTimes.where(q, dataQuerySchema); where != "" {
+		t.Errorf("got condition %q for a query without filter", where)
+	}
+}
@@ -0,0 +1,284 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"time"
+)
+
+type SensorRepository struct {
+	sqlDB *sql.DB
+	createStmt,
+	readStmt,
+	readManyStmt,
+	countStmt,
+	updateStmt,
+	deleteStmt *sql.Stmt
+	ctx context.Context
+}
+
+// sensorColumns are the columns of a reading in the order they are scanned.
+var sensorColumns = "id, sensor_type, device_name, " + dateTime("date_time") + ", measurements, version, " + timestamp("created_at") + ", " + timestamp("updated_at")
+
+// NewSensorRepository initializes the readings of the registered sensor types, they share one table.
+// The measurements of a reading are kept as a JSON object, so a new sensor type needs no new table.
+func NewSensorRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.SensorRepository, error) {
+
+	repo := &SensorRepository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	if err := createSchema(repo.sqlDB,
+		`CREATE TABLE IF NOT EXISTS sensor_readings (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			sensor_type VARCHAR(50) NOT NULL,
+			device_name VARCHAR(50) NOT NULL,
+			date_time TIMESTAMPTZ NOT NULL,
+			measurements JSONB NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL,
+			deleted_at TIMESTAMPTZ,
+			deleted_by VARCHAR(50)
+		)`,
+		// * Tables created before readings went to the trash get its columns
+		`ALTER TABLE sensor_readings ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+		`ALTER TABLE sensor_readings ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(50)`,
+		`CREATE INDEX IF NOT EXISTS idx_sensor_readings_type ON sensor_readings (sensor_type, id)`,
+	); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO sensor_readings (sensor_type, device_name, date_time, measurements, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.createStmt = createStmt
+
+	readStmt, err := repo.sqlDB.Prepare("SELECT " + sensorColumns + " FROM sensor_readings WHERE sensor_type = $1 AND id = $2 AND deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readStmt = readStmt
+
+	readManyStmt, err := repo.sqlDB.Prepare("SELECT " + sensorColumns + " FROM sensor_readings WHERE sensor_type = $1 AND deleted_at IS NULL ORDER BY id LIMIT $2 OFFSET $3")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readManyStmt = readManyStmt
+
+	countStmt, err := repo.sqlDB.Prepare("SELECT COUNT(*) FROM sensor_readings WHERE sensor_type = $1 AND deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.countStmt = countStmt
+
+	updateStmt, err := repo.sqlDB.Prepare(`UPDATE sensor_readings SET device_name = $1, date_time = $2, measurements = $3, version = version + 1, updated_at = $4
+		WHERE sensor_type = $5 AND id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7) RETURNING version, ` + timestamp("created_at"))
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.updateStmt = updateStmt
+
+	deleteStmt, err := repo.sqlDB.Prepare("UPDATE sensor_readings SET deleted_at = $1, deleted_by = $2, updated_at = $3 WHERE sensor_type = $4 AND id = $5 AND deleted_at IS NULL AND ($6 = 0 OR version = $6)")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.deleteStmt = deleteStmt
+
+	go CloseSensors(ctx, repo)
+
+	return repo, nil
+}
+
+func CloseSensors(ctx context.Context, r *SensorRepository) {
+	<-ctx.Done()
+	r.createStmt.Close()
+	r.readStmt.Close()
+	r.readManyStmt.Close()
+	r.countStmt.Close()
+	r.updateStmt.Close()
+	r.deleteStmt.Close()
+	r.sqlDB.Close()
+}
+
+func (r *SensorRepository) Create(reading *models.SensorReading, ctx context.Context) error {
+	measurements, err := json.Marshal(reading.Values)
+	if err != nil {
+		return err
+	}
+	createdAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	var id int
+	if err := DAL.Stmt(ctx, r.sqlDB, r.createStmt).QueryRowContext(ctx, reading.Type, reading.DeviceName, reading.DateTime, string(measurements), createdAt, createdAt).Scan(&id); err != nil {
+		return err
+	}
+	reading.ID = id
+	reading.Version = 1
+	reading.CreatedAt = createdAt
+	reading.UpdatedAt = createdAt
+	return nil
+}
+
+func (r *SensorRepository) ReadOne(sensorType string, id int, ctx context.Context) (*models.SensorReading, error) {
+	reading, err := scanSensorReading(DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, sensorType, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return reading, err
+}
+
+func (r *SensorRepository) ReadMany(sensorType string, page int, rowsPerPage int, ctx context.Context) ([]*models.SensorReading, error) {
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readManyStmt).QueryContext(ctx, sensorType, rowsPerPage, rowsPerPage*(page-1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []*models.SensorReading
+	for rows.Next() {
+		reading, err := scanSensorReading(rows)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, reading)
+	}
+	return readings, rows.Err()
+}
+
+func (r *SensorRepository) Count(sensorType string, ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Stmt(ctx, r.sqlDB, r.countStmt).QueryRowContext(ctx, sensorType).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Update replaces a reading, a version other than 0 must be the stored one. The new version is set on reading.
+func (r *SensorRepository) Update(reading *models.SensorReading, ctx context.Context) (int64, error) {
+	measurements, err := json.Marshal(reading.Values)
+	if err != nil {
+		return 0, err
+	}
+	var version int
+	var createdAt string
+	updatedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	err = DAL.Stmt(ctx, r.sqlDB, r.updateStmt).QueryRowContext(ctx, reading.DeviceName, reading.DateTime, string(measurements), updatedAt,
+		reading.Type, reading.ID, reading.Version).Scan(&version, &createdAt)
+	if err == sql.ErrNoRows {
+		return 0, r.versionMismatch(reading.Type, reading.ID, reading.Version, ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+	reading.Version = version
+	reading.CreatedAt = createdAt
+	reading.UpdatedAt = updatedAt
+	return 1, nil
+}
+
+func (r *SensorRepository) Delete(sensorType string, id int, version int, ctx context.Context) (int64, error) {
+	// * The reading is only marked as deleted, it stays in the trash until it is restored or purged
+	deletedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	res, err := DAL.Stmt(ctx, r.sqlDB, r.deleteStmt).ExecContext(ctx, deletedAt, models.ActorFromContext(ctx), deletedAt, sensorType, id, version)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, r.versionMismatch(sensorType, id, version, ctx)
+	}
+	return rowsAffected, nil
+}
+
+// Restore takes a reading out of the trash.
+func (r *SensorRepository) Restore(sensorType string, id int, ctx context.Context) (int64, error) {
+	res, err := DAL.Conn(ctx, r.sqlDB).ExecContext(ctx, `UPDATE sensor_readings SET deleted_at = NULL, deleted_by = NULL, updated_at = $1 WHERE sensor_type = $2 AND id = $3 AND deleted_at IS NOT NULL`,
+		time.Now().UTC().Format(models.HistoryTimeFormat), sensorType, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ReadTrash returns one page of the deleted readings of every type, most recently deleted first.
+func (r *SensorRepository) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedSensorReading, error) {
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT `+sensorColumns+`, `+timestamp("deleted_at")+`, deleted_by
+		FROM sensor_readings WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC LIMIT $1 OFFSET $2`, rowsPerPage, rowsPerPage*(page-1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trash []*models.TrashedSensorReading
+	for rows.Next() {
+		var t models.TrashedSensorReading
+		var measurements string
+		if err := rows.Scan(&t.ID, &t.Type, &t.DeviceName, &t.DateTime, &measurements, &t.Version, &t.CreatedAt, &t.UpdatedAt, &t.DeletedAt, &t.DeletedBy); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(measurements), &t.Values); err != nil {
+			return nil, err
+		}
+		trash = append(trash, &t)
+	}
+	return trash, rows.Err()
+}
+
+func (r *SensorRepository) CountTrash(ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `SELECT COUNT(*) FROM sensor_readings WHERE deleted_at IS NOT NULL`).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Purge permanently removes the readings deleted before the given time.
+func (r *SensorRepository) Purge(before time.Time, ctx context.Context) (int64, error) {
+	res, err := DAL.Conn(ctx, r.sqlDB).ExecContext(ctx, `DELETE FROM sensor_readings WHERE deleted_at IS NOT NULL AND deleted_at < $1`, before.UTC().Format(models.HistoryTimeFormat))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// versionMismatch tells why a conditional change did not affect a row: the reading was changed by someone else,
+// or it does not exist.
+func (r *SensorRepository) versionMismatch(sensorType string, id int, version int, ctx context.Context) error {
+	if version == 0 {
+		return nil
+	}
+	current, err := r.ReadOne(sensorType, id, ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return models.ErrVersionMismatch
+	}
+	return nil
+}
+
+func scanSensorReading(row scanner) (*models.SensorReading, error) {
+	var reading models.SensorReading
+	var measurements string
+	if err := row.Scan(&reading.ID, &reading.Type, &reading.DeviceName, &reading.DateTime, &measurements, &reading.Version, &reading.CreatedAt, &reading.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(measurements), &reading.Values); err != nil {
+		return nil, err
+	}
+	return &reading, nil
+}
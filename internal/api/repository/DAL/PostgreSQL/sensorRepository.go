@@ -0,0 +1,411 @@
+package PostgreSQL
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"time"
+)
+
+type DHT22Repository struct {
+	sqlDB *sql.DB
+	createStmt,
+	readStmt,
+	readManyStmt,
+	readFirstStmt,
+	readAfterStmt,
+	countStmt,
+	readLatestStmt,
+	updateStmt,
+	deleteStmt *sql.Stmt
+	ctx context.Context
+}
+
+// dht22Columns are the columns of a reading in the order they are scanned.
+var dht22Columns = "id, device_name, temperature, humidity, " + dateTime("date_time") + ", version, " + timestamp("created_at") + ", " + timestamp("updated_at")
+
+func scanDHT22(row scanner, data *models.DHT22Data, extra ...any) error {
+	dest := []any{&data.ID, &data.DeviceName, &data.Temperature, &data.Humidity, &data.DateTime, &data.Version, &data.CreatedAt, &data.UpdatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// * Fields of DHT22Data that list endpoints can filter, sort and select on, the columns of models.DHT22QuerySchema in PostgreSQL
+// * The times are the TIMESTAMPTZ columns, dht22QueryTimes reads them as text and compares them like SQLite
+var dht22QuerySchema = query.Schema{
+	"id":          {Column: "id", Kind: query.Number},
+	"device_name": {Column: "device_name", Kind: query.String},
+	"temperature": {Column: "temperature", Kind: query.Number},
+	"humidity":    {Column: "humidity", Kind: query.Number},
+	"date_time":   {Column: "date_time", Kind: query.String},
+	"version":     {Column: "version", Kind: query.Number},
+	"created_at":  {Column: "created_at", Kind: query.String},
+	"updated_at":  {Column: "updated_at", Kind: query.String},
+}
+
+var dht22QueryTimes = queryTimes{"date_time": dateTimeLayout, "created_at": models.HistoryTimeFormat, "updated_at": models.HistoryTimeFormat}
+
+// NewDHT22Repository initializes the repository for DHT22Data.
+func NewDHT22Repository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.DHT22Repository, error) {
+
+	repo := &DHT22Repository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	err := createSchema(repo.sqlDB,
+		`CREATE TABLE IF NOT EXISTS dht22_data (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			device_name VARCHAR(50) NOT NULL,
+			temperature DOUBLE PRECISION NOT NULL,
+			humidity DOUBLE PRECISION NOT NULL,
+			date_time TIMESTAMPTZ NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL,
+			deleted_at TIMESTAMPTZ,
+			deleted_by VARCHAR(50)
+		)`,
+		// Index used by the per-device queries (latest readings, forecasts)
+		`CREATE INDEX IF NOT EXISTS idx_dht22_device_time ON dht22_data (device_name, date_time)`,
+		// * Index used by the keyset (cursor) pagination
+		`CREATE INDEX IF NOT EXISTS idx_dht22_date_time_id ON dht22_data (date_time, id)`,
+		// * Readings arrive in time order, a BRIN index keeps time range scans cheap at a fraction of the size of a B-tree
+		`CREATE INDEX IF NOT EXISTS idx_dht22_date_time_brin ON dht22_data USING BRIN (date_time)`,
+	)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * The change marker of GET /dht22, for conditional requests
+	if err := watchChanges(repo.sqlDB, "dht22", "dht22_data"); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// Prepare SQL statements
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO dht22_data (device_name, temperature, humidity, date_time, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.createStmt = createStmt
+
+	readStmt, err := repo.sqlDB.Prepare("SELECT " + dht22Columns + " FROM dht22_data WHERE id = $1 AND deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readStmt = readStmt
+
+	readManyStmt, err := repo.sqlDB.Prepare("SELECT " + dht22Columns + " FROM dht22_data WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readManyStmt = readManyStmt
+
+	readFirstStmt, err := repo.sqlDB.Prepare("SELECT " + dht22Columns + " FROM dht22_data WHERE deleted_at IS NULL ORDER BY date_time, id LIMIT $1")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readFirstStmt = readFirstStmt
+
+	readAfterStmt, err := repo.sqlDB.Prepare("SELECT " + dht22Columns + " FROM dht22_data WHERE deleted_at IS NULL AND (date_time, id) > ($1::timestamptz, $2) ORDER BY date_time, id LIMIT $3")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAfterStmt = readAfterStmt
+
+	countStmt, err := repo.sqlDB.Prepare("SELECT COUNT(*) FROM dht22_data WHERE deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.countStmt = countStmt
+
+	readLatestStmt, err := repo.sqlDB.Prepare("SELECT " + dht22Columns + " FROM dht22_data WHERE device_name = $1 AND deleted_at IS NULL ORDER BY date_time DESC, id DESC LIMIT $2")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readLatestStmt = readLatestStmt
+
+	updateStmt, err := repo.sqlDB.Prepare("UPDATE dht22_data SET device_name = $1, temperature = $2, humidity = $3, date_time = $4, version = version + 1, updated_at = $5 WHERE id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7) RETURNING version, " + timestamp("created_at"))
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.updateStmt = updateStmt
+
+	deleteStmt, err := repo.sqlDB.Prepare("UPDATE dht22_data SET deleted_at = $1, deleted_by = $2, updated_at = $3 WHERE id = $4 AND deleted_at IS NULL AND ($5 = 0 OR version = $5)")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.deleteStmt = deleteStmt
+
+	// Handle cleanup when the context is canceled
+	go CloseDHT22(ctx, repo)
+
+	return repo, nil
+}
+
+// Cleanup resources when the context is canceled
+func CloseDHT22(ctx context.Context, r *DHT22Repository) {
+	<-ctx.Done()
+	r.createStmt.Close()
+	r.readStmt.Close()
+	r.readManyStmt.Close()
+	r.readFirstStmt.Close()
+	r.readAfterStmt.Close()
+	r.countStmt.Close()
+	r.readLatestStmt.Close()
+	r.updateStmt.Close()
+	r.deleteStmt.Close()
+	r.sqlDB.Close()
+}
+
+func (r *DHT22Repository) Create(data *models.DHT22Data, ctx context.Context) error {
+	data.CreatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	data.UpdatedAt = data.CreatedAt
+	if err := DAL.Stmt(ctx, r.sqlDB, r.createStmt).QueryRowContext(ctx, data.DeviceName, data.Temperature, data.Humidity, data.DateTime, data.CreatedAt, data.UpdatedAt).Scan(&data.ID); err != nil {
+		return err
+	}
+	data.Version = 1
+	return nil
+}
+
+// CreateMany inserts the readings in one transaction, they are committed together.
+func (r *DHT22Repository) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	if len(data) == 0 {
+		return ctx.Err()
+	}
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	createStmt := tx.StmtContext(ctx, r.createStmt)
+	createdAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	ids := make([]int, len(data))
+	for i, d := range data {
+		if err := createStmt.QueryRowContext(ctx, d.DeviceName, d.Temperature, d.Humidity, d.DateTime, createdAt, createdAt).Scan(&ids[i]); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// * The readings are only changed once they are written
+	for i, d := range data {
+		d.ID = ids[i]
+		d.Version = 1
+		d.CreatedAt = createdAt
+		d.UpdatedAt = createdAt
+	}
+	return nil
+}
+
+func (r *DHT22Repository) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
+	var data models.DHT22Data
+	err := scanDHT22(DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, id), &data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (r *DHT22Repository) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	offset := rowsPerPage * (page - 1)
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readManyStmt).QueryContext(ctx, rowsPerPage, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.DHT22Data
+	for rows.Next() {
+		var d models.DHT22Data
+		if err := scanDHT22(rows, &d); err != nil {
+			return nil, err
+		}
+		data = append(data, &d)
+	}
+	return data, rows.Err()
+}
+
+// ReadAfter returns up to limit rows ordered by (date_time, id) that come after the cursor.
+// A nil cursor starts from the beginning, the returned cursor is nil on the last page.
+func (r *DHT22Repository) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	var rows *sql.Rows
+	var err error
+	// * One extra row is read to know whether there is a next page
+	if cursor == nil {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readFirstStmt).QueryContext(ctx, limit+1)
+	} else {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readAfterStmt).QueryContext(ctx, cursor.DateTime, cursor.ID, limit+1)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.DHT22Data
+	var next *models.Cursor
+	for rows.Next() {
+		if len(data) == limit {
+			next = &models.Cursor{DateTime: data[limit-1].DateTime, ID: data[limit-1].ID}
+			break
+		}
+		var d models.DHT22Data
+		if err := scanDHT22(rows, &d); err != nil {
+			return nil, nil, err
+		}
+		data = append(data, &d)
+	}
+	return data, next, rows.Err()
+}
+
+func (r *DHT22Repository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Stmt(ctx, r.sqlDB, r.countStmt).QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ReadLatest returns the newest readings of a single device, newest first.
+func (r *DHT22Repository) ReadLatest(deviceName string, limit int, ctx context.Context) ([]*models.DHT22Data, error) {
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readLatestStmt).QueryContext(ctx, deviceName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.DHT22Data
+	for rows.Next() {
+		var d models.DHT22Data
+		if err := scanDHT22(rows, &d); err != nil {
+			return nil, err
+		}
+		data = append(data, &d)
+	}
+	return data, rows.Err()
+}
+
+// Query returns one page of the rows matching the filter, in the requested order and with only the selected fields set.
+// The query is built from the whitelisted schema, every value is passed as a parameter.
+func (r *DHT22Repository) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	fields := q.Selected(models.DHT22QueryFields)
+	where, args := dht22QueryTimes.where(q, dht22QuerySchema)
+	sqlQuery := "SELECT " + dht22QueryTimes.columns(dht22QuerySchema, fields) + " FROM dht22_data WHERE " + notDeleted(where)
+	sqlQuery += " ORDER BY " + q.OrderBy(dht22QuerySchema, "id") + " LIMIT ? OFFSET ?"
+	args = append(args, rowsPerPage, rowsPerPage*(page-1))
+
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, rebind(sqlQuery, args), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.DHT22Data
+	for rows.Next() {
+		var d models.DHT22Data
+		targets := dht22QueryTargets(&d)
+		dest := make([]any, len(fields))
+		for i, f := range fields {
+			dest[i] = targets[f]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		data = append(data, &d)
+	}
+	return data, rows.Err()
+}
+
+// CountQuery counts the rows matching the filter of the query.
+func (r *DHT22Repository) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	where, args := dht22QueryTimes.where(q, dht22QuerySchema)
+	sqlQuery := "SELECT COUNT(*) FROM dht22_data WHERE " + notDeleted(where)
+
+	var count int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, rebind(sqlQuery, args), args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func dht22QueryTargets(d *models.DHT22Data) map[string]any {
+	return map[string]any{
+		"id":          &d.ID,
+		"device_name": &d.DeviceName,
+		"temperature": &d.Temperature,
+		"humidity":    &d.Humidity,
+		"date_time":   &d.DateTime,
+		"version":     &d.Version,
+		"created_at":  &d.CreatedAt,
+		"updated_at":  &d.UpdatedAt,
+	}
+}
+
+// Update replaces a reading, a version other than 0 must be the stored one. The new version is set on data.
+func (r *DHT22Repository) Update(data *models.DHT22Data, ctx context.Context) (int64, error) {
+	var version int
+	var createdAt string
+	updatedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	err := DAL.Stmt(ctx, r.sqlDB, r.updateStmt).QueryRowContext(ctx, data.DeviceName, data.Temperature, data.Humidity, data.DateTime, updatedAt, data.ID, data.Version).Scan(&version, &createdAt)
+	if err == sql.ErrNoRows {
+		return 0, r.versionMismatch(data.ID, data.Version, ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+	data.Version = version
+	data.CreatedAt = createdAt
+	data.UpdatedAt = updatedAt
+	return 1, nil
+}
+
+// versionMismatch tells why a conditional change did not affect a row: the reading was changed by someone else,
+// or it does not exist.
+func (r *DHT22Repository) versionMismatch(id int, version int, ctx context.Context) error {
+	if version == 0 {
+		return nil
+	}
+	current, err := r.ReadOne(id, ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return models.ErrVersionMismatch
+	}
+	return nil
+}
+
+func (r *DHT22Repository) Delete(data *models.DHT22Data, ctx context.Context) (int64, error) {
+	// * The reading is only marked as deleted, it stays in the trash until it is restored or purged
+	deletedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	res, err := DAL.Stmt(ctx, r.sqlDB, r.deleteStmt).ExecContext(ctx, deletedAt, models.ActorFromContext(ctx), deletedAt, data.ID, data.Version)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, r.versionMismatch(data.ID, data.Version, ctx)
+	}
+	return rowsAffected, nil
+}
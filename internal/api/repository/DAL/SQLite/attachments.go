@@ -0,0 +1,163 @@
+package SQLite
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"time"
+)
+
+type AttachmentRepository struct {
+	sqlDB *sql.DB
+	createStmt,
+	readStmt,
+	readAllStmt *sql.Stmt
+	ctx context.Context
+}
+
+// NewAttachmentRepository initializes the metadata of the files attached to records, the files themselves are in a blob store.
+func NewAttachmentRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.AttachmentRepository, error) {
+
+	repo := &AttachmentRepository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	// * Attachments are kept while their record is in the trash, they are deleted with the orphans when it is purged
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			data_id INTEGER NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			content_type VARCHAR(100) NOT NULL,
+			size INTEGER NOT NULL,
+			sha256 CHAR(64) NOT NULL,
+			blob_key VARCHAR(64) NOT NULL UNIQUE,
+			created_at TIMESTAMP NOT NULL,
+			created_by VARCHAR(50) NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_attachments_data_id ON attachments (data_id, id)`,
+	}
+	for _, statement := range statements {
+		if _, err := repo.sqlDB.Exec(statement); err != nil {
+			repo.sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO attachments (data_id, file_name, content_type, size, sha256, blob_key, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.createStmt = createStmt
+
+	readStmt, err := repo.sqlDB.Prepare("SELECT id, data_id, file_name, content_type, size, sha256, blob_key, CAST(created_at AS TEXT), created_by FROM attachments WHERE data_id = ? AND id = ?")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readStmt = readStmt
+
+	readAllStmt, err := repo.sqlDB.Prepare("SELECT id, data_id, file_name, content_type, size, sha256, blob_key, CAST(created_at AS TEXT), created_by FROM attachments WHERE data_id = ? ORDER BY id")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAllStmt = readAllStmt
+
+	go CloseAttachments(ctx, repo)
+
+	return repo, nil
+}
+
+func CloseAttachments(ctx context.Context, r *AttachmentRepository) {
+	<-ctx.Done()
+	r.createStmt.Close()
+	r.readStmt.Close()
+	r.readAllStmt.Close()
+	r.sqlDB.Close()
+}
+
+// Create stores the metadata of an attachment whose content is already in the blob store.
+func (r *AttachmentRepository) Create(attachment *models.Attachment, ctx context.Context) error {
+	attachment.CreatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	attachment.CreatedBy = models.ActorFromContext(ctx)
+	res, err := DAL.Stmt(ctx, r.sqlDB, r.createStmt).ExecContext(ctx, attachment.DataID, attachment.FileName, attachment.ContentType, attachment.Size,
+		attachment.SHA256, attachment.BlobKey, attachment.CreatedAt, attachment.CreatedBy)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	attachment.ID = int(id)
+	return nil
+}
+
+// ReadOne returns an attachment of the record, nil if the record has no attachment with the id.
+func (r *AttachmentRepository) ReadOne(dataID int, id int, ctx context.Context) (*models.Attachment, error) {
+	attachment, err := scanAttachment(DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, dataID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return attachment, err
+}
+
+func (r *AttachmentRepository) ReadAll(dataID int, ctx context.Context) ([]*models.Attachment, error) {
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readAllStmt).QueryContext(ctx, dataID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// Delete removes the metadata of an attachment and returns it, so its blob can be deleted. nil if there is none.
+func (r *AttachmentRepository) Delete(dataID int, id int, ctx context.Context) (*models.Attachment, error) {
+	attachment, err := scanAttachment(DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `DELETE FROM attachments WHERE data_id = ? AND id = ?
+		RETURNING id, data_id, file_name, content_type, size, sha256, blob_key, CAST(created_at AS TEXT), created_by`, dataID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return attachment, err
+}
+
+// DeleteOrphans removes the metadata of the attachments of purged records and returns them, so their blobs can be deleted.
+func (r *AttachmentRepository) DeleteOrphans(ctx context.Context) ([]*models.Attachment, error) {
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `DELETE FROM attachments WHERE data_id NOT IN (SELECT id FROM data)
+		RETURNING id, data_id, file_name, content_type, size, sha256, blob_key, CAST(created_at AS TEXT), created_by`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func scanAttachment(row scanner) (*models.Attachment, error) {
+	var a models.Attachment
+	if err := row.Scan(&a.ID, &a.DataID, &a.FileName, &a.ContentType, &a.Size, &a.SHA256, &a.BlobKey, &a.CreatedAt, &a.CreatedBy); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
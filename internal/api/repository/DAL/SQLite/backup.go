@@ -0,0 +1,153 @@
+package SQLite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"goapi/internal/api/repository/DAL"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// InvalidBackupError is returned when a file can not be restored, Reason tells why.
+type InvalidBackupError struct {
+	Path   string
+	Reason string
+}
+
+func (e InvalidBackupError) Error() string {
+	return e.Path + " is not a backup that can be restored: " + e.Reason
+}
+
+// backupTables must be in a backup, they are created by the repositories.
+var backupTables = []string{"data", "dht22_data", "schema_migrations"}
+
+// Backup writes a consistent copy of the database to path with VACUUM INTO, the database stays in use meanwhile.
+// The copy is written to a temporary file next to path and renamed, so path is never a partial backup.
+func Backup(db DAL.SQLDatabase, path string, ctx context.Context) error {
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, err := db.Connection().ExecContext(ctx, `VACUUM INTO ?`, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// ValidateBackup checks that the file is an intact database of this server, with a schema this version can open:
+// every migration applied to it must be known, an older backup is brought up to date when the server starts.
+func ValidateBackup(path string, ctx context.Context) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	sqlDB, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var integrity string
+	if err := sqlDB.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&integrity); err != nil {
+		return InvalidBackupError{Path: path, Reason: err.Error()}
+	}
+	if integrity != "ok" {
+		return InvalidBackupError{Path: path, Reason: "integrity check failed: " + integrity}
+	}
+
+	for _, table := range backupTables {
+		var count int
+		if err := sqlDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return InvalidBackupError{Path: path, Reason: "table " + table + " is missing"}
+		}
+	}
+
+	rows, err := sqlDB.QueryContext(ctx, `SELECT name FROM schema_migrations ORDER BY name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var unknown []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(knownMigrations(), func(m migration) bool { return m.name == name }) {
+			unknown = append(unknown, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return InvalidBackupError{Path: path, Reason: "it was made by a newer version, unknown migrations: " + strings.Join(unknown, ", ")}
+	}
+	return nil
+}
+
+// knownMigrations are all the migrations of this version.
+func knownMigrations() []migration {
+	return slices.Concat(dataMigrations, dht22Migrations, sensorMigrations, []migration{rebuildDataSearchIndex})
+}
+
+// Restore validates the backup and swaps it in as the database file at dbPath. The server must be stopped.
+// The replaced database and its journal files are kept with the suffix .before-restore.
+func Restore(backupPath string, dbPath string, ctx context.Context) error {
+	if err := ValidateBackup(backupPath, ctx); err != nil {
+		return err
+	}
+
+	// * The backup is copied next to the database first, so the swap is a rename
+	tmp := dbPath + ".restore"
+	if err := copyFile(backupPath, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	suffixes := []string{"", "-journal", "-wal", "-shm"}
+	for _, suffix := range suffixes {
+		if err := os.Remove(dbPath + ".before-restore" + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	for _, suffix := range suffixes {
+		if err := os.Rename(dbPath+suffix, dbPath+".before-restore"+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dbPath)
+}
+
+// copyFile copies src to dst and syncs it to disk.
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
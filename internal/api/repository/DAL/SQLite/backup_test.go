@@ -0,0 +1,173 @@
+package SQLite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// createReadings opens the database at path with its repositories and creates n readings.
+func createReadings(t *testing.T, path string, n int) models.DHT22Repository {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	db, err := SQLite.NewSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SQLite.NewDataRepository(db, ctx); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := SQLite.NewDHT22Repository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := repo.Create(&models.DHT22Data{DeviceName: "dht22", Temperature: 20, Humidity: 40, DateTime: fmt.Sprintf("2024-01-01T10:00:%02dZ", i%60)}, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestBackupWhileWriting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "production.db")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	createReadings(t, path, 10)
+	db, err := SQLite.NewSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := SQLite.NewDHT22Repository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// * The server keeps writing while the backup is made
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := repo.Create(&models.DHT22Data{DeviceName: "dht22", Temperature: 20, Humidity: 40, DateTime: "2024-01-02T10:00:00Z"}, context.Background()); err != nil {
+				t.Errorf("Create failed: %v", err)
+			}
+		}
+	}()
+	backup := filepath.Join(dir, "backup.db")
+	if err := SQLite.Backup(db, backup, context.Background()); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	wg.Wait()
+
+	if err := SQLite.ValidateBackup(backup, context.Background()); err != nil {
+		t.Fatalf("ValidateBackup of a backup failed: %v", err)
+	}
+	if _, err := os.Stat(backup + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Backup left its temporary file behind: %v", err)
+	}
+	count, err := readingCount(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count < 10 || count > 60 {
+		t.Errorf("Expected the backup to have between 10 and 60 readings, got %d", count)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	backup := filepath.Join(dir, "backup.db")
+	createReadings(t, filepath.Join(dir, "old.db"), 3)
+	db, err := SQLite.NewSqlite(filepath.Join(dir, "old.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := SQLite.Backup(db, backup, context.Background()); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	path := filepath.Join(dir, "production.db")
+	createReadings(t, path, 7)
+	if err := SQLite.Restore(backup, path, context.Background()); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if count, err := readingCount(path); err != nil || count != 3 {
+		t.Errorf("Expected the restored database to have 3 readings, got %d, %v", count, err)
+	}
+	if count, err := readingCount(path + ".before-restore"); err != nil || count != 7 {
+		t.Errorf("Expected the replaced database to be kept with its 7 readings, got %d, %v", count, err)
+	}
+
+	// * The restored database is opened by the repositories like any other
+	repo := createReadings(t, path, 1)
+	if count, err := repo.Count(context.Background()); err != nil || count != 4 {
+		t.Errorf("Expected 4 readings after a new one, got %d, %v", count, err)
+	}
+}
+
+func TestValidateBackupRejects(t *testing.T) {
+	dir := t.TempDir()
+
+	notDatabase := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notDatabase, []byte("not a database, just some text that is long enough"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	missingTables := filepath.Join(dir, "empty.db")
+	execSQL(t, missingTables, `CREATE TABLE other (id INTEGER)`)
+
+	newer := filepath.Join(dir, "newer.db")
+	createReadings(t, newer, 1)
+	execSQL(t, newer, `INSERT INTO schema_migrations (name, applied_at) VALUES ('9999_from_the_future', '2030-01-01T00:00:00Z')`)
+
+	for _, path := range []string{notDatabase, missingTables, newer} {
+		err := SQLite.ValidateBackup(path, context.Background())
+		var invalid SQLite.InvalidBackupError
+		if !errors.As(err, &invalid) {
+			t.Errorf("Expected InvalidBackupError for %s, got %v", filepath.Base(path), err)
+		}
+		if err := SQLite.Restore(path, filepath.Join(dir, "production.db"), context.Background()); err == nil {
+			t.Errorf("Restore of %s succeeded", filepath.Base(path))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "production.db")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("A rejected backup was restored: %v", err)
+	}
+	if err := SQLite.ValidateBackup(filepath.Join(dir, "missing.db"), context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist for a missing file, got %v", err)
+	}
+}
+
+func readingCount(path string) (int, error) {
+	sqlDB, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer sqlDB.Close()
+	var count int
+	err = sqlDB.QueryRow(`SELECT COUNT(*) FROM dht22_data`).Scan(&count)
+	return count, err
+}
+
+func execSQL(t *testing.T, path string, statement string) {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec(statement); err != nil {
+		t.Fatal(err)
+	}
+}
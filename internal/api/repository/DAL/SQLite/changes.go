@@ -0,0 +1,56 @@
+package SQLite
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+)
+
+// changeMarkerTime is the time of a change as SQLite writes it in a trigger, UTC with milliseconds.
+const changeMarkerTime = `strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`
+
+// watchChanges keeps the change marker of a collection, its version goes up with every insert, update and
+// delete of the tables the collection is read from. The triggers also see changes that bypass the repositories.
+func watchChanges(sqlDB *sql.DB, collection string, tables ...string) error {
+	if _, err := sqlDB.Exec(`CREATE TABLE IF NOT EXISTS change_markers (
+		collection VARCHAR(20) PRIMARY KEY,
+		version INTEGER NOT NULL,
+		changed_at TIMESTAMP NOT NULL
+	);`); err != nil {
+		return err
+	}
+	if _, err := sqlDB.Exec(`INSERT OR IGNORE INTO change_markers (collection, version, changed_at) VALUES (?, 1, `+changeMarkerTime+`)`, collection); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+			trigger := `CREATE TRIGGER IF NOT EXISTS ` + table + `_changes_` + collection + `_` + event + ` AFTER ` + event + ` ON ` + table + ` BEGIN
+				UPDATE change_markers SET version = version + 1, changed_at = ` + changeMarkerTime + ` WHERE collection = '` + collection + `';
+			END`
+			if _, err := sqlDB.Exec(trigger); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readChangeMarker returns the change marker of a collection.
+func readChangeMarker(sqlDB DAL.Querier, collection string, ctx context.Context) (*models.ChangeMarker, error) {
+	var marker models.ChangeMarker
+	err := sqlDB.QueryRowContext(ctx, `SELECT version, CAST(changed_at AS TEXT) FROM change_markers WHERE collection = ?`, collection).Scan(&marker.Version, &marker.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+func (r *DataRepository) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return readChangeMarker(DAL.Conn(ctx, r.sqlDB), "data", ctx)
+}
+
+func (r *DHT22Repository) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return readChangeMarker(DAL.Conn(ctx, r.sqlDB), "dht22", ctx)
+}
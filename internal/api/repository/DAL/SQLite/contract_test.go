@@ -0,0 +1,101 @@
+package SQLite_test
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/DAL/contract"
+	"goapi/internal/api/repository/models"
+	"path/filepath"
+	"testing"
+)
+
+// openDatabase opens a new database file that is closed with the repositories when the test ends.
+func openDatabase(t *testing.T) (*SQLite.SQLite, context.Context) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	db, err := SQLite.NewSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db.(*SQLite.SQLite), ctx
+}
+
+func TestDataRepositoryContract(t *testing.T) {
+	contract.TestDataRepository(t, func(t *testing.T) models.DataRepository {
+		db, ctx := openDatabase(t)
+		repo, err := SQLite.NewDataRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+func TestDHT22RepositoryContract(t *testing.T) {
+	contract.TestDHT22Repository(t, func(t *testing.T) models.DHT22Repository {
+		db, ctx := openDatabase(t)
+		repo, err := SQLite.NewDHT22Repository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+func TestUnitOfWorkContract(t *testing.T) {
+	contract.TestUnitOfWork(t, func(t *testing.T) contract.UnitOfWorkRepositories {
+		db, ctx := openDatabase(t)
+		data, err := SQLite.NewDataRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dht22, err := SQLite.NewDHT22Repository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contract.UnitOfWorkRepositories{Unit: DAL.NewUnitOfWork(db), Data: data, DHT22: dht22}
+	})
+}
+
+func TestDHT22ArchiveRepositoryContract(t *testing.T) {
+	contract.TestDHT22ArchiveRepository(t, func(t *testing.T) contract.DHT22ArchiveRepositories {
+		db, ctx := openDatabase(t)
+		dht22, err := SQLite.NewDHT22Repository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive, err := SQLite.NewDHT22ArchiveRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contract.DHT22ArchiveRepositories{Archive: archive, DHT22: dht22}
+	})
+}
+
+func TestSensorRepositoryContract(t *testing.T) {
+	contract.TestSensorRepository(t, func(t *testing.T) models.SensorRepository {
+		db, ctx := openDatabase(t)
+		repo, err := SQLite.NewSensorRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+func TestDataTrashContract(t *testing.T) {
+	contract.TestDataTrash(t, func(t *testing.T) contract.DataTrashRepositories {
+		db, ctx := openDatabase(t)
+		data, err := SQLite.NewDataRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attachments, err := SQLite.NewAttachmentRepository(db, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contract.DataTrashRepositories{Data: data, Attachments: attachments}
+	})
+}
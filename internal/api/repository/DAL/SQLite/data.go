@@ -3,20 +3,50 @@ package SQLite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"goapi/internal/api/repository/DAL"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 type DataRepository struct {
 	sqlDB *sql.DB
 	createStmt,
 	readStmt,
+	readBySerialStmt,
 	readManyStmt,
+	readFirstStmt,
+	readAfterStmt,
+	countStmt,
 	updateStmt,
-	deleteStmt *sql.Stmt
-	ctx context.Context
+	deleteStmt,
+	historyStmt *sql.Stmt
+	ctx           context.Context
+	searchEnabled bool
 }
 
+const createDataTable = `CREATE TABLE IF NOT EXISTS data (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_id VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50),
+		price INTEGER NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+		serial_number VARCHAR(50) NOT NULL DEFAULT '',
+		data_type VARCHAR(20),
+		date_time TIMESTAMP,
+		description TEXT,
+		attributes TEXT NOT NULL DEFAULT '{}',
+		version INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP,
+		deleted_at TIMESTAMP,
+		deleted_by VARCHAR(50)
+	);`
+
 func NewDataRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.DataRepository, error) {
 
 	repo := &DataRepository{
@@ -24,63 +54,133 @@ func NewDataRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.DataR
 		ctx:   ctx,
 	}
 
-	if _, err := repo.sqlDB.Exec(`DROP TABLE IF EXISTS data`); err != nil {
+	// Create the data table if it doesn't exist
+	//Added the new entity price and SerialNumber to table and removed value
+	if _, err := repo.sqlDB.Exec(createDataTable); err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 
-	// Create the data table if it doesn't exist
-	//Added the new entity price and SerialNumber to table and removed value
-	if _, err := repo.sqlDB.Exec(`CREATE TABLE  IF NOT EXISTS data (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		device_id VARCHAR(50) NOT NULL,
-		device_name VARCHAR(50),
-		price FLOAT,   
-	    serial_number FLOAT,
-		data_type VARCHAR(20),
-		date_time TIMESTAMP,
-		description TEXT
-	);`); err != nil {
+	// * Audit trail of every change to the data table
+	if _, err := repo.sqlDB.Exec(createDataHistoryTable); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Tags of the records, many-to-many
+	if err := createDataTagTables(repo.sqlDB); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Bring tables created by older versions up to date, the data is kept
+	if err := migrate(repo.sqlDB, dataMigrations); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * The change marker of GET /data, for conditional requests
+	if err := watchChanges(repo.sqlDB, "data", "data"); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Serial numbers are unique among the records that are not deleted, records without one are stored with an empty serial number
+	if _, err := repo.sqlDB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_data_serial_number_active ON data (serial_number) WHERE serial_number <> '' AND deleted_at IS NULL`); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	if _, err := repo.sqlDB.Exec(`CREATE INDEX IF NOT EXISTS idx_data_history_data_id ON data_history (data_id, changed_at)`); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Full-text search index, only available when go-sqlite3 is built with FTS5
+	searchEnabled, err := createDataSearchIndex(repo.sqlDB)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.searchEnabled = searchEnabled
+
+	// * Index used by the keyset (cursor) pagination
+	if _, err := repo.sqlDB.Exec(`CREATE INDEX IF NOT EXISTS idx_data_date_time_id ON data (date_time, id)`); err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 
 	// * Create needed Prepared SQL statements, this is more efficient than running each query individually
-	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO data (device_id, device_name, price, serial_number, data_type, date_time, description) VALUES (?, ?, ?, ?, ?, ?,?)`)
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO data (device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		repo.sqlDB.Close() // Close the database connection if statement preparation fails
 		return nil, err
 	}
 	repo.createStmt = createStmt
 
-	readStmt, err := repo.sqlDB.Prepare("SELECT id, device_id, device_name, price, serial_number, data_type, date_time, description FROM data WHERE id = ?")
+	readStmt, err := repo.sqlDB.Prepare("SELECT id, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT) FROM data WHERE id = ? AND deleted_at IS NULL")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.readStmt = readStmt
 
-	readManyStmt, err := repo.sqlDB.Prepare("SELECT id, device_id, device_name, price, serial_number, data_type, date_time, description FROM data LIMIT ? OFFSET ?")
+	readBySerialStmt, err := repo.sqlDB.Prepare("SELECT id, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT) FROM data WHERE serial_number = ? AND serial_number <> '' AND deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readBySerialStmt = readBySerialStmt
+
+	readManyStmt, err := repo.sqlDB.Prepare("SELECT id, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT) FROM data WHERE deleted_at IS NULL ORDER BY id LIMIT ? OFFSET ?")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.readManyStmt = readManyStmt
 
-	updateStmt, err := repo.sqlDB.Prepare("UPDATE data SET device_id = ?, device_name = ?, price = ?, serial_number = ?, data_type = ?, date_time = ?, description = ? WHERE id = ?")
+	// * date_time is also read back as stored text, so the next cursor compares exactly like the column does
+	readFirstStmt, err := repo.sqlDB.Prepare("SELECT id, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT), CAST(date_time AS TEXT) FROM data WHERE deleted_at IS NULL ORDER BY date_time, id LIMIT ?")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readFirstStmt = readFirstStmt
+
+	readAfterStmt, err := repo.sqlDB.Prepare("SELECT id, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT), CAST(date_time AS TEXT) FROM data WHERE deleted_at IS NULL AND (date_time, id) > (?, ?) ORDER BY date_time, id LIMIT ?")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAfterStmt = readAfterStmt
+
+	countStmt, err := repo.sqlDB.Prepare("SELECT COUNT(*) FROM data WHERE deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.countStmt = countStmt
+
+	updateStmt, err := repo.sqlDB.Prepare("UPDATE data SET device_id = ?, device_name = ?, price = ?, currency = ?, serial_number = ?, data_type = ?, date_time = ?, description = ?, attributes = ?, version = version + 1, updated_at = ? WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?) RETURNING version")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.updateStmt = updateStmt
 
-	deleteStmt, err := repo.sqlDB.Prepare("DELETE FROM data WHERE id = ?")
+	deleteStmt, err := repo.sqlDB.Prepare("UPDATE data SET deleted_at = ?, deleted_by = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.deleteStmt = deleteStmt
 
+	historyStmt, err := repo.sqlDB.Prepare(`INSERT INTO data_history (data_id, operation, changed_at, changed_by, changed_fields, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.historyStmt = historyStmt
+
 	go Close(ctx, repo)
 
 	return repo, nil
@@ -91,47 +191,84 @@ func Close(ctx context.Context, r *DataRepository) {
 	<-ctx.Done()
 	r.createStmt.Close()
 	r.readStmt.Close()
+	r.readBySerialStmt.Close()
 	r.updateStmt.Close()
 	r.deleteStmt.Close()
+	r.historyStmt.Close()
 	r.readManyStmt.Close()
+	r.readFirstStmt.Close()
+	r.readAfterStmt.Close()
+	r.countStmt.Close()
 	r.sqlDB.Close()
 }
 
+// * Create, Update and Delete write the change and its history entry in one transaction *
 func (r *DataRepository) Create(data *models.Data, ctx context.Context) error {
 
-	res, err := r.createStmt.ExecContext(ctx, data.DeviceID, data.DeviceName, data.Price, data.SerialNumber, data.Type, data.DateTime, data.Description)
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	// * The timestamps are the server's, whatever the client sent, tags are added with AddTags
+	data.Tags = nil
+	data.CreatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	data.UpdatedAt = data.CreatedAt
+	res, err := tx.StmtContext(ctx, r.createStmt).ExecContext(ctx, data.DeviceID, data.DeviceName, data.Price, data.Currency, data.SerialNumber, data.Type, data.DateTime, data.Description, data.Attributes, data.CreatedAt, data.UpdatedAt)
+	if err != nil {
+		return uniqueSerialNumberError(err)
+	}
 	id, err := res.LastInsertId()
 	if err != nil {
 		return err
 	}
 	data.ID = int(id)
-	return nil
+	data.Version = 1
+
+	if err := r.recordHistory(tx, models.OperationCreate, data, models.ChangedDataFields(&models.Data{}, data), ctx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *DataRepository) ReadOne(id int, ctx context.Context) (*models.Data, error) {
-	row := r.readStmt.QueryRowContext(ctx, id)
+	row := DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, id)
 	var data models.Data
-	err := row.Scan(&data.ID, &data.DeviceID, &data.DeviceName, &data.Price, &data.SerialNumber, &data.Type, &data.DateTime, &data.Description)
+	err := row.Scan(&data.ID, &data.DeviceID, &data.DeviceName, &data.Price, &data.Currency, &data.SerialNumber, &data.Type, &data.DateTime, &data.Description, &data.Attributes, &data.Version, &data.CreatedAt, &data.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if err := readTags(DAL.Conn(ctx, r.sqlDB), []*models.Data{&data}, ctx); err != nil {
+		return nil, err
+	}
 	return &data, nil
 }
 
-func (r *DataRepository) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
-
-	if page < 1 {
-		return r.ReadAll()
+// ReadBySerialNumber returns the record with the exact serial number, nil if there is none.
+func (r *DataRepository) ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error) {
+	row := DAL.Stmt(ctx, r.sqlDB, r.readBySerialStmt).QueryRowContext(ctx, serialNumber)
+	var data models.Data
+	err := row.Scan(&data.ID, &data.DeviceID, &data.DeviceName, &data.Price, &data.Currency, &data.SerialNumber, &data.Type, &data.DateTime, &data.Description, &data.Attributes, &data.Version, &data.CreatedAt, &data.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
 	}
+	if err := readTags(DAL.Conn(ctx, r.sqlDB), []*models.Data{&data}, ctx); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (r *DataRepository) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
 
 	offset := rowsPerPage * (page - 1)
-	rows, err := r.readManyStmt.QueryContext(ctx, rowsPerPage, offset)
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readManyStmt).QueryContext(ctx, rowsPerPage, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -140,48 +277,185 @@ func (r *DataRepository) ReadMany(page int, rowsPerPage int, ctx context.Context
 	var data []*models.Data
 	for rows.Next() {
 		var d models.Data
-		err := rows.Scan(&d.ID, &d.DeviceID, &d.DeviceName, &d.Price, &d.SerialNumber, &d.Type, &d.DateTime, &d.Description)
+		err := rows.Scan(&d.ID, &d.DeviceID, &d.DeviceName, &d.Price, &d.Currency, &d.SerialNumber, &d.Type, &d.DateTime, &d.Description, &d.Attributes, &d.Version, &d.CreatedAt, &d.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
 		data = append(data, &d)
 	}
-	return data, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return data, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
 }
 
-func (r *DataRepository) ReadAll() ([]*models.Data, error) {
-	rows, err := r.sqlDB.QueryContext(context.Background(), "SELECT id, device_id, device_name, price,serial_number, data_type, date_time, description FROM data")
+// ReadAfter returns up to limit rows ordered by (date_time, id) that come after the cursor.
+// A nil cursor starts from the beginning, the returned cursor is nil on the last page.
+func (r *DataRepository) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error) {
+	var rows *sql.Rows
+	var err error
+	// * One extra row is read to know whether there is a next page
+	if cursor == nil {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readFirstStmt).QueryContext(ctx, limit+1)
+	} else {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readAfterStmt).QueryContext(ctx, cursor.DateTime, cursor.ID, limit+1)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	var data []*models.Data
+	var next *models.Cursor
+	var lastDateTime string
 	for rows.Next() {
+		if len(data) == limit {
+			next = &models.Cursor{DateTime: lastDateTime, ID: data[limit-1].ID}
+			break
+		}
 		var d models.Data
-		err := rows.Scan(&d.ID, &d.DeviceID, &d.DeviceName, &d.Price, &d.SerialNumber, &d.Type, &d.DateTime, &d.Description)
+		err := rows.Scan(&d.ID, &d.DeviceID, &d.DeviceName, &d.Price, &d.Currency, &d.SerialNumber, &d.Type, &d.DateTime, &d.Description, &d.Attributes, &d.Version, &d.CreatedAt, &d.UpdatedAt, &lastDateTime)
 		if err != nil {
+			return nil, nil, err
+		}
+		data = append(data, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+	return data, next, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+func (r *DataRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Stmt(ctx, r.sqlDB, r.countStmt).QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Query returns one page of the rows matching the filter, in the requested order and with only the selected fields set.
+// The query is built from the whitelisted schema, every value is passed as a parameter.
+func (r *DataRepository) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	fields := q.Selected(models.DataQueryFields)
+	where, args := q.Where(models.DataQuerySchema)
+	where, args = withTagFilter(q, where, args)
+	sqlQuery := "SELECT " + query.Columns(models.DataQuerySchema, fields) + " FROM data WHERE " + notDeleted(where)
+	sqlQuery += " ORDER BY " + q.OrderBy(models.DataQuerySchema, "id") + " LIMIT ? OFFSET ?"
+	args = append(args, rowsPerPage, rowsPerPage*(page-1))
+
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.Data
+	for rows.Next() {
+		var d models.Data
+		targets := dataQueryTargets(&d)
+		dest := make([]any, len(fields))
+		for i, f := range fields {
+			dest[i] = targets[f]
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 		data = append(data, &d)
 	}
-	return data, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	// * Tags are not a column, records have them when no fields are selected
+	if len(q.Fields) > 0 {
+		return data, nil
+	}
+	return data, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+// CountQuery counts the rows matching the filter of the query.
+func (r *DataRepository) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	where, args := q.Where(models.DataQuerySchema)
+	where, args = withTagFilter(q, where, args)
+	sqlQuery := "SELECT COUNT(*) FROM data WHERE " + notDeleted(where)
+
+	var count int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, sqlQuery, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func dataQueryTargets(d *models.Data) map[string]any {
+	return map[string]any{
+		"id":            &d.ID,
+		"device_id":     &d.DeviceID,
+		"device_name":   &d.DeviceName,
+		"price":         &d.Price,
+		"currency":      &d.Currency,
+		"serial_number": &d.SerialNumber,
+		"type":          &d.Type,
+		"date_time":     &d.DateTime,
+		"description":   &d.Description,
+		"attributes":    &d.Attributes,
+		"version":       &d.Version,
+		"created_at":    &d.CreatedAt,
+		"updated_at":    &d.UpdatedAt,
+	}
 }
 
 func (r *DataRepository) Update(data *models.Data, ctx context.Context) (int64, error) {
-	res, err := r.updateStmt.ExecContext(ctx, data.DeviceID, data.DeviceName, data.Price, data.SerialNumber, data.Type, data.DateTime, data.Description, data.ID)
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
 	if err != nil {
 		return 0, err
 	}
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
+	defer tx.Rollback()
+
+	before, err := r.readOneTx(tx, data.ID, ctx)
+	if err != nil || before == nil {
 		return 0, err
 	}
-	return rowsAffected, nil
+	data.UpdatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	// * A version of 0 updates whatever version is stored, otherwise the statement only matches the stored one
+	var version int
+	err = tx.StmtContext(ctx, r.updateStmt).QueryRowContext(ctx, data.DeviceID, data.DeviceName, data.Price, data.Currency, data.SerialNumber, data.Type, data.DateTime, data.Description, data.Attributes, data.UpdatedAt, data.ID, data.Version, data.Version).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, r.versionMismatch(tx, data.ID, data.Version, ctx)
+	}
+	if err != nil {
+		return 0, uniqueSerialNumberError(err)
+	}
+	data.Version = version
+	data.CreatedAt = before.CreatedAt
+	data.Tags = before.Tags
+
+	// * Only real changes are recorded in the history
+	if changed := models.ChangedDataFields(before, data); len(changed) > 0 {
+		if err := r.recordHistory(tx, models.OperationUpdate, data, changed, ctx); err != nil {
+			return 0, err
+		}
+	}
+	return 1, tx.Commit()
 }
 
 func (r *DataRepository) Delete(data *models.Data, ctx context.Context) (int64, error) {
-	res, err := r.deleteStmt.ExecContext(ctx, data.ID)
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := r.readOneTx(tx, data.ID, ctx)
+	if err != nil || before == nil {
+		return 0, err
+	}
+
+	// * The record is only marked as deleted, it stays in the trash until it is restored or purged
+	deletedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	res, err := tx.StmtContext(ctx, r.deleteStmt).ExecContext(ctx, deletedAt, models.ActorFromContext(ctx), deletedAt, data.ID, data.Version, data.Version)
 	if err != nil {
 		return 0, err
 	}
@@ -189,5 +463,54 @@ func (r *DataRepository) Delete(data *models.Data, ctx context.Context) (int64,
 	if err != nil {
 		return 0, err
 	}
-	return rowsAffected, nil
+	if rowsAffected == 0 {
+		return 0, r.versionMismatch(tx, data.ID, data.Version, ctx)
+	}
+
+	if err := r.recordHistory(tx, models.OperationDelete, before, []string{}, ctx); err != nil {
+		return 0, err
+	}
+	return rowsAffected, tx.Commit()
+}
+
+// versionMismatch tells why a conditional change did not affect a record read in the same transaction: it was
+// changed by someone else, or it no longer exists.
+func (r *DataRepository) versionMismatch(tx *DAL.Tx, id int, version int, ctx context.Context) error {
+	if version == 0 {
+		return nil
+	}
+	current, err := r.readOneTx(tx, id, ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return models.ErrVersionMismatch
+	}
+	return nil
+}
+
+// readOneTx reads a record inside a transaction, nil if it does not exist.
+func (r *DataRepository) readOneTx(tx *DAL.Tx, id int, ctx context.Context) (*models.Data, error) {
+	row := tx.StmtContext(ctx, r.readStmt).QueryRowContext(ctx, id)
+	var data models.Data
+	err := row.Scan(&data.ID, &data.DeviceID, &data.DeviceName, &data.Price, &data.Currency, &data.SerialNumber, &data.Type, &data.DateTime, &data.Description, &data.Attributes, &data.Version, &data.CreatedAt, &data.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := readTags(tx, []*models.Data{&data}, ctx); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// uniqueSerialNumberError turns a violation of the unique serial number index into models.ErrDuplicateSerialNumber.
+func uniqueSerialNumberError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique && strings.Contains(sqliteErr.Error(), "serial_number") {
+		return models.ErrDuplicateSerialNumber
+	}
+	return err
 }
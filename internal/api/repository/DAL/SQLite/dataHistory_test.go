@@ -0,0 +1,99 @@
+package SQLite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"testing"
+	"time"
+)
+
+// * The contract checks ReadHistory and ReadAsOf, this checks the rows of data_history they are read from *
+func TestDataHistoryRows(t *testing.T) {
+	db, ctx := openDatabase(t)
+	repo, err := SQLite.NewDataRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB := db.Connection()
+	alice := models.WithActor(context.Background(), "alice")
+
+	data := &models.Data{DeviceID: "d1", SerialNumber: "SN-1", Price: 1999, DateTime: "2024-01-01T10:00:00Z",
+		Currency: models.BaseCurrency, Attributes: models.Attributes{"color": "red"}}
+	if err := repo.Create(data, alice); err != nil {
+		t.Fatal(err)
+	}
+	other := &models.Data{DeviceID: "d2", SerialNumber: "SN-2", DateTime: "2024-01-01T10:00:00Z", Currency: models.BaseCurrency}
+	if err := repo.Create(other, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	beforeUpdate := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	data.Price = 2499
+	data.Attributes = models.Attributes{"color": "blue"}
+	if _, err := repo.Update(data, models.WithActor(context.Background(), "bob")); err != nil {
+		t.Fatal(err)
+	}
+
+	// * A refused change writes no history, the row is part of the transaction of the change
+	if _, err := repo.Update(&models.Data{ID: data.ID, DeviceID: "d1", SerialNumber: "SN-2", Currency: models.BaseCurrency}, context.Background()); !errors.Is(err, models.ErrDuplicateSerialNumber) {
+		t.Fatalf("Update to a used serial number returned %v, want %v", err, models.ErrDuplicateSerialNumber)
+	}
+	if _, err := repo.Update(&models.Data{ID: data.ID, DeviceID: "d1", Version: 1, Currency: models.BaseCurrency}, context.Background()); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("Update of a stale version returned %v, want %v", err, models.ErrVersionMismatch)
+	}
+	unitFailed := errors.New("unit failed")
+	err = DAL.NewUnitOfWork(db).Do(func(ctx context.Context) error {
+		data.Description = "rolled back"
+		if _, err := repo.Update(data, ctx); err != nil {
+			return err
+		}
+		return unitFailed
+	}, context.Background())
+	if !errors.Is(err, unitFailed) {
+		t.Fatalf("Do returned %v, want %v", err, unitFailed)
+	}
+
+	rows, err := sqlDB.Query(`SELECT operation, CAST(changed_at AS TEXT), changed_by, changed_fields, price, attributes FROM data_history WHERE data_id = ? ORDER BY id`, data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var operation, changedAt, changedBy, changedFields, attributes string
+		var price int64
+		if err := rows.Scan(&operation, &changedAt, &changedBy, &changedFields, &price, &attributes); err != nil {
+			t.Fatal(err)
+		}
+		// * changed_at is stored with a fixed width, ReadAsOf compares it as text
+		if _, err := time.Parse(models.HistoryTimeFormat, changedAt); err != nil || len(changedAt) != len(models.HistoryTimeFormat) {
+			t.Errorf("changed_at %q is not in the history time format: %v", changedAt, err)
+		}
+		got = append(got, fmt.Sprintf("%s by %s [%s] %d %s", operation, changedBy, changedFields, price, attributes))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`create by alice [device_id,price,currency,serial_number,date_time,attributes] 1999 {"color":"red"}`,
+		`update by bob [price,attributes] 2499 {"color":"blue"}`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("data_history has the rows\n%v\nwant\n%v", got, want)
+	}
+
+	// * The time of ReadAsOf is compared in UTC, whatever its zone
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	if got, err := repo.ReadAsOf(data.ID, beforeUpdate.In(zone), context.Background()); err != nil || got == nil || got.Price != 1999 || got.Attributes["color"] != "red" {
+		t.Errorf("ReadAsOf before the update in %v returned %+v, %v, want the created record", zone, got, err)
+	}
+	if got, err := repo.ReadAsOf(data.ID, time.Now().In(zone), context.Background()); err != nil || got == nil || got.Price != 2499 || got.Description == "rolled back" {
+		t.Errorf("ReadAsOf now in %v returned %+v, %v, want the updated record", zone, got, err)
+	}
+}
@@ -0,0 +1,263 @@
+package SQLite
+
+import (
+	"database/sql"
+	"errors"
+	"goapi/internal/api/repository/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dataMigrations upgrade the data and data_history tables of existing databases, in order.
+// A migration that copies a table creates it as it was at that migration, not with the current createDataTable,
+// so the migrations after it still find the columns they upgrade.
+var dataMigrations = []migration{
+	{name: "0001_data_serial_number_text", up: migrateSerialNumberToText},
+	{name: "0002_data_price_minor_units", up: migratePriceToMinorUnits},
+	{name: "0003_data_attributes", up: migrateAddAttributes},
+	{name: "0004_data_soft_delete", up: migrateDataSoftDelete},
+	{name: "0005_data_version", up: func(tx *sql.Tx) error { return addVersionColumn(tx, "data") }},
+	{name: "0006_data_updated_at", up: migrateDataUpdatedAt},
+	{name: "0007_data_created_at", up: migrateDataCreatedAt},
+}
+
+// * The data and data_history tables after 0001_data_serial_number_text *
+const (
+	dataTable0001 = `CREATE TABLE data (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_id VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50),
+		price FLOAT,
+		serial_number VARCHAR(50) NOT NULL DEFAULT '',
+		data_type VARCHAR(20),
+		date_time TIMESTAMP,
+		description TEXT
+	);`
+	dataHistoryTable0001 = `CREATE TABLE data_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		data_id INTEGER NOT NULL,
+		operation VARCHAR(10) NOT NULL,
+		changed_at TIMESTAMP NOT NULL,
+		changed_by VARCHAR(50) NOT NULL,
+		changed_fields TEXT NOT NULL,
+		device_id VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50),
+		price FLOAT,
+		serial_number VARCHAR(50) NOT NULL DEFAULT '',
+		data_type VARCHAR(20),
+		date_time TIMESTAMP,
+		description TEXT
+	);`
+)
+
+// * The data and data_history tables after 0002_data_price_minor_units *
+const (
+	dataTable0002 = `CREATE TABLE data (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_id VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50),
+		price INTEGER NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+		serial_number VARCHAR(50) NOT NULL DEFAULT '',
+		data_type VARCHAR(20),
+		date_time TIMESTAMP,
+		description TEXT
+	);`
+	dataHistoryTable0002 = `CREATE TABLE data_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		data_id INTEGER NOT NULL,
+		operation VARCHAR(10) NOT NULL,
+		changed_at TIMESTAMP NOT NULL,
+		changed_by VARCHAR(50) NOT NULL,
+		changed_fields TEXT NOT NULL,
+		device_id VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50),
+		price INTEGER NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+		serial_number VARCHAR(50) NOT NULL DEFAULT '',
+		data_type VARCHAR(20),
+		date_time TIMESTAMP,
+		description TEXT
+	);`
+)
+
+// serialNumberFromFloat converts a FLOAT serial number to text without a trailing ".0", 0 was the value of a missing serial.
+const serialNumberFromFloat = `CASE
+		WHEN serial_number IS NULL OR serial_number = 0 THEN ''
+		WHEN serial_number = CAST(serial_number AS INTEGER) THEN CAST(CAST(serial_number AS INTEGER) AS TEXT)
+		ELSE CAST(serial_number AS TEXT)
+	END`
+
+// migrateSerialNumberToText changes serial_number from FLOAT to text, SQLite can only do that by copying the table.
+// Duplicate serial numbers can not be made unique automatically, so they fail the migration instead of losing data.
+func migrateSerialNumberToText(tx *sql.Tx) error {
+	tables := []struct {
+		name, create, columns string
+		unique                bool
+	}{
+		{"data", dataTable0001, "id, device_id, device_name, price, serial_number, data_type, date_time, description", true},
+		{"data_history", dataHistoryTable0001, "id, data_id, operation, changed_at, changed_by, changed_fields, device_id, device_name, price, serial_number, data_type, date_time, description", false},
+	}
+
+	for _, table := range tables {
+		typ, err := columnType(tx, table.name, "serial_number")
+		if err != nil {
+			return err
+		}
+		if typ != "FLOAT" {
+			// * Created with the text column already
+			continue
+		}
+
+		if table.unique {
+			var duplicates int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM (SELECT ` + serialNumberFromFloat + ` AS serial FROM ` + table.name + ` GROUP BY serial HAVING serial <> '' AND COUNT(*) > 1)`).Scan(&duplicates)
+			if err != nil {
+				return err
+			}
+			if duplicates > 0 {
+				return errors.New(strconv.Itoa(duplicates) + " serial numbers are used by more than one record in " + table.name + ", make them unique before upgrading")
+			}
+		}
+
+		converted := strings.Replace(table.columns, "serial_number", serialNumberFromFloat, 1)
+		if err := copyTable(tx, table.name, table.create, table.columns, converted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePriceToMinorUnits changes price from a FLOAT to integer minor units and adds its currency.
+// Prices stored before had no currency, they are taken to be in the base currency.
+func migratePriceToMinorUnits(tx *sql.Tx) error {
+	tables := []struct {
+		name, create, columns string
+	}{
+		{"data", dataTable0002, "id, device_id, device_name, price, serial_number, data_type, date_time, description"},
+		{"data_history", dataHistoryTable0002, "id, data_id, operation, changed_at, changed_by, changed_fields, device_id, device_name, price, serial_number, data_type, date_time, description"},
+	}
+
+	for _, table := range tables {
+		typ, err := columnType(tx, table.name, "price")
+		if err != nil {
+			return err
+		}
+		if typ != "FLOAT" {
+			// * Created with minor units already
+			continue
+		}
+
+		digits := models.Currencies[models.BaseCurrency]
+		minorUnits := `CAST(ROUND(COALESCE(price, 0) * 1` + strings.Repeat("0", digits) + `) AS INTEGER)`
+		converted := strings.Replace(table.columns, "price", minorUnits, 1) + `, '` + models.BaseCurrency + `'`
+		if err := copyTable(tx, table.name, table.create, table.columns+", currency", converted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAddAttributes adds the attributes object of the record type, existing records have none.
+func migrateAddAttributes(tx *sql.Tx) error {
+	for _, table := range []string{"data", "data_history"} {
+		if err := addColumn(tx, table, "attributes", `TEXT NOT NULL DEFAULT '{}'`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateDataSoftDelete adds the columns of the trash. A deleted record keeps its serial number,
+// so the serial number index is replaced by one that is only unique among the records that are not deleted.
+func migrateDataSoftDelete(tx *sql.Tx) error {
+	if err := addSoftDeleteColumns(tx, "data"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DROP INDEX IF EXISTS idx_data_serial_number`)
+	return err
+}
+
+// addSoftDeleteColumns adds the time and the user of the deletion, rows that are not deleted have neither.
+func addSoftDeleteColumns(tx *sql.Tx, table string) error {
+	if err := addColumn(tx, table, "deleted_at", `TIMESTAMP`); err != nil {
+		return err
+	}
+	return addColumn(tx, table, "deleted_by", `VARCHAR(50)`)
+}
+
+// addVersionColumn adds the version used for optimistic concurrency, existing rows start at version 1.
+func addVersionColumn(tx *sql.Tx, table string) error {
+	return addColumn(tx, table, "version", `INTEGER NOT NULL DEFAULT 1`)
+}
+
+// migrateDataUpdatedAt adds the time of the last change of a record, taken from its history where there is one.
+func migrateDataUpdatedAt(tx *sql.Tx) error {
+	if err := addColumn(tx, "data", "updated_at", `TIMESTAMP`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE data SET updated_at = COALESCE(
+			(SELECT MAX(changed_at) FROM data_history WHERE data_history.data_id = data.id),
+			?)
+		WHERE updated_at IS NULL`, time.Now().UTC().Format(models.HistoryTimeFormat))
+	return err
+}
+
+// migrateDHT22UpdatedAt adds the time of the last change of a reading, existing readings are taken to be changed now.
+func migrateDHT22UpdatedAt(tx *sql.Tx) error {
+	if err := addColumn(tx, "dht22_data", "updated_at", `TIMESTAMP`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE dht22_data SET updated_at = ? WHERE updated_at IS NULL`, time.Now().UTC().Format(models.HistoryTimeFormat))
+	return err
+}
+
+// migrateDataCreatedAt adds the time a record was created, taken from its history where there is one,
+// otherwise the time of its last change is the earliest known.
+func migrateDataCreatedAt(tx *sql.Tx) error {
+	if err := addColumn(tx, "data", "created_at", `TIMESTAMP`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE data SET created_at = COALESCE(
+			(SELECT MIN(changed_at) FROM data_history WHERE data_history.data_id = data.id AND operation = ?),
+			updated_at)
+		WHERE created_at IS NULL`, models.OperationCreate)
+	return err
+}
+
+// migrateDHT22CreatedAt adds the time a reading was created, the time of its last change is the earliest known.
+func migrateDHT22CreatedAt(tx *sql.Tx) error {
+	if err := addColumn(tx, "dht22_data", "created_at", `TIMESTAMP`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE dht22_data SET created_at = updated_at WHERE created_at IS NULL`)
+	return err
+}
+
+// addColumn adds a column to a table, unless the table was created with it already.
+func addColumn(tx *sql.Tx, table string, column string, definition string) error {
+	typ, err := columnType(tx, table, column)
+	if err != nil || typ != "" {
+		return err
+	}
+	_, err = tx.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	return err
+}
+
+// copyTable recreates a table with a new definition and copies its rows, SQLite can not change the type of a column in place.
+// The values of the columns are selected from the old table with the given expressions.
+func copyTable(tx *sql.Tx, name string, create string, columns string, values string) error {
+	statements := []string{
+		`ALTER TABLE ` + name + ` RENAME TO ` + name + `_old`,
+		create,
+		`INSERT INTO ` + name + ` (` + columns + `) SELECT ` + values + ` FROM ` + name + `_old`,
+		`DROP TABLE ` + name + `_old`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
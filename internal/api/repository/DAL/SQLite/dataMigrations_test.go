@@ -0,0 +1,195 @@
+package SQLite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"path/filepath"
+	"testing"
+)
+
+// baselineDataTable is the data table of the first release, before the migrations.
+const baselineDataTable = `CREATE TABLE IF NOT EXISTS data (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	device_id VARCHAR(50) NOT NULL,
+	device_name VARCHAR(50),
+	price FLOAT,
+	serial_number FLOAT,
+	data_type VARCHAR(20),
+	date_time TIMESTAMP,
+	description TEXT
+);`
+
+// createBaselineDatabase writes a database of the first release with the rows, as (device_id, price, serial_number).
+func createBaselineDatabase(t *testing.T, rows [][3]any) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec(baselineDataTable); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if _, err := sqlDB.Exec(`INSERT INTO data (device_id, device_name, price, serial_number, data_type, date_time, description) VALUES (?, 'device', ?, ?, 'Sensor', '2024-01-01T10:00:00Z', 'old')`, row[0], row[1], row[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// openDataRepository opens the database at path with its data repository, which migrates it.
+func openDataRepository(t *testing.T, path string) (models.DataRepository, *sql.DB, error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	db, err := SQLite.NewSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := SQLite.NewDataRepository(db, ctx)
+	return repo, db.Connection(), err
+}
+
+// assertMigratedData checks the rows of createBaselineDatabase in TestDataMigrations after the migrations.
+func assertMigratedData(t *testing.T, repo models.DataRepository) {
+	t.Helper()
+	want := []struct {
+		serialNumber string
+		price        int64
+	}{
+		{"12345", 1999},
+		{"", 0},
+		{"12.5", 10},
+		{"", 100},
+		{"123456789012", 29},
+	}
+	for i, w := range want {
+		data, err := repo.ReadOne(i+1, context.Background())
+		if err != nil || data == nil {
+			t.Fatalf("ReadOne(%d) returned %v, %v", i+1, data, err)
+		}
+		if data.SerialNumber != w.serialNumber || data.Price != w.price || data.Currency != models.BaseCurrency {
+			t.Errorf("Record %d was migrated to serial number %q and price %d %s, want %q and %d %s",
+				i+1, data.SerialNumber, data.Price, data.Currency, w.serialNumber, w.price, models.BaseCurrency)
+		}
+		if data.DeviceName != "device" || data.Description != "old" || data.Version != 1 || data.CreatedAt == "" {
+			t.Errorf("Record %d lost its values or has no defaults: %+v", i+1, data)
+		}
+	}
+}
+
+func TestDataMigrations(t *testing.T) {
+	// * Serial numbers were FLOATs, 0 or NULL stood for none. Prices were FLOATs in euros, rounded to cents: 0.29 is 28.999...
+	path := createBaselineDatabase(t, [][3]any{
+		{"d1", 19.99, 12345.0},
+		{"d2", nil, 0.0},
+		{"d3", 0.1, 12.5},
+		{"d4", 1.0, nil},
+		{"d5", 0.29, 123456789012.0},
+	})
+
+	repo, sqlDB, err := openDataRepository(t, path)
+	if err != nil {
+		t.Fatalf("Migrating the baseline database failed: %v", err)
+	}
+	assertMigratedData(t, repo)
+
+	// * The serial number is text, the migrated table takes serial numbers that are not numbers
+	serial := &models.Data{DeviceID: "d6", SerialNumber: "SN-0001", DateTime: "2024-01-01T10:00:00Z", Currency: models.BaseCurrency}
+	if err := repo.Create(serial, context.Background()); err != nil {
+		t.Fatalf("Create after the migrations failed: %v", err)
+	}
+
+	// * The migrations run again on the migrated tables change nothing
+	if _, err := sqlDB.Exec(`DELETE FROM schema_migrations WHERE name LIKE '000%_data_%'`); err != nil {
+		t.Fatal(err)
+	}
+	repo, _, err = openDataRepository(t, path)
+	if err != nil {
+		t.Fatalf("Migrating the migrated database again failed: %v", err)
+	}
+	assertMigratedData(t, repo)
+	if got, err := repo.ReadOne(serial.ID, context.Background()); err != nil || got == nil || got.SerialNumber != "SN-0001" {
+		t.Errorf("The record created after the migrations was read as %+v, %v", got, err)
+	}
+}
+
+func TestDataMigrationsDuplicateSerialNumbers(t *testing.T) {
+	// * 12345 and 12345.0 are the same serial number as text, the migration fails instead of dropping a record
+	path := createBaselineDatabase(t, [][3]any{
+		{"d1", 1.0, 12345.0},
+		{"d2", 2.0, 12345},
+		{"d3", 3.0, 0.0},
+		{"d4", 4.0, 0.0},
+	})
+
+	_, _, err := openDataRepository(t, path)
+	var migrationErr SQLite.MigrationError
+	if !errors.As(err, &migrationErr) || migrationErr.Name != "0001_data_serial_number_text" {
+		t.Fatalf("Migrating duplicate serial numbers returned %v, want the error of 0001_data_serial_number_text", err)
+	}
+
+	// * The failed migration is rolled back, the table and its rows are as they were
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	var typ string
+	var count int
+	if err := sqlDB.QueryRow(`SELECT type FROM pragma_table_info('data') WHERE name = 'serial_number'`).Scan(&typ); err != nil || typ != "FLOAT" {
+		t.Errorf("serial_number is %q (%v) after the failed migration, want FLOAT", typ, err)
+	}
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM data`).Scan(&count); err != nil || count != 4 {
+		t.Errorf("The data table has %d rows (%v) after the failed migration, want 4", count, err)
+	}
+}
+
+func TestSensorMigrations(t *testing.T) {
+	// * The sensor_readings table had no trash columns, deleted readings were removed
+	path := filepath.Join(t.TempDir(), "sensors.db")
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`CREATE TABLE sensor_readings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sensor_type VARCHAR(50) NOT NULL,
+		device_name VARCHAR(50) NOT NULL,
+		date_time TIMESTAMP NOT NULL,
+		measurements TEXT NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO sensor_readings (sensor_type, device_name, date_time, measurements, created_at, updated_at)
+		VALUES ('ds18b20', 'greenhouse', '2024-01-01T10:00:00Z', '{"temperature":21.5}', '2024-01-01 10:00:01', '2024-01-01 10:00:01')`); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	db, err := SQLite.NewSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := SQLite.NewSensorRepository(db, ctx)
+	if err != nil {
+		t.Fatalf("Migrating the sensor readings failed: %v", err)
+	}
+
+	if rowsAffected, err := repo.Delete("ds18b20", 1, 1, context.Background()); err != nil || rowsAffected != 1 {
+		t.Fatalf("Delete of the migrated reading returned %v, %v, want 1, nil", rowsAffected, err)
+	}
+	if trash, err := repo.ReadTrash(1, 10, context.Background()); err != nil || len(trash) != 1 || trash[0].Values["temperature"] != 21.5 {
+		t.Errorf("ReadTrash returned %v, %v, want the migrated reading", trash, err)
+	}
+}
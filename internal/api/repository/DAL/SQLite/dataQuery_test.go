@@ -0,0 +1,152 @@
+package SQLite_test
+
+import (
+	"context"
+	"fmt"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"testing"
+	"time"
+)
+
+// queryIDs runs a query of the schema against the repository and returns the ids of the page, with the count of all matches.
+func queryIDs[T any](t *testing.T, schema query.Schema, filter string, sort string,
+	run func(q *query.Query) ([]T, int, error), id func(T) int) ([]int, int) {
+	t.Helper()
+	q, err := query.Parse(filter, sort, "", schema)
+	if err != nil {
+		t.Fatalf("Parse %q failed: %v", filter, err)
+	}
+	rows, count, err := run(q)
+	if err != nil {
+		t.Fatalf("Query %q sorted by %q failed: %v", filter, sort, err)
+	}
+	ids := []int{}
+	for _, row := range rows {
+		ids = append(ids, id(row))
+	}
+	return ids, count
+}
+
+// * The contract checks Query and CountQuery on every backend, this checks the SQL they are translated to in SQLite *
+func TestDataQuerySQL(t *testing.T) {
+	db, ctx := openDatabase(t)
+	repo, err := SQLite.NewDataRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []*models.Data{
+		{DeviceID: "d1", DeviceName: "O'Brien's meter", Price: 150, Type: "Sensor", DateTime: "2024-01-31T23:59:59Z", Description: "x' OR '1'='1",
+			Attributes: models.Attributes{"color": "red"}},
+		{DeviceID: "d2", DeviceName: "Drill", Price: 150, Type: "Tool", DateTime: "2024-02-01T00:00:00Z"},
+		{DeviceID: "d3", DeviceName: "Meter", Price: 99, Type: "Sensor", DateTime: "2024-02-15T12:00:00Z"},
+		{DeviceID: "d4", DeviceName: "Saw", Price: 300, Type: "Tool", DateTime: "2024-03-01T00:00:00Z"},
+	}
+	for _, data := range records {
+		data.Currency = models.BaseCurrency
+		if err := repo.Create(data, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(q *query.Query) ([]*models.Data, int, error) {
+		data, err := repo.Query(q, 1, 10, context.Background())
+		if err != nil {
+			return nil, 0, err
+		}
+		count, err := repo.CountQuery(q, context.Background())
+		return data, count, err
+	}
+	id := func(d *models.Data) int { return d.ID }
+
+	tests := []struct {
+		name   string
+		filter string
+		sort   string
+		want   []int
+	}{
+		// * Values are parameters, quotes in them are matched as text and never end the SQL string
+		{"quote in a value", `device_name == "O'Brien's meter"`, "", []int{1}},
+		{"SQL in a value", `description == "x' OR '1'='1"`, "", []int{1}},
+		{"SQL in a value that matches nothing", `device_name == "x' OR '1'='1"`, "", []int{}},
+		// * date_time is text in RFC 3339, a prefix is a range bound
+		{"date_time range", `date_time >= "2024-02" and date_time < "2024-03"`, "", []int{2, 3}},
+		// * created_at is a TIMESTAMP column, it is compared as the text that is stored
+		{"created_at after", `created_at > "2000-01-01"`, "", []int{1, 2, 3, 4}},
+		{"updated_at before", `updated_at < "2000-01-01"`, "", []int{}},
+		// * not, and before or, and the parentheses of the filter are kept in the SQL
+		{"not", `not type == "Tool"`, "", []int{1, 3}},
+		{"and before or", `type == "Tool" and price > 200 or price < 100`, "", []int{3, 4}},
+		{"parentheses", `type == "Tool" and (price > 200 or price < 100)`, "", []int{4}},
+		// * Ties of the sort are in id order, whatever the direction of the sort
+		{"sort with ties", "", "-price", []int{4, 1, 2, 3}},
+		{"sort by two columns", "", "type,-date_time", []int{3, 1, 4, 2}},
+		{"sort by id descending", `price == 150`, "-id", []int{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, count := queryIDs(t, models.DataQuerySchema, tt.filter, tt.sort, run, id)
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) || count != len(tt.want) {
+				t.Errorf("Query returned %v and CountQuery %d, want %v", ids, count, tt.want)
+			}
+		})
+	}
+
+	// * Each selected field is scanned from its column, the timestamps too
+	q, err := query.Parse(`id == 1`, "", "created_at,attributes,type", models.DataQuerySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Query(q, 1, 10, context.Background())
+	if err != nil || len(got) != 1 {
+		t.Fatalf("Query with fields returned %v, %v", got, err)
+	}
+	if got[0].CreatedAt != records[0].CreatedAt || got[0].Type != "Sensor" || got[0].Attributes["color"] != "red" || got[0].ID != 0 {
+		t.Errorf("Query with fields created_at,attributes,type returned %+v, want the fields of %+v", got[0], records[0])
+	}
+}
+
+func TestDHT22QuerySQL(t *testing.T) {
+	db, ctx := openDatabase(t)
+	repo, err := SQLite.NewDHT22Repository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i, temperature := range []float64{19.5, 21.25, 21.25, 25} {
+		reading := &models.DHT22Data{DeviceName: fmt.Sprintf("greenhouse-%d", i%2), Temperature: temperature, Humidity: 40 + float64(i),
+			DateTime: start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)}
+		if err := repo.Create(reading, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(q *query.Query) ([]*models.DHT22Data, int, error) {
+		data, err := repo.Query(q, 1, 10, context.Background())
+		if err != nil {
+			return nil, 0, err
+		}
+		count, err := repo.CountQuery(q, context.Background())
+		return data, count, err
+	}
+	id := func(d *models.DHT22Data) int { return d.ID }
+
+	tests := []struct {
+		name   string
+		filter string
+		sort   string
+		want   []int
+	}{
+		// * Numbers are compared as numbers, not as text: 19.5 < 21.25 < 25
+		{"decimal bound", `temperature > 21 and temperature <= 21.25`, "", []int{2, 3}},
+		{"numeric sort", "", "-temperature", []int{4, 2, 3, 1}},
+		{"device and time", `device_name == "greenhouse-1" and date_time >= "2024-01-01T11:00:00Z"`, "-date_time", []int{4, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, count := queryIDs(t, models.DHT22QuerySchema, tt.filter, tt.sort, run, id)
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) || count != len(tt.want) {
+				t.Errorf("Query returned %v and CountQuery %d, want %v", ids, count, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package SQLite
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"slices"
+	"strings"
+)
+
+// dataSearchTriggers keep the FTS5 index in sync with the data table.
+var dataSearchTriggers = []string{"data_fts_insert", "data_fts_delete", "data_fts_update"}
+
+// createDataSearchIndex creates the FTS5 index over device_name, data_type and description of the data table.
+// The index uses the data table as external content and is kept in sync by triggers.
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag, without it false is returned and Search
+// scans the table instead. The triggers of an index made by a build with FTS5 are then dropped, they would fail
+// every write, and the index is rebuilt once the next time a build with FTS5 opens the database.
+func createDataSearchIndex(sqlDB *sql.DB) (bool, error) {
+	_, err := sqlDB.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS data_fts USING fts5(
+		device_name,
+		data_type,
+		description,
+		content='data',
+		content_rowid='id'
+	)`)
+	if err != nil {
+		if !strings.Contains(err.Error(), "no such module: fts5") {
+			return false, err
+		}
+		for _, trigger := range dataSearchTriggers {
+			if _, err := sqlDB.Exec(`DROP TRIGGER IF EXISTS ` + trigger); err != nil {
+				return false, err
+			}
+		}
+		if _, err := sqlDB.Exec(`DELETE FROM schema_migrations WHERE name = ?`, rebuildDataSearchIndex.name); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS data_fts_insert AFTER INSERT ON data BEGIN
+			INSERT INTO data_fts(rowid, device_name, data_type, description) VALUES (new.id, new.device_name, new.data_type, new.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS data_fts_delete AFTER DELETE ON data BEGIN
+			INSERT INTO data_fts(data_fts, rowid, device_name, data_type, description) VALUES ('delete', old.id, old.device_name, old.data_type, old.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS data_fts_update AFTER UPDATE ON data BEGIN
+			INSERT INTO data_fts(data_fts, rowid, device_name, data_type, description) VALUES ('delete', old.id, old.device_name, old.data_type, old.description);
+			INSERT INTO data_fts(rowid, device_name, data_type, description) VALUES (new.id, new.device_name, new.data_type, new.description);
+		END`,
+	}
+	for _, stmt := range triggers {
+		if _, err := sqlDB.Exec(stmt); err != nil {
+			return false, err
+		}
+	}
+	// * Rows written while the index did not exist, or without its triggers, are indexed once
+	if err := migrate(sqlDB, []migration{rebuildDataSearchIndex}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rebuildDataSearchIndex indexes the data table from scratch, it reads every row
+var rebuildDataSearchIndex = migration{
+	name: "rebuild_data_fts",
+	up: func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO data_fts(data_fts) VALUES ('rebuild')`)
+		return err
+	},
+}
+
+// Search returns one page of the records matching the text, best match first, with a highlighted snippet.
+// Every word of the text must match, the last word also matches as a prefix.
+func (r *DataRepository) Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	if !r.searchEnabled {
+		return r.searchWithoutIndex(text, page, rowsPerPage, ctx)
+	}
+
+	match := searchMatchExpression(text)
+	if match == "" {
+		return []*models.DataSearchResult{}, 0, nil
+	}
+
+	var total int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `SELECT COUNT(*) FROM data_fts JOIN data d ON d.id = data_fts.rowid WHERE data_fts MATCH ? AND d.deleted_at IS NULL`, match).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT d.id, d.device_id, d.device_name, d.price, d.currency, d.serial_number, d.data_type, d.date_time, d.description, d.attributes, d.version,
+			CAST(d.created_at AS TEXT), CAST(d.updated_at AS TEXT),
+			bm25(data_fts), snippet(data_fts, -1, '<mark>', '</mark>', '…', 12)
+		FROM data_fts JOIN data d ON d.id = data_fts.rowid
+		WHERE data_fts MATCH ? AND d.deleted_at IS NULL
+		ORDER BY bm25(data_fts), d.id
+		LIMIT ? OFFSET ?`, match, rowsPerPage, rowsPerPage*(page-1))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []*models.DataSearchResult
+	for rows.Next() {
+		var res models.DataSearchResult
+		err := rows.Scan(&res.ID, &res.DeviceID, &res.DeviceName, &res.Price, &res.Currency, &res.SerialNumber, &res.Type, &res.DateTime, &res.Description, &res.Attributes, &res.Version, &res.CreatedAt, &res.UpdatedAt, &res.Rank, &res.Snippet)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, &res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	data := make([]*models.Data, len(results))
+	for i, res := range results {
+		data[i] = &res.Data
+	}
+	return results, total, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+// searchWithoutIndex is Search without the FTS5 index, with the matching and ranking of the in-memory backend.
+// The rows that can match are narrowed down with LIKE, which only folds the case of ASCII letters,
+// the others are matched word by word. Every candidate is read, it is slower than the index on large tables.
+func (r *DataRepository) searchWithoutIndex(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	terms := DAL.SearchTokens(text)
+	if len(terms) == 0 {
+		return []*models.DataSearchResult{}, 0, nil
+	}
+
+	where := "deleted_at IS NULL"
+	var args []any
+	for _, term := range terms {
+		if !isASCII(term) {
+			continue
+		}
+		like := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term) + "%"
+		where += ` AND (device_name LIKE ? ESCAPE '\' OR data_type LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`
+		args = append(args, like, like, like)
+	}
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT id, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, version,
+			CAST(created_at AS TEXT), CAST(updated_at AS TEXT)
+		FROM data WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []*models.DataSearchResult
+	for rows.Next() {
+		var res models.DataSearchResult
+		err := rows.Scan(&res.ID, &res.DeviceID, &res.DeviceName, &res.Price, &res.Currency, &res.SerialNumber, &res.Type, &res.DateTime, &res.Description, &res.Attributes, &res.Version, &res.CreatedAt, &res.UpdatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		if rank, snippet, ok := DAL.MatchSearch(&res.Data, terms); ok {
+			res.Rank, res.Snippet = rank, snippet
+			results = append(results, &res)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	slices.SortStableFunc(results, func(a, b *models.DataSearchResult) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), cmp.Compare(a.ID, b.ID))
+	})
+	total := len(results)
+	offset := min(rowsPerPage*(page-1), total)
+	results = results[offset:min(offset+rowsPerPage, total)]
+
+	data := make([]*models.Data, len(results))
+	for i, res := range results {
+		data[i] = &res.Data
+	}
+	return results, total, readTags(DAL.Conn(ctx, r.sqlDB), data, ctx)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// searchMatchExpression quotes every word of the user's text, so FTS5 query syntax in it is matched literally.
+func searchMatchExpression(text string) string {
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	if len(words) > 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
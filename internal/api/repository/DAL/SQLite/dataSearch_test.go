@@ -0,0 +1,115 @@
+package SQLite_test
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+// searchIDs searches the repository and returns the ids of the page in order, with the total.
+func searchIDs(t *testing.T, repo models.DataRepository, text string, page int, rowsPerPage int) ([]int, int, []*models.DataSearchResult) {
+	t.Helper()
+	results, total, err := repo.Search(text, page, rowsPerPage, context.Background())
+	if err != nil {
+		t.Fatalf("Search %q failed: %v", text, err)
+	}
+	ids := make([]int, len(results))
+	for i, res := range results {
+		ids[i] = res.ID
+	}
+	return ids, total, results
+}
+
+func assertIDs(t *testing.T, text string, got []int, want ...int) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("Search %q returned %v, want %v", text, got, want)
+	}
+}
+
+// assertSameIDs is assertIDs for records that match equally well, the index and the scan rank them differently.
+func assertSameIDs(t *testing.T, text string, got []int, want ...int) {
+	t.Helper()
+	got = slices.Clone(got)
+	slices.Sort(got)
+	assertIDs(t, text, got, want...)
+}
+
+// * The same test runs with the FTS5 index (go test -tags sqlite_fts5) and with the scan of the table without it *
+func TestDataSearch(t *testing.T) {
+	db, ctx := openDatabase(t)
+	repo, err := SQLite.NewDataRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := []*models.Data{
+		{DeviceID: "d1", DeviceName: "Bosch BME280", SerialNumber: "SN-1", Type: "Sensor", DateTime: "2024-01-01T10:00:00Z", Description: "Cracked Bosch housing", Currency: models.BaseCurrency},
+		{DeviceID: "d2", DeviceName: "Bosch drill", SerialNumber: "SN-2", Type: "Tool", DateTime: "2024-01-01T10:00:01Z", Description: "Works fine", Currency: models.BaseCurrency},
+		{DeviceID: "d3", DeviceName: "Makita", SerialNumber: "SN-3", Type: "Tool", DateTime: "2024-01-01T10:00:02Z", Description: "cracked case", Currency: models.BaseCurrency},
+	}
+	for _, data := range records {
+		if err := repo.Create(data, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bme, drill, makita := records[0], records[1], records[2]
+
+	// * Every word must match, the last one as a prefix, case is ignored
+	ids, total, results := searchIDs(t, repo, "BOSCH crack", 1, 10)
+	assertIDs(t, "BOSCH crack", ids, bme.ID)
+	if total != 1 || results[0].Snippet != "<mark>Cracked</mark> <mark>Bosch</mark> housing" {
+		t.Errorf("Search returned total %v and snippet %q", total, results[0].Snippet)
+	}
+
+	// * The record with more matches comes first, pages follow that order
+	ids, total, _ = searchIDs(t, repo, "bosch", 1, 10)
+	assertIDs(t, "bosch", ids, bme.ID, drill.ID)
+	if ids, total2, _ := searchIDs(t, repo, "bosch", 2, 1); total2 != total || len(ids) != 1 || ids[0] != drill.ID {
+		t.Errorf("Search page 2 returned %v of %v, want [%v] of %v", ids, total2, drill.ID, total)
+	}
+	ids, _, _ = searchIDs(t, repo, "tool", 1, 10)
+	assertSameIDs(t, "tool", ids, drill.ID, makita.ID)
+	ids, _, _ = searchIDs(t, repo, "nothing", 1, 10)
+	assertIDs(t, "nothing", ids)
+	ids, _, _ = searchIDs(t, repo, "  ", 1, 10)
+	assertIDs(t, "blank", ids)
+
+	// * Updates are searched by their new text only
+	drill.Description = "Cracked chuck"
+	if _, err := repo.Update(drill, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ids, _, _ = searchIDs(t, repo, "bosch crack", 1, 10)
+	assertIDs(t, "bosch crack", ids, bme.ID, drill.ID)
+	ids, _, _ = searchIDs(t, repo, "fine", 1, 10)
+	assertIDs(t, "fine", ids)
+
+	// * Records in the trash are not found, purged ones are gone from the index
+	if _, err := repo.Delete(&models.Data{ID: bme.ID}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ids, _, _ = searchIDs(t, repo, "bosch", 1, 10)
+	assertIDs(t, "bosch", ids, drill.ID)
+	if _, err := repo.Purge(time.Now().Add(time.Hour), context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ids, _, _ = searchIDs(t, repo, "housing", 1, 10)
+	assertIDs(t, "housing", ids)
+
+	// * The index is built once when it is created, opening the database again keeps it as it is
+	var appliedAt string
+	err = db.Connection().QueryRow(`SELECT applied_at FROM schema_migrations WHERE name = 'rebuild_data_fts'`).Scan(&appliedAt)
+	if _, err := SQLite.NewDataRepository(db, ctx); err != nil {
+		t.Fatal(err)
+	}
+	var again string
+	if err2 := db.Connection().QueryRow(`SELECT applied_at FROM schema_migrations WHERE name = 'rebuild_data_fts'`).Scan(&again); (err == nil) != (err2 == nil) || again != appliedAt {
+		t.Errorf("Opening the database again changed the index rebuild from %q (%v) to %q (%v)", appliedAt, err, again, err2)
+	}
+	ids, _, _ = searchIDs(t, repo, "cracked", 1, 10)
+	assertSameIDs(t, "cracked", ids, drill.ID, makita.ID)
+}
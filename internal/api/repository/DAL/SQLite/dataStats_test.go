@@ -0,0 +1,82 @@
+package SQLite_test
+
+import (
+	"context"
+	"fmt"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"testing"
+)
+
+// * The contract checks Stats on every backend, this checks the grouping, the rounding and the device names of its SQL *
+func TestDataStatsSQL(t *testing.T) {
+	db, ctx := openDatabase(t)
+	repo, err := SQLite.NewDataRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []*models.Data{
+		{DeviceID: "d1", DeviceName: "Old name", Price: 100, Currency: "EUR", Type: "Sensor", DateTime: "2024-01-15T10:00:00Z"},
+		{DeviceID: "d1", DeviceName: "New name", Price: 101, Currency: "EUR", Type: "Sensor", DateTime: "2024-03-01T10:00:00Z"},
+		// * Created last but measured first, the name of d1 is the one of its latest date_time, not of its latest id
+		{DeviceID: "d1", DeviceName: "Oldest name", Price: 1, Currency: "USD", Type: "Sensor", DateTime: "2023-12-31T23:59:59Z"},
+		{DeviceID: "d2", DeviceName: "Drill", Price: 2, Currency: "USD", Type: "Sensor", DateTime: "2024-01-01T00:00:00Z"},
+		{DeviceID: "d2", DeviceName: "Drill", Price: 2, Currency: "USD", Type: "Tool", DateTime: "2024-01-31T23:59:59Z"},
+		// * In the trash: not counted and its later name is not the name of d1
+		{DeviceID: "d1", DeviceName: "Trashed name", Price: 1000, Currency: "EUR", Type: "Sensor", DateTime: "2024-05-01T10:00:00Z"},
+	}
+	for _, data := range records {
+		if err := repo.Create(data, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.Delete(records[5], context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := repo.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	var byType, byDevice, byMonth []string
+	for _, s := range stats.ByType {
+		byType = append(byType, fmt.Sprintf("%v:%v%v", s.Type, s.Count, formatPrices(s.Prices)))
+	}
+	for _, s := range stats.ByDevice {
+		byDevice = append(byDevice, fmt.Sprintf("%v/%v:%v%v", s.DeviceID, s.DeviceName, s.Count, formatPrices(s.Prices)))
+	}
+	for _, s := range stats.ByMonth {
+		byMonth = append(byMonth, fmt.Sprintf("%v:%v", s.Month, s.Count))
+	}
+
+	// * Averages are rounded half away from zero to minor units: 100.5 is 101, 1.5 is 2
+	if got, want := fmt.Sprint(byType), "[Sensor:4[EUR 2 201 101 USD 2 3 2] Tool:1[USD 1 2 2]]"; got != want || stats.Total != 5 {
+		t.Errorf("Stats of %d records by type returned %v, want 5 and %v", stats.Total, got, want)
+	}
+	if got, want := fmt.Sprint(byDevice), "[d1/New name:3[EUR 2 201 101 USD 1 1 1] d2/Drill:2[USD 2 4 2]]"; got != want {
+		t.Errorf("Stats by device returned %v, want %v", got, want)
+	}
+	if got, want := fmt.Sprint(byMonth), "[2023-12:1 2024-01:3 2024-03:1]"; got != want {
+		t.Errorf("Stats by month returned %v, want %v", got, want)
+	}
+
+	// * An empty table has empty groups, not null ones
+	db, ctx = openDatabase(t)
+	empty, err := SQLite.NewDataRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err = empty.Stats(context.Background())
+	if err != nil || stats.Total != 0 || stats.ByType == nil || stats.ByDevice == nil || stats.ByMonth == nil {
+		t.Errorf("Stats of an empty table returned %+v, %v", stats, err)
+	}
+}
+
+// formatPrices formats the prices of a stats group as [currency count total average ...].
+func formatPrices(prices []*models.PriceStats) string {
+	var s []string
+	for _, p := range prices {
+		s = append(s, fmt.Sprint(p.Currency, " ", p.Count, " ", p.Total, " ", p.Average))
+	}
+	return fmt.Sprint(s)
+}
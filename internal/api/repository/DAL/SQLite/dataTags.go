@@ -0,0 +1,172 @@
+package SQLite
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"strings"
+	"time"
+)
+
+// createDataTagTables creates the tags and the many-to-many table that tags the records.
+// Tags are kept while a record is in the trash, they are removed with the record when it is purged.
+func createDataTagTables(sqlDB *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(50) NOT NULL UNIQUE
+		);`,
+		`CREATE TABLE IF NOT EXISTS data_tags (
+			data_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (data_id, tag_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_data_tags_tag_id ON data_tags (tag_id, data_id)`,
+		`CREATE TRIGGER IF NOT EXISTS data_tags_purge AFTER DELETE ON data BEGIN
+			DELETE FROM data_tags WHERE data_id = OLD.id;
+		END`,
+	}
+	for _, statement := range statements {
+		if _, err := sqlDB.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryer is a *sql.DB or a *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// readTags sets the tags of the records, in alphabetical order, with one query for all of them.
+func readTags(q queryer, data []*models.Data, ctx context.Context) error {
+	if len(data) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Data, len(data))
+	args := make([]any, 0, len(data))
+	for _, d := range data {
+		byID[d.ID] = d
+		args = append(args, d.ID)
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT dt.data_id, t.name FROM data_tags dt JOIN tags t ON t.id = dt.tag_id
+		WHERE dt.data_id IN (`+placeholders(len(args))+`) ORDER BY dt.data_id, t.name`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		if d, ok := byID[id]; ok {
+			d.Tags = append(d.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// withTagFilter adds the tags of the query to the WHERE clause: records with all of the tags, or any of them.
+func withTagFilter(q *query.Query, where string, args []any) (string, []any) {
+	if q == nil || len(q.Tags) == 0 {
+		return where, args
+	}
+	clause := `id IN (SELECT dt.data_id FROM data_tags dt JOIN tags t ON t.id = dt.tag_id WHERE t.name IN (` + placeholders(len(q.Tags)) + `)`
+	for _, tag := range q.Tags {
+		args = append(args, tag)
+	}
+	if q.AnyTag {
+		clause += `)`
+	} else {
+		clause += ` GROUP BY dt.data_id HAVING COUNT(*) = ?)`
+		args = append(args, len(q.Tags))
+	}
+
+	if where == "" {
+		return clause, args
+	}
+	return "(" + where + ") AND " + clause, args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// AddTags tags a record, tags it already has are kept. 0 if the record does not exist.
+func (r *DataRepository) AddTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return r.changeTags(id, version, ctx, func(tx *DAL.Tx) (int64, error) {
+		var changed int64
+		for _, tag := range tags {
+			if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag); err != nil {
+				return 0, err
+			}
+			res, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO data_tags (data_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`, id, tag)
+			if err != nil {
+				return 0, err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return 0, err
+			}
+			changed += n
+		}
+		return changed, nil
+	})
+}
+
+// RemoveTags removes tags from a record, tags it does not have are ignored. 0 if the record does not exist.
+func (r *DataRepository) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return r.changeTags(id, version, ctx, func(tx *DAL.Tx) (int64, error) {
+		args := []any{id}
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM data_tags WHERE data_id = ? AND tag_id IN (SELECT id FROM tags WHERE name IN (`+placeholders(len(tags))+`))`, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	})
+}
+
+// changeTags runs a change of the tags of a record in a transaction. The tags are part of the record,
+// so a change makes a new version with a history entry, a change that adds or removes nothing does not.
+func (r *DataRepository) changeTags(id int, version int, ctx context.Context, change func(tx *DAL.Tx) (int64, error)) (int64, error) {
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := r.readOneTx(tx, id, ctx)
+	if err != nil || before == nil {
+		return 0, err
+	}
+	if version != 0 && version != before.Version {
+		return 0, models.ErrVersionMismatch
+	}
+
+	changed, err := change(tx)
+	if err != nil {
+		return 0, err
+	}
+	if changed == 0 {
+		return 1, tx.Commit()
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE data SET version = version + 1, updated_at = ? WHERE id = ?`,
+		time.Now().UTC().Format(models.HistoryTimeFormat), id); err != nil {
+		return 0, err
+	}
+	if err := r.recordHistory(tx, models.OperationUpdate, before, []string{"tags"}, ctx); err != nil {
+		return 0, err
+	}
+	return 1, tx.Commit()
+}
@@ -0,0 +1,149 @@
+package SQLite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"testing"
+)
+
+// countTagRows counts the rows of the tags table and of the data_tags table.
+func countTagRows(t *testing.T, sqlDB *sql.DB) (int, int) {
+	t.Helper()
+	var tags, dataTags int
+	if err := sqlDB.QueryRow(`SELECT (SELECT COUNT(*) FROM tags), (SELECT COUNT(*) FROM data_tags)`).Scan(&tags, &dataTags); err != nil {
+		t.Fatal(err)
+	}
+	return tags, dataTags
+}
+
+// * The contract checks AddTags, RemoveTags and the tag filter, this checks the rows of tags and data_tags and the SQL of the filter *
+func TestDataTagRows(t *testing.T) {
+	db, ctx := openDatabase(t)
+	repo, err := SQLite.NewDataRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB := db.Connection()
+	records := []*models.Data{
+		{DeviceID: "d1", Price: 50, Type: "Sensor"},
+		{DeviceID: "d2", Price: 150, Type: "Tool"},
+		{DeviceID: "d3", Price: 250, Type: "Sensor"},
+	}
+	for _, data := range records {
+		data.Currency = models.BaseCurrency
+		if err := repo.Create(data, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// * A tag is stored once and linked to each record, a tag repeated in one call is linked once and makes one version
+	if _, err := repo.AddTags(records[0].ID, []string{"project:apollo", "site:berlin", "project:apollo"}, 0, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddTags(records[1].ID, []string{"project:apollo"}, 0, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddTags(records[2].ID, []string{"site:berlin"}, 0, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if tags, dataTags := countTagRows(t, sqlDB); tags != 2 || dataTags != 4 {
+		t.Errorf("tags has %d rows and data_tags %d, want 2 and 4", tags, dataTags)
+	}
+	if got := readData(t, repo, records[0].ID); got.Version != 2 {
+		t.Errorf("AddTags made version %d, want 2", got.Version)
+	}
+
+	// * Tags added in a unit that fails are rolled back with it
+	unitFailed := errors.New("unit failed")
+	err = DAL.NewUnitOfWork(db).Do(func(ctx context.Context) error {
+		if _, err := repo.AddTags(records[2].ID, []string{"rolled-back"}, 0, ctx); err != nil {
+			return err
+		}
+		return unitFailed
+	}, context.Background())
+	if !errors.Is(err, unitFailed) {
+		t.Fatalf("Do returned %v, want %v", err, unitFailed)
+	}
+	if tags, dataTags := countTagRows(t, sqlDB); tags != 2 || dataTags != 4 {
+		t.Errorf("tags has %d rows and data_tags %d after the rolled back unit, want 2 and 4", tags, dataTags)
+	}
+
+	// * The tag filter is added to the filter of the query in parentheses, an or of the filter does not escape it
+	tests := []struct {
+		name   string
+		filter string
+		tags   []string
+		anyTag bool
+		want   []int
+	}{
+		{"all tags", "", []string{"project:apollo", "site:berlin"}, false, []int{1}},
+		{"any tag, each record once", "", []string{"project:apollo", "site:berlin"}, true, []int{1, 2, 3}},
+		{"or in the filter", `type == "Tool" or price > 200`, []string{"site:berlin"}, false, []int{3}},
+		{"or in the filter, any tag", `type == "Tool" or price > 200`, []string{"project:apollo", "missing"}, true, []int{2}},
+		{"missing tag", "", []string{"project:apollo", "missing"}, false, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := query.Parse(tt.filter, "", "", models.DataQuerySchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.Tags, q.AnyTag = tt.tags, tt.anyTag
+			data, err := repo.Query(q, 1, 10, context.Background())
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			count, err := repo.CountQuery(q, context.Background())
+			if err != nil {
+				t.Fatalf("CountQuery failed: %v", err)
+			}
+			ids := []int{}
+			for _, d := range data {
+				ids = append(ids, d.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) || count != len(tt.want) {
+				t.Errorf("Query returned %v and CountQuery %d, want %v", ids, count, tt.want)
+			}
+		})
+	}
+
+	// * Removing a tag from a record unlinks it there only, the tag stays for the other records
+	if _, err := repo.RemoveTags(records[0].ID, []string{"project:apollo"}, 0, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if tags, dataTags := countTagRows(t, sqlDB); tags != 2 || dataTags != 3 {
+		t.Errorf("tags has %d rows and data_tags %d after RemoveTags, want 2 and 3", tags, dataTags)
+	}
+	if got := readData(t, repo, records[1].ID); fmt.Sprint(got.Tags) != "[project:apollo]" {
+		t.Errorf("The other record has the tags %v after RemoveTags, want [project:apollo]", got.Tags)
+	}
+
+	// * The tags of a page are read with one query, each record gets its own
+	data, err := repo.ReadMany(1, 10, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range data {
+		got = append(got, fmt.Sprintf("%d%v", d.ID, d.Tags))
+	}
+	if want := "[1[site:berlin] 2[project:apollo] 3[site:berlin]]"; fmt.Sprint(got) != want {
+		t.Errorf("ReadMany returned the tags %v, want %v", got, want)
+	}
+}
+
+// readData reads a record that must exist.
+func readData(t *testing.T, repo models.DataRepository, id int) *models.Data {
+	t.Helper()
+	data, err := repo.ReadOne(id, context.Background())
+	if err != nil || data == nil {
+		t.Fatalf("ReadOne(%d) returned %v, %v", id, data, err)
+	}
+	return data
+}
@@ -0,0 +1,129 @@
+package SQLite
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"time"
+)
+
+// notDeleted restricts a WHERE condition to the rows that are not in the trash.
+func notDeleted(where string) string {
+	if where == "" {
+		return "deleted_at IS NULL"
+	}
+	return "deleted_at IS NULL AND (" + where + ")"
+}
+
+// Restore takes a record out of the trash, its serial number must not have been reused in the meantime.
+func (r *DataRepository) Restore(id int, ctx context.Context) (int64, error) {
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE data SET deleted_at = NULL, deleted_by = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`,
+		time.Now().UTC().Format(models.HistoryTimeFormat), id)
+	if err != nil {
+		return 0, uniqueSerialNumberError(err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return 0, err
+	}
+
+	restored, err := r.readOneTx(tx, id, ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.recordHistory(tx, models.OperationRestore, restored, []string{}, ctx); err != nil {
+		return 0, err
+	}
+	return rowsAffected, tx.Commit()
+}
+
+// ReadTrash returns one page of the deleted records, most recently deleted first.
+func (r *DataRepository) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedData, error) {
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT id, device_id, device_name, price, currency, serial_number, data_type, date_time, description, attributes, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT), deleted_at, deleted_by
+		FROM data WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC LIMIT ? OFFSET ?`, rowsPerPage, rowsPerPage*(page-1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trash []*models.TrashedData
+	for rows.Next() {
+		var t models.TrashedData
+		err := rows.Scan(&t.ID, &t.DeviceID, &t.DeviceName, &t.Price, &t.Currency, &t.SerialNumber, &t.Type, &t.DateTime, &t.Description, &t.Attributes, &t.Version, &t.CreatedAt, &t.UpdatedAt, &t.DeletedAt, &t.DeletedBy)
+		if err != nil {
+			return nil, err
+		}
+		trash = append(trash, &t)
+	}
+	return trash, rows.Err()
+}
+
+func (r *DataRepository) CountTrash(ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `SELECT COUNT(*) FROM data WHERE deleted_at IS NOT NULL`).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Purge permanently removes the records deleted before the given time, their history is kept.
+func (r *DataRepository) Purge(before time.Time, ctx context.Context) (int64, error) {
+	res, err := DAL.Conn(ctx, r.sqlDB).ExecContext(ctx, `DELETE FROM data WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before.UTC().Format(models.HistoryTimeFormat))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Restore takes a reading out of the trash.
+func (r *DHT22Repository) Restore(id int, ctx context.Context) (int64, error) {
+	res, err := DAL.Conn(ctx, r.sqlDB).ExecContext(ctx, `UPDATE dht22_data SET deleted_at = NULL, deleted_by = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`,
+		time.Now().UTC().Format(models.HistoryTimeFormat), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ReadTrash returns one page of the deleted readings, most recently deleted first.
+func (r *DHT22Repository) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedDHT22Data, error) {
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, `SELECT id, device_name, temperature, humidity, date_time, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT), deleted_at, deleted_by
+		FROM dht22_data WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC LIMIT ? OFFSET ?`, rowsPerPage, rowsPerPage*(page-1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trash []*models.TrashedDHT22Data
+	for rows.Next() {
+		var t models.TrashedDHT22Data
+		if err := rows.Scan(&t.ID, &t.DeviceName, &t.Temperature, &t.Humidity, &t.DateTime, &t.Version, &t.CreatedAt, &t.UpdatedAt, &t.DeletedAt, &t.DeletedBy); err != nil {
+			return nil, err
+		}
+		trash = append(trash, &t)
+	}
+	return trash, rows.Err()
+}
+
+func (r *DHT22Repository) CountTrash(ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, `SELECT COUNT(*) FROM dht22_data WHERE deleted_at IS NOT NULL`).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Purge permanently removes the readings deleted before the given time.
+func (r *DHT22Repository) Purge(before time.Time, ctx context.Context) (int64, error) {
+	res, err := DAL.Conn(ctx, r.sqlDB).ExecContext(ctx, `DELETE FROM dht22_data WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before.UTC().Format(models.HistoryTimeFormat))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
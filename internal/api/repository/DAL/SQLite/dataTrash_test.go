@@ -0,0 +1,97 @@
+package SQLite_test
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"testing"
+	"time"
+)
+
+// countRows counts the rows of a table that belong to the record, by the column that holds its id.
+func countRows(t *testing.T, sqlDB *sql.DB, table string, column string, id int) int {
+	t.Helper()
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+column+` = ?`, id).Scan(&count); err != nil {
+		t.Fatalf("Counting the rows of %s failed: %v", table, err)
+	}
+	return count
+}
+
+// * The contract checks what the repositories return, this checks the rows a purge leaves in the tables *
+func TestDataPurgeRows(t *testing.T) {
+	db, ctx := openDatabase(t)
+	repo, err := SQLite.NewDataRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachments, err := SQLite.NewAttachmentRepository(db, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB := db.Connection()
+
+	records := []*models.Data{
+		{DeviceID: "d1", SerialNumber: "SN-1", DateTime: "2024-01-01T10:00:00Z", Currency: models.BaseCurrency},
+		{DeviceID: "d2", SerialNumber: "SN-2", DateTime: "2024-01-01T10:00:01Z", Currency: models.BaseCurrency},
+	}
+	for i, data := range records {
+		if err := repo.Create(data, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.AddTags(data.ID, []string{"shared", data.DeviceID}, 0, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		attachment := &models.Attachment{DataID: data.ID, FileName: "manual.pdf", ContentType: "application/pdf", Size: int64(i),
+			SHA256: "0000000000000000000000000000000000000000000000000000000000000000", BlobKey: data.DeviceID, CreatedBy: "test"}
+		if err := attachments.Create(attachment, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	purged, kept := records[0], records[1]
+	if _, err := repo.Delete(&models.Data{ID: purged.ID}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	history := countRows(t, sqlDB, "data_history", "data_id", purged.ID)
+
+	// * The trash keeps every row of the record
+	if got := countRows(t, sqlDB, "data_tags", "data_id", purged.ID); got != 2 {
+		t.Errorf("The record in the trash has %d tag rows, want 2", got)
+	}
+	if n, err := repo.Purge(time.Now().Add(time.Hour), context.Background()); err != nil || n != 1 {
+		t.Fatalf("Purge returned %v, %v, want 1, nil", n, err)
+	}
+
+	// * The trigger removes the tags of the purged record, its history stays, its attachments are orphans until DeleteOrphans
+	if got := countRows(t, sqlDB, "data", "id", purged.ID); got != 0 {
+		t.Errorf("The purged record has %d rows in data, want 0", got)
+	}
+	if got := countRows(t, sqlDB, "data_tags", "data_id", purged.ID); got != 0 {
+		t.Errorf("The purged record has %d tag rows, want 0", got)
+	}
+	if got := countRows(t, sqlDB, "data_history", "data_id", purged.ID); got != history || got < 2 {
+		t.Errorf("The purged record has %d history rows, want the %d it had in the trash", got, history)
+	}
+	if got := countRows(t, sqlDB, "attachments", "data_id", purged.ID); got != 1 {
+		t.Errorf("The purged record has %d attachment rows before DeleteOrphans, want 1", got)
+	}
+	if orphans, err := attachments.DeleteOrphans(context.Background()); err != nil || len(orphans) != 1 || orphans[0].BlobKey != purged.DeviceID {
+		t.Fatalf("DeleteOrphans returned %v, %v, want the attachment of the purged record", orphans, err)
+	}
+	if got := countRows(t, sqlDB, "attachments", "data_id", purged.ID); got != 0 {
+		t.Errorf("The purged record has %d attachment rows after DeleteOrphans, want 0", got)
+	}
+
+	// * The rows of the other record and the shared tag are untouched
+	if got := countRows(t, sqlDB, "data_tags", "data_id", kept.ID); got != 2 {
+		t.Errorf("The live record has %d tag rows, want 2", got)
+	}
+	if got := countRows(t, sqlDB, "attachments", "data_id", kept.ID); got != 1 {
+		t.Errorf("The live record has %d attachment rows, want 1", got)
+	}
+	var tags int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM tags WHERE name = 'shared'`).Scan(&tags); err != nil || tags != 1 {
+		t.Errorf("The shared tag has %d rows (%v), want 1", tags, err)
+	}
+}
@@ -0,0 +1,120 @@
+package SQLite
+
+import (
+	"context"
+	"database/sql"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"time"
+)
+
+type ExchangeRateRepository struct {
+	sqlDB *sql.DB
+	readAllStmt,
+	readStmt,
+	saveStmt *sql.Stmt
+	ctx context.Context
+}
+
+// NewExchangeRateRepository initializes the repository of the exchange rates used to convert prices.
+// The base currency is always stored with a rate of 1, the other rates are set through the API.
+func NewExchangeRateRepository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.ExchangeRateRepository, error) {
+
+	repo := &ExchangeRateRepository{
+		sqlDB: sqlDB.Connection(),
+		ctx:   ctx,
+	}
+
+	if _, err := repo.sqlDB.Exec(`CREATE TABLE IF NOT EXISTS exchange_rates (
+		currency VARCHAR(3) PRIMARY KEY,
+		rate FLOAT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	if _, err := repo.sqlDB.Exec(`INSERT OR IGNORE INTO exchange_rates (currency, rate, updated_at) VALUES (?, 1, ?)`,
+		models.BaseCurrency, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Converted prices in GET /data depend on the rates, so a new rate changes the data collection
+	if err := watchChanges(repo.sqlDB, "data", "exchange_rates"); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	readAllStmt, err := repo.sqlDB.Prepare("SELECT currency, rate, CAST(updated_at AS TEXT) FROM exchange_rates ORDER BY currency")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAllStmt = readAllStmt
+
+	readStmt, err := repo.sqlDB.Prepare("SELECT currency, rate, CAST(updated_at AS TEXT) FROM exchange_rates WHERE currency = ?")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readStmt = readStmt
+
+	saveStmt, err := repo.sqlDB.Prepare(`INSERT INTO exchange_rates (currency, rate, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (currency) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`)
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.saveStmt = saveStmt
+
+	go CloseExchangeRates(ctx, repo)
+
+	return repo, nil
+}
+
+func CloseExchangeRates(ctx context.Context, r *ExchangeRateRepository) {
+	<-ctx.Done()
+	r.readAllStmt.Close()
+	r.readStmt.Close()
+	r.saveStmt.Close()
+	r.sqlDB.Close()
+}
+
+func (r *ExchangeRateRepository) ReadAll(ctx context.Context) ([]*models.ExchangeRate, error) {
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readAllStmt).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*models.ExchangeRate
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, &rate)
+	}
+	return rates, rows.Err()
+}
+
+// ReadOne returns the rate of a currency, nil if it has not been set.
+func (r *ExchangeRateRepository) ReadOne(currency string, ctx context.Context) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, currency).Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// Save creates or replaces the rate of a currency.
+func (r *ExchangeRateRepository) Save(rate *models.ExchangeRate, ctx context.Context) error {
+	rate.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := DAL.Stmt(ctx, r.sqlDB, r.saveStmt).ExecContext(ctx, rate.Currency, rate.Rate, rate.UpdatedAt)
+	return err
+}
@@ -0,0 +1,84 @@
+package SQLite
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// migration is a named, one-time change to the schema or the data of an existing database.
+type migration struct {
+	name string
+	up   func(tx *sql.Tx) error
+}
+
+// migrate applies the migrations that were not applied yet, in order and each in its own transaction.
+// Applied migrations are recorded by name in the schema_migrations table.
+func migrate(sqlDB *sql.DB, migrations []migration) error {
+	if _, err := sqlDB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		name VARCHAR(100) PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	);`); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		var applied int
+		if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE name = ?`, m.name).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		tx, err := sqlDB.Begin()
+		if err != nil {
+			return err
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return MigrationError{Name: m.name, Err: err}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (name, applied_at) VALUES (?, ?)`, m.name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type MigrationError struct {
+	Name string
+	Err  error
+}
+
+func (e MigrationError) Error() string {
+	return "migration " + e.Name + " failed: " + e.Err.Error()
+}
+
+func (e MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// columnType returns the declared type of a column, or an empty string if the column does not exist.
+func columnType(tx *sql.Tx, table string, column string) (string, error) {
+	rows, err := tx.Query(`SELECT name, type FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return "", err
+		}
+		if name == column {
+			return strings.ToUpper(typ), nil
+		}
+	}
+	return "", rows.Err()
+}
@@ -5,13 +5,27 @@ import (
 	"database/sql"
 	"goapi/internal/api/repository/DAL"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"time"
 )
 
+// dht22Migrations upgrade the dht22_data table of existing databases, in order.
+var dht22Migrations = []migration{
+	{name: "dht22_0001_soft_delete", up: func(tx *sql.Tx) error { return addSoftDeleteColumns(tx, "dht22_data") }},
+	{name: "dht22_0002_version", up: func(tx *sql.Tx) error { return addVersionColumn(tx, "dht22_data") }},
+	{name: "dht22_0003_updated_at", up: migrateDHT22UpdatedAt},
+	{name: "dht22_0004_created_at", up: migrateDHT22CreatedAt},
+}
+
 type DHT22Repository struct {
 	sqlDB *sql.DB
 	createStmt,
 	readStmt,
 	readManyStmt,
+	readFirstStmt,
+	readAfterStmt,
+	countStmt,
+	readLatestStmt,
 	updateStmt,
 	deleteStmt *sql.Stmt
 	ctx context.Context
@@ -25,53 +39,107 @@ func NewDHT22Repository(sqlDB DAL.SQLDatabase, ctx context.Context) (models.DHT2
 		ctx:   ctx,
 	}
 
-	if _, err := repo.sqlDB.Exec(`DROP TABLE IF EXISTS dht22_data`); err != nil {
-		repo.sqlDB.Close()
-		return nil, err
-	}
-
 	// Create the `dht22_data` table
 	if _, err := repo.sqlDB.Exec(`CREATE TABLE IF NOT EXISTS dht22_data (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		device_name VARCHAR(50) NOT NULL,
 		temperature FLOAT NOT NULL,
 		humidity FLOAT NOT NULL,
-		date_time TIMESTAMP NOT NULL
+		date_time TIMESTAMP NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP,
+		deleted_at TIMESTAMP,
+		deleted_by VARCHAR(50)
 	);`); err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 
+	// * Bring tables created by older versions up to date, the data is kept
+	if err := migrate(repo.sqlDB, dht22Migrations); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * The change marker of GET /dht22, for conditional requests
+	if err := watchChanges(repo.sqlDB, "dht22", "dht22_data"); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// Index used by the per-device queries (latest readings, forecasts)
+	if _, err := repo.sqlDB.Exec(`CREATE INDEX IF NOT EXISTS idx_dht22_device_time ON dht22_data (device_name, date_time)`); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
+	// * Index used by the keyset (cursor) pagination
+	if _, err := repo.sqlDB.Exec(`CREATE INDEX IF NOT EXISTS idx_dht22_date_time_id ON dht22_data (date_time, id)`); err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+
 	// Prepare SQL statements
-	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO dht22_data (device_name, temperature, humidity, date_time) VALUES (?, ?, ?, ?)`)
+	createStmt, err := repo.sqlDB.Prepare(`INSERT INTO dht22_data (device_name, temperature, humidity, date_time, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.createStmt = createStmt
 
-	readStmt, err := repo.sqlDB.Prepare("SELECT id, device_name, temperature, humidity, date_time FROM dht22_data WHERE id = ?")
+	readStmt, err := repo.sqlDB.Prepare("SELECT id, device_name, temperature, humidity, date_time, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT) FROM dht22_data WHERE id = ? AND deleted_at IS NULL")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.readStmt = readStmt
 
-	readManyStmt, err := repo.sqlDB.Prepare("SELECT id, device_name, temperature, humidity, date_time FROM dht22_data LIMIT ? OFFSET ?")
+	readManyStmt, err := repo.sqlDB.Prepare("SELECT id, device_name, temperature, humidity, date_time, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT) FROM dht22_data WHERE deleted_at IS NULL ORDER BY id LIMIT ? OFFSET ?")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.readManyStmt = readManyStmt
 
-	updateStmt, err := repo.sqlDB.Prepare("UPDATE dht22_data SET device_name = ?, temperature = ?, humidity = ?, date_time = ? WHERE id = ?")
+	// * date_time is also read back as stored text, so the next cursor compares exactly like the column does
+	readFirstStmt, err := repo.sqlDB.Prepare("SELECT id, device_name, temperature, humidity, date_time, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT), CAST(date_time AS TEXT) FROM dht22_data WHERE deleted_at IS NULL ORDER BY date_time, id LIMIT ?")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readFirstStmt = readFirstStmt
+
+	readAfterStmt, err := repo.sqlDB.Prepare("SELECT id, device_name, temperature, humidity, date_time, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT), CAST(date_time AS TEXT) FROM dht22_data WHERE deleted_at IS NULL AND (date_time, id) > (?, ?) ORDER BY date_time, id LIMIT ?")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readAfterStmt = readAfterStmt
+
+	countStmt, err := repo.sqlDB.Prepare("SELECT COUNT(*) FROM dht22_data WHERE deleted_at IS NULL")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.countStmt = countStmt
+
+	// * date_time is RFC 3339 text with any offset and fraction of a second, julianday orders it by the time it stands for
+	readLatestStmt, err := repo.sqlDB.Prepare("SELECT id, device_name, temperature, humidity, date_time, version, CAST(created_at AS TEXT), CAST(updated_at AS TEXT) FROM dht22_data WHERE device_name = ? AND deleted_at IS NULL ORDER BY julianday(date_time) DESC, id DESC LIMIT ?")
+	if err != nil {
+		repo.sqlDB.Close()
+		return nil, err
+	}
+	repo.readLatestStmt = readLatestStmt
+
+	updateStmt, err := repo.sqlDB.Prepare("UPDATE dht22_data SET device_name = ?, temperature = ?, humidity = ?, date_time = ?, version = version + 1, updated_at = ? WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?) RETURNING version, CAST(created_at AS TEXT)")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
 	}
 	repo.updateStmt = updateStmt
 
-	deleteStmt, err := repo.sqlDB.Prepare("DELETE FROM dht22_data WHERE id = ?")
+	deleteStmt, err := repo.sqlDB.Prepare("UPDATE dht22_data SET deleted_at = ?, deleted_by = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)")
 	if err != nil {
 		repo.sqlDB.Close()
 		return nil, err
@@ -92,13 +160,19 @@ func CloseDHT22(ctx context.Context, r *DHT22Repository) {
 	r.updateStmt.Close()
 	r.deleteStmt.Close()
 	r.readManyStmt.Close()
+	r.readFirstStmt.Close()
+	r.readAfterStmt.Close()
+	r.countStmt.Close()
+	r.readLatestStmt.Close()
 	r.sqlDB.Close()
 }
 
 // Implement CRUD operations
 
 func (r *DHT22Repository) Create(data *models.DHT22Data, ctx context.Context) error {
-	res, err := r.createStmt.ExecContext(ctx, data.DeviceName, data.Temperature, data.Humidity, data.DateTime)
+	data.CreatedAt = time.Now().UTC().Format(models.HistoryTimeFormat)
+	data.UpdatedAt = data.CreatedAt
+	res, err := DAL.Stmt(ctx, r.sqlDB, r.createStmt).ExecContext(ctx, data.DeviceName, data.Temperature, data.Humidity, data.DateTime, data.CreatedAt, data.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -107,13 +181,53 @@ func (r *DHT22Repository) Create(data *models.DHT22Data, ctx context.Context) er
 		return err
 	}
 	data.ID = int(id)
+	data.Version = 1
+	return nil
+}
+
+// CreateMany inserts the readings in one transaction, SQLite writes them with one lock and one sync instead of one per reading.
+func (r *DHT22Repository) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	if len(data) == 0 {
+		return ctx.Err()
+	}
+	tx, err := DAL.BeginTx(ctx, r.sqlDB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	createStmt := tx.StmtContext(ctx, r.createStmt)
+	createdAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	ids := make([]int, len(data))
+	for i, d := range data {
+		res, err := createStmt.ExecContext(ctx, d.DeviceName, d.Temperature, d.Humidity, d.DateTime, createdAt, createdAt)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ids[i] = int(id)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// * The readings are only changed once they are written
+	for i, d := range data {
+		d.ID = ids[i]
+		d.Version = 1
+		d.CreatedAt = createdAt
+		d.UpdatedAt = createdAt
+	}
 	return nil
 }
 
 func (r *DHT22Repository) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
-	row := r.readStmt.QueryRowContext(ctx, id)
+	row := DAL.Stmt(ctx, r.sqlDB, r.readStmt).QueryRowContext(ctx, id)
 	var data models.DHT22Data
-	err := row.Scan(&data.ID, &data.DeviceName, &data.Temperature, &data.Humidity, &data.DateTime)
+	err := row.Scan(&data.ID, &data.DeviceName, &data.Temperature, &data.Humidity, &data.DateTime, &data.Version, &data.CreatedAt, &data.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -125,7 +239,7 @@ func (r *DHT22Repository) ReadOne(id int, ctx context.Context) (*models.DHT22Dat
 
 func (r *DHT22Repository) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
 	offset := rowsPerPage * (page - 1)
-	rows, err := r.readManyStmt.QueryContext(ctx, rowsPerPage, offset)
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readManyStmt).QueryContext(ctx, rowsPerPage, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +248,7 @@ func (r *DHT22Repository) ReadMany(page int, rowsPerPage int, ctx context.Contex
 	var data []*models.DHT22Data
 	for rows.Next() {
 		var d models.DHT22Data
-		err := rows.Scan(&d.ID, &d.DeviceName, &d.Temperature, &d.Humidity, &d.DateTime)
+		err := rows.Scan(&d.ID, &d.DeviceName, &d.Temperature, &d.Humidity, &d.DateTime, &d.Version, &d.CreatedAt, &d.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -143,20 +257,162 @@ func (r *DHT22Repository) ReadMany(page int, rowsPerPage int, ctx context.Contex
 	return data, nil
 }
 
-func (r *DHT22Repository) Update(data *models.DHT22Data, ctx context.Context) (int64, error) {
-	res, err := r.updateStmt.ExecContext(ctx, data.DeviceName, data.Temperature, data.Humidity, data.DateTime, data.ID)
+// ReadAfter returns up to limit rows ordered by (date_time, id) that come after the cursor.
+// A nil cursor starts from the beginning, the returned cursor is nil on the last page.
+func (r *DHT22Repository) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	var rows *sql.Rows
+	var err error
+	// * One extra row is read to know whether there is a next page
+	if cursor == nil {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readFirstStmt).QueryContext(ctx, limit+1)
+	} else {
+		rows, err = DAL.Stmt(ctx, r.sqlDB, r.readAfterStmt).QueryContext(ctx, cursor.DateTime, cursor.ID, limit+1)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.DHT22Data
+	var next *models.Cursor
+	var lastDateTime string
+	for rows.Next() {
+		if len(data) == limit {
+			next = &models.Cursor{DateTime: lastDateTime, ID: data[limit-1].ID}
+			break
+		}
+		var d models.DHT22Data
+		err := rows.Scan(&d.ID, &d.DeviceName, &d.Temperature, &d.Humidity, &d.DateTime, &d.Version, &d.CreatedAt, &d.UpdatedAt, &lastDateTime)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = append(data, &d)
+	}
+	return data, next, rows.Err()
+}
+
+func (r *DHT22Repository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := DAL.Stmt(ctx, r.sqlDB, r.countStmt).QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ReadLatest returns the newest readings of a single device, newest first.
+func (r *DHT22Repository) ReadLatest(deviceName string, limit int, ctx context.Context) ([]*models.DHT22Data, error) {
+	rows, err := DAL.Stmt(ctx, r.sqlDB, r.readLatestStmt).QueryContext(ctx, deviceName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.DHT22Data
+	for rows.Next() {
+		var d models.DHT22Data
+		err := rows.Scan(&d.ID, &d.DeviceName, &d.Temperature, &d.Humidity, &d.DateTime, &d.Version, &d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, &d)
+	}
+	return data, rows.Err()
+}
+
+// Query returns one page of the rows matching the filter, in the requested order and with only the selected fields set.
+// The query is built from the whitelisted schema, every value is passed as a parameter.
+func (r *DHT22Repository) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	fields := q.Selected(models.DHT22QueryFields)
+	where, args := q.Where(models.DHT22QuerySchema)
+	sqlQuery := "SELECT " + query.Columns(models.DHT22QuerySchema, fields) + " FROM dht22_data WHERE " + notDeleted(where)
+	sqlQuery += " ORDER BY " + q.OrderBy(models.DHT22QuerySchema, "id") + " LIMIT ? OFFSET ?"
+	args = append(args, rowsPerPage, rowsPerPage*(page-1))
+
+	rows, err := DAL.Conn(ctx, r.sqlDB).QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []*models.DHT22Data
+	for rows.Next() {
+		var d models.DHT22Data
+		targets := dht22QueryTargets(&d)
+		dest := make([]any, len(fields))
+		for i, f := range fields {
+			dest[i] = targets[f]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		data = append(data, &d)
+	}
+	return data, rows.Err()
+}
+
+// CountQuery counts the rows matching the filter of the query.
+func (r *DHT22Repository) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	where, args := q.Where(models.DHT22QuerySchema)
+	sqlQuery := "SELECT COUNT(*) FROM dht22_data WHERE " + notDeleted(where)
+
+	var count int
+	if err := DAL.Conn(ctx, r.sqlDB).QueryRowContext(ctx, sqlQuery, args...).Scan(&count); err != nil {
 		return 0, err
 	}
-	rowsAffected, err := res.RowsAffected()
+	return count, nil
+}
+
+func dht22QueryTargets(d *models.DHT22Data) map[string]any {
+	return map[string]any{
+		"id":          &d.ID,
+		"device_name": &d.DeviceName,
+		"temperature": &d.Temperature,
+		"humidity":    &d.Humidity,
+		"date_time":   &d.DateTime,
+		"version":     &d.Version,
+		"created_at":  &d.CreatedAt,
+		"updated_at":  &d.UpdatedAt,
+	}
+}
+
+// Update replaces a reading, a version other than 0 must be the stored one. The new version is set on data.
+func (r *DHT22Repository) Update(data *models.DHT22Data, ctx context.Context) (int64, error) {
+	var version int
+	var createdAt string
+	updatedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	err := DAL.Stmt(ctx, r.sqlDB, r.updateStmt).QueryRowContext(ctx, data.DeviceName, data.Temperature, data.Humidity, data.DateTime, updatedAt, data.ID, data.Version, data.Version).Scan(&version, &createdAt)
+	if err == sql.ErrNoRows {
+		return 0, r.versionMismatch(data.ID, data.Version, ctx)
+	}
 	if err != nil {
 		return 0, err
 	}
-	return rowsAffected, nil
+	data.Version = version
+	data.CreatedAt = createdAt
+	data.UpdatedAt = updatedAt
+	return 1, nil
+}
+
+// versionMismatch tells why a conditional change did not affect a row: the reading was changed by someone else,
+// or it does not exist.
+func (r *DHT22Repository) versionMismatch(id int, version int, ctx context.Context) error {
+	if version == 0 {
+		return nil
+	}
+	current, err := r.ReadOne(id, ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return models.ErrVersionMismatch
+	}
+	return nil
 }
 
 func (r *DHT22Repository) Delete(data *models.DHT22Data, ctx context.Context) (int64, error) {
-	res, err := r.deleteStmt.ExecContext(ctx, data.ID)
+	// * The reading is only marked as deleted, it stays in the trash until it is restored or purged
+	deletedAt := time.Now().UTC().Format(models.HistoryTimeFormat)
+	res, err := DAL.Stmt(ctx, r.sqlDB, r.deleteStmt).ExecContext(ctx, deletedAt, models.ActorFromContext(ctx), deletedAt, data.ID, data.Version, data.Version)
 	if err != nil {
 		return 0, err
 	}
@@ -164,5 +420,8 @@ func (r *DHT22Repository) Delete(data *models.DHT22Data, ctx context.Context) (i
 	if err != nil {
 		return 0, err
 	}
+	if rowsAffected == 0 {
+		return 0, r.versionMismatch(data.ID, data.Version, ctx)
+	}
 	return rowsAffected, nil
 }
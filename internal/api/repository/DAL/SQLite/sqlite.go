@@ -3,6 +3,7 @@ package SQLite
 import (
 	"database/sql"
 	"goapi/internal/api/repository/DAL"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -15,6 +16,16 @@ type SQLite struct {
 
 func NewSqlite(dataSourceName string) (DAL.SQLDatabase, error) {
 
+	// * Transactions take the write lock when they begin. A deferred transaction that reads and then writes
+	// * fails with "database is locked" when another one is writing, an immediate one waits its turn
+	if !strings.Contains(dataSourceName, "_txlock=") {
+		separator := "?"
+		if strings.Contains(dataSourceName, "?") {
+			separator = "&"
+		}
+		dataSourceName += separator + "_txlock=immediate"
+	}
+
 	sqlDB, err := sql.Open("sqlite3", dataSourceName)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,90 @@
+package contract
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"goapi/internal/api/repository/models"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+// * NewBlobStore returns an empty blob store, it is called once for each test of the suite *
+type NewBlobStore func(t *testing.T) models.BlobStore
+
+// * TestBlobStore runs the contract every store of the content of attachments must fulfil *
+func TestBlobStore(t *testing.T, newStore NewBlobStore) {
+	t.Run("CreateOpenDelete", func(t *testing.T) {
+		store := newStore(t)
+		content := []byte("first line\nsecond line\x00\xff")
+
+		size, sum, err := store.Create("abc123", bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		want := sha256.Sum256(content)
+		if size != int64(len(content)) || sum != hex.EncodeToString(want[:]) {
+			t.Errorf("Create returned %v, %v, want %v, %v", size, sum, len(content), hex.EncodeToString(want[:]))
+		}
+		assertBlob(t, store, "abc123", content)
+
+		// * The reader seeks, for range requests
+		blob, err := store.Open("abc123")
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if _, err := blob.Seek(6, io.SeekStart); err != nil {
+			t.Fatalf("Seek failed: %v", err)
+		}
+		rest, err := io.ReadAll(blob)
+		blob.Close()
+		if err != nil || !bytes.Equal(rest, content[6:]) {
+			t.Errorf("read after Seek returned %q, %v, want %q", rest, err, content[6:])
+		}
+
+		if err := store.Delete("abc123"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := store.Open("abc123"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Open of a deleted blob returned %v, want %v", err, fs.ErrNotExist)
+		}
+		if err := store.Delete("abc123"); err != nil {
+			t.Errorf("Delete of a deleted blob returned %v, want nil", err)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		store := newStore(t)
+		if size, _, err := store.Create("empty1", bytes.NewReader(nil)); err != nil || size != 0 {
+			t.Fatalf("Create of an empty blob returned %v, %v, want 0, nil", size, err)
+		}
+		assertBlob(t, store, "empty1", []byte{})
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		store := newStore(t)
+		if _, _, err := store.Create("abc123", bytes.NewReader([]byte("old"))); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, _, err := store.Create("abc123", bytes.NewReader([]byte("new content"))); err != nil {
+			t.Fatalf("Create of an existing key failed: %v", err)
+		}
+		assertBlob(t, store, "abc123", []byte("new content"))
+	})
+}
+
+// assertBlob reads a whole blob and compares it with the content it should have.
+func assertBlob(t *testing.T, store models.BlobStore, key string, want []byte) {
+	t.Helper()
+	blob, err := store.Open(key)
+	if err != nil {
+		t.Fatalf("Open of %v failed: %v", key, err)
+	}
+	defer blob.Close()
+	got, err := io.ReadAll(blob)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("blob %v is %q, %v, want %q", key, got, err, want)
+	}
+}
@@ -0,0 +1,757 @@
+// Package contract is the conformance test suite of the repositories. Every storage backend runs it
+// from its own tests, so a backend that passes behaves like the SQLite one for the services above it.
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// * NewDataRepository returns an empty repository, it is called once for each test of the suite *
+type NewDataRepository func(t *testing.T) models.DataRepository
+
+// * TestDataRepository runs the contract of models.DataRepository against the repositories made by newRepository *
+func TestDataRepository(t *testing.T, newRepository NewDataRepository) {
+	t.Run("CreateAndReadOne", func(t *testing.T) {
+		repo := newRepository(t)
+		data := newData(1)
+		if err := repo.Create(data, context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if data.ID == 0 || data.Version != 1 || data.CreatedAt == "" || data.UpdatedAt != data.CreatedAt {
+			t.Errorf("Create did not set the id, version and timestamps: got %+v", data)
+		}
+
+		got, err := repo.ReadOne(data.ID, context.Background())
+		if err != nil {
+			t.Fatalf("ReadOne failed: %v", err)
+		}
+		assertSameData(t, got, data)
+
+		got, err = repo.ReadBySerialNumber(data.SerialNumber, context.Background())
+		if err != nil {
+			t.Fatalf("ReadBySerialNumber failed: %v", err)
+		}
+		assertSameData(t, got, data)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 1)[0]
+
+		data.DeviceName = "Renamed"
+		data.Price = 4200
+		rowsAffected, err := repo.Update(data, context.Background())
+		if err != nil || rowsAffected != 1 {
+			t.Fatalf("Update returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if data.Version != 2 {
+			t.Errorf("Update set version %v, want 2", data.Version)
+		}
+		got := readData(t, repo, data.ID)
+		assertSameData(t, got, data)
+
+		// * A stale version is refused and changes nothing, version 0 updates whatever is stored
+		stale := *got
+		stale.Version = 1
+		stale.DeviceName = "Stale"
+		if _, err := repo.Update(&stale, context.Background()); !errors.Is(err, models.ErrVersionMismatch) {
+			t.Errorf("Update with a stale version returned %v, want %v", err, models.ErrVersionMismatch)
+		}
+		if got := readData(t, repo, data.ID); got.DeviceName != "Renamed" {
+			t.Errorf("Update with a stale version changed the record: got %v", got.DeviceName)
+		}
+		unconditional := *got
+		unconditional.Version = 0
+		if rowsAffected, err := repo.Update(&unconditional, context.Background()); err != nil || rowsAffected != 1 {
+			t.Errorf("Update with version 0 returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readData(t, repo, data.ID); got.Version != 3 {
+			t.Errorf("Update with version 0 left version %v, want 3", got.Version)
+		}
+	})
+
+	t.Run("DeleteAndRestore", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 2)
+
+		rowsAffected, err := repo.Delete(data[0], context.Background())
+		if err != nil || rowsAffected != 1 {
+			t.Fatalf("Delete returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readData(t, repo, data[0].ID); got != nil {
+			t.Errorf("ReadOne returned a deleted record: got %+v", got)
+		}
+		assertDataCount(t, repo, 1)
+		if count, err := repo.CountTrash(context.Background()); err != nil || count != 1 {
+			t.Errorf("CountTrash returned %v, %v, want 1, nil", count, err)
+		}
+
+		rowsAffected, err = repo.Restore(data[0].ID, context.Background())
+		if err != nil || rowsAffected != 1 {
+			t.Fatalf("Restore returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readData(t, repo, data[0].ID); got == nil {
+			t.Errorf("ReadOne did not return the restored record")
+		}
+		assertDataCount(t, repo, 2)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepository(t)
+		createData(t, repo, 1)
+
+		// * A missing record is not an error, it is reported as nil or as no affected row
+		if got, err := repo.ReadOne(999, context.Background()); got != nil || err != nil {
+			t.Errorf("ReadOne of a missing record returned %v, %v, want nil, nil", got, err)
+		}
+		if got, err := repo.ReadBySerialNumber("missing", context.Background()); got != nil || err != nil {
+			t.Errorf("ReadBySerialNumber of a missing serial number returned %v, %v, want nil, nil", got, err)
+		}
+		if got, err := repo.ReadBySerialNumber("", context.Background()); got != nil || err != nil {
+			t.Errorf("ReadBySerialNumber of an empty serial number returned %v, %v, want nil, nil", got, err)
+		}
+		missing := newData(999)
+		missing.ID = 999
+		if rowsAffected, err := repo.Update(missing, context.Background()); rowsAffected != 0 || err != nil {
+			t.Errorf("Update of a missing record returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Delete(missing, context.Background()); rowsAffected != 0 || err != nil {
+			t.Errorf("Delete of a missing record returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Restore(999, context.Background()); rowsAffected != 0 || err != nil {
+			t.Errorf("Restore of a missing record returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+	})
+
+	t.Run("DuplicateSerialNumber", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 1)[0]
+
+		duplicate := newData(2)
+		duplicate.SerialNumber = data.SerialNumber
+		if err := repo.Create(duplicate, context.Background()); !errors.Is(err, models.ErrDuplicateSerialNumber) {
+			t.Errorf("Create with a used serial number returned %v, want %v", err, models.ErrDuplicateSerialNumber)
+		}
+		assertDataCount(t, repo, 1)
+
+		// * Records without a serial number do not conflict
+		for i := 0; i < 2; i++ {
+			d := newData(10 + i)
+			d.SerialNumber = ""
+			if err := repo.Create(d, context.Background()); err != nil {
+				t.Errorf("Create without a serial number failed: %v", err)
+			}
+		}
+	})
+
+	t.Run("ReadManyPages", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 5)
+		assertDataCount(t, repo, 5)
+
+		tests := []struct {
+			page, rowsPerPage int
+			want              []*models.Data
+		}{
+			{1, 2, data[0:2]},
+			{2, 2, data[2:4]},
+			{3, 2, data[4:5]},
+			{4, 2, nil},
+			{1, 5, data},
+			{1, 10, data},
+			{2, 5, nil},
+		}
+		for _, tt := range tests {
+			got, err := repo.ReadMany(tt.page, tt.rowsPerPage, context.Background())
+			if err != nil {
+				t.Fatalf("ReadMany(%v, %v) failed: %v", tt.page, tt.rowsPerPage, err)
+			}
+			if ids, want := dataIDs(got), dataIDs(tt.want); fmt.Sprint(ids) != fmt.Sprint(want) {
+				t.Errorf("ReadMany(%v, %v) returned ids %v, want %v", tt.page, tt.rowsPerPage, ids, want)
+			}
+		}
+	})
+
+	t.Run("ReadAfterPages", func(t *testing.T) {
+		repo := newRepository(t)
+		// * Two records share a date_time, the id orders them
+		data := createData(t, repo, 4)
+		data[3].DateTime = data[2].DateTime
+		if _, err := repo.Update(data[3], context.Background()); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+
+		for _, limit := range []int{1, 2, 3, 4, 5} {
+			var ids []int
+			var cursor *models.Cursor
+			for pages := 0; ; pages++ {
+				if pages > len(data) {
+					t.Fatalf("ReadAfter with limit %v did not reach the last page", limit)
+				}
+				page, next, err := repo.ReadAfter(cursor, limit, context.Background())
+				if err != nil {
+					t.Fatalf("ReadAfter with limit %v failed: %v", limit, err)
+				}
+				if len(page) > limit {
+					t.Errorf("ReadAfter with limit %v returned %v records", limit, len(page))
+				}
+				ids = append(ids, dataIDs(page)...)
+				// * The last page has no next cursor, even when it is full
+				if next == nil {
+					break
+				}
+				cursor = next
+			}
+			if want := dataIDs(data); fmt.Sprint(ids) != fmt.Sprint(want) {
+				t.Errorf("ReadAfter with limit %v returned ids %v, want %v", limit, ids, want)
+			}
+		}
+	})
+
+	t.Run("ConcurrentWrites", func(t *testing.T) {
+		repo := newRepository(t)
+		const writers = 20
+
+		var wg sync.WaitGroup
+		errs := make(chan error, writers)
+		ids := make(chan int, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data := newData(i)
+				if err := repo.Create(data, context.Background()); err != nil {
+					errs <- err
+					return
+				}
+				ids <- data.ID
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		close(ids)
+		for err := range errs {
+			t.Errorf("concurrent Create failed: %v", err)
+		}
+		seen := map[int]bool{}
+		for id := range ids {
+			if seen[id] {
+				t.Errorf("concurrent Create gave id %v twice", id)
+			}
+			seen[id] = true
+		}
+		assertDataCount(t, repo, writers)
+
+		// * Unconditional updates of one record are all applied, one version each
+		first, err := repo.ReadMany(1, 1, context.Background())
+		if err != nil || len(first) != 1 {
+			t.Fatalf("ReadMany returned %v, %v", first, err)
+		}
+		errs = make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data := *first[0]
+				data.Version = 0
+				data.Description = fmt.Sprintf("Update %v", i)
+				if _, err := repo.Update(&data, context.Background()); err != nil {
+					errs <- err
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent Update failed: %v", err)
+		}
+		if got := readData(t, repo, first[0].ID); got.Version != 1+writers {
+			t.Errorf("concurrent Updates left version %v, want %v", got.Version, 1+writers)
+		}
+	})
+
+	t.Run("ConcurrentConditionalWrites", func(t *testing.T) {
+		repo := newRepository(t)
+		const writers = 20
+		record := createData(t, repo, 1)[0]
+
+		// * Of the conditional updates of one version, exactly one wins
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		won, lost := 0, 0
+		errs := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data := *record
+				data.Version = 1
+				data.Description = fmt.Sprintf("Update %v", i)
+				_, err := repo.Update(&data, context.Background())
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					won++
+				case errors.Is(err, models.ErrVersionMismatch):
+					lost++
+				default:
+					errs <- err
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent Update failed: %v", err)
+		}
+		if won != 1 || lost != writers-1 {
+			t.Errorf("concurrent Updates of version 1: %v applied and %v refused, want 1 and %v", won, lost, writers-1)
+		}
+		if got := readData(t, repo, record.ID); got.Version != 2 {
+			t.Errorf("concurrent Updates left version %v, want 2", got.Version)
+		}
+	})
+
+	t.Run("QueryAndCountQuery", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 5)
+		for _, d := range []*models.Data{data[1], data[3]} {
+			d.Type = "Tool"
+			if _, err := repo.Update(d, context.Background()); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+		}
+
+		// * The filter and sort order, one page at a time
+		q := parseQuery(t, `type == "Sensor" and price >= 200`, "-price", "")
+		assertQuery(t, repo, q, 1, 10, data[4], data[2])
+		assertQuery(t, repo, q, 2, 1, data[2])
+		assertQuery(t, repo, q, 3, 1)
+		assertCountQuery(t, repo, q, 2)
+		assertQuery(t, repo, parseQuery(t, `type != "Sensor" or price < 150`, "", ""), 1, 10, data[0], data[1], data[3])
+
+		// * Only the selected fields are set
+		got, err := repo.Query(parseQuery(t, `id == `+fmt.Sprint(data[2].ID), "", "id,device_name"), 1, 10, context.Background())
+		if err != nil || len(got) != 1 {
+			t.Fatalf("Query with fields returned %v, %v", got, err)
+		}
+		if want := (models.Data{ID: data[2].ID, DeviceName: data[2].DeviceName}); got[0].ID != want.ID || got[0].DeviceName != want.DeviceName ||
+			got[0].DeviceID != "" || got[0].Price != 0 || got[0].Version != 0 || got[0].Tags != nil {
+			t.Errorf("Query with fields id,device_name returned %+v", got[0])
+		}
+
+		// * Records in the trash do not match
+		if _, err := repo.Delete(data[4], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		assertQuery(t, repo, q, 1, 10, data[2])
+		assertCountQuery(t, repo, q, 1)
+		assertCountQuery(t, repo, parseQuery(t, "", "", ""), 4)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 3)
+
+		// * Tags are read in alphabetical order, a change is a new version
+		if rowsAffected, err := repo.AddTags(data[0].ID, []string{"b", "a"}, data[0].Version, context.Background()); err != nil || rowsAffected != 1 {
+			t.Fatalf("AddTags returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readData(t, repo, data[0].ID); fmt.Sprint(got.Tags) != "[a b]" || got.Version != 2 {
+			t.Errorf("AddTags left tags %v and version %v, want [a b] and 2", got.Tags, got.Version)
+		}
+		// * Tags the record already has change nothing, a stale version is refused
+		if rowsAffected, err := repo.AddTags(data[0].ID, []string{"a"}, 0, context.Background()); err != nil || rowsAffected != 1 {
+			t.Errorf("AddTags of a tag the record has returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readData(t, repo, data[0].ID); got.Version != 2 {
+			t.Errorf("AddTags of a tag the record has made version %v, want 2", got.Version)
+		}
+		if _, err := repo.AddTags(data[0].ID, []string{"c"}, 1, context.Background()); !errors.Is(err, models.ErrVersionMismatch) {
+			t.Errorf("AddTags with a stale version returned %v, want %v", err, models.ErrVersionMismatch)
+		}
+		if _, err := repo.AddTags(data[1].ID, []string{"a"}, 0, context.Background()); err != nil {
+			t.Fatalf("AddTags failed: %v", err)
+		}
+
+		// * The tag filter of a query matches records with all of the tags, or with any of them
+		all := &query.Query{Tags: []string{"a", "b"}}
+		assertQuery(t, repo, all, 1, 10, data[0])
+		assertCountQuery(t, repo, all, 1)
+		anyTag := &query.Query{Tags: []string{"a", "b"}, AnyTag: true}
+		assertQuery(t, repo, anyTag, 1, 10, data[0], data[1])
+		assertCountQuery(t, repo, anyTag, 2)
+		got, err := repo.Query(anyTag, 1, 10, context.Background())
+		if err != nil || len(got) != 2 || fmt.Sprint(got[0].Tags) != "[a b]" || fmt.Sprint(got[1].Tags) != "[a]" {
+			t.Errorf("Query did not return the tags of the records: got %v, %v", got, err)
+		}
+
+		// * Tags the record does not have are ignored
+		if rowsAffected, err := repo.RemoveTags(data[0].ID, []string{"a", "missing"}, 2, context.Background()); err != nil || rowsAffected != 1 {
+			t.Fatalf("RemoveTags returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readData(t, repo, data[0].ID); fmt.Sprint(got.Tags) != "[b]" || got.Version != 3 {
+			t.Errorf("RemoveTags left tags %v and version %v, want [b] and 3", got.Tags, got.Version)
+		}
+		assertQuery(t, repo, all, 1, 10)
+
+		history, err := repo.ReadHistory(data[0].ID, context.Background())
+		if err != nil || len(history) != 3 || fmt.Sprint(history[1].ChangedFields) != "[tags]" || fmt.Sprint(history[2].ChangedFields) != "[tags]" {
+			t.Errorf("Changes of the tags are not in the history: got %v, %v", history, err)
+		}
+
+		if rowsAffected, err := repo.AddTags(999, []string{"a"}, 0, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("AddTags of a missing record returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.RemoveTags(999, []string{"a"}, 0, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("RemoveTags of a missing record returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+	})
+
+	t.Run("HistoryAndAsOf", func(t *testing.T) {
+		repo := newRepository(t)
+		beforeCreate := time.Now()
+		waitForClock()
+		data := createData(t, repo, 1)[0]
+		waitForClock()
+		created := time.Now()
+		waitForClock()
+
+		data.DeviceName = "Renamed"
+		if _, err := repo.Update(data, context.Background()); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		waitForClock()
+		updated := time.Now()
+		waitForClock()
+		if _, err := repo.Delete(data, context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		waitForClock()
+		deleted := time.Now()
+
+		history, err := repo.ReadHistory(data.ID, context.Background())
+		if err != nil {
+			t.Fatalf("ReadHistory failed: %v", err)
+		}
+		var operations []string
+		for _, v := range history {
+			operations = append(operations, v.Operation)
+		}
+		if fmt.Sprint(operations) != fmt.Sprint([]string{models.OperationCreate, models.OperationUpdate, models.OperationDelete}) {
+			t.Fatalf("ReadHistory returned the operations %v", operations)
+		}
+		if history[0].Data.DeviceName != "Device 0" || history[1].Data.DeviceName != "Renamed" || fmt.Sprint(history[1].ChangedFields) != "[device_name]" {
+			t.Errorf("ReadHistory returned %+v and %+v", history[0], history[1])
+		}
+
+		tests := []struct {
+			name       string
+			asOf       time.Time
+			deviceName string
+		}{
+			{"before create", beforeCreate, ""},
+			{"after create", created, "Device 0"},
+			{"after update", updated, "Renamed"},
+			{"after delete", deleted, ""},
+		}
+		for _, tt := range tests {
+			got, err := repo.ReadAsOf(data.ID, tt.asOf, context.Background())
+			if err != nil {
+				t.Fatalf("ReadAsOf %v failed: %v", tt.name, err)
+			}
+			if tt.deviceName == "" && got != nil || tt.deviceName != "" && (got == nil || got.DeviceName != tt.deviceName) {
+				t.Errorf("ReadAsOf %v returned %+v, want device name %q", tt.name, got, tt.deviceName)
+			}
+		}
+
+		if history, err := repo.ReadHistory(999, context.Background()); err != nil || len(history) != 0 {
+			t.Errorf("ReadHistory of a missing record returned %v, %v, want none", history, err)
+		}
+		if got, err := repo.ReadAsOf(999, deleted, context.Background()); err != nil || got != nil {
+			t.Errorf("ReadAsOf of a missing record returned %v, %v, want nil, nil", got, err)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 3)
+		data[1].Description = "Cracked housing"
+		if _, err := repo.Update(data[1], context.Background()); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+
+		// * Case is ignored, the last word also matches as a prefix
+		results, total, err := repo.Search("CRACKED hous", 1, 10, context.Background())
+		if err != nil || total != 1 || len(results) != 1 || results[0].ID != data[1].ID || !strings.Contains(results[0].Snippet, "<mark>") {
+			t.Errorf("Search returned %v of %v, %v, want record %v with a snippet", results, total, err, data[1].ID)
+		}
+		results, total, err = repo.Search("record", 2, 1, context.Background())
+		if err != nil || total != 2 || len(results) != 1 {
+			t.Errorf("Search page 2 returned %v of %v, %v, want 1 of 2", results, total, err)
+		}
+		for _, text := range []string{"missing", "cracked missing", " "} {
+			if results, total, err := repo.Search(text, 1, 10, context.Background()); err != nil || total != 0 || len(results) != 0 {
+				t.Errorf("Search %q returned %v of %v, %v, want none", text, results, total, err)
+			}
+		}
+
+		// * Records in the trash are not found
+		if _, err := repo.Delete(data[1], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if results, total, err := repo.Search("cracked", 1, 10, context.Background()); err != nil || total != 0 || len(results) != 0 {
+			t.Errorf("Search returned a record in the trash: %v of %v, %v", results, total, err)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 4)
+		// * device-0 is renamed by its later record, the last record is in another currency and month
+		data[1].DeviceID = data[0].DeviceID
+		data[1].DeviceName = "Renamed"
+		data[2].Type = "Tool"
+		data[2].Currency = "USD"
+		data[2].DateTime = "2024-02-01T10:00:00Z"
+		for _, d := range data[1:3] {
+			if _, err := repo.Update(d, context.Background()); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+		}
+		// * Records in the trash are not counted
+		if _, err := repo.Delete(data[3], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		stats, err := repo.Stats(context.Background())
+		if err != nil {
+			t.Fatalf("Stats failed: %v", err)
+		}
+		if stats.Total != 3 {
+			t.Errorf("Stats counted %v records, want 3", stats.Total)
+		}
+		var byType, byDevice, byMonth []string
+		for _, s := range stats.ByType {
+			byType = append(byType, fmt.Sprintf("%v:%v%v", s.Type, s.Count, priceStats(s.Prices)))
+		}
+		for _, s := range stats.ByDevice {
+			byDevice = append(byDevice, fmt.Sprintf("%v/%v:%v%v", s.DeviceID, s.DeviceName, s.Count, priceStats(s.Prices)))
+		}
+		for _, s := range stats.ByMonth {
+			byMonth = append(byMonth, fmt.Sprintf("%v:%v", s.Month, s.Count))
+		}
+		if got, want := fmt.Sprint(byType), "[Sensor:2[EUR 2 300 150] Tool:1[USD 1 300 300]]"; got != want {
+			t.Errorf("Stats by type returned %v, want %v", got, want)
+		}
+		if got, want := fmt.Sprint(byDevice), "[device-0/Renamed:2[EUR 2 300 150] device-2/Device 2:1[USD 1 300 300]]"; got != want {
+			t.Errorf("Stats by device returned %v, want %v", got, want)
+		}
+		if got, want := fmt.Sprint(byMonth), "[2024-01:2 2024-02:1]"; got != want {
+			t.Errorf("Stats by month returned %v, want %v", got, want)
+		}
+	})
+
+	t.Run("TrashAndPurge", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 3)
+		if _, err := repo.Delete(data[0], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		waitForClock()
+		cutoff := time.Now()
+		waitForClock()
+		if _, err := repo.Delete(data[1], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		// * Most recently deleted first
+		trash, err := repo.ReadTrash(1, 10, context.Background())
+		if err != nil || len(trash) != 2 || trash[0].ID != data[1].ID || trash[1].ID != data[0].ID || trash[0].DeletedAt == "" {
+			t.Fatalf("ReadTrash returned %v, %v, want records %v and %v", trash, err, data[1].ID, data[0].ID)
+		}
+		if trash, err := repo.ReadTrash(2, 1, context.Background()); err != nil || len(trash) != 1 || trash[0].ID != data[0].ID {
+			t.Errorf("ReadTrash page 2 returned %v, %v, want record %v", trash, err, data[0].ID)
+		}
+
+		// * Only the records deleted before the cutoff are purged, their history is kept
+		if purged, err := repo.Purge(cutoff, context.Background()); err != nil || purged != 1 {
+			t.Fatalf("Purge returned %v, %v, want 1, nil", purged, err)
+		}
+		if count, err := repo.CountTrash(context.Background()); err != nil || count != 1 {
+			t.Errorf("CountTrash after Purge returned %v, %v, want 1, nil", count, err)
+		}
+		if rowsAffected, err := repo.Restore(data[0].ID, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Restore of a purged record returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if history, err := repo.ReadHistory(data[0].ID, context.Background()); err != nil || len(history) != 2 {
+			t.Errorf("ReadHistory of a purged record returned %v, %v, want its 2 versions", history, err)
+		}
+
+		if purged, err := repo.Purge(time.Now().Add(time.Hour), context.Background()); err != nil || purged != 1 {
+			t.Errorf("Purge of the whole trash returned %v, %v, want 1, nil", purged, err)
+		}
+		if count, err := repo.CountTrash(context.Background()); err != nil || count != 0 {
+			t.Errorf("CountTrash after purging everything returned %v, %v, want 0, nil", count, err)
+		}
+		assertDataCount(t, repo, 1)
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createData(t, repo, 1)[0]
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := repo.Create(newData(2), ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Create with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.ReadOne(data.ID, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadOne with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.ReadMany(1, 10, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadMany with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, _, err := repo.ReadAfter(nil, 10, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadAfter with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.Count(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Count with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		changed := *data
+		changed.DeviceName = "Cancelled"
+		if _, err := repo.Update(&changed, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Update with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.Delete(data, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Delete with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+
+		// * Nothing was written
+		assertDataCount(t, repo, 1)
+		assertSameData(t, readData(t, repo, data.ID), data)
+	})
+}
+
+// newData returns a record to create, records with a higher n come later in (date_time, id) order.
+func newData(n int) *models.Data {
+	return &models.Data{
+		DeviceID:     fmt.Sprintf("device-%v", n),
+		DeviceName:   fmt.Sprintf("Device %v", n),
+		Price:        int64(100 * (n + 1)),
+		Currency:     models.BaseCurrency,
+		SerialNumber: fmt.Sprintf("SN-%04d", n),
+		Type:         "Sensor",
+		DateTime:     fmt.Sprintf("2024-01-01T10:%02d:%02dZ", n/60, n%60),
+		Description:  fmt.Sprintf("Record %v", n),
+	}
+}
+
+// createData creates n records one after the other.
+func createData(t *testing.T, repo models.DataRepository, n int) []*models.Data {
+	t.Helper()
+	data := make([]*models.Data, n)
+	for i := range data {
+		data[i] = newData(i)
+		if err := repo.Create(data[i], context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return data
+}
+
+func readData(t *testing.T, repo models.DataRepository, id int) *models.Data {
+	t.Helper()
+	data, err := repo.ReadOne(id, context.Background())
+	if err != nil {
+		t.Fatalf("ReadOne failed: %v", err)
+	}
+	return data
+}
+
+func assertDataCount(t *testing.T, repo models.DataRepository, want int) {
+	t.Helper()
+	count, err := repo.Count(context.Background())
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != want {
+		t.Errorf("Count returned %v, want %v", count, want)
+	}
+}
+
+// assertSameData compares the stored fields of two records.
+func assertSameData(t *testing.T, got *models.Data, want *models.Data) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("got no record, want %+v", want)
+	}
+	if got.ID != want.ID || got.DeviceID != want.DeviceID || got.DeviceName != want.DeviceName || got.Price != want.Price ||
+		got.Currency != want.Currency || got.SerialNumber != want.SerialNumber || got.Type != want.Type ||
+		got.DateTime != want.DateTime || got.Description != want.Description || got.Version != want.Version ||
+		got.CreatedAt != want.CreatedAt {
+		t.Errorf("got record %+v, want %+v", got, want)
+	}
+}
+
+func dataIDs(data []*models.Data) []int {
+	ids := []int{}
+	for _, d := range data {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func parseQuery(t *testing.T, filter string, sort string, fields string) *query.Query {
+	t.Helper()
+	q, err := query.Parse(filter, sort, fields, models.DataQuerySchema)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return q
+}
+
+func assertQuery(t *testing.T, repo models.DataRepository, q *query.Query, page int, rowsPerPage int, want ...*models.Data) {
+	t.Helper()
+	got, err := repo.Query(q, page, rowsPerPage, context.Background())
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if ids, want := dataIDs(got), dataIDs(want); fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("Query page %v of %v returned ids %v, want %v", page, rowsPerPage, ids, want)
+	}
+}
+
+func assertCountQuery(t *testing.T, repo models.DataRepository, q *query.Query, want int) {
+	t.Helper()
+	count, err := repo.CountQuery(q, context.Background())
+	if err != nil {
+		t.Fatalf("CountQuery failed: %v", err)
+	}
+	if count != want {
+		t.Errorf("CountQuery returned %v, want %v", count, want)
+	}
+}
+
+// priceStats formats the prices of a stats group as [currency count total average ...].
+func priceStats(prices []*models.PriceStats) string {
+	var s []string
+	for _, p := range prices {
+		s = append(s, fmt.Sprint(p.Currency, " ", p.Count, " ", p.Total, " ", p.Average))
+	}
+	return fmt.Sprint(s)
+}
+
+// waitForClock lets the clock move on, so the changes before and after it are at different times.
+func waitForClock() {
+	time.Sleep(2 * time.Millisecond)
+}
@@ -0,0 +1,204 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"testing"
+	"time"
+)
+
+// * DHT22ArchiveRepositories are an archive repository and the readings it archives, of the same database *
+type DHT22ArchiveRepositories struct {
+	Archive models.DHT22ArchiveRepository
+	DHT22   models.DHT22Repository
+}
+
+// * NewDHT22ArchiveRepository returns an empty database, it is called once for each test of the suite *
+type NewDHT22ArchiveRepository func(t *testing.T) DHT22ArchiveRepositories
+
+// * TestDHT22ArchiveRepository runs the contract of models.DHT22ArchiveRepository against the databases made by newRepository *
+func TestDHT22ArchiveRepository(t *testing.T, newRepository NewDHT22ArchiveRepository) {
+	t.Run("ReadLive", func(t *testing.T) {
+		r := newRepository(t)
+		data := createDHT22(t, r.DHT22, 5)
+		if _, err := r.DHT22.Delete(data[1], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		live := readLive(t, r.Archive, nil, time.Time{}, 10)
+		if ids := archiveReadingIDs(live); fmt.Sprint(ids) != fmt.Sprint([]int{data[0].ID, data[2].ID, data[3].ID, data[4].ID}) {
+			t.Fatalf("ReadLive returned %v, want the readings that are not in the trash in order", ids)
+		}
+		for _, reading := range live {
+			if reading.Position.ID != reading.ID || reading.Position.DateTime == "" {
+				t.Errorf("ReadLive returned position %+v for reading %d", reading.Position, reading.ID)
+			}
+		}
+		assertSameDHT22(t, &live[0].DHT22Data, data[0])
+
+		// * The readings before 10:00:03 are the first three, one of them in the trash
+		before := time.Date(2024, 1, 1, 10, 0, 3, 0, time.UTC)
+		if ids := archiveReadingIDs(readLive(t, r.Archive, nil, before, 10)); fmt.Sprint(ids) != fmt.Sprint([]int{data[0].ID, data[2].ID}) {
+			t.Errorf("ReadLive before %v returned %v", before, ids)
+		}
+		if ids := archiveReadingIDs(readLive(t, r.Archive, &live[1].Position, time.Time{}, 1)); fmt.Sprint(ids) != fmt.Sprint([]int{data[3].ID}) {
+			t.Errorf("ReadLive after the second reading with a limit of 1 returned %v, want [%d]", ids, data[3].ID)
+		}
+		if got := readLive(t, r.Archive, &live[3].Position, time.Time{}, 10); len(got) != 0 {
+			t.Errorf("ReadLive after the last reading returned %v", archiveReadingIDs(got))
+		}
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		r := newRepository(t)
+		createDHT22(t, r.DHT22, 4)
+		live := readLive(t, r.Archive, nil, time.Time{}, 10)
+
+		archives := []*models.DHT22Archive{newArchive(live[2:3], "b"), newArchive(live[0:2], "a")}
+		if err := r.Archive.Archive(archives, archiveReadingIDs(live[:3]), context.Background()); err != nil {
+			t.Fatalf("Archive failed: %v", err)
+		}
+		for _, a := range archives {
+			if a.ID == 0 || a.CreatedAt == "" {
+				t.Errorf("Archive did not set the id and creation time: got %+v", a)
+			}
+		}
+		assertDHT22Count(t, r.DHT22, 1)
+		if got := readDHT22(t, r.DHT22, live[0].ID); got != nil {
+			t.Errorf("An archived reading is still in the database: %+v", got)
+		}
+
+		// * The manifest is ordered by the first reading of the files
+		got := readArchives(t, r.Archive, nil)
+		if len(got) != 2 || got[0].File != "a" || got[1].File != "b" {
+			t.Fatalf("ReadArchives returned %+v, want the files a and b", got)
+		}
+		if *got[0] != *archives[1] {
+			t.Errorf("ReadArchives returned %+v, want %+v", got[0], archives[1])
+		}
+		if got := readArchives(t, r.Archive, &live[1].Position); len(got) != 1 || got[0].File != "b" {
+			t.Errorf("ReadArchives after the last reading of a returned %+v, want b", got)
+		}
+		if got := readArchives(t, r.Archive, &live[2].Position); len(got) != 0 {
+			t.Errorf("ReadArchives after the last archived reading returned %+v", got)
+		}
+
+		// * A reading is looked for in the files whose ids range over its id
+		if got := readArchivesOf(t, r.Archive, live[1].ID); len(got) != 1 || got[0].File != "a" {
+			t.Errorf("ReadArchivesOf(%d) returned %+v, want a", live[1].ID, got)
+		}
+		if got := readArchivesOf(t, r.Archive, live[3].ID); len(got) != 0 {
+			t.Errorf("ReadArchivesOf the live reading %d returned %+v", live[3].ID, got)
+		}
+	})
+
+	t.Run("ReadArchivesOf", func(t *testing.T) {
+		r := newRepository(t)
+		createDHT22(t, r.DHT22, 3)
+		live := readLive(t, r.Archive, nil, time.Time{}, 10)
+
+		// * The ids of files can overlap, a reading measured late is archived with readings of smaller ids
+		a, b := newArchive(live[0:1], "a"), newArchive(live[1:2], "b")
+		a.MaxID = live[2].ID
+		if err := r.Archive.Archive([]*models.DHT22Archive{b, a}, archiveReadingIDs(live[:2]), context.Background()); err != nil {
+			t.Fatalf("Archive failed: %v", err)
+		}
+		if got := readArchivesOf(t, r.Archive, live[1].ID); len(got) != 2 || got[0].File != "a" || got[1].File != "b" {
+			t.Errorf("ReadArchivesOf(%d) returned %+v, want a and b in order", live[1].ID, got)
+		}
+		if got := readArchivesOf(t, r.Archive, live[0].ID); len(got) != 1 || *got[0] != *a {
+			t.Errorf("ReadArchivesOf(%d) returned %+v, want %+v", live[0].ID, got, a)
+		}
+		if got := readArchivesOf(t, r.Archive, live[2].ID+1); len(got) != 0 {
+			t.Errorf("ReadArchivesOf an id after every file returned %+v", got)
+		}
+	})
+
+	t.Run("ArchiveChanged", func(t *testing.T) {
+		r := newRepository(t)
+		data := createDHT22(t, r.DHT22, 2)
+		live := readLive(t, r.Archive, nil, time.Time{}, 10)
+		if _, err := r.DHT22.Delete(data[1], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		err := r.Archive.Archive([]*models.DHT22Archive{newArchive(live, "a")}, archiveReadingIDs(live), context.Background())
+		if !errors.Is(err, models.ErrArchiveChanged) {
+			t.Fatalf("Archive of a reading in the trash returned %v, want %v", err, models.ErrArchiveChanged)
+		}
+		assertDHT22Count(t, r.DHT22, 1)
+		if got := readArchives(t, r.Archive, nil); len(got) != 0 {
+			t.Errorf("A failed Archive recorded %+v", got)
+		}
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		r := newRepository(t)
+		live := readLive(t, r.Archive, nil, time.Time{}, 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := r.Archive.ReadLive(nil, time.Time{}, 10, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadLive with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if err := r.Archive.Archive([]*models.DHT22Archive{newArchive(live, "a")}, nil, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Archive with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := r.Archive.ReadArchives(nil, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadArchives with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := r.Archive.ReadArchivesOf(1, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadArchivesOf with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+// newArchive returns the manifest entry of a file with the readings.
+func newArchive(readings []*models.DHT22ArchiveReading, file string) *models.DHT22Archive {
+	a := &models.DHT22Archive{DeviceName: "dht22", Month: "2024-01", File: file, Readings: len(readings), Size: 100, SHA256: fmt.Sprintf("%064d", 0)}
+	if len(readings) > 0 {
+		a.First, a.Last = readings[0].Position, readings[len(readings)-1].Position
+		a.MinID, a.MaxID = readings[0].ID, readings[0].ID
+	}
+	for _, r := range readings {
+		a.MinID, a.MaxID = min(a.MinID, r.ID), max(a.MaxID, r.ID)
+	}
+	return a
+}
+
+func readLive(t *testing.T, repo models.DHT22ArchiveRepository, after *models.Cursor, before time.Time, limit int) []*models.DHT22ArchiveReading {
+	t.Helper()
+	readings, err := repo.ReadLive(after, before, limit, context.Background())
+	if err != nil {
+		t.Fatalf("ReadLive failed: %v", err)
+	}
+	return readings
+}
+
+func readArchives(t *testing.T, repo models.DHT22ArchiveRepository, after *models.Cursor) []*models.DHT22Archive {
+	t.Helper()
+	archives, err := repo.ReadArchives(after, context.Background())
+	if err != nil {
+		t.Fatalf("ReadArchives failed: %v", err)
+	}
+	return archives
+}
+
+func readArchivesOf(t *testing.T, repo models.DHT22ArchiveRepository, id int) []*models.DHT22Archive {
+	t.Helper()
+	archives, err := repo.ReadArchivesOf(id, context.Background())
+	if err != nil {
+		t.Fatalf("ReadArchivesOf failed: %v", err)
+	}
+	return archives
+}
+
+func archiveReadingIDs(readings []*models.DHT22ArchiveReading) []int {
+	ids := []int{}
+	for _, r := range readings {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
@@ -0,0 +1,498 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// * NewDHT22Repository returns an empty repository, it is called once for each test of the suite *
+type NewDHT22Repository func(t *testing.T) models.DHT22Repository
+
+// * TestDHT22Repository runs the contract of models.DHT22Repository against the repositories made by newRepository *
+func TestDHT22Repository(t *testing.T, newRepository NewDHT22Repository) {
+	t.Run("CreateAndReadOne", func(t *testing.T) {
+		repo := newRepository(t)
+		data := newDHT22(1)
+		if err := repo.Create(data, context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if data.ID == 0 || data.Version != 1 || data.CreatedAt == "" || data.UpdatedAt != data.CreatedAt {
+			t.Errorf("Create did not set the id, version and timestamps: got %+v", data)
+		}
+		assertSameDHT22(t, readDHT22(t, repo, data.ID), data)
+	})
+
+	t.Run("CreateMany", func(t *testing.T) {
+		repo := newRepository(t)
+		data := []*models.DHT22Data{newDHT22(1), newDHT22(2), newDHT22(3)}
+		if err := repo.CreateMany(data, context.Background()); err != nil {
+			t.Fatalf("CreateMany failed: %v", err)
+		}
+		for i, d := range data {
+			if d.ID == 0 || d.Version != 1 || d.CreatedAt == "" || d.UpdatedAt != d.CreatedAt {
+				t.Errorf("CreateMany did not set the id, version and timestamps of reading %d: got %+v", i, d)
+			}
+			if i > 0 && d.ID <= data[i-1].ID {
+				t.Errorf("CreateMany did not give the readings increasing ids: got %v", dht22IDs(data))
+			}
+			assertSameDHT22(t, readDHT22(t, repo, d.ID), d)
+		}
+		assertDHT22Count(t, repo, 3)
+
+		if err := repo.CreateMany(nil, context.Background()); err != nil {
+			t.Errorf("CreateMany of no readings returned %v, want nil", err)
+		}
+		assertDHT22Count(t, repo, 3)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createDHT22(t, repo, 1)[0]
+
+		data.Temperature = 30.5
+		rowsAffected, err := repo.Update(data, context.Background())
+		if err != nil || rowsAffected != 1 {
+			t.Fatalf("Update returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if data.Version != 2 {
+			t.Errorf("Update set version %v, want 2", data.Version)
+		}
+		assertSameDHT22(t, readDHT22(t, repo, data.ID), data)
+
+		stale := *data
+		stale.Version = 1
+		stale.Temperature = -1
+		if _, err := repo.Update(&stale, context.Background()); !errors.Is(err, models.ErrVersionMismatch) {
+			t.Errorf("Update with a stale version returned %v, want %v", err, models.ErrVersionMismatch)
+		}
+		if got := readDHT22(t, repo, data.ID); got.Temperature != 30.5 {
+			t.Errorf("Update with a stale version changed the reading: got %v", got.Temperature)
+		}
+	})
+
+	t.Run("DeleteAndRestore", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createDHT22(t, repo, 2)
+
+		rowsAffected, err := repo.Delete(data[0], context.Background())
+		if err != nil || rowsAffected != 1 {
+			t.Fatalf("Delete returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readDHT22(t, repo, data[0].ID); got != nil {
+			t.Errorf("ReadOne returned a deleted reading: got %+v", got)
+		}
+		assertDHT22Count(t, repo, 1)
+		if count, err := repo.CountTrash(context.Background()); err != nil || count != 1 {
+			t.Errorf("CountTrash returned %v, %v, want 1, nil", count, err)
+		}
+
+		rowsAffected, err = repo.Restore(data[0].ID, context.Background())
+		if err != nil || rowsAffected != 1 {
+			t.Fatalf("Restore returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		assertDHT22Count(t, repo, 2)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepository(t)
+		createDHT22(t, repo, 1)
+
+		if got, err := repo.ReadOne(999, context.Background()); got != nil || err != nil {
+			t.Errorf("ReadOne of a missing reading returned %v, %v, want nil, nil", got, err)
+		}
+		missing := newDHT22(999)
+		missing.ID = 999
+		if rowsAffected, err := repo.Update(missing, context.Background()); rowsAffected != 0 || err != nil {
+			t.Errorf("Update of a missing reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		missing.Version = 1
+		if rowsAffected, err := repo.Update(missing, context.Background()); rowsAffected != 0 || err != nil {
+			t.Errorf("Update of a missing reading with a version returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Delete(missing, context.Background()); rowsAffected != 0 || err != nil {
+			t.Errorf("Delete of a missing reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Restore(999, context.Background()); rowsAffected != 0 || err != nil {
+			t.Errorf("Restore of a missing reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if got, err := repo.ReadLatest("missing", 10, context.Background()); len(got) != 0 || err != nil {
+			t.Errorf("ReadLatest of a missing device returned %v, %v, want no readings", got, err)
+		}
+	})
+
+	t.Run("ReadManyPages", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createDHT22(t, repo, 5)
+		assertDHT22Count(t, repo, 5)
+
+		tests := []struct {
+			page, rowsPerPage int
+			want              []*models.DHT22Data
+		}{
+			{1, 2, data[0:2]},
+			{2, 2, data[2:4]},
+			{3, 2, data[4:5]},
+			{4, 2, nil},
+			{1, 5, data},
+			{1, 10, data},
+			{2, 5, nil},
+		}
+		for _, tt := range tests {
+			got, err := repo.ReadMany(tt.page, tt.rowsPerPage, context.Background())
+			if err != nil {
+				t.Fatalf("ReadMany(%v, %v) failed: %v", tt.page, tt.rowsPerPage, err)
+			}
+			if ids, want := dht22IDs(got), dht22IDs(tt.want); fmt.Sprint(ids) != fmt.Sprint(want) {
+				t.Errorf("ReadMany(%v, %v) returned ids %v, want %v", tt.page, tt.rowsPerPage, ids, want)
+			}
+		}
+	})
+
+	t.Run("ReadAfterPages", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createDHT22(t, repo, 4)
+		data[3].DateTime = data[2].DateTime
+		if _, err := repo.Update(data[3], context.Background()); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+
+		for _, limit := range []int{1, 2, 3, 4, 5} {
+			var ids []int
+			var cursor *models.Cursor
+			for pages := 0; ; pages++ {
+				if pages > len(data) {
+					t.Fatalf("ReadAfter with limit %v did not reach the last page", limit)
+				}
+				page, next, err := repo.ReadAfter(cursor, limit, context.Background())
+				if err != nil {
+					t.Fatalf("ReadAfter with limit %v failed: %v", limit, err)
+				}
+				if len(page) > limit {
+					t.Errorf("ReadAfter with limit %v returned %v readings", limit, len(page))
+				}
+				ids = append(ids, dht22IDs(page)...)
+				if next == nil {
+					break
+				}
+				cursor = next
+			}
+			if want := dht22IDs(data); fmt.Sprint(ids) != fmt.Sprint(want) {
+				t.Errorf("ReadAfter with limit %v returned ids %v, want %v", limit, ids, want)
+			}
+		}
+	})
+
+	t.Run("ReadLatest", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createDHT22(t, repo, 5)
+		other := newDHT22(9)
+		other.DeviceName = "other"
+		if err := repo.Create(other, context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		got, err := repo.ReadLatest(data[0].DeviceName, 2, context.Background())
+		if err != nil {
+			t.Fatalf("ReadLatest failed: %v", err)
+		}
+		if ids, want := dht22IDs(got), []int{data[4].ID, data[3].ID}; fmt.Sprint(ids) != fmt.Sprint(want) {
+			t.Errorf("ReadLatest returned ids %v, want %v", ids, want)
+		}
+
+		// * The newest readings by time, not by the text: it can carry any offset and fraction of a second
+		var offsets []*models.DHT22Data
+		for i, dateTime := range []string{"2024-01-01T12:00:00+02:00", "2024-01-01T10:30:00Z", "2024-01-01T10:15:00.5Z", "2024-01-01T10:15:00Z"} {
+			reading := newDHT22(i)
+			reading.DeviceName = "offsets"
+			reading.DateTime = dateTime
+			if err := repo.Create(reading, context.Background()); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			offsets = append(offsets, reading)
+		}
+		got, err = repo.ReadLatest("offsets", 3, context.Background())
+		if err != nil {
+			t.Fatalf("ReadLatest failed: %v", err)
+		}
+		if ids, want := dht22IDs(got), []int{offsets[1].ID, offsets[2].ID, offsets[3].ID}; fmt.Sprint(ids) != fmt.Sprint(want) {
+			t.Errorf("ReadLatest of readings with offsets returned ids %v, want %v", ids, want)
+		}
+	})
+
+	t.Run("DateTimeFractionsAndOffsets", func(t *testing.T) {
+		repo := newRepository(t)
+
+		// * The services write date_time with models.UTCDateTime, every backend reads that text back as it is
+		var data []*models.DHT22Data
+		for i, dateTime := range []string{"2024-03-01T10:00:05Z", "2024-03-01T10:00:05.000001Z", "2024-03-01T10:00:05.25Z"} {
+			d := newDHT22(i)
+			d.DateTime = dateTime
+			if err := repo.Create(d, context.Background()); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			if got := readDHT22(t, repo, d.ID); got.DateTime != dateTime {
+				t.Errorf("date_time %v was read back as %v", dateTime, got.DateTime)
+			}
+			data = append(data, d)
+		}
+		// * A cursor of a time with a fraction continues after it, every reading is read once. Within a second
+		// * SQLite and memory order the times as text, 05.25Z before 05Z, PostgreSQL as times
+		var ids []int
+		var cursor *models.Cursor
+		for pages := 0; pages < len(data); pages++ {
+			page, next, err := repo.ReadAfter(cursor, 1, context.Background())
+			if err != nil {
+				t.Fatalf("ReadAfter failed: %v", err)
+			}
+			ids = append(ids, dht22IDs(page)...)
+			if cursor = next; cursor == nil {
+				break
+			}
+		}
+		slices.Sort(ids)
+		if want := dht22IDs(data); fmt.Sprint(ids) != fmt.Sprint(want) {
+			t.Errorf("ReadAfter returned ids %v, want %v", ids, want)
+		}
+
+		// * A time with an offset, stored before the services wrote UTC, is read back as the same time
+		offset := newDHT22(3)
+		offset.DateTime = "2024-03-01T12:00:05.5+02:00"
+		if err := repo.Create(offset, context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		assertSameTime(t, readDHT22(t, repo, offset.ID).DateTime, offset.DateTime)
+	})
+
+	t.Run("ConcurrentWrites", func(t *testing.T) {
+		repo := newRepository(t)
+		const writers = 20
+
+		var wg sync.WaitGroup
+		errs := make(chan error, writers)
+		ids := make(chan int, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data := newDHT22(i)
+				if err := repo.Create(data, context.Background()); err != nil {
+					errs <- err
+					return
+				}
+				ids <- data.ID
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		close(ids)
+		for err := range errs {
+			t.Errorf("concurrent Create failed: %v", err)
+		}
+		seen := map[int]bool{}
+		for id := range ids {
+			if seen[id] {
+				t.Errorf("concurrent Create gave id %v twice", id)
+			}
+			seen[id] = true
+		}
+		assertDHT22Count(t, repo, writers)
+
+		// * Of the conditional updates of one version, exactly one wins
+		first, err := repo.ReadMany(1, 1, context.Background())
+		if err != nil || len(first) != 1 {
+			t.Fatalf("ReadMany returned %v, %v", first, err)
+		}
+		var mu sync.Mutex
+		won, lost := 0, 0
+		errs = make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data := *first[0]
+				data.Temperature = float64(i)
+				_, err := repo.Update(&data, context.Background())
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					won++
+				case errors.Is(err, models.ErrVersionMismatch):
+					lost++
+				default:
+					errs <- err
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent Update failed: %v", err)
+		}
+		if won != 1 || lost != writers-1 {
+			t.Errorf("concurrent Updates of version 1: %v applied and %v refused, want 1 and %v", won, lost, writers-1)
+		}
+		if got := readDHT22(t, repo, first[0].ID); got.Version != 2 {
+			t.Errorf("concurrent Updates left version %v, want 2", got.Version)
+		}
+	})
+
+	t.Run("TrashAndPurge", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createDHT22(t, repo, 3)
+		if _, err := repo.Delete(data[0], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		waitForClock()
+		cutoff := time.Now()
+		waitForClock()
+		if _, err := repo.Delete(data[1], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		// * Most recently deleted first
+		trash, err := repo.ReadTrash(1, 10, context.Background())
+		if err != nil || len(trash) != 2 || trash[0].ID != data[1].ID || trash[1].ID != data[0].ID || trash[0].DeletedAt == "" {
+			t.Fatalf("ReadTrash returned %v, %v, want readings %v and %v", trash, err, data[1].ID, data[0].ID)
+		}
+		if count, err := repo.CountTrash(context.Background()); err != nil || count != 2 {
+			t.Errorf("CountTrash returned %v, %v, want 2, nil", count, err)
+		}
+
+		// * Only the readings deleted before the cutoff are purged, the live ones are never
+		if purged, err := repo.Purge(cutoff, context.Background()); err != nil || purged != 1 {
+			t.Fatalf("Purge returned %v, %v, want 1, nil", purged, err)
+		}
+		if rowsAffected, err := repo.Restore(data[0].ID, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Restore of a purged reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Restore(data[1].ID, context.Background()); err != nil || rowsAffected != 1 {
+			t.Errorf("Restore of a reading deleted after the cutoff returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if purged, err := repo.Purge(time.Now().Add(time.Hour), context.Background()); err != nil || purged != 0 {
+			t.Errorf("Purge of the empty trash returned %v, %v, want 0, nil", purged, err)
+		}
+		assertDHT22Count(t, repo, 2)
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		repo := newRepository(t)
+		data := createDHT22(t, repo, 1)[0]
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := repo.Create(newDHT22(2), ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Create with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if err := repo.CreateMany([]*models.DHT22Data{newDHT22(2), newDHT22(3)}, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("CreateMany with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.ReadOne(data.ID, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadOne with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.ReadMany(1, 10, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadMany with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, _, err := repo.ReadAfter(nil, 10, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadAfter with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.Count(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Count with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.ReadLatest(data.DeviceName, 10, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadLatest with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		changed := *data
+		changed.Temperature = -1
+		if _, err := repo.Update(&changed, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Update with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.Delete(data, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Delete with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+
+		assertDHT22Count(t, repo, 1)
+		assertSameDHT22(t, readDHT22(t, repo, data.ID), data)
+	})
+}
+
+// newDHT22 returns a reading to create, readings with a higher n come later in (date_time, id) order.
+func newDHT22(n int) *models.DHT22Data {
+	return &models.DHT22Data{
+		DeviceName:  "dht22",
+		Temperature: 20 + float64(n)/10,
+		Humidity:    40 + float64(n),
+		DateTime:    fmt.Sprintf("2024-01-01T10:%02d:%02dZ", n/60, n%60),
+	}
+}
+
+// createDHT22 creates n readings one after the other.
+func createDHT22(t *testing.T, repo models.DHT22Repository, n int) []*models.DHT22Data {
+	t.Helper()
+	data := make([]*models.DHT22Data, n)
+	for i := range data {
+		data[i] = newDHT22(i)
+		if err := repo.Create(data[i], context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return data
+}
+
+func readDHT22(t *testing.T, repo models.DHT22Repository, id int) *models.DHT22Data {
+	t.Helper()
+	data, err := repo.ReadOne(id, context.Background())
+	if err != nil {
+		t.Fatalf("ReadOne failed: %v", err)
+	}
+	return data
+}
+
+func assertDHT22Count(t *testing.T, repo models.DHT22Repository, want int) {
+	t.Helper()
+	count, err := repo.Count(context.Background())
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != want {
+		t.Errorf("Count returned %v, want %v", count, want)
+	}
+}
+
+func assertSameDHT22(t *testing.T, got *models.DHT22Data, want *models.DHT22Data) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("got no reading, want %+v", want)
+	}
+	if got.ID != want.ID || got.DeviceName != want.DeviceName || got.Temperature != want.Temperature ||
+		got.Humidity != want.Humidity || got.DateTime != want.DateTime || got.Version != want.Version ||
+		got.CreatedAt != want.CreatedAt {
+		t.Errorf("got reading %+v, want %+v", got, want)
+	}
+}
+
+// assertSameTime compares two RFC 3339 times, that may be written with other offsets.
+func assertSameTime(t *testing.T, got string, want string) {
+	t.Helper()
+	gotTime, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		t.Fatalf("date_time %v is not RFC 3339: %v", got, err)
+	}
+	if wantTime, _ := time.Parse(time.RFC3339, want); !gotTime.Equal(wantTime) {
+		t.Errorf("date_time %v was read back as %v", want, got)
+	}
+}
+
+func dht22IDs(data []*models.DHT22Data) []int {
+	ids := []int{}
+	for _, d := range data {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
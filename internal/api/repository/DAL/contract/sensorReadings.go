@@ -0,0 +1,275 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"maps"
+	"testing"
+	"time"
+)
+
+// * NewSensorRepository returns an empty repository, it is called once for each test of the suite *
+type NewSensorRepository func(t *testing.T) models.SensorRepository
+
+// * TestSensorRepository runs the contract of models.SensorRepository against the repositories made by newRepository *
+func TestSensorRepository(t *testing.T, newRepository NewSensorRepository) {
+	t.Run("CreateAndReadOne", func(t *testing.T) {
+		repo := newRepository(t)
+		reading := newSensorReading("bme280", 1)
+		if err := repo.Create(reading, context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if reading.ID == 0 || reading.Version != 1 || reading.CreatedAt == "" || reading.UpdatedAt != reading.CreatedAt {
+			t.Errorf("Create did not set the id, version and timestamps: got %+v", reading)
+		}
+		assertSameSensorReading(t, readSensorReading(t, repo, "bme280", reading.ID), reading)
+
+		// * A reading is only found under its own type
+		if got := readSensorReading(t, repo, "ds18b20", reading.ID); got != nil {
+			t.Errorf("ReadOne of another type returned %+v, want nil", got)
+		}
+	})
+
+	t.Run("ReadManyAndCount", func(t *testing.T) {
+		repo := newRepository(t)
+		bme280 := createSensorReadings(t, repo, "bme280", 3)
+		createSensorReadings(t, repo, "ds18b20", 2)
+
+		readings, err := repo.ReadMany("bme280", 1, 2, context.Background())
+		if err != nil {
+			t.Fatalf("ReadMany failed: %v", err)
+		}
+		if len(readings) != 2 {
+			t.Fatalf("ReadMany of the first page returned %d readings, want 2", len(readings))
+		}
+		assertSameSensorReading(t, readings[0], bme280[0])
+		assertSameSensorReading(t, readings[1], bme280[1])
+		if readings, err := repo.ReadMany("bme280", 2, 2, context.Background()); err != nil || len(readings) != 1 || readings[0].ID != bme280[2].ID {
+			t.Errorf("ReadMany of the second page returned %v, %v, want reading %d", sensorReadingIDs(readings), err, bme280[2].ID)
+		}
+		if readings, err := repo.ReadMany("scd30", 1, 10, context.Background()); err != nil || len(readings) != 0 {
+			t.Errorf("ReadMany of a type without readings returned %v, %v", sensorReadingIDs(readings), err)
+		}
+		assertSensorCount(t, repo, "bme280", 3)
+		assertSensorCount(t, repo, "ds18b20", 2)
+		assertSensorCount(t, repo, "scd30", 0)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepository(t)
+		reading := createSensorReadings(t, repo, "bme280", 1)[0]
+
+		reading.Values = map[string]float64{"temperature": 25.5, "humidity": 40, "pressure": 990.25}
+		rowsAffected, err := repo.Update(reading, context.Background())
+		if err != nil || rowsAffected != 1 {
+			t.Fatalf("Update returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if reading.Version != 2 {
+			t.Errorf("Update set version %v, want 2", reading.Version)
+		}
+		assertSameSensorReading(t, readSensorReading(t, repo, "bme280", reading.ID), reading)
+
+		stale := *reading
+		stale.Version = 1
+		stale.Values = map[string]float64{"temperature": -1}
+		if _, err := repo.Update(&stale, context.Background()); !errors.Is(err, models.ErrVersionMismatch) {
+			t.Errorf("Update with a stale version returned %v, want %v", err, models.ErrVersionMismatch)
+		}
+		otherType := *reading
+		otherType.Type = "ds18b20"
+		if rowsAffected, err := repo.Update(&otherType, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Update under another type returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		missing := *reading
+		missing.ID = reading.ID + 100
+		if rowsAffected, err := repo.Update(&missing, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Update of a missing reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		assertSameSensorReading(t, readSensorReading(t, repo, "bme280", reading.ID), reading)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepository(t)
+		readings := createSensorReadings(t, repo, "bme280", 2)
+
+		if _, err := repo.Delete("bme280", readings[0].ID, 5, context.Background()); !errors.Is(err, models.ErrVersionMismatch) {
+			t.Errorf("Delete with a stale version returned %v, want %v", err, models.ErrVersionMismatch)
+		}
+		if rowsAffected, err := repo.Delete("ds18b20", readings[0].ID, 0, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Delete under another type returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Delete("bme280", readings[0].ID, 1, context.Background()); err != nil || rowsAffected != 1 {
+			t.Fatalf("Delete returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		if got := readSensorReading(t, repo, "bme280", readings[0].ID); got != nil {
+			t.Errorf("ReadOne of a deleted reading returned %+v", got)
+		}
+		if rowsAffected, err := repo.Delete("bme280", readings[0].ID, 1, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Delete of a deleted reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		assertSensorCount(t, repo, "bme280", 1)
+	})
+
+	t.Run("TrashAndPurge", func(t *testing.T) {
+		repo := newRepository(t)
+		readings := createSensorReadings(t, repo, "bme280", 3)
+		if _, err := repo.Delete("bme280", readings[0].ID, 0, context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		waitForClock()
+		cutoff := time.Now()
+		waitForClock()
+		if _, err := repo.Delete("bme280", readings[1].ID, 0, context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		// * A reading in the trash is neither read nor updated
+		if got, err := repo.ReadMany("bme280", 1, 10, context.Background()); err != nil || len(got) != 1 || got[0].ID != readings[2].ID {
+			t.Errorf("ReadMany returned %v, %v, want only reading %v", sensorReadingIDs(got), err, readings[2].ID)
+		}
+		deleted := *readings[1]
+		if rowsAffected, err := repo.Update(&deleted, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Update of a deleted reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+
+		// * Most recently deleted first
+		trash, err := repo.ReadTrash(1, 10, context.Background())
+		if err != nil || len(trash) != 2 || trash[0].ID != readings[1].ID || trash[1].ID != readings[0].ID || trash[0].DeletedAt == "" {
+			t.Fatalf("ReadTrash returned %v, %v, want readings %v and %v", trash, err, readings[1].ID, readings[0].ID)
+		}
+		if trash[0].Type != "bme280" || !maps.Equal(trash[0].Values, readings[1].Values) {
+			t.Errorf("ReadTrash returned %+v, want the type and values of %+v", trash[0].SensorReading, readings[1])
+		}
+		if count, err := repo.CountTrash(context.Background()); err != nil || count != 2 {
+			t.Errorf("CountTrash returned %v, %v, want 2, nil", count, err)
+		}
+
+		// * Only the readings deleted before the cutoff are purged, the live ones are never
+		if purged, err := repo.Purge(cutoff, context.Background()); err != nil || purged != 1 {
+			t.Fatalf("Purge returned %v, %v, want 1, nil", purged, err)
+		}
+		if rowsAffected, err := repo.Restore("bme280", readings[0].ID, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Restore of a purged reading returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Restore("ds18b20", readings[1].ID, context.Background()); err != nil || rowsAffected != 0 {
+			t.Errorf("Restore under another type returned %v, %v, want 0, nil", rowsAffected, err)
+		}
+		if rowsAffected, err := repo.Restore("bme280", readings[1].ID, context.Background()); err != nil || rowsAffected != 1 {
+			t.Errorf("Restore of a reading deleted after the cutoff returned %v, %v, want 1, nil", rowsAffected, err)
+		}
+		assertSameSensorReading(t, readSensorReading(t, repo, "bme280", readings[1].ID), readings[1])
+		if purged, err := repo.Purge(time.Now().Add(time.Hour), context.Background()); err != nil || purged != 0 {
+			t.Errorf("Purge of the empty trash returned %v, %v, want 0, nil", purged, err)
+		}
+		assertSensorCount(t, repo, "bme280", 2)
+	})
+
+	t.Run("DateTimeFractionsAndOffsets", func(t *testing.T) {
+		repo := newRepository(t)
+		for i, dateTime := range []string{"2024-03-01T10:00:05Z", "2024-03-01T10:00:05.000001Z", "2024-03-01T10:00:05.25Z"} {
+			reading := newSensorReading("bme280", i)
+			reading.DateTime = dateTime
+			if err := repo.Create(reading, context.Background()); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			if got := readSensorReading(t, repo, "bme280", reading.ID); got.DateTime != dateTime {
+				t.Errorf("date_time %v was read back as %v", dateTime, got.DateTime)
+			}
+		}
+
+		offset := newSensorReading("bme280", 3)
+		offset.DateTime = "2024-03-01T12:00:05.5+02:00"
+		if err := repo.Create(offset, context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		assertSameTime(t, readSensorReading(t, repo, "bme280", offset.ID).DateTime, offset.DateTime)
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		repo := newRepository(t)
+		reading := createSensorReadings(t, repo, "bme280", 1)[0]
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := repo.Create(newSensorReading("bme280", 2), ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Create with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.ReadOne("bme280", reading.ID, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadOne with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.ReadMany("bme280", 1, 10, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadMany with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.Update(reading, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Update with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		if _, err := repo.Delete("bme280", reading.ID, 0, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Delete with a cancelled context returned %v, want %v", err, context.Canceled)
+		}
+		assertSensorCount(t, repo, "bme280", 1)
+	})
+}
+
+// newSensorReading returns a reading of a type to create, readings with a higher n are measured later.
+func newSensorReading(sensorType string, n int) *models.SensorReading {
+	return &models.SensorReading{
+		Type:       sensorType,
+		DeviceName: "sensor",
+		DateTime:   fmt.Sprintf("2024-01-01T10:%02d:%02dZ", n/60, n%60),
+		Values:     map[string]float64{"temperature": 20 + float64(n)/10, "humidity": 40.5},
+	}
+}
+
+// createSensorReadings creates n readings of a type one after the other.
+func createSensorReadings(t *testing.T, repo models.SensorRepository, sensorType string, n int) []*models.SensorReading {
+	t.Helper()
+	readings := make([]*models.SensorReading, n)
+	for i := range readings {
+		readings[i] = newSensorReading(sensorType, i)
+		if err := repo.Create(readings[i], context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return readings
+}
+
+func readSensorReading(t *testing.T, repo models.SensorRepository, sensorType string, id int) *models.SensorReading {
+	t.Helper()
+	reading, err := repo.ReadOne(sensorType, id, context.Background())
+	if err != nil {
+		t.Fatalf("ReadOne failed: %v", err)
+	}
+	return reading
+}
+
+func assertSensorCount(t *testing.T, repo models.SensorRepository, sensorType string, want int) {
+	t.Helper()
+	count, err := repo.Count(sensorType, context.Background())
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != want {
+		t.Errorf("Count of %s returned %v, want %v", sensorType, count, want)
+	}
+}
+
+func assertSameSensorReading(t *testing.T, got *models.SensorReading, want *models.SensorReading) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("got no reading, want %+v", want)
+	}
+	if got.ID != want.ID || got.Type != want.Type || got.DeviceName != want.DeviceName || got.DateTime != want.DateTime ||
+		!maps.Equal(got.Values, want.Values) || got.Version != want.Version || got.CreatedAt != want.CreatedAt {
+		t.Errorf("got reading %+v, want %+v", got, want)
+	}
+}
+
+func sensorReadingIDs(readings []*models.SensorReading) []int {
+	ids := []int{}
+	for _, r := range readings {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
@@ -0,0 +1,141 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"testing"
+	"time"
+)
+
+// * DataTrashRepositories are a data repository and the attachments of its records, of the same database *
+type DataTrashRepositories struct {
+	Data        models.DataRepository
+	Attachments models.AttachmentRepository
+}
+
+// * NewDataTrashRepositories returns an empty database, it is called once for each test of the suite *
+type NewDataTrashRepositories func(t *testing.T) DataTrashRepositories
+
+// * TestDataTrash runs the contract of what the trash keeps of a deleted record and what a purge removes *
+func TestDataTrash(t *testing.T, newRepositories NewDataTrashRepositories) {
+	t.Run("AttachmentsOfPurgedRecords", func(t *testing.T) {
+		r := newRepositories(t)
+		data := createData(t, r.Data, 3)
+		attachments := make([]*models.Attachment, len(data))
+		for i, d := range data {
+			attachments[i] = createAttachment(t, r.Attachments, d.ID, i)
+		}
+
+		if _, err := r.Data.Delete(data[0], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		waitForClock()
+		cutoff := time.Now()
+		waitForClock()
+		if _, err := r.Data.Delete(data[1], context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		// * A record in the trash keeps its attachments, it can still be restored with them
+		assertOrphans(t, r.Attachments)
+		if got, err := r.Attachments.ReadAll(data[0].ID, context.Background()); err != nil || len(got) != 1 {
+			t.Errorf("ReadAll of a record in the trash returned %v, %v, want its attachment", got, err)
+		}
+
+		// * The attachments of purged records are orphans, once
+		if purged, err := r.Data.Purge(cutoff, context.Background()); err != nil || purged != 1 {
+			t.Fatalf("Purge returned %v, %v, want 1, nil", purged, err)
+		}
+		assertOrphans(t, r.Attachments, attachments[0])
+		assertOrphans(t, r.Attachments)
+		if got, err := r.Attachments.ReadAll(data[0].ID, context.Background()); err != nil || len(got) != 0 {
+			t.Errorf("ReadAll of a purged record returned %v, %v, want none", got, err)
+		}
+
+		if purged, err := r.Data.Purge(time.Now().Add(time.Hour), context.Background()); err != nil || purged != 1 {
+			t.Fatalf("Purge of the whole trash returned %v, %v, want 1, nil", purged, err)
+		}
+		assertOrphans(t, r.Attachments, attachments[1])
+		if got, err := r.Attachments.ReadAll(data[2].ID, context.Background()); err != nil || len(got) != 1 || *got[0] != *attachments[2] {
+			t.Errorf("ReadAll of a live record returned %v, %v, want %+v", got, err, attachments[2])
+		}
+	})
+
+	t.Run("TagsOfTrashedRecords", func(t *testing.T) {
+		r := newRepositories(t)
+		data := createData(t, r.Data, 2)
+		for _, d := range data {
+			if _, err := r.Data.AddTags(d.ID, []string{"keep", fmt.Sprintf("record-%d", d.ID)}, 0, context.Background()); err != nil {
+				t.Fatalf("AddTags failed: %v", err)
+			}
+		}
+		// * Tagging a record changes its version
+		if _, err := r.Data.Delete(readData(t, r.Data, data[0].ID), context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		// * A record in the trash keeps its tags, Restore brings it back with them
+		tags := readData(t, r.Data, data[1].ID).Tags
+		if _, err := r.Data.Restore(data[0].ID, context.Background()); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		want := fmt.Sprint([]string{"keep", fmt.Sprintf("record-%d", data[0].ID)})
+		if got := readData(t, r.Data, data[0].ID); fmt.Sprint(got.Tags) != want {
+			t.Errorf("Restore brought back tags %v, want %v", got.Tags, want)
+		}
+
+		// * A purged record is no longer found by its tags, the tags of the other records stay
+		if _, err := r.Data.Delete(readData(t, r.Data, data[0].ID), context.Background()); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := r.Data.Purge(time.Now().Add(time.Hour), context.Background()); err != nil {
+			t.Fatalf("Purge failed: %v", err)
+		}
+		q := parseQuery(t, "", "", "")
+		q.Tags = []string{"keep"}
+		assertQuery(t, r.Data, q, 1, 10, readData(t, r.Data, data[1].ID))
+		if got := readData(t, r.Data, data[1].ID); fmt.Sprint(got.Tags) != fmt.Sprint(tags) {
+			t.Errorf("The tags of a live record are %v after the purge, want %v", got.Tags, tags)
+		}
+		q.Tags = []string{fmt.Sprintf("record-%d", data[0].ID)}
+		assertCountQuery(t, r.Data, q, 0)
+	})
+}
+
+// createAttachment stores the metadata of an attachment of the record, the n-th of the test.
+func createAttachment(t *testing.T, repo models.AttachmentRepository, dataID int, n int) *models.Attachment {
+	t.Helper()
+	attachment := &models.Attachment{
+		DataID:      dataID,
+		FileName:    fmt.Sprintf("file-%d.txt", n),
+		ContentType: "text/plain",
+		Size:        int64(n),
+		SHA256:      fmt.Sprintf("%064d", n),
+		BlobKey:     fmt.Sprintf("blob-%d", n),
+		CreatedBy:   "contract",
+	}
+	if err := repo.Create(attachment, context.Background()); err != nil {
+		t.Fatalf("Create attachment failed: %v", err)
+	}
+	return attachment
+}
+
+// assertOrphans deletes the orphaned attachments and checks they are the wanted ones, by id and blob key.
+func assertOrphans(t *testing.T, repo models.AttachmentRepository, want ...*models.Attachment) {
+	t.Helper()
+	orphans, err := repo.DeleteOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("DeleteOrphans failed: %v", err)
+	}
+	got, wanted := []string{}, []string{}
+	for _, a := range orphans {
+		got = append(got, fmt.Sprintf("%d:%s", a.ID, a.BlobKey))
+	}
+	for _, a := range want {
+		wanted = append(wanted, fmt.Sprintf("%d:%s", a.ID, a.BlobKey))
+	}
+	if fmt.Sprint(got) != fmt.Sprint(wanted) {
+		t.Errorf("DeleteOrphans returned %v, want %v", got, wanted)
+	}
+}
@@ -0,0 +1,184 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"goapi/internal/api/repository/models"
+	"testing"
+)
+
+// * UnitOfWorkRepositories are a unit of work and the repositories of the same database *
+type UnitOfWorkRepositories struct {
+	Unit  models.UnitOfWork
+	Data  models.DataRepository
+	DHT22 models.DHT22Repository
+}
+
+// * NewUnitOfWork returns an empty database, it is called once for each test of the suite *
+type NewUnitOfWork func(t *testing.T) UnitOfWorkRepositories
+
+var errUnitFailed = errors.New("unit failed")
+
+// * TestUnitOfWork runs the contract of models.UnitOfWork against the databases made by newUnitOfWork *
+func TestUnitOfWork(t *testing.T, newUnitOfWork NewUnitOfWork) {
+	t.Run("Commit", func(t *testing.T) {
+		r := newUnitOfWork(t)
+		data := createData(t, r.Data, 1)[0]
+		reading := newDHT22(1)
+		created := newData(2)
+
+		err := r.Unit.Do(func(ctx context.Context) error {
+			if err := r.Data.Create(created, ctx); err != nil {
+				return err
+			}
+			if _, err := r.Data.AddTags(created.ID, []string{"imported"}, 0, ctx); err != nil {
+				return err
+			}
+			data.Description = "changed in a unit"
+			if _, err := r.Data.Update(data, ctx); err != nil {
+				return err
+			}
+			// * What the unit wrote is read back inside it, outside it is not there before the commit
+			if got, err := r.Data.ReadOne(created.ID, ctx); err != nil || got == nil {
+				t.Errorf("ReadOne inside the unit returned %v, %v, want the created record", got, err)
+			}
+			if _, err := r.Data.ChangeMarker(ctx); err != nil {
+				return err
+			}
+			return r.DHT22.Create(reading, ctx)
+		}, context.Background())
+		if err != nil {
+			t.Fatalf("Do failed: %v", err)
+		}
+
+		assertDataCount(t, r.Data, 2)
+		assertDHT22Count(t, r.DHT22, 1)
+		if got := readData(t, r.Data, created.ID); got == nil || len(got.Tags) != 1 || got.Tags[0] != "imported" {
+			t.Errorf("The tags added in the unit were not committed: got %+v", got)
+		}
+		if got := readData(t, r.Data, data.ID); got.Description != "changed in a unit" || got.Version != 2 {
+			t.Errorf("The update in the unit was not committed: got %+v", got)
+		}
+		if history, err := r.Data.ReadHistory(data.ID, context.Background()); err != nil || len(history) != 2 {
+			t.Errorf("ReadHistory returned %v entries, %v, want 2", len(history), err)
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		r := newUnitOfWork(t)
+		data := createData(t, r.Data, 1)[0]
+		readings := createDHT22(t, r.DHT22, 1)
+
+		err := r.Unit.Do(func(ctx context.Context) error {
+			if err := r.Data.Create(newData(2), ctx); err != nil {
+				return err
+			}
+			changed := *data
+			changed.Description = "rolled back"
+			if _, err := r.Data.Update(&changed, ctx); err != nil {
+				return err
+			}
+			if _, err := r.Data.AddTags(data.ID, []string{"rolled-back"}, 0, ctx); err != nil {
+				return err
+			}
+			if _, err := r.DHT22.Delete(readings[0], ctx); err != nil {
+				return err
+			}
+			if err := r.DHT22.CreateMany([]*models.DHT22Data{newDHT22(2), newDHT22(3)}, ctx); err != nil {
+				return err
+			}
+			return errUnitFailed
+		}, context.Background())
+		if !errors.Is(err, errUnitFailed) {
+			t.Fatalf("Do returned %v, want the error of fn", err)
+		}
+
+		assertDataCount(t, r.Data, 1)
+		assertSameData(t, readData(t, r.Data, data.ID), data)
+		if history, err := r.Data.ReadHistory(data.ID, context.Background()); err != nil || len(history) != 1 {
+			t.Errorf("ReadHistory returned %v entries, %v, want 1", len(history), err)
+		}
+		assertDHT22Count(t, r.DHT22, 1)
+		assertSameDHT22(t, readDHT22(t, r.DHT22, readings[0].ID), readings[0])
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		r := newUnitOfWork(t)
+		func() {
+			defer func() {
+				if p := recover(); p != errUnitFailed {
+					t.Errorf("Do recovered %v, want the panic of fn", p)
+				}
+			}()
+			r.Unit.Do(func(ctx context.Context) error {
+				if err := r.Data.Create(newData(1), ctx); err != nil {
+					return err
+				}
+				panic(errUnitFailed)
+			}, context.Background())
+		}()
+		assertDataCount(t, r.Data, 0)
+
+		// * The database is still usable after the panic
+		createData(t, r.Data, 1)
+	})
+
+	t.Run("FailedCallInsideUnit", func(t *testing.T) {
+		r := newUnitOfWork(t)
+		data := createData(t, r.Data, 1)[0]
+		reading := newDHT22(1)
+
+		err := r.Unit.Do(func(ctx context.Context) error {
+			stale := *data
+			stale.Version = 5
+			stale.Description = "stale"
+			if _, err := r.Data.Update(&stale, ctx); !errors.Is(err, models.ErrVersionMismatch) {
+				t.Errorf("Update with a stale version returned %v, want %v", err, models.ErrVersionMismatch)
+			}
+			// * The unit goes on without the change that was refused
+			return r.DHT22.Create(reading, ctx)
+		}, context.Background())
+		if err != nil {
+			t.Fatalf("Do failed: %v", err)
+		}
+		assertSameData(t, readData(t, r.Data, data.ID), data)
+		assertDHT22Count(t, r.DHT22, 1)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		r := newUnitOfWork(t)
+
+		err := r.Unit.Do(func(ctx context.Context) error {
+			if err := r.Data.Create(newData(1), ctx); err != nil {
+				return err
+			}
+			// * A unit inside a unit takes part in the outer one, it commits nothing on its own
+			if err := r.Unit.Do(func(ctx context.Context) error {
+				return r.DHT22.Create(newDHT22(1), ctx)
+			}, ctx); err != nil {
+				return err
+			}
+			return errUnitFailed
+		}, context.Background())
+		if !errors.Is(err, errUnitFailed) {
+			t.Fatalf("Do returned %v, want the error of fn", err)
+		}
+		assertDataCount(t, r.Data, 0)
+		assertDHT22Count(t, r.DHT22, 0)
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		r := newUnitOfWork(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := false
+		err := r.Unit.Do(func(ctx context.Context) error {
+			called = true
+			return nil
+		}, ctx)
+		if !errors.Is(err, context.Canceled) || called {
+			t.Errorf("Do with a cancelled context returned %v and called fn %v, want %v without calling fn", err, called, context.Canceled)
+		}
+	})
+}
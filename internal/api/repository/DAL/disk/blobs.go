@@ -0,0 +1,87 @@
+package disk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BlobStore keeps blobs as files in a directory, spread over subdirectories by the first two characters of their key.
+type BlobStore struct {
+	dir string
+}
+
+// NewBlobStore creates the directory if it does not exist.
+func NewBlobStore(dir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	return &BlobStore{dir: dir}, nil
+}
+
+// Create writes the content to a temporary file first and renames it, so a blob is never read half written.
+func (s *BlobStore) Create(key string, content io.Reader) (int64, string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return 0, "", err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(tmp, io.TeeReader(content, hash))
+	if err != nil {
+		return 0, "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		return 0, "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (s *BlobStore) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes a blob, a blob that does not exist is already deleted.
+func (s *BlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path of a blob, keys are generated by the service but are checked anyway so they can never leave the directory.
+func (s *BlobStore) path(key string) (string, error) {
+	if len(key) < 3 || strings.ContainsAny(key, `/\.`) {
+		return "", errors.New("invalid blob key: " + key)
+	}
+	return filepath.Join(s.dir, key[:2], key), nil
+}
@@ -0,0 +1,18 @@
+package disk_test
+
+import (
+	"goapi/internal/api/repository/DAL/contract"
+	"goapi/internal/api/repository/DAL/disk"
+	"goapi/internal/api/repository/models"
+	"testing"
+)
+
+func TestBlobStoreContract(t *testing.T) {
+	contract.TestBlobStore(t, func(t *testing.T) models.BlobStore {
+		store, err := disk.NewBlobStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"goapi/internal/api/repository/models"
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
+)
+
+// AttachmentRepository is the in-memory metadata of the files attached to records.
+type AttachmentRepository struct {
+	db *Database
+}
+
+func NewAttachmentRepository(db *Database) models.AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// Create stores the metadata of an attachment whose content is already in the blob store.
+func (r *AttachmentRepository) Create(attachment *models.Attachment, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	attachment.CreatedAt = now()
+	attachment.CreatedBy = models.ActorFromContext(ctx)
+	r.db.lastAttachmentID++
+	attachment.ID = r.db.lastAttachmentID
+	c := *attachment
+	r.db.attachments[c.ID] = &c
+	return nil
+}
+
+// ReadOne returns an attachment of the record, nil if the record has no attachment with the id.
+func (r *AttachmentRepository) ReadOne(dataID int, id int, ctx context.Context) (*models.Attachment, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	attachment, ok := r.db.attachments[id]
+	if !ok || attachment.DataID != dataID {
+		return nil, nil
+	}
+	c := *attachment
+	return &c, nil
+}
+
+func (r *AttachmentRepository) ReadAll(dataID int, ctx context.Context) ([]*models.Attachment, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	attachments := []*models.Attachment{}
+	for _, attachment := range r.db.attachments {
+		if attachment.DataID == dataID {
+			c := *attachment
+			attachments = append(attachments, &c)
+		}
+	}
+	slices.SortFunc(attachments, func(a, b *models.Attachment) int { return cmp.Compare(a.ID, b.ID) })
+	return attachments, nil
+}
+
+// Delete removes the metadata of an attachment and returns it, so its blob can be deleted. nil if there is none.
+func (r *AttachmentRepository) Delete(dataID int, id int, ctx context.Context) (*models.Attachment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.unlock(ctx)
+
+	attachment, ok := r.db.attachments[id]
+	if !ok || attachment.DataID != dataID {
+		return nil, nil
+	}
+	delete(r.db.attachments, id)
+	return attachment, nil
+}
+
+// DeleteOrphans removes the metadata of the attachments of purged records and returns them, so their blobs can be deleted.
+func (r *AttachmentRepository) DeleteOrphans(ctx context.Context) ([]*models.Attachment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.unlock(ctx)
+
+	var orphans []*models.Attachment
+	for id, attachment := range r.db.attachments {
+		if _, ok := r.db.data[attachment.DataID]; !ok {
+			delete(r.db.attachments, id)
+			orphans = append(orphans, attachment)
+		}
+	}
+	return orphans, nil
+}
+
+// BlobStore keeps blobs in memory, for tests and demos that must not write to disk.
+type BlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewBlobStore() *BlobStore {
+	return &BlobStore{blobs: map[string][]byte{}}
+}
+
+func (s *BlobStore) Create(key string, content io.Reader) (int64, string, error) {
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return 0, "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = b
+	sum := sha256.Sum256(b)
+	return int64(len(b)), hex.EncodeToString(sum[:]), nil
+}
+
+// Open returns a reader of the blob, like os.Open it fails with fs.ErrNotExist for unknown keys.
+func (s *BlobStore) Open(key string) (io.ReadSeekCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.blobs[key]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: key, Err: fs.ErrNotExist}
+	}
+	return nopCloser{bytes.NewReader(b)}, nil
+}
+
+// Delete removes a blob, a blob that does not exist is already deleted.
+func (s *BlobStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package memory_test
+
+import (
+	"goapi/internal/api/repository/DAL/contract"
+	"goapi/internal/api/repository/DAL/memory"
+	"goapi/internal/api/repository/models"
+	"testing"
+)
+
+func TestDataRepositoryContract(t *testing.T) {
+	contract.TestDataRepository(t, func(t *testing.T) models.DataRepository {
+		return memory.NewDataRepository(memory.NewDatabase())
+	})
+}
+
+func TestDHT22RepositoryContract(t *testing.T) {
+	contract.TestDHT22Repository(t, func(t *testing.T) models.DHT22Repository {
+		return memory.NewDHT22Repository(memory.NewDatabase())
+	})
+}
+
+func TestUnitOfWorkContract(t *testing.T) {
+	contract.TestUnitOfWork(t, func(t *testing.T) contract.UnitOfWorkRepositories {
+		db := memory.NewDatabase()
+		return contract.UnitOfWorkRepositories{
+			Unit:  memory.NewUnitOfWork(db),
+			Data:  memory.NewDataRepository(db),
+			DHT22: memory.NewDHT22Repository(db),
+		}
+	})
+}
+
+func TestDHT22ArchiveRepositoryContract(t *testing.T) {
+	contract.TestDHT22ArchiveRepository(t, func(t *testing.T) contract.DHT22ArchiveRepositories {
+		db := memory.NewDatabase()
+		return contract.DHT22ArchiveRepositories{Archive: memory.NewDHT22ArchiveRepository(db), DHT22: memory.NewDHT22Repository(db)}
+	})
+}
+
+func TestSensorRepositoryContract(t *testing.T) {
+	contract.TestSensorRepository(t, func(t *testing.T) models.SensorRepository {
+		return memory.NewSensorRepository(memory.NewDatabase())
+	})
+}
+
+func TestDataTrashContract(t *testing.T) {
+	contract.TestDataTrash(t, func(t *testing.T) contract.DataTrashRepositories {
+		db := memory.NewDatabase()
+		return contract.DataTrashRepositories{Data: memory.NewDataRepository(db), Attachments: memory.NewAttachmentRepository(db)}
+	})
+}
+
+func TestBlobStoreContract(t *testing.T) {
+	contract.TestBlobStore(t, func(t *testing.T) models.BlobStore {
+		return memory.NewBlobStore()
+	})
+}
@@ -0,0 +1,412 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"slices"
+	"time"
+)
+
+// DataRepository is the in-memory models.DataRepository, with the semantics of the SQLite one.
+type DataRepository struct {
+	db *Database
+}
+
+func NewDataRepository(db *Database) models.DataRepository {
+	return &DataRepository{db: db}
+}
+
+// copyData returns a copy the caller can change, attributes are copied through JSON like they are stored in SQLite.
+func copyData(d *models.Data) *models.Data {
+	c := *d
+	c.Attributes = copyAttributes(d.Attributes)
+	c.Tags = slices.Clone(d.Tags)
+	return &c
+}
+
+func copyAttributes(a models.Attributes) models.Attributes {
+	value, err := a.Value()
+	if err != nil {
+		return nil
+	}
+	var c models.Attributes
+	if err := c.Scan(value); err != nil {
+		return nil
+	}
+	return c
+}
+
+// active returns the records that are not in the trash, ordered by id. The caller holds the lock.
+func (r *DataRepository) active() []*dataRow {
+	rows := make([]*dataRow, 0, len(r.db.data))
+	for _, row := range r.db.data {
+		if row.deletedAt == "" {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b *dataRow) int { return cmp.Compare(a.ID, b.ID) })
+	return rows
+}
+
+// serialNumberUsed tells if another record that is not in the trash has the serial number. The caller holds the lock.
+func (r *DataRepository) serialNumberUsed(serialNumber string, id int) bool {
+	if serialNumber == "" {
+		return false
+	}
+	for _, row := range r.db.data {
+		if row.deletedAt == "" && row.ID != id && row.SerialNumber == serialNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// recordHistory stores a version of the record, the caller holds the write lock.
+func (r *DataRepository) recordHistory(operation string, data *models.Data, changedFields []string, ctx context.Context) {
+	r.db.lastHistoryID++
+	version := &models.DataVersion{
+		HistoryID:     r.db.lastHistoryID,
+		Operation:     operation,
+		ChangedAt:     now(),
+		ChangedBy:     models.ActorFromContext(ctx),
+		ChangedFields: slices.Clone(changedFields),
+	}
+	if version.ChangedFields == nil {
+		version.ChangedFields = []string{}
+	}
+	// * Like the history table, a version has the fields of the record but not its version, timestamps and tags
+	version.Data = models.Data{
+		ID:           data.ID,
+		DeviceID:     data.DeviceID,
+		DeviceName:   data.DeviceName,
+		Price:        data.Price,
+		Currency:     data.Currency,
+		SerialNumber: data.SerialNumber,
+		Type:         data.Type,
+		DateTime:     data.DateTime,
+		Description:  data.Description,
+		Attributes:   copyAttributes(data.Attributes),
+	}
+	r.db.history = append(r.db.history, version)
+}
+
+func (r *DataRepository) Create(data *models.Data, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	if r.serialNumberUsed(data.SerialNumber, 0) {
+		return models.ErrDuplicateSerialNumber
+	}
+
+	// * The timestamps are the server's, whatever the client sent, tags are added with AddTags
+	data.Tags = nil
+	data.CreatedAt = now()
+	data.UpdatedAt = data.CreatedAt
+	r.db.lastDataID++
+	data.ID = r.db.lastDataID
+	data.Version = 1
+
+	r.db.data[data.ID] = &dataRow{Data: *copyData(data)}
+	r.recordHistory(models.OperationCreate, data, models.ChangedDataFields(&models.Data{}, data), ctx)
+	r.db.changed("data")
+	return nil
+}
+
+func (r *DataRepository) ReadOne(id int, ctx context.Context) (*models.Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	row, ok := r.db.data[id]
+	if !ok || row.deletedAt != "" {
+		return nil, nil
+	}
+	return copyData(&row.Data), nil
+}
+
+// ReadBySerialNumber returns the record with the exact serial number, nil if there is none.
+func (r *DataRepository) ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	if serialNumber == "" {
+		return nil, nil
+	}
+	for _, row := range r.db.data {
+		if row.deletedAt == "" && row.SerialNumber == serialNumber {
+			return copyData(&row.Data), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *DataRepository) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var data []*models.Data
+	for _, row := range pageOf(r.active(), page, rowsPerPage) {
+		data = append(data, copyData(&row.Data))
+	}
+	return data, nil
+}
+
+// ReadAfter returns up to limit rows ordered by (date_time, id) that come after the cursor.
+// A nil cursor starts from the beginning, the returned cursor is nil on the last page.
+func (r *DataRepository) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	rows := r.active()
+	slices.SortFunc(rows, func(a, b *dataRow) int {
+		return cmp.Or(cmp.Compare(a.DateTime, b.DateTime), cmp.Compare(a.ID, b.ID))
+	})
+
+	var data []*models.Data
+	var next *models.Cursor
+	for _, row := range rows {
+		if cursor != nil && cmp.Or(cmp.Compare(row.DateTime, cursor.DateTime), cmp.Compare(row.ID, cursor.ID)) <= 0 {
+			continue
+		}
+		if len(data) == limit {
+			last := data[limit-1]
+			next = &models.Cursor{DateTime: last.DateTime, ID: last.ID}
+			break
+		}
+		data = append(data, copyData(&row.Data))
+	}
+	return data, next, nil
+}
+
+func (r *DataRepository) Count(ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+	return len(r.active()), nil
+}
+
+// Query returns one page of the rows matching the filter, in the requested order and with only the selected fields set.
+func (r *DataRepository) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	rows := r.matching(q)
+	slices.SortStableFunc(rows, func(a, b *dataRow) int {
+		return q.Compare(dataValues(&a.Data), dataValues(&b.Data), "id")
+	})
+
+	var data []*models.Data
+	for _, row := range pageOf(rows, page, rowsPerPage) {
+		data = append(data, selectDataFields(&row.Data, q))
+	}
+	return data, nil
+}
+
+// CountQuery counts the rows matching the filter of the query.
+func (r *DataRepository) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+	return len(r.matching(q)), nil
+}
+
+// matching returns the records that are not in the trash and match the filter and tags of the query. The caller holds the lock.
+func (r *DataRepository) matching(q *query.Query) []*dataRow {
+	var rows []*dataRow
+	for _, row := range r.active() {
+		if q.Matches(dataValues(&row.Data)) && hasTags(&row.Data, q) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// dataValues are the queryable fields of a record, as query.Values.
+func dataValues(d *models.Data) query.Values {
+	return func(field string) any {
+		switch field {
+		case "id":
+			return float64(d.ID)
+		case "device_id":
+			return d.DeviceID
+		case "device_name":
+			return d.DeviceName
+		case "price":
+			return float64(d.Price)
+		case "currency":
+			return d.Currency
+		case "serial_number":
+			return d.SerialNumber
+		case "type":
+			return d.Type
+		case "date_time":
+			return d.DateTime
+		case "description":
+			return d.Description
+		case "attributes":
+			value, _ := d.Attributes.Value()
+			s, _ := value.(string)
+			return s
+		case "version":
+			return float64(d.Version)
+		case "created_at":
+			return d.CreatedAt
+		case "updated_at":
+			return d.UpdatedAt
+		}
+		return nil
+	}
+}
+
+// selectDataFields copies only the selected fields of a record, tags are only set when no fields are selected.
+func selectDataFields(d *models.Data, q *query.Query) *models.Data {
+	if q == nil || len(q.Fields) == 0 {
+		return copyData(d)
+	}
+	var s models.Data
+	for _, field := range q.Fields {
+		switch field {
+		case "id":
+			s.ID = d.ID
+		case "device_id":
+			s.DeviceID = d.DeviceID
+		case "device_name":
+			s.DeviceName = d.DeviceName
+		case "price":
+			s.Price = d.Price
+		case "currency":
+			s.Currency = d.Currency
+		case "serial_number":
+			s.SerialNumber = d.SerialNumber
+		case "type":
+			s.Type = d.Type
+		case "date_time":
+			s.DateTime = d.DateTime
+		case "description":
+			s.Description = d.Description
+		case "attributes":
+			s.Attributes = copyAttributes(d.Attributes)
+		case "version":
+			s.Version = d.Version
+		case "created_at":
+			s.CreatedAt = d.CreatedAt
+		case "updated_at":
+			s.UpdatedAt = d.UpdatedAt
+		}
+	}
+	return &s
+}
+
+func (r *DataRepository) Update(data *models.Data, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.data[data.ID]
+	if !ok || row.deletedAt != "" {
+		return 0, nil
+	}
+	before := copyData(&row.Data)
+	// * A version of 0 updates whatever version is stored, otherwise it must be the stored one
+	if data.Version != 0 && data.Version != before.Version {
+		return 0, models.ErrVersionMismatch
+	}
+	if r.serialNumberUsed(data.SerialNumber, data.ID) {
+		return 0, models.ErrDuplicateSerialNumber
+	}
+
+	data.UpdatedAt = now()
+	data.Version = before.Version + 1
+	data.CreatedAt = before.CreatedAt
+	data.Tags = before.Tags
+	row.Data = *copyData(data)
+
+	// * Only real changes are recorded in the history
+	if changed := models.ChangedDataFields(before, data); len(changed) > 0 {
+		r.recordHistory(models.OperationUpdate, data, changed, ctx)
+	}
+	r.db.changed("data")
+	return 1, nil
+}
+
+func (r *DataRepository) Delete(data *models.Data, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.data[data.ID]
+	if !ok || row.deletedAt != "" {
+		return 0, nil
+	}
+	if data.Version != 0 && data.Version != row.Version {
+		return 0, models.ErrVersionMismatch
+	}
+
+	// * The record is only marked as deleted, it stays in the trash until it is restored or purged
+	row.deletedAt = now()
+	row.deletedBy = models.ActorFromContext(ctx)
+	row.UpdatedAt = row.deletedAt
+	r.recordHistory(models.OperationDelete, &row.Data, []string{}, ctx)
+	r.db.changed("data")
+	return 1, nil
+}
+
+// ReadHistory returns every recorded version of a record, oldest first.
+func (r *DataRepository) ReadHistory(id int, ctx context.Context) ([]*models.DataVersion, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var versions []*models.DataVersion
+	for _, v := range r.db.history {
+		if v.Data.ID == id {
+			c := *v
+			c.ChangedFields = slices.Clone(v.ChangedFields)
+			c.Data = *copyData(&v.Data)
+			versions = append(versions, &c)
+		}
+	}
+	return versions, nil
+}
+
+// ReadAsOf returns the record as it was at the given time, nil if it did not exist or was deleted at that time.
+func (r *DataRepository) ReadAsOf(id int, asOf time.Time, ctx context.Context) (*models.Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	at := asOf.UTC().Format(models.HistoryTimeFormat)
+	var last *models.DataVersion
+	for _, v := range r.db.history {
+		if v.Data.ID == id && v.ChangedAt <= at {
+			last = v
+		}
+	}
+	if last == nil || last.Operation == models.OperationDelete {
+		return nil, nil
+	}
+	return copyData(&last.Data), nil
+}
+
+func (r *DataRepository) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return r.db.changeMarker("data", ctx)
+}
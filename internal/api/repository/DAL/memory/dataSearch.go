@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/models"
+	"slices"
+)
+
+// Search returns one page of the records matching the text, best match first, with a highlighted snippet.
+// Every word of the text must match a word of device_name, type or description, the last word also matches as a prefix.
+// Words are compared case-insensitively like the unicode61 tokenizer of FTS5, the rank is minus the number of matching words.
+func (r *DataRepository) Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	terms := DAL.SearchTokens(text)
+	if len(terms) == 0 {
+		return []*models.DataSearchResult{}, 0, nil
+	}
+
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer r.db.runlock(ctx)
+
+	var results []*models.DataSearchResult
+	for _, row := range r.active() {
+		rank, snippet, ok := DAL.MatchSearch(&row.Data, terms)
+		if ok {
+			results = append(results, &models.DataSearchResult{Data: *copyData(&row.Data), Rank: rank, Snippet: snippet})
+		}
+	}
+	slices.SortStableFunc(results, func(a, b *models.DataSearchResult) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), cmp.Compare(a.ID, b.ID))
+	})
+	return pageOf(results, page, rowsPerPage), len(results), nil
+}
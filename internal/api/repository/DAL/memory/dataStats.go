@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/models"
+	"math"
+	"slices"
+)
+
+// Stats counts the records that are not in the trash by type, device and month of their DateTime.
+// Prices are summed and averaged per currency, in the same order as the SQLite repository returns them.
+func (r *DataRepository) Stats(ctx context.Context) (*models.DataStats, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	rows := r.active()
+	stats := &models.DataStats{Total: len(rows), ByType: []*models.TypeStats{}, ByDevice: []*models.DeviceStats{}, ByMonth: []*models.MonthCount{}}
+
+	types := map[string]*models.TypeStats{}
+	devices := map[string]*models.DeviceStats{}
+	latest := map[string]*dataRow{}
+	months := map[string]*models.MonthCount{}
+	for _, row := range rows {
+		t, ok := types[row.Type]
+		if !ok {
+			t = &models.TypeStats{Type: row.Type}
+			types[row.Type] = t
+			stats.ByType = append(stats.ByType, t)
+		}
+		t.Count++
+		t.Prices = addPrice(t.Prices, row)
+
+		d, ok := devices[row.DeviceID]
+		if !ok {
+			d = &models.DeviceStats{DeviceID: row.DeviceID}
+			devices[row.DeviceID] = d
+			stats.ByDevice = append(stats.ByDevice, d)
+		}
+		d.Count++
+		d.Prices = addPrice(d.Prices, row)
+		// * A device can be renamed, the name is the one of its latest record
+		if l := latest[row.DeviceID]; l == nil || cmp.Or(cmp.Compare(row.DateTime, l.DateTime), cmp.Compare(row.ID, l.ID)) > 0 {
+			latest[row.DeviceID] = row
+			d.DeviceName = row.DeviceName
+		}
+
+		month := row.DateTime[:min(7, len(row.DateTime))]
+		m, ok := months[month]
+		if !ok {
+			m = &models.MonthCount{Month: month}
+			months[month] = m
+			stats.ByMonth = append(stats.ByMonth, m)
+		}
+		m.Count++
+	}
+
+	for _, t := range stats.ByType {
+		finishPrices(t.Prices)
+	}
+	for _, d := range stats.ByDevice {
+		finishPrices(d.Prices)
+	}
+	slices.SortFunc(stats.ByType, func(a, b *models.TypeStats) int { return cmp.Compare(a.Type, b.Type) })
+	slices.SortFunc(stats.ByDevice, func(a, b *models.DeviceStats) int { return cmp.Compare(a.DeviceID, b.DeviceID) })
+	slices.SortFunc(stats.ByMonth, func(a, b *models.MonthCount) int { return cmp.Compare(a.Month, b.Month) })
+	return stats, nil
+}
+
+// addPrice adds the price of a record to the stats of its currency.
+func addPrice(prices []*models.PriceStats, row *dataRow) []*models.PriceStats {
+	i := slices.IndexFunc(prices, func(p *models.PriceStats) bool { return p.Currency == row.Currency })
+	if i < 0 {
+		prices = append(prices, &models.PriceStats{Currency: row.Currency})
+		i = len(prices) - 1
+	}
+	prices[i].Count++
+	prices[i].Total += row.Price
+	return prices
+}
+
+// finishPrices sorts the prices by currency and rounds their averages half away from zero, like SQLite's ROUND.
+func finishPrices(prices []*models.PriceStats) {
+	slices.SortFunc(prices, func(a, b *models.PriceStats) int { return cmp.Compare(a.Currency, b.Currency) })
+	for _, p := range prices {
+		p.Average = int64(math.Round(float64(p.Total) / float64(p.Count)))
+	}
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"slices"
+)
+
+// hasTags tells if a record has all of the tags of the query, or any of them.
+func hasTags(d *models.Data, q *query.Query) bool {
+	if q == nil || len(q.Tags) == 0 {
+		return true
+	}
+	if q.AnyTag {
+		return slices.ContainsFunc(q.Tags, func(tag string) bool { return slices.Contains(d.Tags, tag) })
+	}
+	for _, tag := range q.Tags {
+		if !slices.Contains(d.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddTags tags a record, tags it already has are kept. 0 if the record does not exist.
+func (r *DataRepository) AddTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return r.changeTags(id, version, ctx, func(current []string) []string {
+		for _, tag := range tags {
+			if !slices.Contains(current, tag) {
+				current = append(current, tag)
+			}
+		}
+		return current
+	})
+}
+
+// RemoveTags removes tags from a record, tags it does not have are ignored. 0 if the record does not exist.
+func (r *DataRepository) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return r.changeTags(id, version, ctx, func(current []string) []string {
+		return slices.DeleteFunc(current, func(tag string) bool { return slices.Contains(tags, tag) })
+	})
+}
+
+// changeTags changes the tags of a record. The tags are part of the record,
+// so a change makes a new version with a history entry, a change that adds or removes nothing does not.
+func (r *DataRepository) changeTags(id int, version int, ctx context.Context, change func(current []string) []string) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.data[id]
+	if !ok || row.deletedAt != "" {
+		return 0, nil
+	}
+	if version != 0 && version != row.Version {
+		return 0, models.ErrVersionMismatch
+	}
+
+	before := copyData(&row.Data)
+	tags := change(slices.Clone(row.Tags))
+	// * Tags are kept in alphabetical order, like they are read from SQLite
+	slices.Sort(tags)
+	if slices.Equal(tags, row.Tags) {
+		return 1, nil
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+
+	row.Tags = tags
+	row.Version++
+	row.UpdatedAt = now()
+	r.recordHistory(models.OperationUpdate, before, []string{"tags"}, ctx)
+	r.db.changed("data")
+	return 1, nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/models"
+	"slices"
+	"time"
+)
+
+// Restore takes a record out of the trash, its serial number must not have been reused in the meantime.
+func (r *DataRepository) Restore(id int, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.data[id]
+	if !ok || row.deletedAt == "" {
+		return 0, nil
+	}
+	if r.serialNumberUsed(row.SerialNumber, id) {
+		return 0, models.ErrDuplicateSerialNumber
+	}
+
+	row.deletedAt, row.deletedBy = "", ""
+	row.UpdatedAt = now()
+	r.recordHistory(models.OperationRestore, &row.Data, []string{}, ctx)
+	r.db.changed("data")
+	return 1, nil
+}
+
+// ReadTrash returns one page of the deleted records, most recently deleted first.
+func (r *DataRepository) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedData, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var rows []*dataRow
+	for _, row := range r.db.data {
+		if row.deletedAt != "" {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b *dataRow) int {
+		return cmp.Or(cmp.Compare(b.deletedAt, a.deletedAt), cmp.Compare(b.ID, a.ID))
+	})
+
+	var trash []*models.TrashedData
+	for _, row := range pageOf(rows, page, rowsPerPage) {
+		// * The trash is read without tags, like from SQLite
+		d := copyData(&row.Data)
+		d.Tags = nil
+		trash = append(trash, &models.TrashedData{Data: *d, DeletedAt: row.deletedAt, DeletedBy: row.deletedBy})
+	}
+	return trash, nil
+}
+
+func (r *DataRepository) CountTrash(ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+
+	count := 0
+	for _, row := range r.db.data {
+		if row.deletedAt != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Purge permanently removes the records deleted before the given time, their history is kept.
+func (r *DataRepository) Purge(before time.Time, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	at := before.UTC().Format(models.HistoryTimeFormat)
+	var purged int64
+	for id, row := range r.db.data {
+		if row.deletedAt != "" && row.deletedAt < at {
+			delete(r.db.data, id)
+			purged++
+		}
+	}
+	if purged > 0 {
+		r.db.changed("data")
+	}
+	return purged, nil
+}
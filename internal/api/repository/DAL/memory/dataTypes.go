@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"goapi/internal/api/repository/models"
+	"slices"
+)
+
+// DataTypeRepository is the in-memory registry of record types.
+type DataTypeRepository struct {
+	db *Database
+}
+
+func NewDataTypeRepository(db *Database) models.DataTypeRepository {
+	return &DataTypeRepository{db: db}
+}
+
+func copyDataType(t *models.DataType) *models.DataType {
+	c := *t
+	c.Schema = json.RawMessage(slices.Clone(t.Schema))
+	return &c
+}
+
+func (r *DataTypeRepository) Create(dataType *models.DataType, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	if _, ok := r.db.dataTypes[dataType.Name]; ok {
+		return models.ErrDuplicateDataType
+	}
+	r.db.dataTypes[dataType.Name] = copyDataType(dataType)
+	return nil
+}
+
+// ReadOne returns the type with the name, nil if it is not registered.
+func (r *DataTypeRepository) ReadOne(name string, ctx context.Context) (*models.DataType, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	dataType, ok := r.db.dataTypes[name]
+	if !ok {
+		return nil, nil
+	}
+	return copyDataType(dataType), nil
+}
+
+func (r *DataTypeRepository) ReadAll(ctx context.Context) ([]*models.DataType, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var dataTypes []*models.DataType
+	for _, dataType := range r.db.dataTypes {
+		dataTypes = append(dataTypes, copyDataType(dataType))
+	}
+	slices.SortFunc(dataTypes, func(a, b *models.DataType) int { return cmp.Compare(a.Name, b.Name) })
+	return dataTypes, nil
+}
+
+func (r *DataTypeRepository) Update(dataType *models.DataType, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	if _, ok := r.db.dataTypes[dataType.Name]; !ok {
+		return 0, nil
+	}
+	r.db.dataTypes[dataType.Name] = copyDataType(dataType)
+	return 1, nil
+}
+
+// Delete removes a type, types that records still use, also in the trash, can not be deleted.
+func (r *DataTypeRepository) Delete(name string, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	for _, row := range r.db.data {
+		if row.Type == name {
+			return 0, models.ErrDataTypeInUse
+		}
+	}
+	if _, ok := r.db.dataTypes[name]; !ok {
+		return 0, nil
+	}
+	delete(r.db.dataTypes, name)
+	return 1, nil
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"goapi/internal/api/repository/models"
+	"sync"
+	"time"
+)
+
+// Database keeps the records, readings and everything around them in memory, nothing is written to disk.
+// The repositories of one Database share it like the SQLite repositories share a database file,
+// every repository call holds the lock for its whole duration, so each call is atomic,
+// and a unit of work holds it for all of its calls.
+type Database struct {
+	mu sync.RWMutex
+
+	data          map[int]*dataRow
+	lastDataID    int
+	history       []*models.DataVersion
+	lastHistoryID int
+
+	dht22         map[int]*dht22Row
+	lastDHT22ID   int
+	archives      []*models.DHT22Archive
+	lastArchiveID int
+
+	sensors      map[int]*sensorRow
+	lastSensorID int
+
+	dataTypes        map[string]*models.DataType
+	rates            map[string]*models.ExchangeRate
+	attachments      map[int]*models.Attachment
+	lastAttachmentID int
+
+	markers map[string]*models.ChangeMarker
+}
+
+// dataRow is a record with its trash columns.
+type dataRow struct {
+	models.Data
+	deletedAt string
+	deletedBy string
+}
+
+// dht22Row is a reading with its trash columns.
+type dht22Row struct {
+	models.DHT22Data
+	deletedAt string
+	deletedBy string
+}
+
+// sensorRow is a reading of a registered sensor type with its trash columns.
+type sensorRow struct {
+	models.SensorReading
+	deletedAt string
+	deletedBy string
+}
+
+// NewDatabase returns an empty database, the base currency has its rate of 1 like in SQLite.
+func NewDatabase() *Database {
+	db := &Database{
+		data:        map[int]*dataRow{},
+		dht22:       map[int]*dht22Row{},
+		sensors:     map[int]*sensorRow{},
+		dataTypes:   map[string]*models.DataType{},
+		rates:       map[string]*models.ExchangeRate{},
+		attachments: map[int]*models.Attachment{},
+		markers:     map[string]*models.ChangeMarker{},
+	}
+	db.rates[models.BaseCurrency] = &models.ExchangeRate{Currency: models.BaseCurrency, Rate: 1, UpdatedAt: time.Now().UTC().Format(time.RFC3339)}
+	db.changed("data")
+	db.changed("dht22")
+	return db
+}
+
+// lock takes the write lock for a repository call, a call with a cancelled context fails
+// without taking it, like the SQLite repositories fail before they start a statement.
+// In a unit of work the unit already holds the lock.
+func (db *Database) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !db.inUnit(ctx) {
+		db.mu.Lock()
+	}
+	return nil
+}
+
+// unlock releases the lock taken by lock.
+func (db *Database) unlock(ctx context.Context) {
+	if !db.inUnit(ctx) {
+		db.mu.Unlock()
+	}
+}
+
+// rlock takes the read lock for a repository call, see lock.
+func (db *Database) rlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !db.inUnit(ctx) {
+		db.mu.RLock()
+	}
+	return nil
+}
+
+// runlock releases the lock taken by rlock.
+func (db *Database) runlock(ctx context.Context) {
+	if !db.inUnit(ctx) {
+		db.mu.RUnlock()
+	}
+}
+
+// changed bumps the change marker of a collection, the caller holds the write lock.
+func (db *Database) changed(collection string) {
+	marker, ok := db.markers[collection]
+	if !ok {
+		marker = &models.ChangeMarker{}
+		db.markers[collection] = marker
+	}
+	marker.Version++
+	marker.ChangedAt = now()
+}
+
+// changeMarker returns a copy of the change marker of a collection.
+func (db *Database) changeMarker(collection string, ctx context.Context) (*models.ChangeMarker, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.runlock(ctx)
+	marker := *db.markers[collection]
+	return &marker, nil
+}
+
+// now is the time of a change in the format the SQLite repositories store.
+func now() string {
+	return time.Now().UTC().Format(models.HistoryTimeFormat)
+}
+
+// pageOf returns one page of rows, like LIMIT and OFFSET.
+func pageOf[T any](rows []T, page int, rowsPerPage int) []T {
+	offset := rowsPerPage * (page - 1)
+	if offset < 0 || offset >= len(rows) || rowsPerPage <= 0 {
+		return nil
+	}
+	return rows[offset:min(offset+rowsPerPage, len(rows))]
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/models"
+	"slices"
+	"time"
+)
+
+// DHT22ArchiveRepository is the in-memory models.DHT22ArchiveRepository, the readings are moved out of the DHT22Repository of the same Database.
+type DHT22ArchiveRepository struct {
+	db *Database
+}
+
+func NewDHT22ArchiveRepository(db *Database) models.DHT22ArchiveRepository {
+	return &DHT22ArchiveRepository{db: db}
+}
+
+func (r *DHT22ArchiveRepository) ReadLive(after *models.Cursor, before time.Time, limit int, ctx context.Context) ([]*models.DHT22ArchiveReading, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var readings []*models.DHT22ArchiveReading
+	for _, row := range r.db.dht22 {
+		position := models.Cursor{DateTime: row.DateTime, ID: row.ID}
+		if row.deletedAt != "" || (after != nil && models.ComparePositions(position, *after) <= 0) ||
+			(!before.IsZero() && row.DateTime >= before.UTC().Format(time.RFC3339)) {
+			continue
+		}
+		readings = append(readings, &models.DHT22ArchiveReading{DHT22Data: row.DHT22Data, Position: position})
+	}
+	slices.SortFunc(readings, func(a, b *models.DHT22ArchiveReading) int { return models.ComparePositions(a.Position, b.Position) })
+	return readings[:min(limit, len(readings))], nil
+}
+
+func (r *DHT22ArchiveRepository) Archive(archives []*models.DHT22Archive, ids []int, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	// * Everything is checked first, so a failed call changes nothing
+	for _, id := range ids {
+		if row, ok := r.db.dht22[id]; !ok || row.deletedAt != "" {
+			return models.ErrArchiveChanged
+		}
+	}
+
+	createdAt := now()
+	for _, a := range archives {
+		r.db.lastArchiveID++
+		a.ID = r.db.lastArchiveID
+		a.CreatedAt = createdAt
+		stored := *a
+		r.db.archives = append(r.db.archives, &stored)
+	}
+	for _, id := range ids {
+		delete(r.db.dht22, id)
+	}
+	if len(ids) > 0 {
+		r.db.changed("dht22")
+	}
+	return nil
+}
+
+func (r *DHT22ArchiveRepository) ReadArchives(after *models.Cursor, ctx context.Context) ([]*models.DHT22Archive, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	return r.readArchives(func(a *models.DHT22Archive) bool {
+		return after == nil || models.ComparePositions(a.Last, *after) > 0
+	}), nil
+}
+
+func (r *DHT22ArchiveRepository) ReadArchivesOf(id int, ctx context.Context) ([]*models.DHT22Archive, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	return r.readArchives(func(a *models.DHT22Archive) bool { return a.MinID <= id && id <= a.MaxID }), nil
+}
+
+// readArchives returns copies of the manifest entries that match, ordered by their first reading. The lock must be held.
+func (r *DHT22ArchiveRepository) readArchives(match func(a *models.DHT22Archive) bool) []*models.DHT22Archive {
+	archives := []*models.DHT22Archive{}
+	for _, a := range r.db.archives {
+		if match(a) {
+			copied := *a
+			archives = append(archives, &copied)
+		}
+	}
+	slices.SortFunc(archives, func(a, b *models.DHT22Archive) int {
+		return cmp.Or(models.ComparePositions(a.First, b.First), cmp.Compare(a.ID, b.ID))
+	})
+	return archives
+}
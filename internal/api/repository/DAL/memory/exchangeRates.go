@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/models"
+	"slices"
+	"time"
+)
+
+// ExchangeRateRepository is the in-memory repository of the exchange rates used to convert prices.
+type ExchangeRateRepository struct {
+	db *Database
+}
+
+func NewExchangeRateRepository(db *Database) models.ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+func (r *ExchangeRateRepository) ReadAll(ctx context.Context) ([]*models.ExchangeRate, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var rates []*models.ExchangeRate
+	for _, rate := range r.db.rates {
+		c := *rate
+		rates = append(rates, &c)
+	}
+	slices.SortFunc(rates, func(a, b *models.ExchangeRate) int { return cmp.Compare(a.Currency, b.Currency) })
+	return rates, nil
+}
+
+// ReadOne returns the rate of a currency, nil if it has not been set.
+func (r *ExchangeRateRepository) ReadOne(currency string, ctx context.Context) (*models.ExchangeRate, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	rate, ok := r.db.rates[currency]
+	if !ok {
+		return nil, nil
+	}
+	c := *rate
+	return &c, nil
+}
+
+// Save creates or replaces the rate of a currency.
+func (r *ExchangeRateRepository) Save(rate *models.ExchangeRate, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	rate.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	c := *rate
+	r.db.rates[rate.Currency] = &c
+	// * Converted prices in GET /data depend on the rates, so a new rate changes the data collection
+	r.db.changed("data")
+	return nil
+}
@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/models"
+	"maps"
+	"slices"
+	"time"
+)
+
+// SensorRepository is the in-memory models.SensorRepository, with the semantics of the SQLite one.
+type SensorRepository struct {
+	db *Database
+}
+
+func NewSensorRepository(db *Database) models.SensorRepository {
+	return &SensorRepository{db: db}
+}
+
+// copySensorReading copies a reading with its values, the stored readings are never shared with callers.
+func copySensorReading(reading *models.SensorReading) *models.SensorReading {
+	copied := *reading
+	copied.Values = maps.Clone(reading.Values)
+	return &copied
+}
+
+// ofType returns the readings of a sensor type that are not in the trash, ordered by id. The caller holds the lock.
+func (r *SensorRepository) ofType(sensorType string) []*models.SensorReading {
+	var readings []*models.SensorReading
+	for _, row := range r.db.sensors {
+		if row.Type == sensorType && row.deletedAt == "" {
+			readings = append(readings, &row.SensorReading)
+		}
+	}
+	slices.SortFunc(readings, func(a, b *models.SensorReading) int { return cmp.Compare(a.ID, b.ID) })
+	return readings
+}
+
+// stored returns the row of a reading of a sensor type that is not in the trash, nil if there is none. The caller holds the lock.
+func (r *SensorRepository) stored(sensorType string, id int) *sensorRow {
+	row, ok := r.db.sensors[id]
+	if !ok || row.Type != sensorType || row.deletedAt != "" {
+		return nil
+	}
+	return row
+}
+
+func (r *SensorRepository) Create(reading *models.SensorReading, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	reading.CreatedAt = now()
+	reading.UpdatedAt = reading.CreatedAt
+	r.db.lastSensorID++
+	reading.ID = r.db.lastSensorID
+	reading.Version = 1
+	r.db.sensors[reading.ID] = &sensorRow{SensorReading: *copySensorReading(reading)}
+	return nil
+}
+
+func (r *SensorRepository) ReadOne(sensorType string, id int, ctx context.Context) (*models.SensorReading, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	row := r.stored(sensorType, id)
+	if row == nil {
+		return nil, nil
+	}
+	return copySensorReading(&row.SensorReading), nil
+}
+
+func (r *SensorRepository) ReadMany(sensorType string, page int, rowsPerPage int, ctx context.Context) ([]*models.SensorReading, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var readings []*models.SensorReading
+	for _, reading := range pageOf(r.ofType(sensorType), page, rowsPerPage) {
+		readings = append(readings, copySensorReading(reading))
+	}
+	return readings, nil
+}
+
+func (r *SensorRepository) Count(sensorType string, ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+	return len(r.ofType(sensorType)), nil
+}
+
+func (r *SensorRepository) Update(reading *models.SensorReading, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row := r.stored(reading.Type, reading.ID)
+	if row == nil {
+		return 0, nil
+	}
+	if reading.Version != 0 && reading.Version != row.Version {
+		return 0, models.ErrVersionMismatch
+	}
+	reading.Version = row.Version + 1
+	reading.CreatedAt = row.CreatedAt
+	reading.UpdatedAt = now()
+	r.db.sensors[reading.ID] = &sensorRow{SensorReading: *copySensorReading(reading)}
+	return 1, nil
+}
+
+func (r *SensorRepository) Delete(sensorType string, id int, version int, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row := r.stored(sensorType, id)
+	if row == nil {
+		return 0, nil
+	}
+	if version != 0 && version != row.Version {
+		return 0, models.ErrVersionMismatch
+	}
+
+	// * The reading is only marked as deleted, it stays in the trash until it is restored or purged
+	row.deletedAt = now()
+	row.deletedBy = models.ActorFromContext(ctx)
+	row.UpdatedAt = row.deletedAt
+	return 1, nil
+}
+
+// Restore takes a reading out of the trash.
+func (r *SensorRepository) Restore(sensorType string, id int, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.sensors[id]
+	if !ok || row.Type != sensorType || row.deletedAt == "" {
+		return 0, nil
+	}
+	row.deletedAt, row.deletedBy = "", ""
+	row.UpdatedAt = now()
+	return 1, nil
+}
+
+// ReadTrash returns one page of the deleted readings of every type, most recently deleted first.
+func (r *SensorRepository) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedSensorReading, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var rows []*sensorRow
+	for _, row := range r.db.sensors {
+		if row.deletedAt != "" {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b *sensorRow) int {
+		return cmp.Or(cmp.Compare(b.deletedAt, a.deletedAt), cmp.Compare(b.ID, a.ID))
+	})
+
+	var trash []*models.TrashedSensorReading
+	for _, row := range pageOf(rows, page, rowsPerPage) {
+		trash = append(trash, &models.TrashedSensorReading{SensorReading: *copySensorReading(&row.SensorReading), DeletedAt: row.deletedAt, DeletedBy: row.deletedBy})
+	}
+	return trash, nil
+}
+
+func (r *SensorRepository) CountTrash(ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+
+	count := 0
+	for _, row := range r.db.sensors {
+		if row.deletedAt != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Purge permanently removes the readings deleted before the given time.
+func (r *SensorRepository) Purge(before time.Time, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	at := before.UTC().Format(models.HistoryTimeFormat)
+	var purged int64
+	for id, row := range r.db.sensors {
+		if row.deletedAt != "" && row.deletedAt < at {
+			delete(r.db.sensors, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,387 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"slices"
+	"time"
+)
+
+// DHT22Repository is the in-memory models.DHT22Repository, with the semantics of the SQLite one.
+type DHT22Repository struct {
+	db *Database
+}
+
+func NewDHT22Repository(db *Database) models.DHT22Repository {
+	return &DHT22Repository{db: db}
+}
+
+// active returns the readings that are not in the trash, ordered by id. The caller holds the lock.
+func (r *DHT22Repository) active() []*dht22Row {
+	rows := make([]*dht22Row, 0, len(r.db.dht22))
+	for _, row := range r.db.dht22 {
+		if row.deletedAt == "" {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b *dht22Row) int { return cmp.Compare(a.ID, b.ID) })
+	return rows
+}
+
+// measuredAt is the time of a reading, the zero time sorts a date_time that does not parse last.
+func measuredAt(row *dht22Row) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, row.DateTime)
+	return t
+}
+
+func copyDHT22(row *dht22Row) *models.DHT22Data {
+	d := row.DHT22Data
+	return &d
+}
+
+func (r *DHT22Repository) Create(data *models.DHT22Data, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	data.CreatedAt = now()
+	data.UpdatedAt = data.CreatedAt
+	r.db.lastDHT22ID++
+	data.ID = r.db.lastDHT22ID
+	data.Version = 1
+	r.db.dht22[data.ID] = &dht22Row{DHT22Data: *data}
+	r.db.changed("dht22")
+	return nil
+}
+
+func (r *DHT22Repository) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	if err := r.db.lock(ctx); err != nil {
+		return err
+	}
+	defer r.db.unlock(ctx)
+
+	createdAt := now()
+	for _, d := range data {
+		d.CreatedAt = createdAt
+		d.UpdatedAt = createdAt
+		r.db.lastDHT22ID++
+		d.ID = r.db.lastDHT22ID
+		d.Version = 1
+		r.db.dht22[d.ID] = &dht22Row{DHT22Data: *d}
+	}
+	if len(data) > 0 {
+		r.db.changed("dht22")
+	}
+	return nil
+}
+
+func (r *DHT22Repository) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	row, ok := r.db.dht22[id]
+	if !ok || row.deletedAt != "" {
+		return nil, nil
+	}
+	return copyDHT22(row), nil
+}
+
+func (r *DHT22Repository) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var data []*models.DHT22Data
+	for _, row := range pageOf(r.active(), page, rowsPerPage) {
+		data = append(data, copyDHT22(row))
+	}
+	return data, nil
+}
+
+// ReadAfter returns up to limit rows ordered by (date_time, id) that come after the cursor.
+// A nil cursor starts from the beginning, the returned cursor is nil on the last page.
+func (r *DHT22Repository) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	rows := r.active()
+	slices.SortFunc(rows, func(a, b *dht22Row) int {
+		return cmp.Or(cmp.Compare(a.DateTime, b.DateTime), cmp.Compare(a.ID, b.ID))
+	})
+
+	var data []*models.DHT22Data
+	var next *models.Cursor
+	for _, row := range rows {
+		if cursor != nil && cmp.Or(cmp.Compare(row.DateTime, cursor.DateTime), cmp.Compare(row.ID, cursor.ID)) <= 0 {
+			continue
+		}
+		if len(data) == limit {
+			last := data[limit-1]
+			next = &models.Cursor{DateTime: last.DateTime, ID: last.ID}
+			break
+		}
+		data = append(data, copyDHT22(row))
+	}
+	return data, next, nil
+}
+
+func (r *DHT22Repository) Count(ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+	return len(r.active()), nil
+}
+
+// Query returns one page of the rows matching the filter, in the requested order and with only the selected fields set.
+func (r *DHT22Repository) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	rows := r.matching(q)
+	slices.SortStableFunc(rows, func(a, b *dht22Row) int {
+		return q.Compare(dht22Values(&a.DHT22Data), dht22Values(&b.DHT22Data), "id")
+	})
+
+	var data []*models.DHT22Data
+	for _, row := range pageOf(rows, page, rowsPerPage) {
+		data = append(data, selectDHT22Fields(&row.DHT22Data, q))
+	}
+	return data, nil
+}
+
+// CountQuery counts the rows matching the filter of the query.
+func (r *DHT22Repository) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+	return len(r.matching(q)), nil
+}
+
+// matching returns the readings that are not in the trash and match the filter. The caller holds the lock.
+func (r *DHT22Repository) matching(q *query.Query) []*dht22Row {
+	var rows []*dht22Row
+	for _, row := range r.active() {
+		if q.Matches(dht22Values(&row.DHT22Data)) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// dht22Values are the queryable fields of a reading, as query.Values.
+func dht22Values(d *models.DHT22Data) query.Values {
+	return func(field string) any {
+		switch field {
+		case "id":
+			return float64(d.ID)
+		case "device_name":
+			return d.DeviceName
+		case "temperature":
+			return d.Temperature
+		case "humidity":
+			return d.Humidity
+		case "date_time":
+			return d.DateTime
+		case "version":
+			return float64(d.Version)
+		case "created_at":
+			return d.CreatedAt
+		case "updated_at":
+			return d.UpdatedAt
+		}
+		return nil
+	}
+}
+
+// selectDHT22Fields copies only the selected fields of a reading.
+func selectDHT22Fields(d *models.DHT22Data, q *query.Query) *models.DHT22Data {
+	if q == nil || len(q.Fields) == 0 {
+		c := *d
+		return &c
+	}
+	var s models.DHT22Data
+	for _, field := range q.Fields {
+		switch field {
+		case "id":
+			s.ID = d.ID
+		case "device_name":
+			s.DeviceName = d.DeviceName
+		case "temperature":
+			s.Temperature = d.Temperature
+		case "humidity":
+			s.Humidity = d.Humidity
+		case "date_time":
+			s.DateTime = d.DateTime
+		case "version":
+			s.Version = d.Version
+		case "created_at":
+			s.CreatedAt = d.CreatedAt
+		case "updated_at":
+			s.UpdatedAt = d.UpdatedAt
+		}
+	}
+	return &s
+}
+
+// ReadLatest returns the newest readings of a single device, newest first.
+func (r *DHT22Repository) ReadLatest(deviceName string, limit int, ctx context.Context) ([]*models.DHT22Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var rows []*dht22Row
+	for _, row := range r.active() {
+		if row.DeviceName == deviceName {
+			rows = append(rows, row)
+		}
+	}
+	// * Newest first by the time the text stands for, readings can carry any offset and fraction of a second
+	slices.SortFunc(rows, func(a, b *dht22Row) int {
+		return cmp.Or(measuredAt(b).Compare(measuredAt(a)), cmp.Compare(b.ID, a.ID))
+	})
+
+	var data []*models.DHT22Data
+	for _, row := range rows[:min(limit, len(rows))] {
+		data = append(data, copyDHT22(row))
+	}
+	return data, nil
+}
+
+// Update replaces a reading, a version other than 0 must be the stored one. The new version is set on data.
+func (r *DHT22Repository) Update(data *models.DHT22Data, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.dht22[data.ID]
+	if !ok || row.deletedAt != "" {
+		return 0, nil
+	}
+	if data.Version != 0 && data.Version != row.Version {
+		return 0, models.ErrVersionMismatch
+	}
+
+	data.Version = row.Version + 1
+	data.CreatedAt = row.CreatedAt
+	data.UpdatedAt = now()
+	row.DHT22Data = *data
+	r.db.changed("dht22")
+	return 1, nil
+}
+
+func (r *DHT22Repository) Delete(data *models.DHT22Data, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.dht22[data.ID]
+	if !ok || row.deletedAt != "" {
+		return 0, nil
+	}
+	if data.Version != 0 && data.Version != row.Version {
+		return 0, models.ErrVersionMismatch
+	}
+
+	// * The reading is only marked as deleted, it stays in the trash until it is restored or purged
+	row.deletedAt = now()
+	row.deletedBy = models.ActorFromContext(ctx)
+	row.UpdatedAt = row.deletedAt
+	r.db.changed("dht22")
+	return 1, nil
+}
+
+// Restore takes a reading out of the trash.
+func (r *DHT22Repository) Restore(id int, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	row, ok := r.db.dht22[id]
+	if !ok || row.deletedAt == "" {
+		return 0, nil
+	}
+	row.deletedAt, row.deletedBy = "", ""
+	row.UpdatedAt = now()
+	r.db.changed("dht22")
+	return 1, nil
+}
+
+// ReadTrash returns one page of the deleted readings, most recently deleted first.
+func (r *DHT22Repository) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedDHT22Data, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.db.runlock(ctx)
+
+	var rows []*dht22Row
+	for _, row := range r.db.dht22 {
+		if row.deletedAt != "" {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b *dht22Row) int {
+		return cmp.Or(cmp.Compare(b.deletedAt, a.deletedAt), cmp.Compare(b.ID, a.ID))
+	})
+
+	var trash []*models.TrashedDHT22Data
+	for _, row := range pageOf(rows, page, rowsPerPage) {
+		trash = append(trash, &models.TrashedDHT22Data{DHT22Data: row.DHT22Data, DeletedAt: row.deletedAt, DeletedBy: row.deletedBy})
+	}
+	return trash, nil
+}
+
+func (r *DHT22Repository) CountTrash(ctx context.Context) (int, error) {
+	if err := r.db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.runlock(ctx)
+
+	count := 0
+	for _, row := range r.db.dht22 {
+		if row.deletedAt != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Purge permanently removes the readings deleted before the given time.
+func (r *DHT22Repository) Purge(before time.Time, ctx context.Context) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.db.unlock(ctx)
+
+	at := before.UTC().Format(models.HistoryTimeFormat)
+	var purged int64
+	for id, row := range r.db.dht22 {
+		if row.deletedAt != "" && row.deletedAt < at {
+			delete(r.db.dht22, id)
+			purged++
+		}
+	}
+	if purged > 0 {
+		r.db.changed("dht22")
+	}
+	return purged, nil
+}
+
+func (r *DHT22Repository) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return r.db.changeMarker("dht22", ctx)
+}
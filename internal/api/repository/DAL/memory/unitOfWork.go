@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"goapi/internal/api/repository/models"
+	"slices"
+)
+
+// unitKey is the context key of the unit of work of a database.
+type unitKey struct {
+	db *Database
+}
+
+// inUnit tells if the context belongs to a unit of work of the database, which holds the write lock.
+func (db *Database) inUnit(ctx context.Context) bool {
+	return ctx.Value(unitKey{db}) != nil
+}
+
+// UnitOfWork runs the repositories of one Database as one transaction, the Database is locked meanwhile.
+type UnitOfWork struct {
+	db *Database
+}
+
+func NewUnitOfWork(db *Database) models.UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do holds the write lock while fn runs and puts the database back the way it was when fn fails.
+// The attachment files are kept in the BlobStore, they are not part of the unit.
+func (u *UnitOfWork) Do(fn func(ctx context.Context) error, ctx context.Context) (err error) {
+	if u.db.inUnit(ctx) {
+		return fn(ctx)
+	}
+	if err := u.db.lock(ctx); err != nil {
+		return err
+	}
+	defer u.db.mu.Unlock()
+
+	saved := u.db.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			u.db.restore(saved)
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, unitKey{u.db}, u)); err != nil {
+		u.db.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// snapshot copies the rows of the database. The repositories replace the slices and maps
+// of a row instead of changing them, so copying the rows is enough. The caller holds the write lock.
+func (db *Database) snapshot() *Database {
+	saved := &Database{
+		data:             make(map[int]*dataRow, len(db.data)),
+		lastDataID:       db.lastDataID,
+		history:          slices.Clone(db.history),
+		lastHistoryID:    db.lastHistoryID,
+		dht22:            make(map[int]*dht22Row, len(db.dht22)),
+		lastDHT22ID:      db.lastDHT22ID,
+		archives:         slices.Clone(db.archives),
+		lastArchiveID:    db.lastArchiveID,
+		sensors:          make(map[int]*sensorRow, len(db.sensors)),
+		lastSensorID:     db.lastSensorID,
+		dataTypes:        make(map[string]*models.DataType, len(db.dataTypes)),
+		rates:            make(map[string]*models.ExchangeRate, len(db.rates)),
+		attachments:      make(map[int]*models.Attachment, len(db.attachments)),
+		lastAttachmentID: db.lastAttachmentID,
+		markers:          make(map[string]*models.ChangeMarker, len(db.markers)),
+	}
+	for id, row := range db.data {
+		copied := *row
+		saved.data[id] = &copied
+	}
+	for id, row := range db.dht22 {
+		copied := *row
+		saved.dht22[id] = &copied
+	}
+	copyValues(saved.sensors, db.sensors)
+	copyValues(saved.dataTypes, db.dataTypes)
+	copyValues(saved.rates, db.rates)
+	copyValues(saved.attachments, db.attachments)
+	copyValues(saved.markers, db.markers)
+	return saved
+}
+
+// restore puts back a snapshot, the caller holds the write lock.
+func (db *Database) restore(saved *Database) {
+	db.data, db.lastDataID = saved.data, saved.lastDataID
+	db.history, db.lastHistoryID = saved.history, saved.lastHistoryID
+	db.dht22, db.lastDHT22ID = saved.dht22, saved.lastDHT22ID
+	db.archives, db.lastArchiveID = saved.archives, saved.lastArchiveID
+	db.sensors, db.lastSensorID = saved.sensors, saved.lastSensorID
+	db.dataTypes, db.rates = saved.dataTypes, saved.rates
+	db.attachments, db.lastAttachmentID = saved.attachments, saved.lastAttachmentID
+	db.markers = saved.markers
+}
+
+// copyValues copies the values the pointers of src point to into dst.
+func copyValues[K comparable, V any](dst map[K]*V, src map[K]*V) {
+	for k, v := range src {
+		copied := *v
+		dst[k] = &copied
+	}
+}
@@ -0,0 +1,86 @@
+package DAL
+
+import (
+	"goapi/internal/api/repository/models"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SearchTokens splits text into lower case words, like the unicode61 tokenizer of FTS5.
+func SearchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+}
+
+// MatchSearch tells if every term is a word of device_name, type or description of the record, the last one as a prefix,
+// and highlights the terms in the best matching field. The rank is minus the number of matching words, lower is better.
+// It is the search of the backends without a full-text index.
+func MatchSearch(d *models.Data, terms []string) (float64, string, bool) {
+	matchesTerm := func(word string, i int) bool {
+		if i == len(terms)-1 {
+			return strings.HasPrefix(word, terms[i])
+		}
+		return word == terms[i]
+	}
+	matchesAny := func(word string) bool {
+		for i := range terms {
+			if matchesTerm(word, i) {
+				return true
+			}
+		}
+		return false
+	}
+
+	fields := []string{d.DeviceName, d.Type, d.Description}
+	var words []string
+	for _, field := range fields {
+		words = append(words, SearchTokens(field)...)
+	}
+	for i := range terms {
+		if !slices.ContainsFunc(words, func(word string) bool { return matchesTerm(word, i) }) {
+			return 0, "", false
+		}
+	}
+
+	hits, best, bestHits := 0, "", -1
+	for _, field := range fields {
+		fieldHits := 0
+		for _, word := range SearchTokens(field) {
+			if matchesAny(word) {
+				fieldHits++
+			}
+		}
+		hits += fieldHits
+		if fieldHits > bestHits {
+			best, bestHits = field, fieldHits
+		}
+	}
+	return -float64(hits), highlight(best, matchesAny), true
+}
+
+// highlight wraps the matching words of a text in <mark>.
+func highlight(text string, matches func(word string) bool) string {
+	var sb strings.Builder
+	word := []rune{}
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		if matches(strings.ToLower(string(word))) {
+			sb.WriteString("<mark>" + string(word) + "</mark>")
+		} else {
+			sb.WriteString(string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		sb.WriteRune(r)
+	}
+	flush()
+	return sb.String()
+}
@@ -0,0 +1,133 @@
+package DAL
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"sync/atomic"
+)
+
+// unitKey is the context key of the unit of work of a database.
+type unitKey struct {
+	sqlDB *sql.DB
+}
+
+// unit is the transaction of a unit of work, savepoints number the repository transactions nested in it.
+type unit struct {
+	tx         *sql.Tx
+	savepoints atomic.Int64
+}
+
+// SQLUnitOfWork runs the repositories of one database in one transaction.
+type SQLUnitOfWork struct {
+	sqlDB *sql.DB
+}
+
+func NewUnitOfWork(sqlDB SQLDatabase) models.UnitOfWork {
+	return &SQLUnitOfWork{sqlDB: sqlDB.Connection()}
+}
+
+// Do runs fn in a transaction, repository calls with the context fn gets take part in it.
+// A unit started inside another unit of the same database takes part in the outer one.
+func (u *SQLUnitOfWork) Do(fn func(ctx context.Context) error, ctx context.Context) (err error) {
+	if _, ok := ctx.Value(unitKey{u.sqlDB}).(*unit); ok {
+		return fn(ctx)
+	}
+
+	tx, err := u.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, unitKey{u.sqlDB}, &unit{tx: tx})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Querier runs statements on the database or in the transaction of a unit of work.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Conn returns the transaction of the unit of work of ctx, or sqlDB outside of one.
+func Conn(ctx context.Context, sqlDB *sql.DB) Querier {
+	if u, ok := ctx.Value(unitKey{sqlDB}).(*unit); ok {
+		return u.tx
+	}
+	return sqlDB
+}
+
+// Stmt returns the prepared statement for the transaction of the unit of work of ctx, or stmt outside of one.
+func Stmt(ctx context.Context, sqlDB *sql.DB, stmt *sql.Stmt) *sql.Stmt {
+	if u, ok := ctx.Value(unitKey{sqlDB}).(*unit); ok {
+		return u.tx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
+
+// Tx is the transaction of a repository call. In a unit of work it is a savepoint of the unit's
+// transaction, so the call is still all or nothing and the unit decides whether it is committed.
+type Tx struct {
+	*sql.Tx
+	savepoint string
+	done      bool
+}
+
+// BeginTx starts the transaction of a repository call.
+func BeginTx(ctx context.Context, sqlDB *sql.DB) (*Tx, error) {
+	u, ok := ctx.Value(unitKey{sqlDB}).(*unit)
+	if !ok {
+		tx, err := sqlDB.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &Tx{Tx: tx}, nil
+	}
+
+	savepoint := fmt.Sprintf("repository_%d", u.savepoints.Add(1))
+	if _, err := u.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: u.tx, savepoint: savepoint}, nil
+}
+
+// Commit commits the transaction, or releases the savepoint in a unit of work.
+func (tx *Tx) Commit() error {
+	if tx.savepoint == "" {
+		return tx.Tx.Commit()
+	}
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	_, err := tx.Tx.Exec("RELEASE SAVEPOINT " + tx.savepoint)
+	return err
+}
+
+// Rollback rolls the transaction back, or back to the savepoint in a unit of work.
+// Like sql.Tx it can be deferred, after Commit it does nothing.
+func (tx *Tx) Rollback() error {
+	if tx.savepoint == "" {
+		return tx.Tx.Rollback()
+	}
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	if _, err := tx.Tx.Exec("ROLLBACK TO SAVEPOINT " + tx.savepoint); err != nil {
+		return err
+	}
+	_, err := tx.Tx.Exec("RELEASE SAVEPOINT " + tx.savepoint)
+	return err
+}
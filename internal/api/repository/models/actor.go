@@ -0,0 +1,20 @@
+package models
+
+import "context"
+
+type actorKey struct{}
+
+// * WithActor returns a context that carries the name of the authenticated user making the change *
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// * ActorFromContext returns the authenticated user, or "unknown" when the context has none *
+func ActorFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+			return actor
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// * ErrArchiveChanged is returned when readings being archived were changed or deleted meanwhile, nothing is archived *
+var ErrArchiveChanged = errors.New("the readings changed while they were archived")
+
+// * DHT22Archive is an entry of the archive manifest, one compressed file of the readings of a device in a month *
+type DHT22Archive struct {
+	ID         int    `json:"id"`
+	DeviceName string `json:"device_name"`
+	// * Month of the readings, as YYYY-MM in UTC
+	Month string `json:"month"`
+	// * File is the path of the file relative to the archive directory, with forward slashes
+	File     string `json:"file"`
+	Readings int    `json:"readings"`
+	// * First and Last are the positions of the first and the last reading of the file
+	First Cursor `json:"first"`
+	Last  Cursor `json:"last"`
+	// * MinID and MaxID are the smallest and the largest id of the readings of the file
+	MinID     int    `json:"min_id"`
+	MaxID     int    `json:"max_id"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	CreatedAt string `json:"created_at"`
+}
+
+// * DHT22ArchiveReading is a reading with its position in the (date_time, id) order of cursor pagination *
+// * The position is how the database orders date_time, which is not always the text of DateTime *
+type DHT22ArchiveReading struct {
+	DHT22Data
+	Position Cursor `json:"position"`
+}
+
+type DHT22ArchiveRepository interface {
+	// * ReadLive returns up to limit readings that are not in the trash, ordered by position, after the cursor and
+	// * measured before the time. A nil cursor starts from the beginning, a zero time has no bound.
+	ReadLive(after *Cursor, before time.Time, limit int, ctx context.Context) ([]*DHT22ArchiveReading, error)
+	// * Archive records the files in the manifest and removes the readings with the ids, in one transaction.
+	// * It fails with ErrArchiveChanged when one of them is no longer a live reading.
+	Archive(archives []*DHT22Archive, ids []int, ctx context.Context) error
+	// * ReadArchives returns the files with readings after the cursor, ordered by their first reading
+	ReadArchives(after *Cursor, ctx context.Context) ([]*DHT22Archive, error)
+	// * ReadArchivesOf returns the files whose ids range over the id, ordered by their first reading
+	ReadArchivesOf(id int, ctx context.Context) ([]*DHT22Archive, error)
+}
+
+// * ComparePositions orders cursors like the keyset pagination does, by date_time as text and then by id *
+func ComparePositions(a, b Cursor) int {
+	return cmp.Or(strings.Compare(a.DateTime, b.DateTime), cmp.Compare(a.ID, b.ID))
+}
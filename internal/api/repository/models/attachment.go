@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// * Attachment is a file uploaded to a record, e.g. a photo, datasheet or invoice *
+// * The content is kept in a BlobStore under BlobKey, the metadata with the records *
+type Attachment struct {
+	ID          int    `json:"id"`
+	DataID      int    `json:"data_id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   string `json:"created_by"`
+	BlobKey     string `json:"-"`
+}
+
+// * ErrAttachmentTooLarge is returned when an uploaded file is over the size limit *
+var ErrAttachmentTooLarge = errors.New("attachment is too large")
+
+type AttachmentRepository interface {
+	Create(attachment *Attachment, ctx context.Context) error
+	ReadOne(dataID int, id int, ctx context.Context) (*Attachment, error)
+	ReadAll(dataID int, ctx context.Context) ([]*Attachment, error)
+	Delete(dataID int, id int, ctx context.Context) (*Attachment, error)
+	DeleteOrphans(ctx context.Context) ([]*Attachment, error)
+}
+
+// * BlobStore keeps the content of attachments by key *
+type BlobStore interface {
+	// * Create writes the content under the key and returns its size and SHA-256 in hex
+	Create(key string, content io.Reader) (int64, string, error)
+	Open(key string) (io.ReadSeekCloser, error)
+	Delete(key string) error
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// * Attributes are the type specific fields of a record, validated against the JSON Schema of its type *
+// * They are stored as a JSON object in a TEXT column *
+type Attributes map[string]any
+
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]any(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (a *Attributes) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("attributes: cannot scan %T", src)
+	}
+
+	var attrs map[string]any
+	if err := json.Unmarshal(b, &attrs); err != nil {
+		return err
+	}
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+	*a = attrs
+	return nil
+}
@@ -0,0 +1,8 @@
+package models
+
+// * Backup is a snapshot of the database written to a file *
+type Backup struct {
+	File      string `json:"file"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"created_at"`
+}
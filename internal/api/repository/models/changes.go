@@ -0,0 +1,7 @@
+package models
+
+// * ChangeMarker changes with every write to a collection, so a client can tell whether its copy of a list is current *
+type ChangeMarker struct {
+	Version   int64
+	ChangedAt string
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// * BaseCurrency is the currency every exchange rate is relative to, its own rate is always 1 *
+const BaseCurrency = "EUR"
+
+// * Currencies are the ISO 4217 codes prices can be stored in, with the number of digits of their minor unit *
+var Currencies = map[string]int{
+	"AUD": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"CZK": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HUF": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"NOK": 2,
+	"PLN": 2,
+	"SEK": 2,
+	"USD": 2,
+}
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// * NoExchangeRateError is returned when a price can not be converted because the rate of its currency was never set *
+type NoExchangeRateError struct {
+	Currency string
+}
+
+func (e NoExchangeRateError) Error() string {
+	return "no exchange rate for " + e.Currency
+}
+
+// * ExchangeRate is how many units of Currency one unit of the BaseCurrency buys *
+type ExchangeRate struct {
+	Currency  string  `json:"currency"`
+	Rate      float64 `json:"rate"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+type ExchangeRateRepository interface {
+	ReadAll(ctx context.Context) ([]*ExchangeRate, error)
+	ReadOne(currency string, ctx context.Context) (*ExchangeRate, error)
+	Save(rate *ExchangeRate, ctx context.Context) error
+}
+
+// ConvertPrice converts an amount in minor units from one currency to another with the rates of both,
+// rounding half away from zero to the minor unit of the target currency.
+func ConvertPrice(amount int64, from *ExchangeRate, to *ExchangeRate) (int64, error) {
+	fromDigits, ok := Currencies[from.Currency]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+	toDigits, ok := Currencies[to.Currency]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+	if from.Currency == to.Currency {
+		return amount, nil
+	}
+
+	major := float64(amount) / math.Pow10(fromDigits)
+	converted := major / from.Rate * to.Rate
+	return int64(math.Round(converted * math.Pow10(toDigits))), nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import "testing"
+
+func TestConvertPrice(t *testing.T) {
+	eur := &ExchangeRate{Currency: "EUR", Rate: 1}
+	usd := &ExchangeRate{Currency: "USD", Rate: 1.0842}
+	jpy := &ExchangeRate{Currency: "JPY", Rate: 161.23}
+
+	tests := []struct {
+		name     string
+		amount   int64
+		from, to *ExchangeRate
+		want     int64
+	}{
+		{"same currency", 1999, eur, eur, 1999},
+		{"EUR to USD", 10000, eur, usd, 10842},
+		{"USD to EUR rounds to the cent", 10000, usd, eur, 9223},
+		{"EUR to JPY has no minor unit", 1000, eur, jpy, 1612},
+		{"JPY to USD", 1612, jpy, usd, 1084},
+		{"negative amounts round away from zero", -50, eur, usd, -54},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertPrice(tt.amount, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ConvertPrice(%d, %s, %s) = %d, want %d", tt.amount, tt.from.Currency, tt.to.Currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertPriceUnknownCurrency(t *testing.T) {
+	_, err := ConvertPrice(100, &ExchangeRate{Currency: "EUR", Rate: 1}, &ExchangeRate{Currency: "XYZ", Rate: 2})
+	if err != ErrUnknownCurrency {
+		t.Errorf("expected ErrUnknownCurrency, got %v", err)
+	}
+}
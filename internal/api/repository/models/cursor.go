@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// * Position in a (date_time, id) ordered list, the last row of the previous page *
+type Cursor struct {
+	DateTime string `json:"t"`
+	ID       int    `json:"i"`
+}
+
+// * Encode returns the cursor as an opaque URL-safe string *
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// * DecodeCursor parses a cursor produced by Encode, an empty string is the start of the list *
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// * Envelope of a cursor paginated list response *
+type CursorPage[T any] struct {
+	Data       []T    `json:"data"`
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func NewCursorPage[T any](data []T, perPage int, next *Cursor) *CursorPage[T] {
+	if data == nil {
+		data = []T{}
+	}
+	page := &CursorPage[T]{Data: data, PerPage: perPage}
+	if next != nil {
+		page.NextCursor = next.Encode()
+	}
+	return page
+}
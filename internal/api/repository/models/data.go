@@ -1,23 +1,79 @@
 package models
 
-import "context"
+import (
+	"context"
+	"errors"
+	"goapi/internal/api/repository/query"
+	"time"
+)
 
 type Data struct {
 	ID         int    `json:"id"`
 	DeviceID   string `json:"device_id"`
 	DeviceName string `json:"device_name"`
 	//removed value and added Price and Serial Number
-	Price        float64 `json:"price"`
-	SerialNumber float64 `json:"SerialNumber"`
-	Type         string  `json:"type"`
-	DateTime     string  `json:"date_time"`
-	Description  string  `json:"description"`
+	// * Price is in minor units of the currency (cents for EUR and USD), Currency is an ISO 4217 code
+	Price        int64      `json:"price"`
+	Currency     string     `json:"currency"`
+	SerialNumber string     `json:"serial_number"`
+	Type         string     `json:"type"`
+	DateTime     string     `json:"date_time"`
+	Description  string     `json:"description"`
+	Attributes   Attributes `json:"attributes,omitempty"`
+	// * Version counts the changes of the record, it is the ETag of the record *
+	Version int `json:"version,omitempty"`
+	// * CreatedAt and UpdatedAt are set by the server when the record is written, DateTime is the client's *
+	// * They are read-only, values sent by clients are ignored. UpdatedAt is the Last-Modified of the record *
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	// * Tags are changed with POST and DELETE /data/{id}/tags, tags sent with the record are ignored *
+	Tags []string `json:"tags,omitempty"`
 }
 
+// * ErrDuplicateSerialNumber is returned when a serial number is already used by another record *
+var ErrDuplicateSerialNumber = errors.New("serial number is already used by another record")
+
 type DataRepository interface {
 	Create(Data *Data, ctx context.Context) error
 	ReadOne(id int, ctx context.Context) (*Data, error)
+	ReadBySerialNumber(serialNumber string, ctx context.Context) (*Data, error)
 	ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*Data, error)
+	ReadAfter(cursor *Cursor, limit int, ctx context.Context) ([]*Data, *Cursor, error)
+	Count(ctx context.Context) (int, error)
+	Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*Data, error)
+	CountQuery(q *query.Query, ctx context.Context) (int, error)
+	Stats(ctx context.Context) (*DataStats, error)
+	ChangeMarker(ctx context.Context) (*ChangeMarker, error)
+	ReadHistory(id int, ctx context.Context) ([]*DataVersion, error)
+	ReadAsOf(id int, asOf time.Time, ctx context.Context) (*Data, error)
+	Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*DataSearchResult, int, error)
 	Update(data *Data, ctx context.Context) (int64, error)
 	Delete(data *Data, ctx context.Context) (int64, error)
+	Restore(id int, ctx context.Context) (int64, error)
+	AddTags(id int, tags []string, version int, ctx context.Context) (int64, error)
+	RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error)
+	ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*TrashedData, error)
+	CountTrash(ctx context.Context) (int, error)
+	Purge(before time.Time, ctx context.Context) (int64, error)
+}
+
+// * Fields of Data that list endpoints can filter, sort and select on, keyed by JSON name *
+var DataQuerySchema = query.Schema{
+	"id":            {Column: "id", Kind: query.Number},
+	"device_id":     {Column: "device_id", Kind: query.String},
+	"device_name":   {Column: "device_name", Kind: query.String},
+	"price":         {Column: "price", Kind: query.Number},
+	"currency":      {Column: "currency", Kind: query.String},
+	"serial_number": {Column: "serial_number", Kind: query.String},
+	"type":          {Column: "data_type", Kind: query.String},
+	"date_time":     {Column: "date_time", Kind: query.String},
+	"description":   {Column: "description", Kind: query.String},
+	"attributes":    {Column: "attributes", Kind: query.String},
+	"version":       {Column: "version", Kind: query.Number},
+	// * The timestamps have a fixed width, so they compare and sort as text: created_at>"2024-03-01"
+	"created_at": {Column: "CAST(created_at AS TEXT)", Kind: query.String},
+	"updated_at": {Column: "CAST(updated_at AS TEXT)", Kind: query.String},
 }
+
+// * DataQueryFields lists the queryable fields in the order they are returned *
+var DataQueryFields = []string{"id", "device_id", "device_name", "price", "currency", "serial_number", "type", "date_time", "description", "attributes", "version", "created_at", "updated_at"}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// * DataType describes a category of records, Schema is the JSON Schema their attributes must match *
+type DataType struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"`
+}
+
+var (
+	// * ErrDuplicateDataType is returned when a type with the same name is already registered *
+	ErrDuplicateDataType = errors.New("data type is already registered")
+	// * ErrDataTypeInUse is returned when a type that records still use is deleted *
+	ErrDataTypeInUse = errors.New("data type is used by records")
+)
+
+type DataTypeRepository interface {
+	Create(dataType *DataType, ctx context.Context) error
+	ReadOne(name string, ctx context.Context) (*DataType, error)
+	ReadAll(ctx context.Context) ([]*DataType, error)
+	Update(dataType *DataType, ctx context.Context) (int64, error)
+	Delete(name string, ctx context.Context) (int64, error)
+}
@@ -0,0 +1,19 @@
+package models
+
+// * Forecast of a DHT22 sensor series at a point in the future *
+type DHT22Forecast struct {
+	DeviceName   string        `json:"device_name"`
+	Horizon      string        `json:"horizon"`
+	ForecastTime string        `json:"forecast_time"`
+	Samples      int           `json:"samples"`
+	Temperature  ForecastValue `json:"temperature"`
+	Humidity     ForecastValue `json:"humidity"`
+}
+
+// * Predicted value with a 95% prediction interval and the fitted trend *
+type ForecastValue struct {
+	Predicted    float64 `json:"predicted"`
+	Lower        float64 `json:"lower"`
+	Upper        float64 `json:"upper"`
+	SlopePerHour float64 `json:"slope_per_hour"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "reflect"
+
+// * Operations recorded in the history of a record *
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+)
+
+// * DataVersion is one entry of the audit trail of a Data record *
+// * Data is the record as it was after the change, for a delete it is the record that was removed *
+type DataVersion struct {
+	HistoryID     int      `json:"history_id"`
+	Operation     string   `json:"operation"`
+	ChangedAt     string   `json:"changed_at"`
+	ChangedBy     string   `json:"changed_by"`
+	ChangedFields []string `json:"changed_fields"`
+	Data          Data     `json:"data"`
+}
+
+// * HistoryTimeFormat is a fixed width UTC format, so history timestamps sort correctly as text *
+const HistoryTimeFormat = "2006-01-02T15:04:05.000000Z"
+
+// * ChangedDataFields returns the JSON names of the fields that differ between two versions *
+func ChangedDataFields(before, after *Data) []string {
+	var changed []string
+	if before.DeviceID != after.DeviceID {
+		changed = append(changed, "device_id")
+	}
+	if before.DeviceName != after.DeviceName {
+		changed = append(changed, "device_name")
+	}
+	if before.Price != after.Price {
+		changed = append(changed, "price")
+	}
+	if before.Currency != after.Currency {
+		changed = append(changed, "currency")
+	}
+	if before.SerialNumber != after.SerialNumber {
+		changed = append(changed, "serial_number")
+	}
+	if before.Type != after.Type {
+		changed = append(changed, "type")
+	}
+	if before.DateTime != after.DateTime {
+		changed = append(changed, "date_time")
+	}
+	if before.Description != after.Description {
+		changed = append(changed, "description")
+	}
+	if (len(before.Attributes) > 0 || len(after.Attributes) > 0) && !reflect.DeepEqual(before.Attributes, after.Attributes) {
+		changed = append(changed, "attributes")
+	}
+	return changed
+}
@@ -0,0 +1,34 @@
+package models
+
+// * Page metadata returned with every paginated list *
+type PageMeta struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// * Envelope of a paginated list response *
+type Page[T any] struct {
+	Data []T      `json:"data"`
+	Meta PageMeta `json:"meta"`
+}
+
+func NewPage[T any](data []T, page int, perPage int, total int) *Page[T] {
+	if data == nil {
+		data = []T{}
+	}
+	totalPages := 0
+	if perPage > 0 {
+		totalPages = (total + perPage - 1) / perPage
+	}
+	return &Page[T]{
+		Data: data,
+		Meta: PageMeta{
+			Page:       page,
+			PerPage:    perPage,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+}
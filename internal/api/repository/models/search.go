@@ -0,0 +1,8 @@
+package models
+
+// * Data record matched by a full-text search, lower rank is a better match *
+type DataSearchResult struct {
+	Data
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// * SensorField is a measurement of a sensor type, a reading is valid when the value is between Min and Max *
+type SensorField struct {
+	Name string  `json:"name"`
+	Unit string  `json:"unit"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	// * Optional measurements can be left out of a reading, the others are required
+	Optional bool `json:"optional,omitempty"`
+}
+
+// * SensorType declares a kind of sensor, its readings are stored and served under /sensors/{name} *
+type SensorType struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Fields      []SensorField `json:"fields"`
+}
+
+// * Field returns the declaration of a measurement, nil if the type has no such field *
+func (t *SensorType) Field(name string) *SensorField {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
+
+// * SensorReading is a reading of a sensor of any registered type, Values are its measurements by field name *
+type SensorReading struct {
+	ID         int                `json:"id"`
+	Type       string             `json:"type"`
+	DeviceName string             `json:"device_name"`
+	DateTime   string             `json:"date_time"`
+	Values     map[string]float64 `json:"values"`
+	Version    int                `json:"version,omitempty"`
+	// * CreatedAt and UpdatedAt are when the reading reached the server, DateTime is when it was measured
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// * SensorRepository stores the readings of every sensor type in one place, the values of a type need no schema changes *
+type SensorRepository interface {
+	Create(reading *SensorReading, ctx context.Context) error
+	ReadOne(sensorType string, id int, ctx context.Context) (*SensorReading, error)
+	ReadMany(sensorType string, page int, rowsPerPage int, ctx context.Context) ([]*SensorReading, error)
+	Count(sensorType string, ctx context.Context) (int, error)
+	// * Update replaces a reading of its type, a version other than 0 must be the stored one, ErrVersionMismatch otherwise
+	Update(reading *SensorReading, ctx context.Context) (int64, error)
+	// * Delete moves a reading to the trash, like a DHT22 reading it can be restored until it is purged
+	Delete(sensorType string, id int, version int, ctx context.Context) (int64, error)
+	Restore(sensorType string, id int, ctx context.Context) (int64, error)
+	// * ReadTrash lists the deleted readings of every type, most recently deleted first
+	ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*TrashedSensorReading, error)
+	CountTrash(ctx context.Context) (int, error)
+	Purge(before time.Time, ctx context.Context) (int64, error)
+}
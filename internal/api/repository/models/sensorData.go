@@ -1,6 +1,10 @@
 package models
 
-import "context"
+import (
+	"context"
+	"goapi/internal/api/repository/query"
+	"time"
+)
 
 type DHT22Data struct {
 	ID          int     `json:"id"`
@@ -8,12 +12,54 @@ type DHT22Data struct {
 	Temperature float64 `json:"temperature"`
 	Humidity    float64 `json:"humidity"`
 	DateTime    string  `json:"date_time"`
+	Version     int     `json:"version,omitempty"`
+	// CreatedAt and UpdatedAt are when the reading reached the server, DateTime is when it was measured
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// * UTCDateTime writes a date_time a device sent in RFC 3339 in UTC, with the microseconds the databases keep *
+// * Every backend returns the same text, and readings of devices in other time zones are stored in the order of their time *
+// * A value that is not RFC 3339 is returned as it is, for the validation to reject *
+func UTCDateTime(dateTime string) string {
+	t, err := time.Parse(time.RFC3339, dateTime)
+	if err != nil {
+		return dateTime
+	}
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
 }
 
 type DHT22Repository interface {
 	Create(data *DHT22Data, ctx context.Context) error
+	// CreateMany inserts the readings in one transaction, either all of them are written or none
+	CreateMany(data []*DHT22Data, ctx context.Context) error
 	ReadOne(id int, ctx context.Context) (*DHT22Data, error)
 	ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*DHT22Data, error)
+	ReadAfter(cursor *Cursor, limit int, ctx context.Context) ([]*DHT22Data, *Cursor, error)
+	Count(ctx context.Context) (int, error)
+	Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*DHT22Data, error)
+	CountQuery(q *query.Query, ctx context.Context) (int, error)
+	ChangeMarker(ctx context.Context) (*ChangeMarker, error)
+	ReadLatest(deviceName string, limit int, ctx context.Context) ([]*DHT22Data, error)
 	Update(data *DHT22Data, ctx context.Context) (int64, error)
 	Delete(data *DHT22Data, ctx context.Context) (int64, error)
+	Restore(id int, ctx context.Context) (int64, error)
+	ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*TrashedDHT22Data, error)
+	CountTrash(ctx context.Context) (int, error)
+	Purge(before time.Time, ctx context.Context) (int64, error)
 }
+
+// * Fields of DHT22Data that list endpoints can filter, sort and select on, keyed by JSON name *
+var DHT22QuerySchema = query.Schema{
+	"id":          {Column: "id", Kind: query.Number},
+	"device_name": {Column: "device_name", Kind: query.String},
+	"temperature": {Column: "temperature", Kind: query.Number},
+	"humidity":    {Column: "humidity", Kind: query.Number},
+	"date_time":   {Column: "date_time", Kind: query.String},
+	"version":     {Column: "version", Kind: query.Number},
+	"created_at":  {Column: "CAST(created_at AS TEXT)", Kind: query.String},
+	"updated_at":  {Column: "CAST(updated_at AS TEXT)", Kind: query.String},
+}
+
+// * DHT22QueryFields lists the queryable fields in the order they are returned *
+var DHT22QueryFields = []string{"id", "device_name", "temperature", "humidity", "date_time", "version", "created_at", "updated_at"}
@@ -0,0 +1,39 @@
+package models
+
+// * DataStats is the inventory in numbers, computed over all records that are not in the trash *
+// * Prices are in minor units and never converted, so every price total and average is per currency *
+type DataStats struct {
+	Total    int            `json:"total"`
+	ByType   []*TypeStats   `json:"by_type"`
+	ByDevice []*DeviceStats `json:"by_device"`
+	ByMonth  []*MonthCount  `json:"by_month"`
+}
+
+// * TypeStats counts the records of a type and sums up their prices *
+type TypeStats struct {
+	Type   string        `json:"type"`
+	Count  int           `json:"count"`
+	Prices []*PriceStats `json:"prices"`
+}
+
+// * DeviceStats counts the records of a device and sums up their prices *
+type DeviceStats struct {
+	DeviceID   string        `json:"device_id"`
+	DeviceName string        `json:"device_name"`
+	Count      int           `json:"count"`
+	Prices     []*PriceStats `json:"prices"`
+}
+
+// * PriceStats of the records in one currency, the average is rounded to whole minor units *
+type PriceStats struct {
+	Currency string `json:"currency"`
+	Count    int    `json:"count"`
+	Total    int64  `json:"total"`
+	Average  int64  `json:"average"`
+}
+
+// * MonthCount is one bar of the DateTime histogram, Month is YYYY-MM *
+type MonthCount struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "strings"
+
+// * MaxTagLength is the longest tag, in bytes *
+const MaxTagLength = 50
+
+// * NormalizeTag returns the tag in lower case without surrounding spaces, ok is false when it is not a valid tag *
+// * Tags are letters, digits and - _ . : / so they read well in URLs, e.g. project:apollo or location/lab-2 *
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > MaxTagLength {
+		return "", false
+	}
+	for _, c := range tag {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case strings.ContainsRune("-_.:/", c):
+		default:
+			return "", false
+		}
+	}
+	return tag, true
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	for tag, want := range map[string]string{"project:apollo": "project:apollo", " Location/Lab-2 ": "location/lab-2", "v1.2_rc": "v1.2_rc"} {
+		if got, ok := NormalizeTag(tag); !ok || got != want {
+			t.Errorf("NormalizeTag(%q) = %q, %v, want %q, true", tag, got, ok, want)
+		}
+	}
+	for _, tag := range []string{"", "  ", "two words", "a,b", `"quoted"`, "ünïcode", strings.Repeat("a", MaxTagLength+1)} {
+		if _, ok := NormalizeTag(tag); ok {
+			t.Errorf("NormalizeTag(%q) should fail", tag)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+// * TrashedData is a deleted record, it can be restored until it is purged *
+type TrashedData struct {
+	Data
+	DeletedAt string `json:"deleted_at"`
+	DeletedBy string `json:"deleted_by"`
+}
+
+// * TrashedDHT22Data is a deleted reading, it can be restored until it is purged *
+type TrashedDHT22Data struct {
+	DHT22Data
+	DeletedAt string `json:"deleted_at"`
+	DeletedBy string `json:"deleted_by"`
+}
+
+// * TrashedSensorReading is a deleted reading of a registered sensor type, it can be restored until it is purged *
+type TrashedSensorReading struct {
+	SensorReading
+	DeletedAt string `json:"deleted_at"`
+	DeletedBy string `json:"deleted_by"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "context"
+
+// UnitOfWork makes several repository calls one transaction, e.g. a record, its tags and readings
+type UnitOfWork interface {
+	// Do calls fn with a context the repositories of the unit's database share a transaction through.
+	// The changes are committed when fn returns nil and rolled back when it returns an error or panics,
+	// when a repository call fails fn should return its error, PostgreSQL can not go on after a failed statement.
+	// The context must not be used by several goroutines at once or after fn returns.
+	Do(fn func(ctx context.Context) error, ctx context.Context) error
+}
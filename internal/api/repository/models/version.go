@@ -0,0 +1,28 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// * ErrVersionMismatch is returned when a change is based on a version of the resource that is no longer the current one *
+var ErrVersionMismatch = errors.New("the resource has been changed since the given version")
+
+// * ETag is the entity tag of a version of a resource, a strong tag so it can be used with If-Match *
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// * ParseETag returns the version of an entity tag made by ETag, false for weak or foreign tags *
+func ParseETag(etag string) (int, bool) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 3 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(etag[1 : len(etag)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,14 @@
+package models
+
+import "testing"
+
+func TestParseETag(t *testing.T) {
+	if v, ok := ParseETag(ETag(7)); !ok || v != 7 {
+		t.Errorf("ParseETag(ETag(7)) = %v, %v, want 7, true", v, ok)
+	}
+	for _, etag := range []string{``, `*`, `7`, `W/"7"`, `"0"`, `"abc"`, `"1", "2"`} {
+		if _, ok := ParseETag(etag); ok {
+			t.Errorf("ParseETag(%q) should fail", etag)
+		}
+	}
+}
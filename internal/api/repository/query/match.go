@@ -0,0 +1,87 @@
+package query
+
+import (
+	"cmp"
+)
+
+// * Values returns the value of a field of a row by JSON name: a string, or a float64 for Number fields *
+type Values func(field string) any
+
+// * Matches evaluates the filter against a row like the SQL of Where does, for repositories that are not SQL *
+func (q *Query) Matches(row Values) bool {
+	if q == nil || q.Filter == nil {
+		return true
+	}
+	return matches(q.Filter, row)
+}
+
+func matches(e Expr, row Values) bool {
+	switch e := e.(type) {
+	case Comparison:
+		c, ok := compare(row(e.Field), e.Value)
+		if !ok {
+			return false
+		}
+		switch e.Op {
+		case "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case ">":
+			return c > 0
+		case ">=":
+			return c >= 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		}
+	case Logical:
+		if e.Op == "or" {
+			return matches(e.Left, row) || matches(e.Right, row)
+		}
+		return matches(e.Left, row) && matches(e.Right, row)
+	case Not:
+		return !matches(e.Expr, row)
+	}
+	return false
+}
+
+// * Compare orders two rows like the SQL of OrderBy does, tieBreaker is the last sort field so the order is stable *
+func (q *Query) Compare(a, b Values, tieBreaker string) int {
+	hasTieBreaker := false
+	if q != nil {
+		for _, s := range q.Sort {
+			if s.Field == tieBreaker {
+				hasTieBreaker = true
+			}
+			c, _ := compare(a(s.Field), b(s.Field))
+			if s.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+	}
+	if hasTieBreaker {
+		return 0
+	}
+	c, _ := compare(a(tieBreaker), b(tieBreaker))
+	return c
+}
+
+// compare compares two values of the same kind, strings byte by byte like SQLite's BINARY collation.
+func compare(a, b any) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b), true
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,256 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// * Expr is a node of the filter AST *
+type Expr interface {
+	expr()
+}
+
+// * Comparison compares a field with a literal, e.g. price > 100 *
+type Comparison struct {
+	Field string
+	Op    string
+	Value any // * string or float64, matching the Kind of the field
+}
+
+// * Logical combines two expressions with "and" or "or" *
+type Logical struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// * Not negates an expression *
+type Not struct {
+	Expr Expr
+}
+
+func (Comparison) expr() {}
+func (Logical) expr()    {}
+func (Not) expr()        {}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any
+	pos   int
+}
+
+// * Grammar, "and" binds tighter than "or":
+// *   expr       = andExpr { "or" andExpr }
+// *   andExpr    = unary { "and" unary }
+// *   unary      = "not" unary | "(" expr ")" | comparison
+// *   comparison = field op literal
+type parser struct {
+	tokens      []token
+	pos         int
+	schema      Schema
+	comparisons int
+}
+
+func parseFilter(input string, schema Schema) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, schema: schema}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, Error{Message: "Unexpected " + strconv.Quote(t.text) + " at position " + strconv.Itoa(t.pos) + " in filter."}
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, word)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.isKeyword("not") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: inner}, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, Error{Message: "Missing closing parenthesis at position " + strconv.Itoa(t.pos) + " in filter."}
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	fieldTok := p.next()
+	if fieldTok.kind != tokIdent {
+		return nil, Error{Message: "Expected a field name at position " + strconv.Itoa(fieldTok.pos) + " in filter."}
+	}
+	field, ok := p.schema[fieldTok.text]
+	if !ok {
+		return nil, Error{Message: "Unknown filter field: " + fieldTok.text + "."}
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, Error{Message: "Expected an operator after " + fieldTok.text + " in filter."}
+	}
+
+	valueTok := p.next()
+	switch {
+	case field.Kind == Number && valueTok.kind == tokNumber:
+	case field.Kind == String && valueTok.kind == tokString:
+	default:
+		kind := "a quoted string"
+		if field.Kind == Number {
+			kind = "a number"
+		}
+		return nil, Error{Message: "Field " + fieldTok.text + " must be compared with " + kind + "."}
+	}
+
+	p.comparisons++
+	if p.comparisons > maxComparisons {
+		return nil, Error{Message: "Filter has too many comparisons."}
+	}
+	return Comparison{Field: fieldTok.text, Op: opTok.text, Value: valueTok.value}, nil
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(input) && rune(input[i]) != c {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				sb.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return nil, Error{Message: "Unterminated string at position " + strconv.Itoa(start) + " in filter."}
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: input[start:i], value: sb.String(), pos: start})
+		case strings.ContainsRune("=!<>", c):
+			start := i
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' {
+				op += "="
+			}
+			i += len(op)
+			switch op {
+			case "=", "==":
+				op = "=="
+			case "!":
+				return nil, Error{Message: "Unknown operator at position " + strconv.Itoa(start) + " in filter."}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			start := i
+			i++
+			for i < len(input) && (input[i] == '.' || input[i] == 'e' || input[i] == 'E' || unicode.IsDigit(rune(input[i]))) {
+				i++
+			}
+			n, err := strconv.ParseFloat(input[start:i], 64)
+			if err != nil {
+				return nil, Error{Message: "Invalid number at position " + strconv.Itoa(start) + " in filter."}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: input[start:i], value: n, pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(input) && (input[i] == '_' || unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[start:i], pos: start})
+		default:
+			return nil, Error{Message: "Unexpected character " + strconv.QuoteRune(c) + " at position " + strconv.Itoa(i) + " in filter."}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
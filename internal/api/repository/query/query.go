@@ -0,0 +1,106 @@
+package query
+
+import (
+	"strings"
+)
+
+// * Kind of value a field can be compared against *
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+)
+
+// * Field is a queryable field of a model: the JSON name clients use maps to a database column *
+type Field struct {
+	Column string
+	Kind   Kind
+}
+
+// * Schema is the whitelist of fields a model can be filtered, sorted and selected on, keyed by JSON name *
+type Schema map[string]Field
+
+// * Query is a validated filter, sort order and field selection for a list endpoint *
+type Query struct {
+	Filter Expr
+	Sort   []SortField
+	Fields []string
+	// * Tags the rows must have: all of them, or at least one with AnyTag. Only records have tags
+	Tags   []string
+	AnyTag bool
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// * Error is returned for any query the client sent that can not be parsed or is not allowed *
+type Error struct {
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+const (
+	maxFilterLength = 1000
+	maxComparisons  = 20
+)
+
+// * Parse parses the filter, sort and fields query parameters and validates them against the schema *
+// * Empty parameters are allowed, Parse("", "", "", schema) returns an empty query *
+func Parse(filter string, sort string, fields string, schema Schema) (*Query, error) {
+	q := &Query{}
+
+	if filter != "" {
+		if len(filter) > maxFilterLength {
+			return nil, Error{Message: "Filter is too long."}
+		}
+		expr, err := parseFilter(filter, schema)
+		if err != nil {
+			return nil, err
+		}
+		q.Filter = expr
+	}
+
+	if sort != "" {
+		seen := map[string]bool{}
+		for _, s := range strings.Split(sort, ",") {
+			s = strings.TrimSpace(s)
+			desc := strings.HasPrefix(s, "-")
+			name := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+			if _, ok := schema[name]; !ok {
+				return nil, Error{Message: "Unknown sort field: " + name + "."}
+			}
+			if seen[name] {
+				return nil, Error{Message: "Duplicate sort field: " + name + "."}
+			}
+			seen[name] = true
+			q.Sort = append(q.Sort, SortField{Field: name, Desc: desc})
+		}
+	}
+
+	if fields != "" {
+		seen := map[string]bool{}
+		for _, f := range strings.Split(fields, ",") {
+			f = strings.TrimSpace(f)
+			if _, ok := schema[f]; !ok {
+				return nil, Error{Message: "Unknown field: " + f + "."}
+			}
+			if !seen[f] {
+				seen[f] = true
+				q.Fields = append(q.Fields, f)
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// * IsEmpty reports whether the query neither filters, sorts nor selects fields *
+func (q *Query) IsEmpty() bool {
+	return q == nil || (q.Filter == nil && len(q.Sort) == 0 && len(q.Fields) == 0 && len(q.Tags) == 0)
+}
@@ -0,0 +1,137 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testSchema = Schema{
+	"id":        {Column: "id", Kind: Number},
+	"price":     {Column: "price", Kind: Number},
+	"type":      {Column: "data_type", Kind: String},
+	"date_time": {Column: "date_time", Kind: String},
+}
+
+func TestParseFilterToSQL(t *testing.T) {
+	tests := []struct {
+		filter string
+		where  string
+		args   []any
+	}{
+		{`price>100`, `price > ?`, []any{100.0}},
+		{`price > 100 and type=="sensor"`, `(price > ? AND data_type = ?)`, []any{100.0, "sensor"}},
+		{`type = 'a' or type != "b" and price <= -1.5`, `(data_type = ? OR (data_type <> ? AND price <= ?))`, []any{"a", "b", -1.5}},
+		{`(type == "a" or type == "b") AND not price < 10`, `((data_type = ? OR data_type = ?) AND NOT (price < ?))`, []any{"a", "b", 10.0}},
+		{`type == "it's \"quoted\""`, `data_type = ?`, []any{`it's "quoted"`}},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.filter, "", "", testSchema)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.filter, err)
+			continue
+		}
+		where, args := q.Where(testSchema)
+		if where != tt.where || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: got %q %v want %q %v", tt.filter, where, args, tt.where, tt.args)
+		}
+	}
+}
+
+func TestParseRejectsInvalidQueries(t *testing.T) {
+	tests := []struct{ filter, sort, fields string }{
+		{`price >`, "", ""},
+		{`secret == "x"`, "", ""},
+		{`price == "100"`, "", ""},
+		{`type == 5`, "", ""},
+		{`type == "a"; DROP TABLE data`, "", ""},
+		{`(price > 1`, "", ""},
+		{`price > 1 price < 2`, "", ""},
+		{`type == "unterminated`, "", ""},
+		{"", "-secret", ""},
+		{"", "price,-price", ""},
+		{"", "", "id,password"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Parse(tt.filter, tt.sort, tt.fields, testSchema); err == nil {
+			t.Errorf("%q %q %q: expected an error", tt.filter, tt.sort, tt.fields)
+		} else if _, ok := err.(Error); !ok {
+			t.Errorf("%q: expected a query.Error, got %T", tt.filter, err)
+		}
+	}
+}
+
+func TestOrderByAndSelected(t *testing.T) {
+	q, err := Parse("", "-date_time,price", "id, type,id", testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := q.OrderBy(testSchema, "id"); got != "date_time DESC, price, id" {
+		t.Errorf("unexpected ORDER BY: %s", got)
+	}
+	if got := Columns(testSchema, q.Selected([]string{"id", "price"})); got != "id, data_type" {
+		t.Errorf("unexpected columns: %s", got)
+	}
+
+	empty, _ := Parse("", "", "", testSchema)
+	if !empty.IsEmpty() || empty.OrderBy(testSchema, "id") != "id" {
+		t.Errorf("expected an empty query ordered by id")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	row := map[string]any{"id": 7.0, "price": 150.0, "type": "sensor", "date_time": "2024-03-01T00:00:00Z"}
+	values := func(field string) any { return row[field] }
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`price>100`, true},
+		{`price > 100 and type=="sensor"`, true},
+		{`type = 'a' or type != "b" and price <= -1.5`, false},
+		{`(type == "a" or type == "sensor") AND not price < 10`, true},
+		{`date_time >= "2024-03" and date_time < "2024-04"`, true},
+		{`id != 7`, false},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.filter, "", "", testSchema)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.filter, err)
+			continue
+		}
+		if got := q.Matches(values); got != tt.want {
+			t.Errorf("%s: got %v want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := map[string]any{"id": 1.0, "price": 100.0, "type": "b"}
+	b := map[string]any{"id": 2.0, "price": 100.0, "type": "a"}
+	va := func(field string) any { return a[field] }
+	vb := func(field string) any { return b[field] }
+
+	tests := []struct {
+		sort string
+		want int
+	}{
+		{``, -1},
+		{`type`, 1},
+		{`-type`, -1},
+		{`price`, -1},
+		{`price,-id`, 1},
+	}
+	for _, tt := range tests {
+		q, err := Parse("", tt.sort, "", testSchema)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.sort, err)
+			continue
+		}
+		if got := q.Compare(va, vb, "id"); got != tt.want {
+			t.Errorf("%s: got %v want %v", tt.sort, got, tt.want)
+		}
+	}
+}
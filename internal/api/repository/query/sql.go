@@ -0,0 +1,90 @@
+package query
+
+import (
+	"strings"
+)
+
+var sqlOperators = map[string]string{
+	"==": "=",
+	"!=": "<>",
+	">":  ">",
+	">=": ">=",
+	"<":  "<",
+	"<=": "<=",
+}
+
+// * Where translates the filter to a parameterized SQL condition, column names only ever come from the schema *
+// * It returns an empty string when there is no filter *
+func (q *Query) Where(schema Schema) (string, []any) {
+	if q == nil || q.Filter == nil {
+		return "", nil
+	}
+	var sb strings.Builder
+	var args []any
+	writeExpr(&sb, &args, q.Filter, schema)
+	return sb.String(), args
+}
+
+func writeExpr(sb *strings.Builder, args *[]any, e Expr, schema Schema) {
+	switch e := e.(type) {
+	case Comparison:
+		sb.WriteString(schema[e.Field].Column)
+		sb.WriteString(" ")
+		sb.WriteString(sqlOperators[e.Op])
+		sb.WriteString(" ?")
+		*args = append(*args, e.Value)
+	case Logical:
+		sb.WriteString("(")
+		writeExpr(sb, args, e.Left, schema)
+		if e.Op == "or" {
+			sb.WriteString(" OR ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		writeExpr(sb, args, e.Right, schema)
+		sb.WriteString(")")
+	case Not:
+		sb.WriteString("NOT (")
+		writeExpr(sb, args, e.Expr, schema)
+		sb.WriteString(")")
+	}
+}
+
+// * OrderBy translates the sort order to SQL, tieBreaker is appended so pages are stable *
+func (q *Query) OrderBy(schema Schema, tieBreaker string) string {
+	var parts []string
+	hasTieBreaker := false
+	if q != nil {
+		for _, s := range q.Sort {
+			column := schema[s.Field].Column
+			if column == tieBreaker {
+				hasTieBreaker = true
+			}
+			if s.Desc {
+				column += " DESC"
+			}
+			parts = append(parts, column)
+		}
+	}
+	if !hasTieBreaker {
+		parts = append(parts, tieBreaker)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// * Selected returns the selected field names, or all when the query does not select any *
+func (q *Query) Selected(all []string) []string {
+	if q == nil || len(q.Fields) == 0 {
+		return all
+	}
+	return q.Fields
+}
+
+// * Columns returns the database columns of the given fields *
+func Columns(schema Schema, fields []string) string {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = schema[f].Column
+	}
+	return strings.Join(columns, ", ")
+}
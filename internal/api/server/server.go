@@ -5,41 +5,138 @@ import (
 	"goapi/internal/api/handlers/data"
 	"goapi/internal/api/middleware"
 	"goapi/internal/api/service"
+	"goapi/internal/api/service/backup"
+	dataService "goapi/internal/api/service/data"
+	"goapi/internal/api/service/dht22"
+	"goapi/internal/api/service/sensor"
 	"log"
 	"net/http"
+	"time"
 )
 
+// * Config holds the options of the API server *
+type Config struct {
+	// * RequireIfMatch rejects changes to records and readings without an If-Match header (strict mode)
+	RequireIfMatch bool
+	// * CacheControl of the CacheableRoutes by route, e.g. "GET /dht22": "private, max-age=5", the others get private, no-cache
+	CacheControl map[string]string
+	// * DataService and DHT22Service select the backends, the zero values are SQLite
+	DataService  service.DataServiceType
+	DHT22Service service.DHT22ServiceType
+	// * DHT22Ingest queues POST /dht22 readings and writes them in batches, a BatchSize of 0 or 1 writes each reading before answering
+	DHT22Ingest dht22.IngestConfig
+}
+
+// * CacheableRoutes answer conditional GETs with 304 Not Modified, their Cache-Control can be configured *
+var CacheableRoutes = []string{"GET /data", "GET /data/stats", "GET /data/{id}", "GET /dht22", "GET /dht22/{id}", "GET /sensors/{type}/{id}"}
+
+// * ContentTypeRules of the routes that take other requests than JSON: files are uploaded as forms and downloaded from browsers *
+var ContentTypeRules = middleware.ContentTypeRules{
+	"POST /data/{id}/attachments":             {"multipart/form-data"},
+	"GET /data/{id}/attachments/{attachment}": {},
+}
+
+// * ShutdownTimeout is how long Shutdown waits for the requests in progress *
+const ShutdownTimeout = 10 * time.Second
+
 type Server struct {
-	ctx        context.Context
-	HTTPServer *http.Server
-	logger     *log.Logger
+	ctx         context.Context
+	HTTPServer  *http.Server
+	logger      *log.Logger
+	trashPurger *service.TrashPurger
+	ingest      *dht22.IngestQueue
+	backups     backup.BackupService
+	archive     dht22.ArchiveService
 }
 
-func NewServer(ctx context.Context, sf *service.ServiceFactory, logger *log.Logger) *Server {
+func NewServer(ctx context.Context, sf *service.ServiceFactory, logger *log.Logger, config Config) *Server {
 
-	mux := http.NewServeMux()
-	err := setupDataHandlers(mux, sf, logger)
+	ds, err := sf.CreateDataService(config.DataService)
+	if err != nil {
+		logger.Fatalf("Error setting up data service: %v", err)
+	}
+
+	dht22Service, err := sf.CreateDHT22Service(config.DHT22Service)
+	if err != nil {
+		logger.Fatalf("Error setting up DHT22 service: %v", err)
+	}
+
+	// * Readings of many sensors at once are written behind the requests in batches, SQLite takes its write lock once per batch
+	var ingest *dht22.IngestQueue
+	if config.DHT22Ingest.BatchSize > 1 {
+		ingest = dht22.NewIngestQueue(dht22Service, config.DHT22Ingest, logger)
+		dht22Service = ingest
+	} else {
+		// * Readings a queue could not write before are written, even when the server no longer queues them
+		dht22.ReplayDeadLetters(dht22Service, config.DHT22Ingest, logger)
+	}
+
+	archive, err := sf.CreateDHT22ArchiveService(config.DHT22Service)
+	if err != nil {
+		logger.Fatalf("Error setting up DHT22 archive: %v", err)
+	}
+
+	// * The readings of the registered sensor types have their own table, sensor_readings, in the database of the DHT22 readings
+	sensorService, err := sf.CreateSensorService(config.DHT22Service)
+	if err != nil {
+		logger.Fatalf("Error setting up sensor service: %v", err)
+	}
+
+	backupService, err := sf.CreateBackupService(config.DataService)
 	if err != nil {
-		logger.Fatalf("Error setting up data handlers: %v", err)
+		logger.Fatalf("Error setting up backup service: %v", err)
 	}
 
+	mux := http.NewServeMux()
+	setupDataHandlers(mux, ds, dht22Service, sensorService, archive, logger, config)
+	setupSensorHandlers(mux, sensorService, logger, config)
+	setupAdminHandlers(mux, backupService, logger)
+
 	middlewares := []middleware.Middleware{
 		middleware.BasicAuthenticationMiddleware,
-		middleware.CommonMiddleware,
+		middleware.NewCommonMiddleware(ContentTypeRules),
 	}
 
 	return &Server{
-		ctx:    ctx,
-		logger: logger,
+		ctx:         ctx,
+		logger:      logger,
+		trashPurger: service.NewTrashPurger(ds, dht22Service, sensorService, logger),
+		ingest:      ingest,
+		backups:     backupService,
+		archive:     archive,
 		HTTPServer: &http.Server{
 			Handler: middleware.ChainMiddleware(mux, middlewares...),
 		},
 	}
 }
 
+// Shutdown stops accepting requests, waits for the ones in progress and writes the readings still queued.
+// The databases are closed with the server context, it must be canceled after Shutdown returns.
 func (api *Server) Shutdown() error {
 	api.logger.Println("Gracefully shutting down server...")
-	return api.HTTPServer.Shutdown(api.ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	err := api.HTTPServer.Shutdown(ctx)
+	if api.ingest != nil {
+		api.ingest.Close()
+	}
+	return err
+}
+
+// StartTrashPurge permanently removes what has been in the trash longer than the retention, once an hour until shutdown.
+func (api *Server) StartTrashPurge(retention time.Duration) {
+	go api.trashPurger.Run(api.ctx, retention, time.Hour)
+}
+
+// StartBackups backs up the database every interval until shutdown, Config.Backup of the service factory says where and how many are kept.
+func (api *Server) StartBackups(interval time.Duration) {
+	go backup.Run(api.ctx, api.backups, interval, api.logger)
+}
+
+// StartArchive moves the readings measured longer than age ago into the archive, once an hour until shutdown.
+// Config.Archive of the service factory says where the files are written.
+func (api *Server) StartArchive(age time.Duration) {
+	go dht22.RunArchive(api.ctx, api.archive, age, time.Hour, api.logger)
 }
 
 func (api *Server) ListenAndServe(addr string) error {
@@ -47,17 +144,57 @@ func (api *Server) ListenAndServe(addr string) error {
 	return api.HTTPServer.ListenAndServe()
 }
 
-// * REST API handlers
-func setupDataHandlers(mux *http.ServeMux, sf *service.ServiceFactory, logger *log.Logger) error {
+// * Administration of the server
+func setupAdminHandlers(mux *http.ServeMux, backupService backup.BackupService, logger *log.Logger) {
+	mux.HandleFunc("POST /admin/backup", func(w http.ResponseWriter, r *http.Request) {
+		data.BackupHandler(w, r, logger, backupService)
+	})
+}
 
-	ds, err := sf.CreateDataService(service.SQLiteDataService)
-	if err != nil {
-		return err
+// * Readings of the registered sensor types, every type gets the same routes under /sensors/{type}
+func setupSensorHandlers(mux *http.ServeMux, sensorService sensor.SensorService, logger *log.Logger, config Config) {
+	conditional := func(handler http.HandlerFunc) http.HandlerFunc {
+		if config.RequireIfMatch {
+			return data.RequireIfMatch(handler)
+		}
+		return handler
 	}
 
-	dht22Service, err := sf.CreateDHT22Service(service.SQLiteDHT22Service)
-	if err != nil {
-		return err
+	mux.HandleFunc("GET /sensors", func(w http.ResponseWriter, r *http.Request) {
+		data.GetSensorTypesHandler(w, r, logger, sensorService)
+	})
+	mux.HandleFunc("GET /sensors/{type}", func(w http.ResponseWriter, r *http.Request) {
+		data.GetSensorReadingsHandler(w, r, logger, sensorService)
+	})
+	mux.HandleFunc("POST /sensors/{type}", func(w http.ResponseWriter, r *http.Request) {
+		data.PostSensorReadingHandler(w, r, logger, sensorService)
+	})
+	mux.HandleFunc("GET /sensors/{type}/{id}", data.CacheControl(config.CacheControl["GET /sensors/{type}/{id}"], func(w http.ResponseWriter, r *http.Request) {
+		data.GetSensorReadingHandler(w, r, logger, sensorService)
+	}))
+	mux.HandleFunc("PUT /sensors/{type}/{id}", conditional(func(w http.ResponseWriter, r *http.Request) {
+		data.PutSensorReadingHandler(w, r, logger, sensorService)
+	}))
+	mux.HandleFunc("DELETE /sensors/{type}/{id}", conditional(func(w http.ResponseWriter, r *http.Request) {
+		data.DeleteSensorReadingHandler(w, r, logger, sensorService)
+	}))
+	mux.HandleFunc("POST /sensors/{type}/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		data.RestoreSensorReadingHandler(w, r, logger, sensorService)
+	})
+}
+
+// * REST API handlers
+func setupDataHandlers(mux *http.ServeMux, ds dataService.DataService, dht22Service dht22.DHT22Service, sensorService sensor.SensorService, archive dht22.ArchiveService, logger *log.Logger, config Config) {
+
+	// * Changes to a record or a reading must name the version they are based on in strict mode
+	conditional := func(handler http.HandlerFunc) http.HandlerFunc {
+		if config.RequireIfMatch {
+			return data.RequireIfMatch(handler)
+		}
+		return handler
+	}
+	cacheable := func(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+		return data.CacheControl(config.CacheControl[pattern], handler)
 	}
 
 	mux.HandleFunc("OPTIONS /*", func(w http.ResponseWriter, r *http.Request) {
@@ -66,35 +203,125 @@ func setupDataHandlers(mux *http.ServeMux, sf *service.ServiceFactory, logger *l
 	mux.HandleFunc("POST /data", func(w http.ResponseWriter, r *http.Request) {
 		data.PostHandler(w, r, logger, ds)
 	})
-	mux.HandleFunc("PUT /data", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("PUT /data", conditional(func(w http.ResponseWriter, r *http.Request) {
 		data.PutHandler(w, r, logger, ds)
-	})
-	mux.HandleFunc("GET /data", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	mux.HandleFunc("GET /data", cacheable("GET /data", func(w http.ResponseWriter, r *http.Request) {
 		data.GetHandler(w, r, logger, ds)
+	}))
+	mux.HandleFunc("GET /data/search", func(w http.ResponseWriter, r *http.Request) {
+		data.SearchHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("GET /data/stats", cacheable("GET /data/stats", func(w http.ResponseWriter, r *http.Request) {
+		data.StatsHandler(w, r, logger, ds)
+	}))
+	mux.HandleFunc("GET /data/types", func(w http.ResponseWriter, r *http.Request) {
+		data.GetDataTypesHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("POST /data/types", func(w http.ResponseWriter, r *http.Request) {
+		data.PostDataTypeHandler(w, r, logger, ds)
 	})
-	mux.HandleFunc("GET /data/{id}", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("PUT /data/types/{name}", func(w http.ResponseWriter, r *http.Request) {
+		data.PutDataTypeHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("GET /data/by-serial/{serial}", func(w http.ResponseWriter, r *http.Request) {
+		data.GetBySerialHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("GET /data/{id}", cacheable("GET /data/{id}", func(w http.ResponseWriter, r *http.Request) {
 		data.GetByIDHandler(w, r, logger, ds)
+	}))
+	// * GET /data/types/{name} overlaps the sub-resources of /data/{id} (/data/types/history matches both),
+	// * ServeMux refuses to register such patterns, so both are served by one route
+	mux.HandleFunc("GET /data/{id}/{resource}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "types" {
+			r.SetPathValue("name", r.PathValue("resource"))
+			data.GetDataTypeHandler(w, r, logger, ds)
+			return
+		}
+		switch r.PathValue("resource") {
+		case "history":
+			data.HistoryHandler(w, r, logger, ds)
+		case "attachments":
+			data.GetAttachmentsHandler(w, r, logger, ds)
+		default:
+			http.NotFound(w, r)
+		}
 	})
-	mux.HandleFunc("DELETE /data/{id}", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("PATCH /data/{id}", conditional(func(w http.ResponseWriter, r *http.Request) {
+		data.PatchHandler(w, r, logger, ds)
+	}))
+	mux.HandleFunc("DELETE /data/{id}", conditional(func(w http.ResponseWriter, r *http.Request) {
 		data.DeleteHandler(w, r, logger, ds)
+	}))
+	mux.HandleFunc("POST /data/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		data.RestoreHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("POST /data/{id}/tags", conditional(func(w http.ResponseWriter, r *http.Request) {
+		data.PostTagsHandler(w, r, logger, ds)
+	}))
+	mux.HandleFunc("POST /data/{id}/attachments", func(w http.ResponseWriter, r *http.Request) {
+		data.PostAttachmentHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("GET /data/{id}/attachments/{attachment}", func(w http.ResponseWriter, r *http.Request) {
+		data.GetAttachmentHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("DELETE /data/{id}/attachments/{attachment}", func(w http.ResponseWriter, r *http.Request) {
+		data.DeleteAttachmentHandler(w, r, logger, ds)
+	})
+	// * DELETE /data/types/{name} overlaps DELETE /data/{id}/tags (/data/types/tags matches both), one route serves both
+	mux.HandleFunc("DELETE /data/{id}/{resource}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "types" {
+			r.SetPathValue("name", r.PathValue("resource"))
+			data.DeleteDataTypeHandler(w, r, logger, ds)
+			return
+		}
+		switch r.PathValue("resource") {
+		case "tags":
+			conditional(func(w http.ResponseWriter, r *http.Request) {
+				data.DeleteTagsHandler(w, r, logger, ds)
+			})(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("GET /trash", func(w http.ResponseWriter, r *http.Request) {
+		data.TrashHandler(w, r, logger, ds, dht22Service, sensorService)
+	})
+
+	mux.HandleFunc("GET /exchange-rates", func(w http.ResponseWriter, r *http.Request) {
+		data.GetExchangeRatesHandler(w, r, logger, ds)
+	})
+	mux.HandleFunc("PUT /exchange-rates/{currency}", func(w http.ResponseWriter, r *http.Request) {
+		data.PutExchangeRateHandler(w, r, logger, ds)
 	})
 
 	// DHT22-specific
 	mux.HandleFunc("POST /dht22", func(w http.ResponseWriter, r *http.Request) {
 		data.CreateDHT22Handler(w, r, logger, dht22Service)
 	})
-	mux.HandleFunc("PUT /dht22", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("PUT /dht22", conditional(func(w http.ResponseWriter, r *http.Request) {
 		data.UpdateDHT22Handler(w, r, logger, dht22Service)
+	}))
+	mux.HandleFunc("PUT /dht22/{id}", conditional(func(w http.ResponseWriter, r *http.Request) {
+		data.UpdateDHT22Handler(w, r, logger, dht22Service)
+	}))
+	mux.HandleFunc("GET /dht22", cacheable("GET /dht22", func(w http.ResponseWriter, r *http.Request) {
+		data.GetDHT22Handler(w, r, logger, dht22Service, archive)
+	}))
+	mux.HandleFunc("GET /dht22/forecast", func(w http.ResponseWriter, r *http.Request) {
+		data.ForecastDHT22Handler(w, r, logger, dht22Service)
 	})
-	mux.HandleFunc("GET /dht22", func(w http.ResponseWriter, r *http.Request) {
-		data.GetDHT22Handler(w, r, logger, dht22Service)
-	})
-	mux.HandleFunc("GET /dht22/{id}", func(w http.ResponseWriter, r *http.Request) {
-		data.GetDHT22ByIDHandler(w, r, logger, dht22Service)
-	})
-	mux.HandleFunc("DELETE /dht22/{id}", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("GET /dht22/{id}", cacheable("GET /dht22/{id}", func(w http.ResponseWriter, r *http.Request) {
+		data.GetDHT22ByIDHandler(w, r, logger, dht22Service, archive)
+	}))
+	mux.HandleFunc("PATCH /dht22/{id}", conditional(func(w http.ResponseWriter, r *http.Request) {
+		data.PatchDHT22Handler(w, r, logger, dht22Service)
+	}))
+	mux.HandleFunc("DELETE /dht22/{id}", conditional(func(w http.ResponseWriter, r *http.Request) {
 		data.DeleteDHT22Handler(w, r, logger, dht22Service)
+	}))
+	mux.HandleFunc("POST /dht22/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		data.RestoreDHT22Handler(w, r, logger, dht22Service)
 	})
-
-	return err
 }
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/service"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// * The whole API, middlewares and routes included, runs on the in-memory services without touching disk *
+func TestServerInMemory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.New(io.Discard, "", 0)
+	sf := service.NewServiceFactory(nil, logger, ctx, service.Config{})
+	api := NewServer(ctx, sf, logger, Config{DataService: service.MemoryDataService, DHT22Service: service.MemoryDHT22Service})
+	ts := httptest.NewServer(api.HTTPServer.Handler)
+	defer ts.Close()
+
+	do := func(method string, path string, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("prakash", "12345678")
+		req.Header.Set("Content-Type", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	res := do("POST", "/data", `{"device_id": "d1", "device_name": "Sensor", "price": 1000, "currency": "EUR", "type": "Sensor", "date_time": "2024-01-05T10:00:00Z"}`)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /data returned wrong status code: got %v want %v", res.StatusCode, http.StatusCreated)
+	}
+
+	res = do("GET", "/data/1", "")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET /data/1 returned wrong status code: got %v want %v", res.StatusCode, http.StatusOK)
+	}
+	var data models.Data
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	if data.ID != 1 || data.DeviceName != "Sensor" || data.Currency != models.BaseCurrency || data.Version != 1 {
+		t.Errorf("GET /data/1 returned unexpected data: got %+v", data)
+	}
+
+	res = do("GET", "/data/2", "")
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /data/2 returned wrong status code: got %v want %v", res.StatusCode, http.StatusNotFound)
+	}
+
+	res = do("POST", "/dht22", `{"device_name": "dht", "temperature": 21.5, "humidity": 40, "date_time": "2024-01-05T10:00:00Z"}`)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /dht22 returned wrong status code: got %v want %v", res.StatusCode, http.StatusCreated)
+	}
+	res = do("GET", "/dht22?filter=temperature>20", "")
+	var page models.Page[*models.DHT22Data]
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Meta.Total != 1 || page.Data[0].DeviceName != "dht" {
+		t.Errorf("GET /dht22 returned unexpected readings: got %+v", page)
+	}
+}
@@ -0,0 +1,20 @@
+package backup
+
+import (
+	"context"
+	"goapi/internal/api/repository/models"
+)
+
+// MockBackupServiceSuccessful: Simulates a backup that is written
+type MockBackupServiceSuccessful struct{}
+
+func (m *MockBackupServiceSuccessful) Backup(ctx context.Context) (*models.Backup, error) {
+	return &models.Backup{File: "backups/backup-20241222T120000.000000Z.db", Size: 8192, CreatedAt: "2024-12-22T12:00:00Z"}, nil
+}
+
+// MockBackupServiceError: Simulates a backup that fails
+type MockBackupServiceError struct{}
+
+func (m *MockBackupServiceError) Backup(ctx context.Context) (*models.Backup, error) {
+	return nil, BackupError("Error backing up the database")
+}
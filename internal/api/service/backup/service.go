@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BackupService makes backups of the database while the server keeps serving
+type BackupService interface {
+	Backup(ctx context.Context) (*models.Backup, error)
+}
+
+type BackupError string
+
+func (e BackupError) Error() string {
+	return string(e)
+}
+
+var ErrNotSupported = BackupError("Backups are only made of SQLite databases, back up other databases with their own tools.")
+
+// Config of the backups
+type Config struct {
+	// Dir the backups are written to
+	Dir string
+	// Keep is how many backups are kept, the oldest ones are removed after a backup
+	Keep int
+}
+
+// backupPrefix and backupTimeFormat name the backups, their names sort by time
+const (
+	backupPrefix     = "backup-"
+	backupSuffix     = ".db"
+	backupTimeFormat = "20060102T150405.000000Z"
+)
+
+// sqliteBackupService writes backups of a SQLite database with VACUUM INTO
+type sqliteBackupService struct {
+	db     DAL.SQLDatabase
+	config Config
+	logger *log.Logger
+	// * One backup at a time, a scheduled one and one asked for can not write the same file
+	mu sync.Mutex
+}
+
+func NewSQLiteBackupService(db DAL.SQLDatabase, config Config, logger *log.Logger) BackupService {
+	return &sqliteBackupService{
+		db:     db,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (s *sqliteBackupService) Backup(ctx context.Context) (*models.Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	createdAt := time.Now().UTC()
+	path := filepath.Join(s.config.Dir, backupPrefix+createdAt.Format(backupTimeFormat)+backupSuffix)
+	if err := SQLite.Backup(s.db, path, ctx); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// * A failed rotation leaves one backup too many, the backup itself is done
+	if err := s.rotate(); err != nil {
+		s.logger.Println("Could not remove old backups:", err)
+	}
+	return &models.Backup{File: path, Size: info.Size(), CreatedAt: createdAt.Format(time.RFC3339)}, nil
+}
+
+// rotate removes the oldest backups beyond Keep, files that are not backups are left alone
+func (s *sqliteBackupService) rotate() error {
+	if s.config.Keep < 1 {
+		return nil
+	}
+	entries, err := os.ReadDir(s.config.Dir)
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, entry := range entries {
+		if name := entry.Name(); entry.Type().IsRegular() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			backups = append(backups, name)
+		}
+	}
+	slices.Sort(backups)
+	for len(backups) > s.config.Keep {
+		if err := os.Remove(filepath.Join(s.config.Dir, backups[0])); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// unsupportedBackupService is the BackupService of the databases that are not backed up by the server
+type unsupportedBackupService struct{}
+
+func NewUnsupportedBackupService() BackupService {
+	return unsupportedBackupService{}
+}
+
+func (unsupportedBackupService) Backup(ctx context.Context) (*models.Backup, error) {
+	return nil, ErrNotSupported
+}
+
+// Run makes a backup every interval until the context is canceled, errors are logged so the next run tries again
+func Run(ctx context.Context, service BackupService, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if b, err := service.Backup(ctx); err != nil {
+			logger.Println("Could not back up the database:", err)
+		} else {
+			logger.Printf("Backed up the database to %s (%d bytes)", b.File, b.Size)
+		}
+	}
+}
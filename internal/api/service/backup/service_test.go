@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRotation(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, err := SQLite.NewSqlite(filepath.Join(dir, "production.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SQLite.NewDataRepository(db, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SQLite.NewDHT22Repository(db, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := filepath.Join(dir, "backups")
+	if err := os.MkdirAll(backups, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// * Files that are not backups are never removed
+	if err := os.WriteFile(filepath.Join(backups, "notes.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewSQLiteBackupService(db, Config{Dir: backups, Keep: 2}, log.New(io.Discard, "", 0))
+	var files []string
+	for i := 0; i < 4; i++ {
+		b, err := service.Backup(context.Background())
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		if b.Size == 0 {
+			t.Errorf("Expected the size of the backup, got %+v", b)
+		}
+		files = append(files, b.File)
+	}
+
+	for i, file := range files {
+		_, err := os.Stat(file)
+		if kept := i >= 2; kept != (err == nil) {
+			t.Errorf("Expected backup %d kept %v, got %v", i, kept, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(backups, "notes.txt")); err != nil {
+		t.Errorf("Rotation removed a file that is not a backup: %v", err)
+	}
+	if err := SQLite.ValidateBackup(files[3], context.Background()); err != nil {
+		t.Errorf("ValidateBackup of the newest backup failed: %v", err)
+	}
+}
+
+func TestUnsupportedBackup(t *testing.T) {
+	if _, err := NewUnsupportedBackupService().Backup(context.Background()); err != ErrNotSupported {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -3,24 +3,44 @@ package data
 import (
 	"context"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"goapi/internal/api/service/jsonschema"
+	"math"
+	"slices"
+	"strconv"
 	"time"
 )
 
 // * Implementation of DataService for SQLite database *
 type DataServiceSQLite struct {
-	repo models.DataRepository
+	repo  models.DataRepository
+	rates models.ExchangeRateRepository
+	types models.DataTypeRepository
+	// * Files attached to the records, metadata in attachments and content in blobs
+	attachments models.AttachmentRepository
+	blobs       models.BlobStore
+	limits      AttachmentLimits
 }
 
-func NewDataServiceSQLite(repo models.DataRepository) *DataServiceSQLite {
+func NewDataServiceSQLite(repo models.DataRepository, rates models.ExchangeRateRepository, types models.DataTypeRepository, attachments models.AttachmentRepository, blobs models.BlobStore, limits AttachmentLimits) *DataServiceSQLite {
 	return &DataServiceSQLite{
-		repo: repo,
+		repo:        repo,
+		rates:       rates,
+		types:       types,
+		attachments: attachments,
+		blobs:       blobs,
+		limits:      limits,
 	}
 }
 
 func (ds *DataServiceSQLite) Create(data *models.Data, ctx context.Context) error {
 
-	if err := ds.ValidateData(data); err != nil {
-		return DataError{Message: "InvalMockDataServiceSuccessfulid data."}
+	// * Prices without a currency are in the base currency
+	if data.Currency == "" {
+		data.Currency = models.BaseCurrency
+	}
+	if err := ds.validate(data, ctx); err != nil {
+		return err
 	}
 	return ds.repo.Create(data, ctx)
 }
@@ -40,42 +60,294 @@ func (ds *DataServiceSQLite) ReadOne(id int, ctx context.Context) (*models.Data,
 	return data, nil
 }
 
+func (ds *DataServiceSQLite) ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error) {
+	return ds.repo.ReadBySerialNumber(serialNumber, ctx)
+}
+
 func (ds *DataServiceSQLite) ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
 	return ds.repo.ReadMany(page, rowsPerPage, ctx)
 }
 
+func (ds *DataServiceSQLite) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error) {
+	return ds.repo.ReadAfter(cursor, limit, ctx)
+}
+
+func (ds *DataServiceSQLite) Count(ctx context.Context) (int, error) {
+	return ds.repo.Count(ctx)
+}
+
+func (ds *DataServiceSQLite) Stats(ctx context.Context) (*models.DataStats, error) {
+	return ds.repo.Stats(ctx)
+}
+
+func (ds *DataServiceSQLite) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	return ds.repo.Query(q, page, rowsPerPage, ctx)
+}
+
+func (ds *DataServiceSQLite) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	return ds.repo.CountQuery(q, ctx)
+}
+
+func (ds *DataServiceSQLite) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return ds.repo.ChangeMarker(ctx)
+}
+
+func (ds *DataServiceSQLite) History(id int, ctx context.Context) ([]*models.DataVersion, error) {
+	return ds.repo.ReadHistory(id, ctx)
+}
+
+func (ds *DataServiceSQLite) ReadAsOf(id int, asOf time.Time, ctx context.Context) (*models.Data, error) {
+	return ds.repo.ReadAsOf(id, asOf, ctx)
+}
+
+func (ds *DataServiceSQLite) Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	if len(text) > 200 {
+		return nil, 0, DataError{Message: "Search text must be less than 200 characters."}
+	}
+	return ds.repo.Search(text, page, rowsPerPage, ctx)
+}
+
 func (ds *DataServiceSQLite) Update(data *models.Data, ctx context.Context) (int64, error) {
 
-	if err := ds.ValidateData(data); err != nil {
-		return 0, DataError{Message: "Invalid data: " + err.Error()}
+	if data.Currency == "" {
+		data.Currency = models.BaseCurrency
+	}
+
+	if err := ds.validate(data, ctx); err != nil {
+		return 0, err
 	}
 	return ds.repo.Update(data, ctx)
 }
 
+// ConvertPrices converts the prices of the records to the currency in place, with the stored exchange rates.
+// Records read without their currency (not selected) are left as they are.
+func (ds *DataServiceSQLite) ConvertPrices(data []*models.Data, currency string, ctx context.Context) error {
+	if _, ok := models.Currencies[currency]; !ok {
+		return DataError{Message: "Unknown currency: " + currency + "."}
+	}
+
+	rates := map[string]*models.ExchangeRate{}
+	rate := func(currency string) (*models.ExchangeRate, error) {
+		if r, ok := rates[currency]; ok {
+			return r, nil
+		}
+		r, err := ds.rates.ReadOne(currency, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			return nil, models.NoExchangeRateError{Currency: currency}
+		}
+		rates[currency] = r
+		return r, nil
+	}
+
+	to, err := rate(currency)
+	if err != nil {
+		return err
+	}
+	for _, d := range data {
+		if d.Currency == "" || d.Currency == currency {
+			continue
+		}
+		from, err := rate(d.Currency)
+		if err != nil {
+			return err
+		}
+		price, err := models.ConvertPrice(d.Price, from, to)
+		if err != nil {
+			return err
+		}
+		d.Price = price
+		d.Currency = currency
+	}
+	return nil
+}
+
+func (ds *DataServiceSQLite) ExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	return ds.rates.ReadAll(ctx)
+}
+
+func (ds *DataServiceSQLite) SetExchangeRate(rate *models.ExchangeRate, ctx context.Context) error {
+	if _, ok := models.Currencies[rate.Currency]; !ok {
+		return DataError{Message: "Unknown currency: " + rate.Currency + "."}
+	}
+	if rate.Currency == models.BaseCurrency {
+		return DataError{Message: models.BaseCurrency + " is the base currency, its rate is always 1."}
+	}
+	if !(rate.Rate > 0) || math.IsInf(rate.Rate, 0) {
+		return DataError{Message: "Rate must be a positive number."}
+	}
+	return ds.rates.Save(rate, ctx)
+}
+
 func (ds *DataServiceSQLite) Delete(data *models.Data, ctx context.Context) (int64, error) {
 	return ds.repo.Delete(data, ctx)
 }
 
+// Restore takes a deleted record out of the trash, 0 if it is not in the trash.
+func (ds *DataServiceSQLite) Restore(id int, ctx context.Context) (int64, error) {
+	return ds.repo.Restore(id, ctx)
+}
+
+// AddTags tags a record, 0 if it does not exist. A version other than 0 must be the stored one.
+func (ds *DataServiceSQLite) AddTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	return ds.repo.AddTags(id, tags, version, ctx)
+}
+
+// RemoveTags removes tags from a record, 0 if it does not exist. A version other than 0 must be the stored one.
+func (ds *DataServiceSQLite) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	return ds.repo.RemoveTags(id, tags, version, ctx)
+}
+
+// maxTagsPerRequest limits how many tags one request can add or remove.
+const maxTagsPerRequest = 20
+
+// normalizeTags validates the tags of a request, in lower case and without duplicates.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 || len(tags) > maxTagsPerRequest {
+		return nil, DataError{Message: "Tags are required, at most " + strconv.Itoa(maxTagsPerRequest) + " at a time."}
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		n, ok := models.NormalizeTag(tag)
+		if !ok {
+			return nil, DataError{Message: "Invalid tag: " + tag + ", use up to " + strconv.Itoa(models.MaxTagLength) + " letters, digits, -, _, ., : and /."}
+		}
+		if !slices.Contains(normalized, n) {
+			normalized = append(normalized, n)
+		}
+	}
+	return normalized, nil
+}
+
+func (ds *DataServiceSQLite) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedData, error) {
+	return ds.repo.ReadTrash(page, rowsPerPage, ctx)
+}
+
+func (ds *DataServiceSQLite) CountTrash(ctx context.Context) (int, error) {
+	return ds.repo.CountTrash(ctx)
+}
+
+// Purge permanently removes the records that were deleted before the given time, with their attachments.
+func (ds *DataServiceSQLite) Purge(before time.Time, ctx context.Context) (int64, error) {
+	n, err := ds.repo.Purge(before, ctx)
+	if err != nil {
+		return n, err
+	}
+	// * Orphans of an earlier run that failed are deleted too
+	return n, ds.deleteOrphanedAttachments(ctx)
+}
+
+// ValidateData checks the fields every record has, whatever its type.
 func (ds *DataServiceSQLite) ValidateData(data *models.Data) error {
-	var errMsg string
+	var errs []jsonschema.FieldError
+	fail := func(field string, message string) {
+		errs = append(errs, jsonschema.FieldError{Field: field, Message: message})
+	}
 	if data.DeviceID == "" || len(data.DeviceID) > 50 {
-		errMsg += "DeviceID is required and must be less than 50 characters. "
+		fail("device_id", "is required and must be less than 50 characters")
 	}
 	if len(data.DeviceName) > 50 {
-		errMsg += "DeviceName must be less than 50 characters. "
+		fail("device_name", "must be less than 50 characters")
+	}
+	if _, ok := models.Currencies[data.Currency]; !ok {
+		fail("currency", "must be a supported ISO 4217 code, e.g. EUR or USD")
+	}
+	if len(data.SerialNumber) > 50 {
+		fail("serial_number", "must be less than 50 characters")
 	}
 	if len(data.Type) > 20 {
-		errMsg += "Type must be less than 20 characters. "
+		fail("type", "must be less than 20 characters")
 	}
 	if len(data.Description) > 100 {
-		errMsg += "Description must be less than 100 characters. "
+		fail("description", "must be less than 100 characters")
 	}
 	_, err := time.Parse("2006-01-02T15:04:05Z", data.DateTime)
 	if err != nil {
-		errMsg += "DateTime must be in the format: 2021-01-01T12:00:00Z. "
+		fail("date_time", "must be in the format: 2021-01-01T12:00:00Z")
+	}
+	if len(errs) > 0 {
+		return ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validate checks the common fields and the attributes against the JSON Schema of the type, when the type is registered.
+// Records of types that are not registered can have any attributes.
+func (ds *DataServiceSQLite) validate(data *models.Data, ctx context.Context) error {
+	var errs []jsonschema.FieldError
+	if err := ds.ValidateData(data); err != nil {
+		errs = err.(ValidationError).Errors
+	}
+
+	dataType, err := ds.types.ReadOne(data.Type, ctx)
+	if err != nil {
+		return err
+	}
+	if dataType != nil {
+		schema, err := jsonschema.Compile(dataType.Schema)
+		if err != nil {
+			return err
+		}
+		// * Records without attributes are validated as an empty object, so required attributes are reported
+		attributes := map[string]any(data.Attributes)
+		if attributes == nil {
+			attributes = map[string]any{}
+		}
+		errs = append(errs, schema.Validate(attributes, "attributes")...)
+	}
+
+	if len(errs) > 0 {
+		return ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func (ds *DataServiceSQLite) DataTypes(ctx context.Context) ([]*models.DataType, error) {
+	return ds.types.ReadAll(ctx)
+}
+
+func (ds *DataServiceSQLite) DataType(name string, ctx context.Context) (*models.DataType, error) {
+	return ds.types.ReadOne(name, ctx)
+}
+
+func (ds *DataServiceSQLite) CreateDataType(dataType *models.DataType, ctx context.Context) error {
+	if err := validateDataType(dataType); err != nil {
+		return err
+	}
+	return ds.types.Create(dataType, ctx)
+}
+
+// UpdateDataType replaces the description and schema of a type, records stored before are not validated again.
+func (ds *DataServiceSQLite) UpdateDataType(dataType *models.DataType, ctx context.Context) (int64, error) {
+	if err := validateDataType(dataType); err != nil {
+		return 0, err
+	}
+	return ds.types.Update(dataType, ctx)
+}
+
+func (ds *DataServiceSQLite) DeleteDataType(name string, ctx context.Context) (int64, error) {
+	return ds.types.Delete(name, ctx)
+}
+
+func validateDataType(dataType *models.DataType) error {
+	if dataType.Name == "" || len(dataType.Name) > 20 {
+		return DataError{Message: "Name is required and must be less than 20 characters."}
+	}
+	if len(dataType.Schema) == 0 {
+		return DataError{Message: "Schema is required."}
 	}
-	if errMsg != "" {
-		return DataError{Message: errMsg}
+	if _, err := jsonschema.Compile(dataType.Schema); err != nil {
+		return DataError{Message: "Invalid schema: " + err.Error() + "."}
 	}
 	return nil
 }
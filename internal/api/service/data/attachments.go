@@ -0,0 +1,139 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"goapi/internal/api/repository/models"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// * AttachmentLimits of uploads, larger files and files of other types are rejected *
+type AttachmentLimits struct {
+	MaxSize      int64
+	ContentTypes []string
+}
+
+// * DefaultAttachmentLimits allow photos, PDFs (datasheets, invoices) and text files of up to 10 MiB *
+var DefaultAttachmentLimits = AttachmentLimits{
+	MaxSize:      10 << 20,
+	ContentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf", "text/plain", "text/csv"},
+}
+
+// CreateAttachment stores a file for a record, 0 if the record does not exist.
+// The type of the file is detected from its content, the type the client sent is only used to tell text files apart.
+func (ds *DataServiceSQLite) CreateAttachment(attachment *models.Attachment, content io.Reader, ctx context.Context) (int64, error) {
+	attachment.FileName = strings.TrimSpace(attachment.FileName)
+	if attachment.FileName == "" || len(attachment.FileName) > 255 || !utf8.ValidString(attachment.FileName) {
+		return 0, DataError{Message: "File name is required and must be less than 255 characters."}
+	}
+
+	data, err := ds.repo.ReadOne(attachment.DataID, ctx)
+	if err != nil || data == nil {
+		return 0, err
+	}
+
+	buffered := bufio.NewReaderSize(content, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return 0, err
+	}
+	attachment.ContentType = detectContentType(head, attachment.ContentType)
+	if !slices.Contains(ds.limits.ContentTypes, attachment.ContentType) {
+		return 0, DataError{Message: "Unsupported file type: " + attachment.ContentType + ", allowed are " + strings.Join(ds.limits.ContentTypes, ", ") + "."}
+	}
+
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return 0, err
+	}
+	attachment.BlobKey = hex.EncodeToString(key)
+
+	// * One byte more than the limit is read, to tell a file of exactly the limit from a larger one
+	size, sum, err := ds.blobs.Create(attachment.BlobKey, io.LimitReader(buffered, ds.limits.MaxSize+1))
+	if err != nil {
+		return 0, err
+	}
+	if size > ds.limits.MaxSize {
+		ds.blobs.Delete(attachment.BlobKey)
+		return 0, models.ErrAttachmentTooLarge
+	}
+	attachment.Size, attachment.SHA256 = size, sum
+
+	if err := ds.attachments.Create(attachment, ctx); err != nil {
+		ds.blobs.Delete(attachment.BlobKey)
+		return 0, err
+	}
+	return 1, nil
+}
+
+// detectContentType sniffs the media type of the content, text is text/plain unless the client said which text it is.
+func detectContentType(head []byte, declared string) string {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if declared, _, err := mime.ParseMediaType(declared); err == nil && detected == "text/plain" && strings.HasPrefix(declared, "text/") {
+		return declared
+	}
+	return detected
+}
+
+// Attachments lists the files of a record, nil if the record does not exist.
+func (ds *DataServiceSQLite) Attachments(dataID int, ctx context.Context) ([]*models.Attachment, error) {
+	data, err := ds.repo.ReadOne(dataID, ctx)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return ds.attachments.ReadAll(dataID, ctx)
+}
+
+// Attachment returns a file of a record with its content, the caller closes it. nil if there is none.
+func (ds *DataServiceSQLite) Attachment(dataID int, id int, ctx context.Context) (*models.Attachment, io.ReadSeekCloser, error) {
+	data, err := ds.repo.ReadOne(dataID, ctx)
+	if err != nil || data == nil {
+		return nil, nil, err
+	}
+	attachment, err := ds.attachments.ReadOne(dataID, id, ctx)
+	if err != nil || attachment == nil {
+		return nil, nil, err
+	}
+	content, err := ds.blobs.Open(attachment.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment removes a file of a record, 0 if there is none.
+func (ds *DataServiceSQLite) DeleteAttachment(dataID int, id int, ctx context.Context) (int64, error) {
+	data, err := ds.repo.ReadOne(dataID, ctx)
+	if err != nil || data == nil {
+		return 0, err
+	}
+	attachment, err := ds.attachments.Delete(dataID, id, ctx)
+	if err != nil || attachment == nil {
+		return 0, err
+	}
+	return 1, ds.blobs.Delete(attachment.BlobKey)
+}
+
+// deleteOrphanedAttachments removes the files of the records that were purged.
+func (ds *DataServiceSQLite) deleteOrphanedAttachments(ctx context.Context) error {
+	orphans, err := ds.attachments.DeleteOrphans(ctx)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range orphans {
+		if err := ds.blobs.Delete(attachment.BlobKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,15 +3,48 @@ package data
 import (
 	"context"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"goapi/internal/api/service/jsonschema"
+	"io"
+	"strings"
+	"time"
 )
 
 type DataService interface {
 	Create(data *models.Data, ctx context.Context) error
 	ReadOne(id int, ctx context.Context) (*models.Data, error)
+	ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error)
 	ReadMany(page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error)
+	ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error)
+	Count(ctx context.Context) (int, error)
+	Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error)
+	CountQuery(q *query.Query, ctx context.Context) (int, error)
+	Stats(ctx context.Context) (*models.DataStats, error)
+	ChangeMarker(ctx context.Context) (*models.ChangeMarker, error)
+	History(id int, ctx context.Context) ([]*models.DataVersion, error)
+	ReadAsOf(id int, asOf time.Time, ctx context.Context) (*models.Data, error)
+	Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error)
+	ConvertPrices(data []*models.Data, currency string, ctx context.Context) error
+	ExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error)
+	SetExchangeRate(rate *models.ExchangeRate, ctx context.Context) error
 	Update(data *models.Data, ctx context.Context) (int64, error)
 	Delete(data *models.Data, ctx context.Context) (int64, error)
+	Restore(id int, ctx context.Context) (int64, error)
+	AddTags(id int, tags []string, version int, ctx context.Context) (int64, error)
+	RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error)
+	ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedData, error)
+	CountTrash(ctx context.Context) (int, error)
+	Purge(before time.Time, ctx context.Context) (int64, error)
 	ValidateData(data *models.Data) error
+	DataTypes(ctx context.Context) ([]*models.DataType, error)
+	DataType(name string, ctx context.Context) (*models.DataType, error)
+	CreateDataType(dataType *models.DataType, ctx context.Context) error
+	UpdateDataType(dataType *models.DataType, ctx context.Context) (int64, error)
+	DeleteDataType(name string, ctx context.Context) (int64, error)
+	CreateAttachment(attachment *models.Attachment, content io.Reader, ctx context.Context) (int64, error)
+	Attachments(dataID int, ctx context.Context) ([]*models.Attachment, error)
+	Attachment(dataID int, id int, ctx context.Context) (*models.Attachment, io.ReadSeekCloser, error)
+	DeleteAttachment(dataID int, id int, ctx context.Context) (int64, error)
 }
 
 type DataError struct {
@@ -21,3 +54,16 @@ type DataError struct {
 func (de DataError) Error() string {
 	return de.Message
 }
+
+// * ValidationError lists every field of a record that is not valid *
+type ValidationError struct {
+	Errors []jsonschema.FieldError
+}
+
+func (ve ValidationError) Error() string {
+	msgs := make([]string, len(ve.Errors))
+	for i, e := range ve.Errors {
+		msgs[i] = e.Field + " " + e.Message
+	}
+	return "Invalid data: " + strings.Join(msgs, ", ") + "."
+}
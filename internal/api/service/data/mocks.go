@@ -3,6 +3,10 @@ package data
 import (
 	"context"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"io"
+	"strings"
+	"time"
 )
 
 // * Mock implementation of DataService for testing purposes, always returns a successful response and Data object(s) *
@@ -15,7 +19,8 @@ func (m *MockDataServiceSuccessful) ReadMany(page int, rowsPerPage int, ctx cont
 			DeviceID:     "device1",
 			DeviceName:   "device1",
 			Price:        1000, //Added price
-			SerialNumber: 12689,
+			Currency:     "EUR",
+			SerialNumber: "12689",
 			Type:         "type1",
 			DateTime:     "2021-01-01 00:00:00",
 			Description:  "description1",
@@ -25,7 +30,8 @@ func (m *MockDataServiceSuccessful) ReadMany(page int, rowsPerPage int, ctx cont
 			DeviceID:     "device2",
 			DeviceName:   "device2",
 			Price:        52300, //Added price
-			SerialNumber: 6225965,
+			Currency:     "USD",
+			SerialNumber: "0006225965",
 			Type:         "type2",
 			DateTime:     "2021-01-01 00:00:00",
 			Description:  "description2",
@@ -33,23 +39,167 @@ func (m *MockDataServiceSuccessful) ReadMany(page int, rowsPerPage int, ctx cont
 	}, nil
 }
 
+func (m *MockDataServiceSuccessful) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error) {
+	data, _ := m.ReadMany(1, limit, ctx)
+	return data, &models.Cursor{DateTime: data[len(data)-1].DateTime, ID: data[len(data)-1].ID}, nil
+}
+
+func (m *MockDataServiceSuccessful) Count(ctx context.Context) (int, error) {
+	return 2, nil
+}
+
+func (m *MockDataServiceSuccessful) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	return m.ReadMany(page, rowsPerPage, ctx)
+}
+
+func (m *MockDataServiceSuccessful) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	return 2, nil
+}
+
+func (m *MockDataServiceSuccessful) Stats(ctx context.Context) (*models.DataStats, error) {
+	return &models.DataStats{
+		Total: 2,
+		ByType: []*models.TypeStats{
+			{Type: "type1", Count: 1, Prices: []*models.PriceStats{{Currency: "EUR", Count: 1, Total: 1000, Average: 1000}}},
+			{Type: "type2", Count: 1, Prices: []*models.PriceStats{{Currency: "USD", Count: 1, Total: 52300, Average: 52300}}},
+		},
+		ByDevice: []*models.DeviceStats{
+			{DeviceID: "device1", DeviceName: "device1", Count: 1, Prices: []*models.PriceStats{{Currency: "EUR", Count: 1, Total: 1000, Average: 1000}}},
+			{DeviceID: "device2", DeviceName: "device2", Count: 1, Prices: []*models.PriceStats{{Currency: "USD", Count: 1, Total: 52300, Average: 52300}}},
+		},
+		ByMonth: []*models.MonthCount{{Month: "2021-01", Count: 2}},
+	}, nil
+}
+
+func (m *MockDataServiceSuccessful) CreateAttachment(attachment *models.Attachment, content io.Reader, ctx context.Context) (int64, error) {
+	body, _ := io.ReadAll(content)
+	attachment.ID = 1
+	attachment.ContentType = "text/plain"
+	attachment.Size = int64(len(body))
+	attachment.CreatedAt = "2021-01-01T00:00:00.000000Z"
+	attachment.CreatedBy = "prakash"
+	return 1, nil
+}
+
+func (m *MockDataServiceSuccessful) Attachments(dataID int, ctx context.Context) ([]*models.Attachment, error) {
+	attachment, _, _ := m.Attachment(dataID, 1, ctx)
+	return []*models.Attachment{attachment}, nil
+}
+
+func (m *MockDataServiceSuccessful) Attachment(dataID int, id int, ctx context.Context) (*models.Attachment, io.ReadSeekCloser, error) {
+	return &models.Attachment{
+		ID:          id,
+		DataID:      dataID,
+		FileName:    "notes.txt",
+		ContentType: "text/plain",
+		Size:        11,
+		SHA256:      "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		CreatedAt:   "2021-01-01T00:00:00.000000Z",
+		CreatedBy:   "prakash",
+	}, nopSeekCloser{strings.NewReader("hello world")}, nil
+}
+
+func (m *MockDataServiceSuccessful) DeleteAttachment(dataID int, id int, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+// * nopSeekCloser is the content of a mocked attachment *
+type nopSeekCloser struct {
+	*strings.Reader
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+func (m *MockDataServiceSuccessful) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return &models.ChangeMarker{Version: 1, ChangedAt: "2021-01-01T00:00:00Z"}, nil
+}
+
+func (m *MockDataServiceSuccessful) Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	data, _ := m.ReadMany(page, rowsPerPage, ctx)
+	results := make([]*models.DataSearchResult, len(data))
+	for i, d := range data {
+		results[i] = &models.DataSearchResult{Data: *d, Rank: -float64(len(data) - i), Snippet: "<mark>" + d.DeviceName + "</mark>"}
+	}
+	return results, len(results), nil
+}
+
+func (m *MockDataServiceSuccessful) History(id int, ctx context.Context) ([]*models.DataVersion, error) {
+	current, _ := m.ReadOne(id, ctx)
+	created := *current
+	created.Price = 900
+	return []*models.DataVersion{
+		{HistoryID: 1, Operation: models.OperationCreate, ChangedAt: "2021-01-01T00:00:00Z", ChangedBy: "prakash", ChangedFields: []string{"device_id", "device_name", "price", "serial_number", "type", "date_time", "description"}, Data: created},
+		{HistoryID: 2, Operation: models.OperationUpdate, ChangedAt: "2021-04-01T00:00:00Z", ChangedBy: "prakash", ChangedFields: []string{"price"}, Data: *current},
+	}, nil
+}
+
+func (m *MockDataServiceSuccessful) ReadAsOf(id int, asOf time.Time, ctx context.Context) (*models.Data, error) {
+	return m.ReadOne(id, ctx)
+}
+
 func (m *MockDataServiceSuccessful) ReadOne(id int, ctx context.Context) (*models.Data, error) {
 	return &models.Data{
 		ID:           1,
 		DeviceID:     "device1",
 		DeviceName:   "device1",
 		Price:        1000, //Added price
-		SerialNumber: 12689,
+		Currency:     "EUR",
+		SerialNumber: "12689",
 		Type:         "type1",
 		DateTime:     "2021-01-01 00:00:00",
 		Description:  "description1",
+		Version:      1,
+		CreatedAt:    "2021-01-01T00:00:00.000000Z",
+		UpdatedAt:    "2021-01-01T00:00:00.000000Z",
+		Tags:         []string{"project:apollo"},
 	}, nil
 }
 
+func (m *MockDataServiceSuccessful) ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error) {
+	return m.ReadOne(1, ctx)
+}
+
 func (m *MockDataServiceSuccessful) Create(data *models.Data, ctx context.Context) error {
 	return nil
 }
 
+// * Converts with fixed rates, 1 EUR buys 1.25 USD *
+var mockExchangeRates = []*models.ExchangeRate{
+	{Currency: "EUR", Rate: 1, UpdatedAt: "2021-01-01T00:00:00Z"},
+	{Currency: "USD", Rate: 1.25, UpdatedAt: "2021-01-01T00:00:00Z"},
+}
+
+func (m *MockDataServiceSuccessful) ConvertPrices(data []*models.Data, currency string, ctx context.Context) error {
+	rates := map[string]*models.ExchangeRate{}
+	for _, r := range mockExchangeRates {
+		rates[r.Currency] = r
+	}
+	to, ok := rates[currency]
+	if !ok {
+		return DataError{Message: "Unknown currency: " + currency + "."}
+	}
+	for _, d := range data {
+		price, err := models.ConvertPrice(d.Price, rates[d.Currency], to)
+		if err != nil {
+			return err
+		}
+		d.Price = price
+		d.Currency = currency
+	}
+	return nil
+}
+
+func (m *MockDataServiceSuccessful) ExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	return mockExchangeRates, nil
+}
+
+func (m *MockDataServiceSuccessful) SetExchangeRate(rate *models.ExchangeRate, ctx context.Context) error {
+	rate.UpdatedAt = "2021-01-01T00:00:00Z"
+	return nil
+}
+
 func (m *MockDataServiceSuccessful) Update(data *models.Data, ctx context.Context) (int64, error) {
 	return 1, nil
 }
@@ -58,10 +208,64 @@ func (m *MockDataServiceSuccessful) Delete(data *models.Data, ctx context.Contex
 	return 1, nil
 }
 
+func (m *MockDataServiceSuccessful) AddTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDataServiceSuccessful) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDataServiceSuccessful) Restore(id int, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDataServiceSuccessful) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedData, error) {
+	data, _ := m.ReadOne(1, ctx)
+	return []*models.TrashedData{
+		{Data: *data, DeletedAt: "2021-02-01T00:00:00.000000Z", DeletedBy: "prakash"},
+	}, nil
+}
+
+func (m *MockDataServiceSuccessful) CountTrash(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+func (m *MockDataServiceSuccessful) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
 func (m *MockDataServiceSuccessful) ValidateData(data *models.Data) error {
 	return nil
 }
 
+// * The registered type of the mock, its records need a supply voltage *
+var mockDataType = &models.DataType{
+	Name:        "sensor",
+	Description: "Sensors on the I2C bus",
+	Schema:      []byte(`{"type":"object","required":["voltage"],"properties":{"voltage":{"type":"number","minimum":1.8,"maximum":5.5}}}`),
+}
+
+func (m *MockDataServiceSuccessful) DataTypes(ctx context.Context) ([]*models.DataType, error) {
+	return []*models.DataType{mockDataType}, nil
+}
+
+func (m *MockDataServiceSuccessful) DataType(name string, ctx context.Context) (*models.DataType, error) {
+	return mockDataType, nil
+}
+
+func (m *MockDataServiceSuccessful) CreateDataType(dataType *models.DataType, ctx context.Context) error {
+	return nil
+}
+
+func (m *MockDataServiceSuccessful) UpdateDataType(dataType *models.DataType, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDataServiceSuccessful) DeleteDataType(name string, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
 // * Mock implementation of DataService for testing purposes, always returns empty data *
 
 type MockDataServiceNotFound struct{}
@@ -70,14 +274,82 @@ func (m *MockDataServiceNotFound) ReadMany(page int, rowsPerPage int, ctx contex
 	return []*models.Data{}, nil
 }
 
+func (m *MockDataServiceNotFound) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error) {
+	return []*models.Data{}, nil, nil
+}
+
+func (m *MockDataServiceNotFound) Count(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	return []*models.Data{}, nil
+}
+
+func (m *MockDataServiceNotFound) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) Stats(ctx context.Context) (*models.DataStats, error) {
+	return &models.DataStats{ByType: []*models.TypeStats{}, ByDevice: []*models.DeviceStats{}, ByMonth: []*models.MonthCount{}}, nil
+}
+
+func (m *MockDataServiceNotFound) CreateAttachment(attachment *models.Attachment, content io.Reader, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) Attachments(dataID int, ctx context.Context) ([]*models.Attachment, error) {
+	return nil, nil
+}
+
+func (m *MockDataServiceNotFound) Attachment(dataID int, id int, ctx context.Context) (*models.Attachment, io.ReadSeekCloser, error) {
+	return nil, nil, nil
+}
+
+func (m *MockDataServiceNotFound) DeleteAttachment(dataID int, id int, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return &models.ChangeMarker{Version: 1, ChangedAt: "2021-01-01T00:00:00Z"}, nil
+}
+
+func (m *MockDataServiceNotFound) Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	return []*models.DataSearchResult{}, 0, nil
+}
+
+func (m *MockDataServiceNotFound) History(id int, ctx context.Context) ([]*models.DataVersion, error) {
+	return []*models.DataVersion{}, nil
+}
+
+func (m *MockDataServiceNotFound) ReadAsOf(id int, asOf time.Time, ctx context.Context) (*models.Data, error) {
+	return nil, nil
+}
+
 func (m *MockDataServiceNotFound) ReadOne(id int, ctx context.Context) (*models.Data, error) {
 	return nil, nil
 }
 
+func (m *MockDataServiceNotFound) ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error) {
+	return nil, nil
+}
+
 func (m *MockDataServiceNotFound) Create(data *models.Data, ctx context.Context) error {
 	return nil
 }
 
+func (m *MockDataServiceNotFound) ConvertPrices(data []*models.Data, currency string, ctx context.Context) error {
+	return nil
+}
+
+func (m *MockDataServiceNotFound) ExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	return []*models.ExchangeRate{}, nil
+}
+
+func (m *MockDataServiceNotFound) SetExchangeRate(rate *models.ExchangeRate, ctx context.Context) error {
+	return nil
+}
+
 func (m *MockDataServiceNotFound) Update(data *models.Data, ctx context.Context) (int64, error) {
 	return 0, nil
 }
@@ -86,10 +358,54 @@ func (m *MockDataServiceNotFound) Delete(data *models.Data, ctx context.Context)
 	return 0, nil
 }
 
+func (m *MockDataServiceNotFound) AddTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) Restore(id int, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedData, error) {
+	return []*models.TrashedData{}, nil
+}
+
+func (m *MockDataServiceNotFound) CountTrash(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
 func (m *MockDataServiceNotFound) ValidateData(data *models.Data) error {
 	return nil
 }
 
+func (m *MockDataServiceNotFound) DataTypes(ctx context.Context) ([]*models.DataType, error) {
+	return []*models.DataType{}, nil
+}
+
+func (m *MockDataServiceNotFound) DataType(name string, ctx context.Context) (*models.DataType, error) {
+	return nil, nil
+}
+
+func (m *MockDataServiceNotFound) CreateDataType(dataType *models.DataType, ctx context.Context) error {
+	return nil
+}
+
+func (m *MockDataServiceNotFound) UpdateDataType(dataType *models.DataType, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDataServiceNotFound) DeleteDataType(name string, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
 // * Mock implementation of DataService for testing purposes, always returns an error *
 type MockDataServiceError struct{}
 
@@ -97,14 +413,82 @@ func (m *MockDataServiceError) ReadMany(page int, rowsPerPage int, ctx context.C
 	return nil, DataError{Message: "Error reading data."}
 }
 
+func (m *MockDataServiceError) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.Data, *models.Cursor, error) {
+	return nil, nil, DataError{Message: "Error reading data."}
+}
+
+func (m *MockDataServiceError) Count(ctx context.Context) (int, error) {
+	return 0, DataError{Message: "Error counting data."}
+}
+
+func (m *MockDataServiceError) Query(q *query.Query, page int, rowsPerPage int, ctx context.Context) ([]*models.Data, error) {
+	return nil, DataError{Message: "Error reading data."}
+}
+
+func (m *MockDataServiceError) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	return 0, DataError{Message: "Error counting data."}
+}
+
+func (m *MockDataServiceError) Stats(ctx context.Context) (*models.DataStats, error) {
+	return nil, DataError{Message: "Error reading data statistics."}
+}
+
+func (m *MockDataServiceError) CreateAttachment(attachment *models.Attachment, content io.Reader, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error attaching file."}
+}
+
+func (m *MockDataServiceError) Attachments(dataID int, ctx context.Context) ([]*models.Attachment, error) {
+	return nil, DataError{Message: "Error reading attachments."}
+}
+
+func (m *MockDataServiceError) Attachment(dataID int, id int, ctx context.Context) (*models.Attachment, io.ReadSeekCloser, error) {
+	return nil, nil, DataError{Message: "Error reading attachment."}
+}
+
+func (m *MockDataServiceError) DeleteAttachment(dataID int, id int, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error deleting attachment."}
+}
+
+func (m *MockDataServiceError) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return nil, DataError{Message: "Error reading changes."}
+}
+
+func (m *MockDataServiceError) Search(text string, page int, rowsPerPage int, ctx context.Context) ([]*models.DataSearchResult, int, error) {
+	return nil, 0, DataError{Message: "Error searching data."}
+}
+
+func (m *MockDataServiceError) History(id int, ctx context.Context) ([]*models.DataVersion, error) {
+	return nil, DataError{Message: "Error reading data history."}
+}
+
+func (m *MockDataServiceError) ReadAsOf(id int, asOf time.Time, ctx context.Context) (*models.Data, error) {
+	return nil, DataError{Message: "Error reading data."}
+}
+
 func (m *MockDataServiceError) ReadOne(id int, ctx context.Context) (*models.Data, error) {
 	return nil, DataError{Message: "Error reading data."}
 }
 
+func (m *MockDataServiceError) ReadBySerialNumber(serialNumber string, ctx context.Context) (*models.Data, error) {
+	return nil, DataError{Message: "Error reading data."}
+}
+
 func (m *MockDataServiceError) Create(data *models.Data, ctx context.Context) error {
 	return DataError{Message: "Error creating data."}
 }
 
+func (m *MockDataServiceError) ConvertPrices(data []*models.Data, currency string, ctx context.Context) error {
+	return DataError{Message: "Error converting prices."}
+}
+
+func (m *MockDataServiceError) ExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	return nil, DataError{Message: "Error reading exchange rates."}
+}
+
+func (m *MockDataServiceError) SetExchangeRate(rate *models.ExchangeRate, ctx context.Context) error {
+	return DataError{Message: "Error saving exchange rate."}
+}
+
 func (m *MockDataServiceError) Update(data *models.Data, ctx context.Context) (int64, error) {
 	return 0, DataError{Message: "Error updating data."}
 }
@@ -113,6 +497,50 @@ func (m *MockDataServiceError) Delete(data *models.Data, ctx context.Context) (i
 	return 0, DataError{Message: "Error deleting data."}
 }
 
+func (m *MockDataServiceError) AddTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error tagging data."}
+}
+
+func (m *MockDataServiceError) RemoveTags(id int, tags []string, version int, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error untagging data."}
+}
+
+func (m *MockDataServiceError) Restore(id int, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error restoring data."}
+}
+
+func (m *MockDataServiceError) ReadTrash(page int, rowsPerPage int, ctx context.Context) ([]*models.TrashedData, error) {
+	return nil, DataError{Message: "Error reading trash."}
+}
+
+func (m *MockDataServiceError) CountTrash(ctx context.Context) (int, error) {
+	return 0, DataError{Message: "Error reading trash."}
+}
+
+func (m *MockDataServiceError) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error purging trash."}
+}
+
 func (m *MockDataServiceError) ValidateData(data *models.Data) error {
 	return nil
 }
+
+func (m *MockDataServiceError) DataTypes(ctx context.Context) ([]*models.DataType, error) {
+	return nil, DataError{Message: "Error reading data types."}
+}
+
+func (m *MockDataServiceError) DataType(name string, ctx context.Context) (*models.DataType, error) {
+	return nil, DataError{Message: "Error reading data type."}
+}
+
+func (m *MockDataServiceError) CreateDataType(dataType *models.DataType, ctx context.Context) error {
+	return DataError{Message: "Error creating data type."}
+}
+
+func (m *MockDataServiceError) UpdateDataType(dataType *models.DataType, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error updating data type."}
+}
+
+func (m *MockDataServiceError) DeleteDataType(name string, ctx context.Context) (int64, error) {
+	return 0, DataError{Message: "Error deleting data type."}
+}
@@ -3,11 +3,19 @@ package dht22
 import (
 	"context"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"time"
 )
 
 type MockDHT22ServiceSuccessful struct{}
 
 func (m *MockDHT22ServiceSuccessful) Create(data *models.DHT22Data, ctx context.Context) error {
+	data.ID = 1
+	data.Version = 1
+	return nil
+}
+
+func (m *MockDHT22ServiceSuccessful) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
 	return nil
 }
 
@@ -18,6 +26,9 @@ func (m *MockDHT22ServiceSuccessful) ReadOne(id int, ctx context.Context) (*mode
 		Temperature: 10,
 		Humidity:    23.0,
 		DateTime:    "2024-12-22T12:00:00Z",
+		Version:     1,
+		CreatedAt:   "2024-12-22T12:00:00.000000Z",
+		UpdatedAt:   "2024-12-22T12:00:00.000000Z",
 	}, nil
 }
 
@@ -40,6 +51,27 @@ func (m *MockDHT22ServiceSuccessful) ReadMany(page, rowsPerPage int, ctx context
 	}, nil
 }
 
+func (m *MockDHT22ServiceSuccessful) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	data, _ := m.ReadMany(1, limit, ctx)
+	return data, &models.Cursor{DateTime: data[len(data)-1].DateTime, ID: data[len(data)-1].ID}, nil
+}
+
+func (m *MockDHT22ServiceSuccessful) Count(ctx context.Context) (int, error) {
+	return 2, nil
+}
+
+func (m *MockDHT22ServiceSuccessful) Query(q *query.Query, page, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	return m.ReadMany(page, rowsPerPage, ctx)
+}
+
+func (m *MockDHT22ServiceSuccessful) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	return 2, nil
+}
+
+func (m *MockDHT22ServiceSuccessful) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return &models.ChangeMarker{Version: 1, ChangedAt: "2021-01-01T00:00:00Z"}, nil
+}
+
 func (m *MockDHT22ServiceSuccessful) Update(data *models.DHT22Data, ctx context.Context) error {
 	return nil
 }
@@ -48,6 +80,36 @@ func (m *MockDHT22ServiceSuccessful) Delete(data *models.DHT22Data, ctx context.
 	return nil
 }
 
+func (m *MockDHT22ServiceSuccessful) Restore(id int, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDHT22ServiceSuccessful) ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedDHT22Data, error) {
+	data, _ := m.ReadOne(1, ctx)
+	return []*models.TrashedDHT22Data{
+		{DHT22Data: *data, DeletedAt: "2021-02-01T00:00:00.000000Z", DeletedBy: "prakash"},
+	}, nil
+}
+
+func (m *MockDHT22ServiceSuccessful) CountTrash(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+func (m *MockDHT22ServiceSuccessful) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDHT22ServiceSuccessful) Forecast(deviceName string, horizon time.Duration, ctx context.Context) (*models.DHT22Forecast, error) {
+	return &models.DHT22Forecast{
+		DeviceName:   deviceName,
+		Horizon:      horizon.String(),
+		ForecastTime: "2024-12-22T13:00:00Z",
+		Samples:      2,
+		Temperature:  models.ForecastValue{Predicted: 26.1, Lower: 25.1, Upper: 27.1, SlopePerHour: 1.8},
+		Humidity:     models.ForecastValue{Predicted: 60.0, Lower: 55.0, Upper: 65.0, SlopePerHour: 5.0},
+	}, nil
+}
+
 // MockDHT22ServiceNotFound: Simulates not found responses
 type MockDHT22ServiceNotFound struct{}
 
@@ -55,6 +117,10 @@ func (m *MockDHT22ServiceNotFound) Create(data *models.DHT22Data, ctx context.Co
 	return nil
 }
 
+func (m *MockDHT22ServiceNotFound) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	return nil
+}
+
 func (m *MockDHT22ServiceNotFound) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
 	return nil, nil
 }
@@ -63,6 +129,26 @@ func (m *MockDHT22ServiceNotFound) ReadMany(page, rowsPerPage int, ctx context.C
 	return []*models.DHT22Data{}, nil
 }
 
+func (m *MockDHT22ServiceNotFound) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	return []*models.DHT22Data{}, nil, nil
+}
+
+func (m *MockDHT22ServiceNotFound) Count(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDHT22ServiceNotFound) Query(q *query.Query, page, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	return []*models.DHT22Data{}, nil
+}
+
+func (m *MockDHT22ServiceNotFound) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDHT22ServiceNotFound) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return &models.ChangeMarker{Version: 1, ChangedAt: "2021-01-01T00:00:00Z"}, nil
+}
+
 func (m *MockDHT22ServiceNotFound) Update(data *models.DHT22Data, ctx context.Context) error {
 	return nil
 }
@@ -71,6 +157,26 @@ func (m *MockDHT22ServiceNotFound) Delete(data *models.DHT22Data, ctx context.Co
 	return nil
 }
 
+func (m *MockDHT22ServiceNotFound) Restore(id int, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDHT22ServiceNotFound) ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedDHT22Data, error) {
+	return []*models.TrashedDHT22Data{}, nil
+}
+
+func (m *MockDHT22ServiceNotFound) CountTrash(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDHT22ServiceNotFound) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDHT22ServiceNotFound) Forecast(deviceName string, horizon time.Duration, ctx context.Context) (*models.DHT22Forecast, error) {
+	return nil, ErrNotEnoughReadings
+}
+
 // MockDHT22ServiceError: Simulates error responses
 type MockDHT22ServiceError struct{}
 
@@ -78,6 +184,10 @@ func (m *MockDHT22ServiceError) Create(data *models.DHT22Data, ctx context.Conte
 	return DHT22Error("Error creating DHT22 data")
 }
 
+func (m *MockDHT22ServiceError) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	return DHT22Error("Error creating DHT22 data")
+}
+
 func (m *MockDHT22ServiceError) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
 	return nil, DHT22Error("Error reading DHT22 data")
 }
@@ -86,6 +196,26 @@ func (m *MockDHT22ServiceError) ReadMany(page, rowsPerPage int, ctx context.Cont
 	return nil, DHT22Error("Error reading multiple DHT22 data entries")
 }
 
+func (m *MockDHT22ServiceError) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	return nil, nil, DHT22Error("Error reading multiple DHT22 data entries")
+}
+
+func (m *MockDHT22ServiceError) Count(ctx context.Context) (int, error) {
+	return 0, DHT22Error("Error counting DHT22 data")
+}
+
+func (m *MockDHT22ServiceError) Query(q *query.Query, page, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	return nil, DHT22Error("Error reading multiple DHT22 data entries")
+}
+
+func (m *MockDHT22ServiceError) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	return 0, DHT22Error("Error counting DHT22 data")
+}
+
+func (m *MockDHT22ServiceError) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	return nil, DHT22Error("Error reading DHT22 changes")
+}
+
 func (m *MockDHT22ServiceError) Update(data *models.DHT22Data, ctx context.Context) error {
 	return DHT22Error("Error updating DHT22 data")
 }
@@ -93,3 +223,63 @@ func (m *MockDHT22ServiceError) Update(data *models.DHT22Data, ctx context.Conte
 func (m *MockDHT22ServiceError) Delete(data *models.DHT22Data, ctx context.Context) error {
 	return DHT22Error("Error deleting DHT22 data")
 }
+
+func (m *MockDHT22ServiceError) Restore(id int, ctx context.Context) (int64, error) {
+	return 0, DHT22Error("Error restoring DHT22 data")
+}
+
+func (m *MockDHT22ServiceError) ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedDHT22Data, error) {
+	return nil, DHT22Error("Error reading DHT22 trash")
+}
+
+func (m *MockDHT22ServiceError) CountTrash(ctx context.Context) (int, error) {
+	return 0, DHT22Error("Error reading DHT22 trash")
+}
+
+func (m *MockDHT22ServiceError) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 0, DHT22Error("Error purging DHT22 trash")
+}
+
+func (m *MockDHT22ServiceError) Forecast(deviceName string, horizon time.Duration, ctx context.Context) (*models.DHT22Forecast, error) {
+	return nil, DHT22Error("Error forecasting DHT22 data")
+}
+
+// MockArchiveService reads two readings that are out of the database, Archive archives nothing
+type MockArchiveService struct{}
+
+func (m *MockArchiveService) Archive(before time.Time, ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockArchiveService) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	return m.ReadRange(DateTimeRange{}, cursor, limit, ctx)
+}
+
+func (m *MockArchiveService) ReadRange(dateTimes DateTimeRange, cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	var data []*models.DHT22Data
+	for _, d := range mockArchivedReadings() {
+		if dateTimes.contains(models.Cursor{DateTime: d.DateTime, ID: d.ID}) {
+			data = append(data, d)
+		}
+	}
+	if limit < len(data) {
+		return data[:limit], &models.Cursor{DateTime: data[limit-1].DateTime, ID: data[limit-1].ID}, nil
+	}
+	return data, nil, nil
+}
+
+func (m *MockArchiveService) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
+	for _, d := range mockArchivedReadings() {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+func mockArchivedReadings() []*models.DHT22Data {
+	return []*models.DHT22Data{
+		{ID: 1, DeviceName: "DHT22 Sensor 1", Temperature: 18.5, Humidity: 45.0, DateTime: "2019-01-01T10:00:00Z"},
+		{ID: 2, DeviceName: "DHT22 Sensor 1", Temperature: 18.7, Humidity: 46.0, DateTime: "2019-01-01T11:00:00Z"},
+	}
+}
@@ -0,0 +1,430 @@
+package dht22
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ArchiveConfig tells where archived readings are written
+type ArchiveConfig struct {
+	// Dir holds the archive files, a directory per device with a directory per month
+	Dir string
+	// ChunkSize readings are archived in one transaction, a run archives as many chunks as it takes
+	ChunkSize int
+	// FileSize is the most readings written to a file. A page decompresses the file it starts in from the beginning,
+	// so this bounds what a page costs however deep it is
+	FileSize int
+}
+
+var DefaultArchiveConfig = ArchiveConfig{Dir: "archive", ChunkSize: 10000, FileSize: 1000}
+
+// DateTimeRange bounds the date_time of the readings read, compared as text like the positions are. An empty bound is open.
+type DateTimeRange struct {
+	From string
+	// FromExcluded leaves out the readings measured at From
+	FromExcluded bool
+	To           string
+	// ToIncluded also reads the readings measured at To
+	ToIncluded bool
+}
+
+// start is the position the readings of the range are read after, the later of the cursor and the start of the range.
+func (d DateTimeRange) start(cursor *models.Cursor) *models.Cursor {
+	if d.From == "" {
+		return cursor
+	}
+	// * No reading has the id 0, so this is the position right before the first reading at From
+	from := models.Cursor{DateTime: d.From}
+	if cursor != nil && models.ComparePositions(*cursor, from) > 0 {
+		return cursor
+	}
+	return &from
+}
+
+// contains reports whether the reading at the position is in the range.
+func (d DateTimeRange) contains(p models.Cursor) bool {
+	if d.From != "" && (p.DateTime < d.From || d.FromExcluded && p.DateTime == d.From) {
+		return false
+	}
+	return !d.past(p)
+}
+
+// past reports whether the position is after the range, no reading after it is in the range either.
+func (d DateTimeRange) past(p models.Cursor) bool {
+	return d.To != "" && (p.DateTime > d.To || !d.ToIncluded && p.DateTime == d.To)
+}
+
+// ArchiveService moves old readings out of the database into gzip compressed NDJSON files, and reads them back
+type ArchiveService interface {
+	// Archive moves the readings measured before the time into archive files and returns how many were moved
+	Archive(before time.Time, ctx context.Context) (int, error)
+	// ReadAfter is ReadAfter of DHT22Service over the archived and the live readings together
+	ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error)
+	// ReadRange is ReadAfter of the readings measured in the range
+	ReadRange(dateTimes DateTimeRange, cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error)
+	// ReadOne returns an archived reading by its id, nil when no archive file holds it
+	ReadOne(id int, ctx context.Context) (*models.DHT22Data, error)
+}
+
+// archiveService writes a file per device and month for each chunk, the manifest in the database lists them.
+// A file is only read through the manifest, so a file left behind by a run that failed is never read.
+type archiveService struct {
+	repository models.DHT22ArchiveRepository
+	unit       models.UnitOfWork
+	config     ArchiveConfig
+}
+
+// NewArchiveService archives the readings of the database of the unit of work, the repository must be of the same database.
+func NewArchiveService(repository models.DHT22ArchiveRepository, unit models.UnitOfWork, config ArchiveConfig) ArchiveService {
+	if config.Dir == "" {
+		config.Dir = DefaultArchiveConfig.Dir
+	}
+	if config.ChunkSize < 1 {
+		config.ChunkSize = DefaultArchiveConfig.ChunkSize
+	}
+	if config.FileSize < 1 {
+		config.FileSize = DefaultArchiveConfig.FileSize
+	}
+	return &archiveService{repository: repository, unit: unit, config: config}
+}
+
+func (s *archiveService) Archive(before time.Time, ctx context.Context) (int, error) {
+	archived := 0
+	for {
+		n, err := s.archiveChunk(before, ctx)
+		archived += n
+		if err != nil || n < s.config.ChunkSize {
+			return archived, err
+		}
+	}
+}
+
+// archiveChunk reads, writes to files and removes the oldest readings in one unit of work,
+// a reading changed meanwhile can not be lost. The files of a unit that fails are deleted.
+func (s *archiveService) archiveChunk(before time.Time, ctx context.Context) (int, error) {
+	var written []string
+	archived := 0
+	err := s.unit.Do(func(ctx context.Context) error {
+		readings, err := s.repository.ReadLive(nil, before, s.config.ChunkSize, ctx)
+		if err != nil || len(readings) == 0 {
+			return err
+		}
+
+		var archives []*models.DHT22Archive
+		for _, file := range partition(readings, s.config.FileSize) {
+			archive, err := s.writeFile(file)
+			if err != nil {
+				return err
+			}
+			written = append(written, archive.File)
+			archives = append(archives, archive)
+		}
+		ids := make([]int, len(readings))
+		for i, r := range readings {
+			ids[i] = r.ID
+		}
+		if err := s.repository.Archive(archives, ids, ctx); err != nil {
+			return err
+		}
+		archived = len(readings)
+		return nil
+	}, ctx)
+	if err != nil {
+		for _, file := range written {
+			os.Remove(s.path(file))
+		}
+		return 0, err
+	}
+	return archived, nil
+}
+
+// partition groups readings ordered by position by device and month into files of at most size readings,
+// each file stays in order.
+func partition(readings []*models.DHT22ArchiveReading, size int) [][]*models.DHT22ArchiveReading {
+	var files [][]*models.DHT22ArchiveReading
+	index := map[[2]string]int{}
+	for _, r := range readings {
+		key := [2]string{r.DeviceName, archiveMonth(r)}
+		i, ok := index[key]
+		if !ok || len(files[i]) == size {
+			i = len(files)
+			index[key] = i
+			files = append(files, nil)
+		}
+		files[i] = append(files[i], r)
+	}
+	return files
+}
+
+// archiveMonth is the month the reading was measured in UTC, as YYYY-MM.
+func archiveMonth(r *models.DHT22ArchiveReading) string {
+	if t, err := time.Parse(time.RFC3339, r.DateTime); err == nil {
+		return t.UTC().Format("2006-01")
+	}
+	if len(r.DateTime) >= 7 {
+		return r.DateTime[:7]
+	}
+	return "unknown"
+}
+
+// archiveDir turns a device name into a directory name, the manifest keeps the real name.
+func archiveDir(deviceName string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, deviceName)
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+func (s *archiveService) path(file string) string {
+	return filepath.Join(s.config.Dir, filepath.FromSlash(file))
+}
+
+// writeFile writes the readings of a device and month to a new file, named after their ids so no two files have the same name.
+// The file is written to a temporary file first and renamed, so it is never read half written.
+func (s *archiveService) writeFile(readings []*models.DHT22ArchiveReading) (*models.DHT22Archive, error) {
+	first, last := readings[0], readings[len(readings)-1]
+	archive := &models.DHT22Archive{
+		DeviceName: first.DeviceName,
+		Month:      archiveMonth(first),
+		File:       path.Join(archiveDir(first.DeviceName), archiveMonth(first), fmt.Sprintf("%d-%d.ndjson.gz", first.ID, last.ID)),
+		Readings:   len(readings),
+		First:      first.Position,
+		Last:       last.Position,
+		MinID:      first.ID,
+		MaxID:      first.ID,
+	}
+	for _, r := range readings {
+		archive.MinID = min(archive.MinID, r.ID)
+		archive.MaxID = max(archive.MaxID, r.ID)
+	}
+
+	target := s.path(archive.File)
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".archive-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	zw := gzip.NewWriter(io.MultiWriter(tmp, hash))
+	encoder := json.NewEncoder(zw)
+	for _, r := range readings {
+		if err := encoder.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return nil, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, err
+	}
+	archive.Size = info.Size()
+	archive.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return archive, nil
+}
+
+// ReadAfter merges the live readings with the archive files that have readings after the cursor.
+func (s *archiveService) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	return s.ReadRange(DateTimeRange{}, cursor, limit, ctx)
+}
+
+// ReadRange starts at the later of the cursor and the start of the range, so a range deep in the archive is not paged to.
+// The files are ordered by their first reading, a file that starts after the last reading of the page or after the range
+// is not opened, and a file that ends before the start is not listed by the manifest.
+// A reading archived while the page is read can be both live and archived, it is returned once.
+func (s *archiveService) ReadRange(dateTimes DateTimeRange, cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	after := dateTimes.start(cursor)
+
+	// * The live readings are read first, a reading archived after that is in the manifest read next
+	readings, err := s.readLive(dateTimes, after, limit+1, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	archives, err := s.repository.ReadArchives(after, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, archive := range archives {
+		if dateTimes.past(archive.First) || len(readings) > limit && models.ComparePositions(archive.First, readings[limit].Position) > 0 {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		archived, err := s.readFile(archive, dateTimes, after, limit+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range archived {
+			if !slices.ContainsFunc(readings, func(live *models.DHT22ArchiveReading) bool { return live.ID == r.ID }) {
+				readings = append(readings, r)
+			}
+		}
+		slices.SortFunc(readings, func(a, b *models.DHT22ArchiveReading) int { return models.ComparePositions(a.Position, b.Position) })
+		readings = readings[:min(len(readings), limit+1)]
+	}
+
+	var next *models.Cursor
+	if len(readings) > limit {
+		readings = readings[:limit]
+		position := readings[limit-1].Position
+		next = &position
+	}
+	data := make([]*models.DHT22Data, len(readings))
+	for i, r := range readings {
+		reading := r.DHT22Data
+		data[i] = &reading
+	}
+	return data, next, nil
+}
+
+// readLive returns up to limit live readings of the range after the cursor. The readings at From left out of the range
+// are read past, so fewer than limit readings means there are no more.
+func (s *archiveService) readLive(dateTimes DateTimeRange, after *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22ArchiveReading, error) {
+	var readings []*models.DHT22ArchiveReading
+	for len(readings) < limit {
+		wanted := limit - len(readings)
+		read, err := s.repository.ReadLive(after, time.Time{}, wanted, ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range read {
+			if dateTimes.past(r.Position) {
+				return readings, nil
+			}
+			if dateTimes.contains(r.Position) {
+				readings = append(readings, r)
+			}
+		}
+		if len(read) < wanted {
+			break
+		}
+		after = &read[len(read)-1].Position
+	}
+	return readings, nil
+}
+
+// readFile returns up to limit readings of the range of an archive file after the cursor, the file is in position order.
+// Decoding stops at the end of the page or of the range, the readings before the cursor are decoded and skipped:
+// ArchiveConfig.FileSize bounds them.
+func (s *archiveService) readFile(archive *models.DHT22Archive, dateTimes DateTimeRange, cursor *models.Cursor, limit int) ([]*models.DHT22ArchiveReading, error) {
+	var readings []*models.DHT22ArchiveReading
+	err := s.scanFile(archive, func(r *models.DHT22ArchiveReading) bool {
+		if dateTimes.past(r.Position) {
+			return false
+		}
+		if (cursor == nil || models.ComparePositions(r.Position, *cursor) > 0) && dateTimes.contains(r.Position) {
+			readings = append(readings, r)
+		}
+		return len(readings) < limit
+	})
+	return readings, err
+}
+
+// ReadOne decodes the files whose ids range over the id until it finds the reading.
+func (s *archiveService) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
+	archives, err := s.repository.ReadArchivesOf(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var found *models.DHT22Data
+		err := s.scanFile(archive, func(r *models.DHT22ArchiveReading) bool {
+			if r.ID == id {
+				found = &r.DHT22Data
+			}
+			return found == nil
+		})
+		if err != nil || found != nil {
+			return found, err
+		}
+	}
+	return nil, nil
+}
+
+// scanFile decodes the readings of an archive file in order and passes them to visit, until it returns false.
+func (s *archiveService) scanFile(archive *models.DHT22Archive, visit func(r *models.DHT22ArchiveReading) bool) error {
+	f, err := os.Open(s.path(archive.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("archive %s: %w", archive.File, err)
+	}
+	defer zr.Close()
+
+	decoder := json.NewDecoder(zr)
+	for {
+		var r models.DHT22ArchiveReading
+		if err := decoder.Decode(&r); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("archive %s: %w", archive.File, err)
+		}
+		if !visit(&r) {
+			return nil
+		}
+	}
+}
+
+// RunArchive archives the readings older than age right away and then every interval, until the context is canceled.
+// Errors are logged so the next run tries again.
+func RunArchive(ctx context.Context, service ArchiveService, age time.Duration, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().Add(-age)
+		if n, err := service.Archive(before, ctx); err != nil {
+			logger.Printf("Could not archive DHT22 readings, %d were archived before the error: %v", n, err)
+		} else if n > 0 {
+			logger.Printf("Archived %d DHT22 readings measured before %s", n, before.UTC().Format(time.RFC3339))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
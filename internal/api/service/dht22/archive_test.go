@@ -0,0 +1,342 @@
+package dht22
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/DAL/memory"
+	"goapi/internal/api/repository/models"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// archiveFixture is an archive of an in-memory database, with readings of two devices over three months
+type archiveFixture struct {
+	service  ArchiveService
+	dht22    DHT22Service
+	archives models.DHT22ArchiveRepository
+	dir      string
+	readings []*models.DHT22Data
+}
+
+func newArchiveFixture(t *testing.T, config ArchiveConfig) *archiveFixture {
+	t.Helper()
+	db := memory.NewDatabase()
+	f := &archiveFixture{
+		dht22:    NewDHT22Service(memory.NewDHT22Repository(db)),
+		archives: memory.NewDHT22ArchiveRepository(db),
+		dir:      t.TempDir(),
+	}
+	config.Dir = f.dir
+	f.service = NewArchiveService(f.archives, memory.NewUnitOfWork(db), config)
+	f.readings = createArchiveReadings(t, f.dht22)
+	return f
+}
+
+// createArchiveReadings creates a reading of living room and of kitchen/1 on the 1st and the 15th of January, February and March 2020
+func createArchiveReadings(t *testing.T, service DHT22Service) []*models.DHT22Data {
+	t.Helper()
+	var readings []*models.DHT22Data
+	for month := time.January; month <= time.March; month++ {
+		for _, day := range []int{1, 15} {
+			for _, device := range []string{"living room", "kitchen/1"} {
+				reading := &models.DHT22Data{
+					DeviceName:  device,
+					Temperature: 20,
+					Humidity:    50,
+					DateTime:    time.Date(2020, month, day, 12, 0, 0, 0, time.UTC).Format(time.RFC3339),
+				}
+				if err := service.Create(reading, context.Background()); err != nil {
+					t.Fatalf("Create failed: %v", err)
+				}
+				readings = append(readings, reading)
+			}
+		}
+	}
+	return readings
+}
+
+// readAll pages through the live and archived readings
+func readAll(t *testing.T, service ArchiveService, perPage int) []int {
+	t.Helper()
+	return readRange(t, service, DateTimeRange{}, perPage)
+}
+
+// readRange pages through the live and archived readings of the range
+func readRange(t *testing.T, service ArchiveService, dateTimes DateTimeRange, perPage int) []int {
+	t.Helper()
+	ids := []int{}
+	var cursor *models.Cursor
+	for page := 0; page < 100; page++ {
+		data, next, err := service.ReadRange(dateTimes, cursor, perPage, context.Background())
+		if err != nil {
+			t.Fatalf("ReadRange failed: %v", err)
+		}
+		for _, d := range data {
+			ids = append(ids, d.ID)
+		}
+		if next == nil {
+			return ids
+		}
+		cursor = next
+	}
+	t.Fatalf("ReadAfter did not reach the last page")
+	return nil
+}
+
+func readingIDs(readings []*models.DHT22Data) []int {
+	ids := []int{}
+	for _, r := range readings {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestArchiveService_Archive(t *testing.T) {
+	f := newArchiveFixture(t, ArchiveConfig{ChunkSize: 3})
+
+	// * The readings of January and February, 8 of them, are archived in chunks of 3
+	n, err := f.service.Archive(time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC), context.Background())
+	if err != nil || n != 8 {
+		t.Fatalf("Archive returned %v, %v, want 8 archived", n, err)
+	}
+	if count, _ := f.dht22.Count(context.Background()); count != 4 {
+		t.Errorf("Expected the 4 readings of March to stay in the database, got %d", count)
+	}
+
+	archives, err := f.archives.ReadArchives(nil, context.Background())
+	if err != nil {
+		t.Fatalf("ReadArchives failed: %v", err)
+	}
+	archived := 0
+	for _, a := range archives {
+		archived += a.Readings
+		if a.Month != "2020-01" && a.Month != "2020-02" {
+			t.Errorf("Expected the files of January and February, got %+v", a)
+		}
+		if dir := filepath.ToSlash(filepath.Dir(filepath.Dir(a.File))); dir != "living_room" && dir != "kitchen_1" {
+			t.Errorf("Expected the files in a directory per device, got %s", a.File)
+		}
+		assertArchiveFile(t, f.dir, a)
+	}
+	if archived != 8 {
+		t.Errorf("Expected the manifest to list 8 readings, got %d in %+v", archived, archives)
+	}
+
+	// * Nothing is left to archive
+	if n, err := f.service.Archive(time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC), context.Background()); err != nil || n != 0 {
+		t.Errorf("A second Archive returned %v, %v, want nothing archived", n, err)
+	}
+}
+
+// assertArchiveFile checks that the file of a manifest entry holds its readings, in order
+func assertArchiveFile(t *testing.T, dir string, a *models.DHT22Archive) {
+	t.Helper()
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(a.File)))
+	if err != nil {
+		t.Fatalf("The archive file of %+v can not be opened: %v", a, err)
+	}
+	defer file.Close()
+	info, _ := file.Stat()
+	if info.Size() != a.Size {
+		t.Errorf("Expected %s to be %d bytes, got %d", a.File, a.Size, info.Size())
+	}
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("%s is not gzip compressed: %v", a.File, err)
+	}
+	decoder := json.NewDecoder(zr)
+	var readings []models.DHT22ArchiveReading
+	for decoder.More() {
+		var r models.DHT22ArchiveReading
+		if err := decoder.Decode(&r); err != nil {
+			t.Fatalf("%s is not NDJSON: %v", a.File, err)
+		}
+		if r.DeviceName != a.DeviceName || r.DateTime[:7] != a.Month {
+			t.Errorf("%s holds a reading of another device or month: %+v", a.File, r)
+		}
+		readings = append(readings, r)
+	}
+	if len(readings) != a.Readings || readings[0].Position != a.First || readings[len(readings)-1].Position != a.Last {
+		t.Errorf("%s does not hold the readings of %+v", a.File, a)
+	}
+}
+
+func TestArchiveService_ReadAfter(t *testing.T) {
+	f := newArchiveFixture(t, ArchiveConfig{ChunkSize: 5})
+	want := fmt.Sprint(readAll(t, f.service, 100))
+
+	if _, err := f.service.Archive(time.Date(2020, time.February, 10, 0, 0, 0, 0, time.UTC), context.Background()); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+
+	// * The pages are the same as before the readings were archived, in (date_time, id) order
+	for _, perPage := range []int{1, 3, 5, 100} {
+		if got := fmt.Sprint(readAll(t, f.service, perPage)); got != want {
+			t.Errorf("Expected the readings %s with %d per page, got %s", want, perPage, got)
+		}
+	}
+
+	// * The readings of a page are the same as before they were archived
+	data, _, err := f.service.ReadAfter(nil, 1, context.Background())
+	if err != nil || len(data) != 1 {
+		t.Fatalf("ReadAfter returned %v, %v", data, err)
+	}
+	if first := *f.readings[0]; *data[0] != first {
+		t.Errorf("Expected the archived reading %+v, got %+v", first, *data[0])
+	}
+
+	// * Without the archive only the live readings are read
+	live, _, err := f.dht22.ReadAfter(nil, 100, context.Background())
+	if err != nil || len(live) != 6 {
+		t.Errorf("Expected the 6 readings from the 15th of February to stay live, got %v, %v", readingIDs(live), err)
+	}
+}
+
+func TestArchiveService_ReadRange(t *testing.T) {
+	f := newArchiveFixture(t, ArchiveConfig{ChunkSize: 5, FileSize: 2})
+	if _, err := f.service.Archive(time.Date(2020, time.February, 10, 0, 0, 0, 0, time.UTC), context.Background()); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+
+	// * No file holds more than FileSize readings, a page decodes at most that many readings before its cursor
+	archives, err := f.archives.ReadArchives(nil, context.Background())
+	if err != nil {
+		t.Fatalf("ReadArchives failed: %v", err)
+	}
+	for _, a := range archives {
+		if a.Readings > 2 {
+			t.Errorf("Expected files of at most 2 readings, got %+v", a)
+		}
+		assertArchiveFile(t, f.dir, a)
+	}
+
+	// * The readings are measured at noon on the 1st and the 15th, two at a time
+	tests := []struct {
+		name      string
+		dateTimes DateTimeRange
+		want      []*models.DHT22Data
+	}{
+		{"archived", DateTimeRange{From: "2020-01-15T12:00:00Z", To: "2020-02-01T12:00:00Z"}, f.readings[2:4]},
+		{"archived and live", DateTimeRange{From: "2020-01-15T12:00:00Z", FromExcluded: true, To: "2020-02-15T12:00:00Z", ToIncluded: true}, f.readings[4:8]},
+		{"live", DateTimeRange{From: "2020-03-01"}, f.readings[8:]},
+		{"until", DateTimeRange{To: "2020-01-15T12:00:00Z", ToIncluded: true}, f.readings[:4]},
+		{"empty", DateTimeRange{From: "2020-01-02", To: "2020-01-03"}, nil},
+	}
+	for _, tt := range tests {
+		for _, perPage := range []int{1, 3, 100} {
+			if got, want := fmt.Sprint(readRange(t, f.service, tt.dateTimes, perPage)), fmt.Sprint(readingIDs(tt.want)); got != want {
+				t.Errorf("%s: expected the readings %s with %d per page, got %s", tt.name, want, perPage, got)
+			}
+		}
+	}
+
+	// * A cursor before the range starts at the range, one after it ends the pages
+	dateTimes := DateTimeRange{From: "2020-02-01T12:00:00Z", To: "2020-03-01T12:00:00Z"}
+	data, _, err := f.service.ReadRange(dateTimes, &models.Cursor{DateTime: "2020-01-01T12:00:00Z", ID: f.readings[0].ID}, 1, context.Background())
+	if err != nil || len(data) != 1 || data[0].ID != f.readings[4].ID {
+		t.Errorf("ReadRange after a cursor before the range returned %v, %v, want [%d]", readingIDs(data), err, f.readings[4].ID)
+	}
+	data, next, err := f.service.ReadRange(dateTimes, &models.Cursor{DateTime: "2020-03-01T12:00:00Z", ID: f.readings[8].ID}, 10, context.Background())
+	if err != nil || len(data) != 0 || next != nil {
+		t.Errorf("ReadRange after a cursor after the range returned %v, %v, %v", readingIDs(data), next, err)
+	}
+}
+
+func TestArchiveService_ReadOne(t *testing.T) {
+	f := newArchiveFixture(t, ArchiveConfig{FileSize: 2})
+	if _, err := f.service.Archive(time.Date(2020, time.February, 10, 0, 0, 0, 0, time.UTC), context.Background()); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+
+	// * An archived reading is the same as before it was archived
+	for _, reading := range f.readings[:6] {
+		got, err := f.service.ReadOne(reading.ID, context.Background())
+		if err != nil || got == nil || *got != *reading {
+			t.Errorf("ReadOne(%d) returned %+v, %v, want %+v", reading.ID, got, err, *reading)
+		}
+	}
+	// * Live readings and unknown ids are not in the archive
+	for _, id := range []int{f.readings[6].ID, 1000} {
+		if got, err := f.service.ReadOne(id, context.Background()); err != nil || got != nil {
+			t.Errorf("ReadOne(%d) returned %+v, %v, want nothing", id, got, err)
+		}
+	}
+}
+
+// failingArchive fails to record the archive files
+type failingArchive struct {
+	models.DHT22ArchiveRepository
+}
+
+var errArchiveFailed = errors.New("archive failed")
+
+func (f failingArchive) Archive(archives []*models.DHT22Archive, ids []int, ctx context.Context) error {
+	return errArchiveFailed
+}
+
+func TestArchiveService_FailedArchiveRemovesFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := memory.NewDatabase()
+	live := NewDHT22Service(memory.NewDHT22Repository(db))
+	createArchiveReadings(t, live)
+	service := NewArchiveService(failingArchive{memory.NewDHT22ArchiveRepository(db)}, memory.NewUnitOfWork(db), ArchiveConfig{Dir: dir})
+
+	if _, err := service.Archive(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), context.Background()); !errors.Is(err, errArchiveFailed) {
+		t.Fatalf("Expected the error of the repository, got %v", err)
+	}
+	if count, _ := live.Count(context.Background()); count != 12 {
+		t.Errorf("Expected the readings to stay in the database, got %d", count)
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("Expected the files of the failed archive to be removed, found %s", path)
+		}
+		return err
+	})
+}
+
+func TestArchiveService_SQLite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, err := SQLite.NewSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSqlite failed: %v", err)
+	}
+	repo, err := SQLite.NewDHT22Repository(db, ctx)
+	if err != nil {
+		t.Fatalf("NewDHT22Repository failed: %v", err)
+	}
+	archives, err := SQLite.NewDHT22ArchiveRepository(db, ctx)
+	if err != nil {
+		t.Fatalf("NewDHT22ArchiveRepository failed: %v", err)
+	}
+	live := NewDHT22Service(repo)
+	service := NewArchiveService(archives, DAL.NewUnitOfWork(db), ArchiveConfig{Dir: t.TempDir(), ChunkSize: 4})
+	readings := createArchiveReadings(t, live)
+
+	n, err := service.Archive(time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC), context.Background())
+	if err != nil || n != 8 {
+		t.Fatalf("Archive returned %v, %v, want 8 archived", n, err)
+	}
+	if count, _ := live.Count(context.Background()); count != 4 {
+		t.Errorf("Expected the 4 readings of March to stay in the database, got %d", count)
+	}
+	if got, want := fmt.Sprint(readAll(t, service, 3)), fmt.Sprint(readingIDs(readings)); got != want {
+		t.Errorf("Expected the readings %s, got %s", want, got)
+	}
+	dateTimes := DateTimeRange{From: "2020-02-01T12:00:00Z", FromExcluded: true, To: "2020-03-01T12:00:00Z", ToIncluded: true}
+	if got, want := fmt.Sprint(readRange(t, service, dateTimes, 1)), fmt.Sprint(readingIDs(readings[6:10])); got != want {
+		t.Errorf("Expected the readings %s of the range, got %s", want, got)
+	}
+	if got, err := service.ReadOne(readings[0].ID, context.Background()); err != nil || got == nil || *got != *readings[0] {
+		t.Errorf("ReadOne(%d) returned %+v, %v, want %+v", readings[0].ID, got, err, *readings[0])
+	}
+}
@@ -0,0 +1,120 @@
+package dht22
+
+import (
+	"context"
+	"goapi/internal/api/repository/models"
+	"math"
+	"time"
+)
+
+// ForecastSamples is the number of most recent readings the trend is fitted over
+const ForecastSamples = 60
+
+// z-score of a two-sided 95% interval
+const forecastZ = 1.96
+
+var ErrNotEnoughReadings = DHT22Error("At least 3 readings are needed for a forecast.")
+
+// ErrSingleTimestamp is returned when every reading has the same date_time, no trend can be fitted through them
+var ErrSingleTimestamp = DHT22Error("Readings must span more than one point in time.")
+
+// Forecast fits a least-squares line over the latest readings of a device and
+// extrapolates it to horizon after the newest reading.
+func (s *dht22Service) Forecast(deviceName string, horizon time.Duration, ctx context.Context) (*models.DHT22Forecast, error) {
+	readings, err := s.repository.ReadLatest(deviceName, ForecastSamples, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ForecastReadings(deviceName, readings, horizon)
+}
+
+// ForecastReadings builds a forecast from readings in any order.
+func ForecastReadings(deviceName string, readings []*models.DHT22Data, horizon time.Duration) (*models.DHT22Forecast, error) {
+	if len(readings) < 3 {
+		return nil, ErrNotEnoughReadings
+	}
+
+	times := make([]time.Time, len(readings))
+	var newest time.Time
+	for i, r := range readings {
+		t, err := time.Parse(time.RFC3339, r.DateTime)
+		if err != nil {
+			return nil, DHT22Error("Invalid date_time in stored reading: " + r.DateTime)
+		}
+		times[i] = t
+		if t.After(newest) {
+			newest = t
+		}
+	}
+
+	// * x is hours relative to the newest reading, so the slope is per hour *
+	xs := make([]float64, len(readings))
+	temps := make([]float64, len(readings))
+	hums := make([]float64, len(readings))
+	for i, r := range readings {
+		xs[i] = times[i].Sub(newest).Hours()
+		temps[i] = r.Temperature
+		hums[i] = r.Humidity
+	}
+
+	x0 := horizon.Hours()
+	temperature, err := linearForecast(xs, temps, x0)
+	if err != nil {
+		return nil, err
+	}
+	humidity, err := linearForecast(xs, hums, x0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DHT22Forecast{
+		DeviceName:   deviceName,
+		Horizon:      horizon.String(),
+		ForecastTime: newest.Add(horizon).UTC().Format(time.RFC3339),
+		Samples:      len(readings),
+		Temperature:  temperature,
+		Humidity:     humidity,
+	}, nil
+}
+
+// linearForecast predicts y at x0 with a prediction interval from the residual standard error.
+func linearForecast(xs, ys []float64, x0 float64) (models.ForecastValue, error) {
+	n := float64(len(xs))
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var sxx, sxy float64
+	for i := range xs {
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+		sxy += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	if sxx == 0 {
+		return models.ForecastValue{}, ErrSingleTimestamp
+	}
+
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for i := range xs {
+		residual := ys[i] - (intercept + slope*xs[i])
+		sse += residual * residual
+	}
+	se := math.Sqrt(sse / (n - 2))
+
+	predicted := intercept + slope*x0
+	margin := forecastZ * se * math.Sqrt(1+1/n+(x0-meanX)*(x0-meanX)/sxx)
+
+	return models.ForecastValue{
+		Predicted:    predicted,
+		Lower:        predicted - margin,
+		Upper:        predicted + margin,
+		SlopePerHour: slope,
+	}, nil
+}
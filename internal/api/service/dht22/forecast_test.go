@@ -0,0 +1,64 @@
+package dht22
+
+import (
+	"goapi/internal/api/repository/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestForecastReadings_LinearTrend(t *testing.T) {
+	// * Temperature rises exactly 1 degree and humidity drops 2% every 10 minutes *
+	var readings []*models.DHT22Data
+	start := time.Date(2024, 12, 22, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		readings = append(readings, &models.DHT22Data{
+			DeviceName:  "greenhouse-1",
+			Temperature: 20 + float64(i),
+			Humidity:    60 - 2*float64(i),
+			DateTime:    start.Add(time.Duration(i) * 10 * time.Minute).Format(time.RFC3339),
+		})
+	}
+
+	forecast, err := ForecastReadings("greenhouse-1", readings, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if forecast.ForecastTime != "2024-12-22T13:50:00Z" {
+		t.Errorf("Expected forecast time 2024-12-22T13:50:00Z, got %s", forecast.ForecastTime)
+	}
+	if math.Abs(forecast.Temperature.Predicted-31) > 1e-9 || math.Abs(forecast.Temperature.SlopePerHour-6) > 1e-9 {
+		t.Errorf("Expected temperature 31 rising 6/h, got %+v", forecast.Temperature)
+	}
+	if math.Abs(forecast.Humidity.Predicted-38) > 1e-9 {
+		t.Errorf("Expected humidity 38, got %+v", forecast.Humidity)
+	}
+	// * A perfect fit has no residual error, so the band collapses to the prediction *
+	if math.Abs(forecast.Temperature.Upper-forecast.Temperature.Lower) > 1e-9 {
+		t.Errorf("Expected an empty band for a perfect fit, got %+v", forecast.Temperature)
+	}
+}
+
+func TestForecastReadings_NotEnoughReadings(t *testing.T) {
+	readings := []*models.DHT22Data{
+		{Temperature: 20, Humidity: 50, DateTime: "2024-12-22T12:00:00Z"},
+		{Temperature: 21, Humidity: 50, DateTime: "2024-12-22T12:10:00Z"},
+	}
+
+	if _, err := ForecastReadings("greenhouse-1", readings, time.Hour); err != ErrNotEnoughReadings {
+		t.Errorf("Expected ErrNotEnoughReadings, got %v", err)
+	}
+}
+
+func TestForecastReadings_SingleTimestamp(t *testing.T) {
+	readings := []*models.DHT22Data{
+		{Temperature: 20, Humidity: 50, DateTime: "2024-12-22T12:00:00Z"},
+		{Temperature: 21, Humidity: 51, DateTime: "2024-12-22T12:00:00Z"},
+		{Temperature: 22, Humidity: 52, DateTime: "2024-12-22T14:00:00+02:00"},
+	}
+
+	if _, err := ForecastReadings("greenhouse-1", readings, time.Hour); err != ErrSingleTimestamp {
+		t.Errorf("Expected ErrSingleTimestamp, got %v", err)
+	}
+}
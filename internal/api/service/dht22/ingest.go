@@ -0,0 +1,261 @@
+package dht22
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goapi/internal/api/repository/models"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// IngestConfig sizes the batches readings are written in
+type IngestConfig struct {
+	// BatchSize readings are written in one transaction, a batch is written as soon as it is full
+	BatchSize int
+	// FlushInterval is the longest a reading waits for its batch to fill up
+	FlushInterval time.Duration
+	// QueueSize readings can wait to be written, beyond that Create fails with IngestQueueFullError
+	QueueSize int
+	// Retries of a batch that can not be written, the first one RetryDelay later and each next one twice as late
+	Retries    int
+	RetryDelay time.Duration
+	// DeadLetterFile is where the readings of a batch that still can not be written are appended as NDJSON,
+	// they were accepted so they are not dropped. The queue writes them again when it starts.
+	DeadLetterFile string
+}
+
+var DefaultIngestConfig = IngestConfig{
+	BatchSize:      500,
+	FlushInterval:  100 * time.Millisecond,
+	QueueSize:      10000,
+	Retries:        3,
+	RetryDelay:     500 * time.Millisecond,
+	DeadLetterFile: "dht22-dead-letter.ndjson",
+}
+
+// withDefaults fills in the zero values with the ones of DefaultIngestConfig
+func (config IngestConfig) withDefaults() IngestConfig {
+	if config.BatchSize < 1 {
+		config.BatchSize = DefaultIngestConfig.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultIngestConfig.FlushInterval
+	}
+	if config.QueueSize < 1 {
+		config.QueueSize = DefaultIngestConfig.QueueSize
+	}
+	if config.Retries < 1 {
+		config.Retries = DefaultIngestConfig.Retries
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = DefaultIngestConfig.RetryDelay
+	}
+	if config.DeadLetterFile == "" {
+		config.DeadLetterFile = DefaultIngestConfig.DeadLetterFile
+	}
+	return config
+}
+
+// IngestQueueFullError is returned when more readings are waiting to be written than the queue holds, the client should retry later
+type IngestQueueFullError struct {
+	// RetryAfter is when there should be room again
+	RetryAfter time.Duration
+}
+
+func (e IngestQueueFullError) Error() string {
+	return "Too many readings are waiting to be written, retry later."
+}
+
+var ErrIngestQueueClosed = DHT22Error("The server is shutting down, readings are no longer accepted.")
+
+// IngestQueue is a DHT22Service that writes new readings behind the callers, in batches instead of one transaction each.
+// Create returns once the reading is valid and queued, it has no id until its batch is written.
+type IngestQueue struct {
+	DHT22Service
+	config  IngestConfig
+	logger  *log.Logger
+	queue   chan *models.DHT22Data
+	mu      sync.RWMutex
+	closed  bool
+	stopped chan struct{}
+}
+
+// NewIngestQueue starts writing the readings created through the queue with the service, until Close.
+func NewIngestQueue(service DHT22Service, config IngestConfig, logger *log.Logger) *IngestQueue {
+	config = config.withDefaults()
+	q := &IngestQueue{
+		DHT22Service: service,
+		config:       config,
+		logger:       logger,
+		queue:        make(chan *models.DHT22Data, config.QueueSize),
+		stopped:      make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Create validates the reading and queues a copy of it to be written.
+// A full queue is not waited on, the caller is asked to retry with IngestQueueFullError.
+func (q *IngestQueue) Create(data *models.DHT22Data, ctx context.Context) error {
+	if err := ValidateDHT22Data(data); err != nil {
+		return err
+	}
+	data.DateTime = models.UTCDateTime(data.DateTime)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// * The caller keeps its reading, the queue writes its own copy
+	queued := *data
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrIngestQueueClosed
+	}
+	select {
+	case q.queue <- &queued:
+		return nil
+	default:
+		return IngestQueueFullError{RetryAfter: q.config.FlushInterval}
+	}
+}
+
+// Close stops accepting readings and returns once the queued ones are written.
+func (q *IngestQueue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	q.mu.Unlock()
+	<-q.stopped
+}
+
+// run writes a batch when it is full or FlushInterval after its first reading, and what is left when the queue is closed.
+func (q *IngestQueue) run() {
+	defer close(q.stopped)
+	ReplayDeadLetters(q.DHT22Service, q.config, q.logger)
+
+	timer := time.NewTimer(q.config.FlushInterval)
+	timer.Stop()
+	batch := make([]*models.DHT22Data, 0, q.config.BatchSize)
+	for {
+		select {
+		case data, ok := <-q.queue:
+			if !ok {
+				timer.Stop()
+				q.flush(batch)
+				return
+			}
+			batch = append(batch, data)
+			if len(batch) == 1 {
+				timer.Reset(q.config.FlushInterval)
+			}
+			if len(batch) < q.config.BatchSize {
+				continue
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+		}
+		q.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush writes the batch in one transaction. The callers are gone, a batch that can not be written is retried
+// and then appended to the dead letter file, its readings were accepted and must not be lost.
+func (q *IngestQueue) flush(batch []*models.DHT22Data) {
+	if len(batch) == 0 {
+		return
+	}
+	err := q.DHT22Service.CreateMany(batch, context.Background())
+	delay := q.config.RetryDelay
+	for retry := 1; err != nil && retry <= q.config.Retries; retry++ {
+		q.logger.Printf("Could not write %d DHT22 readings, retry %d of %d in %v: %v", len(batch), retry, q.config.Retries, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+		err = q.DHT22Service.CreateMany(batch, context.Background())
+	}
+	if err == nil {
+		return
+	}
+	if spillErr := appendDeadLetters(q.config.DeadLetterFile, batch); spillErr != nil {
+		q.logger.Printf("Lost %d DHT22 readings, they could not be written (%v) nor kept in %s: %v", len(batch), err, q.config.DeadLetterFile, spillErr)
+		return
+	}
+	q.logger.Printf("Could not write %d DHT22 readings, they are kept in %s and written when the server starts again: %v", len(batch), q.config.DeadLetterFile, err)
+}
+
+// appendDeadLetters appends the readings to a dead letter file, one JSON object per line
+func appendDeadLetters(path string, readings []*models.DHT22Data) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, data := range readings {
+		if err := encoder.Encode(data); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReplayDeadLetters writes the readings kept in the dead letter file of the config with the service, in batches.
+// The file is removed once all are written, otherwise it is left with the readings that were not.
+// An ingest queue replays them when it starts, a server without one must call it itself.
+func ReplayDeadLetters(service DHT22Service, config IngestConfig, logger *log.Logger) {
+	config = config.withDefaults()
+	file, err := os.Open(config.DeadLetterFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		logger.Printf("Could not read the DHT22 dead letter file %s: %v", config.DeadLetterFile, err)
+		return
+	}
+	var readings []*models.DHT22Data
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var data models.DHT22Data
+		if err := decoder.Decode(&data); err != nil {
+			file.Close()
+			logger.Printf("Could not read the DHT22 dead letter file %s, it is left as it is: %v", config.DeadLetterFile, err)
+			return
+		}
+		readings = append(readings, &data)
+	}
+	file.Close()
+
+	for written := 0; written < len(readings); written += config.BatchSize {
+		batch := readings[written:min(written+config.BatchSize, len(readings))]
+		if err := service.CreateMany(batch, context.Background()); err != nil {
+			logger.Printf("Could not write the DHT22 readings of %s, %d are left in it: %v", config.DeadLetterFile, len(readings)-written, err)
+			// * The readings written are not kept, they would be written twice
+			tmp := config.DeadLetterFile + ".tmp"
+			os.Remove(tmp)
+			if err := appendDeadLetters(tmp, readings[written:]); err != nil {
+				logger.Printf("Could not rewrite the DHT22 dead letter file %s: %v", config.DeadLetterFile, err)
+			} else if err := os.Rename(tmp, config.DeadLetterFile); err != nil {
+				logger.Printf("Could not rewrite the DHT22 dead letter file %s: %v", config.DeadLetterFile, err)
+			}
+			return
+		}
+	}
+	if err := os.Remove(config.DeadLetterFile); err != nil {
+		logger.Printf("Could not remove the DHT22 dead letter file %s, its readings are written: %v", config.DeadLetterFile, err)
+		return
+	}
+	logger.Printf("Wrote the %d DHT22 readings of %s", len(readings), config.DeadLetterFile)
+}
@@ -0,0 +1,344 @@
+package dht22
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/models"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder records the batches written through CreateMany, a batch is held back until release is closed
+type batchRecorder struct {
+	MockDHT22ServiceSuccessful
+	mu      sync.Mutex
+	batches [][]*models.DHT22Data
+	release chan struct{}
+	started chan struct{}
+}
+
+func (b *batchRecorder) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	if b.release != nil {
+		b.started <- struct{}{}
+		<-b.release
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, append([]*models.DHT22Data(nil), data...))
+	return nil
+}
+
+func (b *batchRecorder) batchSizes() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sizes := make([]int, len(b.batches))
+	for i, batch := range b.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func reading(n int) *models.DHT22Data {
+	return &models.DHT22Data{
+		DeviceName:  fmt.Sprintf("sensor-%d", n%50),
+		Temperature: 20 + float64(n%10),
+		Humidity:    50,
+		DateTime:    time.Date(2024, 12, 22, 12, 0, n%60, 0, time.UTC).Format(time.RFC3339),
+	}
+}
+
+func newTestQueue(service DHT22Service, config IngestConfig) *IngestQueue {
+	return NewIngestQueue(service, config, log.New(io.Discard, "", 0))
+}
+
+func TestIngestQueue_WritesFullBatches(t *testing.T) {
+	recorder := &batchRecorder{}
+	q := newTestQueue(recorder, IngestConfig{BatchSize: 5, FlushInterval: time.Hour, QueueSize: 20})
+
+	for i := 0; i < 12; i++ {
+		if err := q.Create(reading(i), context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	q.Close()
+
+	// * Two full batches are written right away, the last two readings on Close
+	if sizes := recorder.batchSizes(); fmt.Sprint(sizes) != "[5 5 2]" {
+		t.Errorf("Expected batches of [5 5 2] readings, got %v", sizes)
+	}
+}
+
+func TestIngestQueue_FlushesAfterInterval(t *testing.T) {
+	recorder := &batchRecorder{}
+	q := newTestQueue(recorder, IngestConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond, QueueSize: 100})
+	defer q.Close()
+
+	data := reading(1)
+	if err := q.Create(data, context.Background()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if data.ID != 0 {
+		t.Errorf("Expected a queued reading to have no id yet, got %d", data.ID)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.batchSizes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if sizes := recorder.batchSizes(); fmt.Sprint(sizes) != "[1]" {
+		t.Errorf("Expected one batch of 1 reading after the flush interval, got %v", sizes)
+	}
+}
+
+func TestIngestQueue_Full(t *testing.T) {
+	recorder := &batchRecorder{release: make(chan struct{}), started: make(chan struct{}, 1)}
+	q := newTestQueue(recorder, IngestConfig{BatchSize: 1, FlushInterval: 2 * time.Second, QueueSize: 2})
+
+	// * The first reading is held in CreateMany, the next two fill the queue
+	if err := q.Create(reading(1), context.Background()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	<-recorder.started
+	for i := 2; i <= 3; i++ {
+		if err := q.Create(reading(i), context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	err := q.Create(reading(4), context.Background())
+	var full IngestQueueFullError
+	if !errors.As(err, &full) {
+		t.Fatalf("Expected IngestQueueFullError, got %v", err)
+	}
+	if full.RetryAfter != 2*time.Second {
+		t.Errorf("Expected RetryAfter of the flush interval, got %v", full.RetryAfter)
+	}
+
+	go func() {
+		for range recorder.started {
+		}
+	}()
+	close(recorder.release)
+	q.Close()
+	close(recorder.started)
+	if sizes := recorder.batchSizes(); fmt.Sprint(sizes) != "[1 1 1]" {
+		t.Errorf("Expected the 3 accepted readings to be written, got batches %v", sizes)
+	}
+}
+
+func TestIngestQueue_Validation(t *testing.T) {
+	recorder := &batchRecorder{}
+	q := newTestQueue(recorder, IngestConfig{BatchSize: 10, FlushInterval: time.Millisecond, QueueSize: 10})
+
+	invalid := reading(1)
+	invalid.Humidity = 120
+	if _, ok := q.Create(invalid, context.Background()).(DHT22ValidationError); !ok {
+		t.Errorf("Expected DHT22ValidationError for an invalid reading")
+	}
+	q.Close()
+	if sizes := recorder.batchSizes(); len(sizes) != 0 {
+		t.Errorf("Expected the invalid reading not to be queued, got batches %v", sizes)
+	}
+}
+
+func TestIngestQueue_CloseWritesQueuedReadings(t *testing.T) {
+	recorder := &batchRecorder{}
+	q := newTestQueue(recorder, IngestConfig{BatchSize: 100, FlushInterval: time.Hour, QueueSize: 100})
+
+	for i := 0; i < 3; i++ {
+		if err := q.Create(reading(i), context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	q.Close()
+
+	if sizes := recorder.batchSizes(); fmt.Sprint(sizes) != "[3]" {
+		t.Errorf("Expected the 3 queued readings to be written on Close, got batches %v", sizes)
+	}
+	if err := q.Create(reading(4), context.Background()); err != ErrIngestQueueClosed {
+		t.Errorf("Expected ErrIngestQueueClosed after Close, got %v", err)
+	}
+	q.Close()
+}
+
+// flakyRecorder fails the first failures writes, then records the batches
+type flakyRecorder struct {
+	batchRecorder
+	failures int
+	attempts int
+}
+
+var errWriteFailed = errors.New("database is locked")
+
+func (f *flakyRecorder) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	f.attempts++
+	if f.attempts <= f.failures {
+		return errWriteFailed
+	}
+	return f.batchRecorder.CreateMany(data, ctx)
+}
+
+func TestIngestQueue_RetriesFailedBatch(t *testing.T) {
+	deadLetters := filepath.Join(t.TempDir(), "dead-letter.ndjson")
+	recorder := &flakyRecorder{failures: 2}
+	q := newTestQueue(recorder, IngestConfig{BatchSize: 3, FlushInterval: time.Hour, QueueSize: 10, Retries: 3, RetryDelay: time.Millisecond, DeadLetterFile: deadLetters})
+
+	for i := 0; i < 3; i++ {
+		if err := q.Create(reading(i), context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	q.Close()
+
+	if sizes := recorder.batchSizes(); fmt.Sprint(sizes) != "[3]" || recorder.attempts != 3 {
+		t.Errorf("Expected the batch to be written on the second retry, got batches %v after %d attempts", sizes, recorder.attempts)
+	}
+	if _, err := os.Stat(deadLetters); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no dead letter file for a batch that was written, got %v", err)
+	}
+}
+
+func TestIngestQueue_KeepsAndReplaysDeadLetters(t *testing.T) {
+	deadLetters := filepath.Join(t.TempDir(), "dead-letter.ndjson")
+	config := IngestConfig{BatchSize: 2, FlushInterval: time.Hour, QueueSize: 10, Retries: 2, RetryDelay: time.Millisecond, DeadLetterFile: deadLetters}
+
+	// * The database is gone for good, the accepted readings are kept in the dead letter file
+	failing := &flakyRecorder{failures: 1000}
+	q := newTestQueue(failing, config)
+	for i := 0; i < 3; i++ {
+		if err := q.Create(reading(i), context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	q.Close()
+	if len(failing.batchSizes()) != 0 || failing.attempts != 6 {
+		t.Fatalf("Expected 2 batches tried 3 times each, got %d attempts", failing.attempts)
+	}
+	if content, err := os.ReadFile(deadLetters); err != nil || len(content) == 0 {
+		t.Fatalf("Expected the readings in the dead letter file, got %v", err)
+	}
+
+	// * A later queue writes them first, the batch that fails is kept for the next start
+	firstOnly := &failsAfterFirst{}
+	q = newTestQueue(firstOnly, config)
+	q.Close()
+	if sizes := firstOnly.batchSizes(); fmt.Sprint(sizes) != "[2]" {
+		t.Fatalf("Expected the first batch of the dead letter file to be written, got %v", sizes)
+	}
+
+	recorder := &batchRecorder{}
+	ReplayDeadLetters(recorder, config, log.New(io.Discard, "", 0))
+	if sizes := recorder.batchSizes(); fmt.Sprint(sizes) != "[1]" {
+		t.Errorf("Expected only the reading left in the dead letter file to be written again, got %v", sizes)
+	}
+	if _, err := os.Stat(deadLetters); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the dead letter file to be removed once written, got %v", err)
+	}
+}
+
+// failsAfterFirst writes the first batch and fails every later one
+type failsAfterFirst struct {
+	batchRecorder
+	calls int
+}
+
+func (f *failsAfterFirst) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	f.calls++
+	if f.calls > 1 {
+		return errWriteFailed
+	}
+	return f.batchRecorder.CreateMany(data, ctx)
+}
+
+func TestIngestQueue_SQLite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := newSQLiteService(t, ctx)
+	q := newTestQueue(service, IngestConfig{BatchSize: 7, FlushInterval: time.Millisecond, QueueSize: 100})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := q.Create(reading(i), context.Background()); err != nil {
+				t.Errorf("Create failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	q.Close()
+
+	if count, err := service.Count(context.Background()); err != nil || count != 50 {
+		t.Errorf("Expected the 50 readings to be written, got %v, %v", count, err)
+	}
+}
+
+func newSQLiteService(tb testing.TB, ctx context.Context) DHT22Service {
+	tb.Helper()
+	db, err := SQLite.NewSqlite(filepath.Join(tb.TempDir(), "test.db"))
+	if err != nil {
+		tb.Fatalf("NewSqlite failed: %v", err)
+	}
+	repo, err := SQLite.NewDHT22Repository(db, ctx)
+	if err != nil {
+		tb.Fatalf("NewDHT22Repository failed: %v", err)
+	}
+	return NewDHT22Service(repo)
+}
+
+// * The benchmarks post readings from many sensors at once to a SQLite database on disk and wait until all are written,
+// * go test -run NONE -bench Ingest -benchtime 5000x ./internal/api/service/dht22 *
+
+func benchmarkIngest(b *testing.B, create func(data *models.DHT22Data) error) {
+	b.SetParallelism(32)
+	var mu sync.Mutex
+	next := 0
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.Lock()
+			next++
+			data := reading(next)
+			mu.Unlock()
+			for {
+				err := create(data)
+				var full IngestQueueFullError
+				if !errors.As(err, &full) {
+					if err != nil {
+						b.Errorf("Create failed: %v", err)
+					}
+					break
+				}
+				// * Like a sensor told to retry later
+				time.Sleep(time.Millisecond)
+			}
+		}
+	})
+}
+
+func BenchmarkIngest_OneTransactionPerReading(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := newSQLiteService(b, ctx)
+
+	b.ResetTimer()
+	benchmarkIngest(b, func(data *models.DHT22Data) error { return service.Create(data, context.Background()) })
+}
+
+func BenchmarkIngest_Batched(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := newTestQueue(newSQLiteService(b, ctx), DefaultIngestConfig)
+
+	b.ResetTimer()
+	benchmarkIngest(b, func(data *models.DHT22Data) error { return q.Create(data, context.Background()) })
+	// * The readings still queued are part of the work
+	q.Close()
+}
@@ -3,15 +3,28 @@ package dht22
 import (
 	"context"
 	"goapi/internal/api/repository/models"
+	"goapi/internal/api/repository/query"
+	"time"
 )
 
 // DHT22Service handles the business logic for DHT22Data operations
 type DHT22Service interface {
 	Create(data *models.DHT22Data, ctx context.Context) error
+	CreateMany(data []*models.DHT22Data, ctx context.Context) error
 	ReadOne(id int, ctx context.Context) (*models.DHT22Data, error)
 	ReadMany(page, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error)
+	ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error)
+	Count(ctx context.Context) (int, error)
+	Query(q *query.Query, page, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error)
+	CountQuery(q *query.Query, ctx context.Context) (int, error)
+	ChangeMarker(ctx context.Context) (*models.ChangeMarker, error)
 	Update(data *models.DHT22Data, ctx context.Context) error
 	Delete(data *models.DHT22Data, ctx context.Context) error
+	Restore(id int, ctx context.Context) (int64, error)
+	ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedDHT22Data, error)
+	CountTrash(ctx context.Context) (int, error)
+	Purge(before time.Time, ctx context.Context) (int64, error)
+	Forecast(deviceName string, horizon time.Duration, ctx context.Context) (*models.DHT22Forecast, error)
 }
 
 type DHT22Error string
@@ -20,6 +33,13 @@ func (e DHT22Error) Error() string {
 	return string(e)
 }
 
+// DHT22ValidationError is returned when a reading is rejected by validation, it is a client error
+type DHT22ValidationError string
+
+func (e DHT22ValidationError) Error() string {
+	return string(e)
+}
+
 // dht22Service implements the DHT22Service interface
 type dht22Service struct {
 	repository models.DHT22Repository
@@ -32,6 +52,10 @@ func NewDHT22Service(repository models.DHT22Repository) DHT22Service {
 }
 
 func (s *dht22Service) Create(data *models.DHT22Data, ctx context.Context) error {
+	if err := ValidateDHT22Data(data); err != nil {
+		return err
+	}
+	data.DateTime = models.UTCDateTime(data.DateTime)
 	// Call repository to create data
 	if err := s.repository.Create(data, ctx); err != nil {
 		return err
@@ -39,6 +63,20 @@ func (s *dht22Service) Create(data *models.DHT22Data, ctx context.Context) error
 	return nil
 }
 
+// CreateMany validates every reading first, one invalid reading rejects them all
+func (s *dht22Service) CreateMany(data []*models.DHT22Data, ctx context.Context) error {
+	for _, d := range data {
+		if err := ValidateDHT22Data(d); err != nil {
+			return err
+		}
+	}
+	for _, d := range data {
+		d.DateTime = models.UTCDateTime(d.DateTime)
+	}
+	// Call repository to create the readings in one transaction
+	return s.repository.CreateMany(data, ctx)
+}
+
 func (s *dht22Service) ReadOne(id int, ctx context.Context) (*models.DHT22Data, error) {
 	// Call repository to fetch data by ID
 	data, err := s.repository.ReadOne(id, ctx)
@@ -57,7 +95,36 @@ func (s *dht22Service) ReadMany(page, rowsPerPage int, ctx context.Context) ([]*
 	return data, nil
 }
 
+func (s *dht22Service) ReadAfter(cursor *models.Cursor, limit int, ctx context.Context) ([]*models.DHT22Data, *models.Cursor, error) {
+	// Call repository to fetch the records after the cursor
+	return s.repository.ReadAfter(cursor, limit, ctx)
+}
+
+func (s *dht22Service) Count(ctx context.Context) (int, error) {
+	// Call repository to count all records
+	return s.repository.Count(ctx)
+}
+
+func (s *dht22Service) Query(q *query.Query, page, rowsPerPage int, ctx context.Context) ([]*models.DHT22Data, error) {
+	// Call repository to fetch the records matching the query
+	return s.repository.Query(q, page, rowsPerPage, ctx)
+}
+
+func (s *dht22Service) CountQuery(q *query.Query, ctx context.Context) (int, error) {
+	// Call repository to count the records matching the query
+	return s.repository.CountQuery(q, ctx)
+}
+
+func (s *dht22Service) ChangeMarker(ctx context.Context) (*models.ChangeMarker, error) {
+	// Call repository to read the change marker of the readings
+	return s.repository.ChangeMarker(ctx)
+}
+
 func (s *dht22Service) Update(data *models.DHT22Data, ctx context.Context) error {
+	if err := ValidateDHT22Data(data); err != nil {
+		return err
+	}
+	data.DateTime = models.UTCDateTime(data.DateTime)
 	// Call repository to update data
 	_, err := s.repository.Update(data, ctx)
 	if err != nil {
@@ -74,3 +141,46 @@ func (s *dht22Service) Delete(data *models.DHT22Data, ctx context.Context) error
 	}
 	return nil
 }
+
+func (s *dht22Service) Restore(id int, ctx context.Context) (int64, error) {
+	// Call repository to take the reading out of the trash, 0 if it is not in the trash
+	return s.repository.Restore(id, ctx)
+}
+
+func (s *dht22Service) ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedDHT22Data, error) {
+	// Call repository to fetch the deleted readings
+	return s.repository.ReadTrash(page, rowsPerPage, ctx)
+}
+
+func (s *dht22Service) CountTrash(ctx context.Context) (int, error) {
+	// Call repository to count the deleted readings
+	return s.repository.CountTrash(ctx)
+}
+
+func (s *dht22Service) Purge(before time.Time, ctx context.Context) (int64, error) {
+	// Call repository to permanently remove the readings deleted before the given time
+	return s.repository.Purge(before, ctx)
+}
+
+// ValidateDHT22Data checks a reading against the column sizes and the DHT22 measuring range
+// It runs on Create and Update too, not only on the merged result of a PATCH: since PATCH /dht22/{id} was added,
+// POST and PUT /dht22 answer 400 to a reading they used to store, e.g. one without a device name or out of range
+func ValidateDHT22Data(data *models.DHT22Data) error {
+	var errMsg string
+	if data.DeviceName == "" || len(data.DeviceName) > 50 {
+		errMsg += "DeviceName is required and must be less than 50 characters. "
+	}
+	if data.Temperature < -40 || data.Temperature > 80 {
+		errMsg += "Temperature must be between -40 and 80. "
+	}
+	if data.Humidity < 0 || data.Humidity > 100 {
+		errMsg += "Humidity must be between 0 and 100. "
+	}
+	if _, err := time.Parse(time.RFC3339, data.DateTime); err != nil {
+		errMsg += "DateTime must be in the format: 2021-01-01T12:00:00Z. "
+	}
+	if errMsg != "" {
+		return DHT22ValidationError(errMsg)
+	}
+	return nil
+}
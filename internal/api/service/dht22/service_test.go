@@ -0,0 +1,43 @@
+package dht22
+
+import (
+	"context"
+	"goapi/internal/api/repository/DAL/memory"
+	"goapi/internal/api/repository/models"
+	"testing"
+)
+
+func TestDHT22Service_DateTimeInUTC(t *testing.T) {
+	service := NewDHT22Service(memory.NewDHT22Repository(memory.NewDatabase()))
+	tests := []struct {
+		dateTime string
+		want     string
+	}{
+		{"2024-12-22T12:00:00Z", "2024-12-22T12:00:00Z"},
+		{"2024-12-22T14:00:00+02:00", "2024-12-22T12:00:00Z"},
+		{"2024-12-22T07:30:00.25-04:30", "2024-12-22T12:00:00.25Z"},
+		{"2024-12-22T12:00:00.1234567Z", "2024-12-22T12:00:00.123456Z"},
+	}
+	for _, tt := range tests {
+		data := &models.DHT22Data{DeviceName: "greenhouse", Temperature: 21.5, Humidity: 50, DateTime: tt.dateTime}
+		if err := service.Create(data, context.Background()); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		stored, err := service.ReadOne(data.ID, context.Background())
+		if err != nil || stored == nil {
+			t.Fatalf("ReadOne returned %v, %v", stored, err)
+		}
+		if data.DateTime != tt.want || stored.DateTime != tt.want {
+			t.Errorf("Create of date_time %v returned %v and stored %v, want %v", tt.dateTime, data.DateTime, stored.DateTime, tt.want)
+		}
+
+		// * An update is written in UTC too
+		data.DateTime = tt.dateTime
+		if err := service.Update(data, context.Background()); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if data.DateTime != tt.want {
+			t.Errorf("Update of date_time %v returned %v, want %v", tt.dateTime, data.DateTime, tt.want)
+		}
+	}
+}
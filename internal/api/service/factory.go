@@ -3,9 +3,15 @@ package service
 import (
 	"context"
 	"goapi/internal/api/repository/DAL"
+	"goapi/internal/api/repository/DAL/PostgreSQL"
 	"goapi/internal/api/repository/DAL/SQLite"
+	"goapi/internal/api/repository/DAL/disk"
+	"goapi/internal/api/repository/DAL/memory"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/service/backup"
 	service "goapi/internal/api/service/data"
 	"goapi/internal/api/service/dht22"
+	"goapi/internal/api/service/sensor"
 	"log"
 )
 
@@ -15,24 +21,51 @@ type DHT22ServiceType int
 
 const (
 	SQLiteDHT22Service DHT22ServiceType = iota
+	// * MemoryDHT22Service keeps the readings in memory, nothing is written to disk
+	MemoryDHT22Service
+	// * PostgreSQLDHT22Service keeps the readings in a PostgreSQL database that several servers can share
+	PostgreSQLDHT22Service
 )
 
 const (
 	SQLiteDataService DataServiceType = iota
+	// * MemoryDataService keeps the records, their types, rates and attachments in memory, nothing is written to disk
+	MemoryDataService
+	// * PostgreSQLDataService keeps the records, their types and rates in PostgreSQL, attachments are on disk like with SQLite
+	PostgreSQLDataService
 )
 
+// * Config of the services that is not in the database *
+type Config struct {
+	// * AttachmentsDir is the directory the files attached to records are stored in, with PostgreSQL they are in the database
+	AttachmentsDir   string
+	AttachmentLimits service.AttachmentLimits
+	// * Backup is where backups of the SQLite database are written and how many are kept
+	Backup backup.Config
+	// * Archive is where the readings moved out of the database are written
+	Archive dht22.ArchiveConfig
+	// * SensorTypes are declared besides sensor.BuiltinTypes, e.g. loaded with sensor.LoadTypes
+	SensorTypes []models.SensorType
+}
+
 type ServiceFactory struct {
 	db     DAL.SQLDatabase
 	logger *log.Logger
 	ctx    context.Context
+	config Config
+	// * db is the SQLite or PostgreSQL database of the services of that type
+	// * memory is shared by the in-memory services, like the SQLite ones share db
+	memory *memory.Database
 }
 
-// * Factory for creating data service *
-func NewServiceFactory(db DAL.SQLDatabase, logger *log.Logger, ctx context.Context) *ServiceFactory {
+// * Factory for creating data service, db can be nil when only in-memory services are created *
+func NewServiceFactory(db DAL.SQLDatabase, logger *log.Logger, ctx context.Context, config Config) *ServiceFactory {
 	return &ServiceFactory{
 		db:     db,
 		logger: logger,
 		ctx:    ctx,
+		config: config,
+		memory: memory.NewDatabase(),
 	}
 }
 
@@ -45,7 +78,52 @@ func (sf *ServiceFactory) CreateDataService(serviceType DataServiceType) (*servi
 		if err != nil {
 			return nil, err
 		}
-		ds := service.NewDataServiceSQLite(repo)
+		rates, err := SQLite.NewExchangeRateRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		types, err := SQLite.NewDataTypeRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		attachments, err := SQLite.NewAttachmentRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		blobs, err := disk.NewBlobStore(sf.config.AttachmentsDir)
+		if err != nil {
+			return nil, err
+		}
+		ds := service.NewDataServiceSQLite(repo, rates, types, attachments, blobs, sf.config.AttachmentLimits)
+		return ds, nil
+	case PostgreSQLDataService:
+		repo, err := PostgreSQL.NewDataRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		rates, err := PostgreSQL.NewExchangeRateRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		types, err := PostgreSQL.NewDataTypeRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		attachments, err := PostgreSQL.NewAttachmentRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		// * The servers that share the database do not share a disk, the files are kept in the database too
+		blobs, err := PostgreSQL.NewBlobStore(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		ds := service.NewDataServiceSQLite(repo, rates, types, attachments, blobs, sf.config.AttachmentLimits)
+		return ds, nil
+	case MemoryDataService:
+		// * The service is the same, only its repositories are in memory
+		ds := service.NewDataServiceSQLite(memory.NewDataRepository(sf.memory), memory.NewExchangeRateRepository(sf.memory),
+			memory.NewDataTypeRepository(sf.memory), memory.NewAttachmentRepository(sf.memory), memory.NewBlobStore(), sf.config.AttachmentLimits)
 		return ds, nil
 	default:
 		return nil, service.DataError{Message: "Invalid data service type."}
@@ -61,7 +139,78 @@ func (sf *ServiceFactory) CreateDHT22Service(serviceType DHT22ServiceType) (dht2
 		}
 		ds := dht22.NewDHT22Service(repo)
 		return ds, nil
+	case PostgreSQLDHT22Service:
+		repo, err := PostgreSQL.NewDHT22Repository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		return dht22.NewDHT22Service(repo), nil
+	case MemoryDHT22Service:
+		return dht22.NewDHT22Service(memory.NewDHT22Repository(sf.memory)), nil
 	default:
 		return nil, dht22.DHT22Error("Invalid DHT22 service type.")
 	}
 }
+
+// * CreateDHT22ArchiveService returns the archive of the readings of the DHT22 services of a type *
+// * The archive files of every backend are on disk, in Config.Archive.Dir
+func (sf *ServiceFactory) CreateDHT22ArchiveService(serviceType DHT22ServiceType) (dht22.ArchiveService, error) {
+	switch serviceType {
+	case SQLiteDHT22Service:
+		repo, err := SQLite.NewDHT22ArchiveRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		return dht22.NewArchiveService(repo, DAL.NewUnitOfWork(sf.db), sf.config.Archive), nil
+	case PostgreSQLDHT22Service:
+		repo, err := PostgreSQL.NewDHT22ArchiveRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		return dht22.NewArchiveService(repo, DAL.NewUnitOfWork(sf.db), sf.config.Archive), nil
+	case MemoryDHT22Service:
+		return dht22.NewArchiveService(memory.NewDHT22ArchiveRepository(sf.memory), memory.NewUnitOfWork(sf.memory), sf.config.Archive), nil
+	default:
+		return nil, dht22.DHT22Error("Invalid DHT22 service type.")
+	}
+}
+
+// * CreateSensorService returns the readings of the registered sensor types, kept in their own table in the database of the DHT22 services of a type *
+// * The builtin types and Config.SensorTypes are registered, a declaration that is not valid is an error
+func (sf *ServiceFactory) CreateSensorService(serviceType DHT22ServiceType) (sensor.SensorService, error) {
+	registry, err := sensor.NewRegistry(append(append([]models.SensorType{}, sensor.BuiltinTypes...), sf.config.SensorTypes...)...)
+	if err != nil {
+		return nil, err
+	}
+	switch serviceType {
+	case SQLiteDHT22Service:
+		repo, err := SQLite.NewSensorRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		return sensor.NewSensorService(repo, registry), nil
+	case PostgreSQLDHT22Service:
+		repo, err := PostgreSQL.NewSensorRepository(sf.db, sf.ctx)
+		if err != nil {
+			return nil, err
+		}
+		return sensor.NewSensorService(repo, registry), nil
+	case MemoryDHT22Service:
+		return sensor.NewSensorService(memory.NewSensorRepository(sf.memory), registry), nil
+	default:
+		return nil, dht22.DHT22Error("Invalid DHT22 service type.")
+	}
+}
+
+// * CreateBackupService returns the backups of the database behind the data services of a type *
+// * Only SQLite is backed up by the server, PostgreSQL has its own tools and memory has nothing to keep
+func (sf *ServiceFactory) CreateBackupService(serviceType DataServiceType) (backup.BackupService, error) {
+	switch serviceType {
+	case SQLiteDataService:
+		return backup.NewSQLiteBackupService(sf.db, sf.config.Backup, sf.logger), nil
+	case PostgreSQLDataService, MemoryDataService:
+		return backup.NewUnsupportedBackupService(), nil
+	default:
+		return nil, service.DataError{Message: "Invalid data service type."}
+	}
+}
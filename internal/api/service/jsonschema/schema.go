@@ -0,0 +1,357 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+)
+
+// * Schema is a compiled JSON Schema, the subset of draft 2020-12 needed to describe the attributes of a record: *
+// * type, properties, required, additionalProperties, items, enum, const, minimum, maximum, exclusiveMinimum, *
+// * exclusiveMaximum, minLength, maxLength, pattern, minItems and maxItems. Other keywords are rejected by Compile *
+// * instead of being silently ignored, so a schema never looks stricter than it is. *
+type Schema struct {
+	types                []string
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	noAdditional         bool
+	items                *Schema
+	enum                 []any
+	constValue           any
+	hasConst             bool
+	minimum              *float64
+	maximum              *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	minLength            *int
+	maxLength            *int
+	pattern              *regexp.Regexp
+	minItems             *int
+	maxItems             *int
+}
+
+// * FieldError is one value that does not match the schema, Field is the path to it, e.g. "ports[0].speed" *
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// * annotations are keywords that do not constrain a value *
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+}
+
+var typeNames = map[string]bool{
+	"object":  true,
+	"array":   true,
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"null":    true,
+}
+
+// Compile parses a JSON Schema document.
+func Compile(raw []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	return compile(doc, "")
+}
+
+func compile(doc any, path string) (*Schema, error) {
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", location(path))
+	}
+
+	s := &Schema{}
+	for keyword, value := range obj {
+		var err error
+		switch keyword {
+		case "type":
+			err = s.compileType(value)
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: properties must be an object", location(path))
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, prop := range props {
+				if s.properties[name], err = compile(prop, join(path, name)); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			if s.required, err = stringList(value); err != nil {
+				err = fmt.Errorf("required %w", err)
+			}
+		case "additionalProperties":
+			if allowed, ok := value.(bool); ok {
+				s.noAdditional = !allowed
+			} else if s.additionalProperties, err = compile(value, join(path, "*")); err != nil {
+				return nil, err
+			}
+		case "items":
+			if s.items, err = compile(value, path+"[]"); err != nil {
+				return nil, err
+			}
+		case "enum":
+			values, ok := value.([]any)
+			if !ok || len(values) == 0 {
+				return nil, fmt.Errorf("%s: enum must be a non-empty array", location(path))
+			}
+			s.enum = values
+		case "const":
+			s.constValue, s.hasConst = value, true
+		case "minimum":
+			s.minimum, err = number(keyword, value)
+		case "maximum":
+			s.maximum, err = number(keyword, value)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = number(keyword, value)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = number(keyword, value)
+		case "minLength":
+			s.minLength, err = count(keyword, value)
+		case "maxLength":
+			s.maxLength, err = count(keyword, value)
+		case "minItems":
+			s.minItems, err = count(keyword, value)
+		case "maxItems":
+			s.maxItems, err = count(keyword, value)
+		case "pattern":
+			p, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: pattern must be a string", location(path))
+			}
+			if s.pattern, err = regexp.Compile(p); err != nil {
+				err = fmt.Errorf("pattern is not a valid regular expression")
+			}
+		default:
+			if !annotations[keyword] {
+				return nil, fmt.Errorf("%s: keyword %q is not supported", location(path), keyword)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location(path), err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) compileType(value any) error {
+	switch t := value.(type) {
+	case string:
+		s.types = []string{t}
+	case []any:
+		names, err := stringList(t)
+		if err != nil {
+			return fmt.Errorf("type %w", err)
+		}
+		s.types = names
+	default:
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	for _, name := range s.types {
+		if !typeNames[name] {
+			return fmt.Errorf("unknown type %q", name)
+		}
+	}
+	return nil
+}
+
+// Validate checks a value decoded by encoding/json against the schema, every mismatch is returned.
+// Field errors are reported under prefix, e.g. "attributes".
+func (s *Schema) Validate(value any, prefix string) []FieldError {
+	var errs []FieldError
+	s.validate(value, prefix, &errs)
+	return errs
+}
+
+func (s *Schema) validate(value any, path string, errs *[]FieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !s.matchesType(value) {
+		if len(s.types) == 1 {
+			fail("must be of type %s", s.types[0])
+		} else {
+			fail("must be of one of the types %v", s.types)
+		}
+		return
+	}
+	if s.hasConst && !equal(value, s.constValue) {
+		fail("must be %s", encode(s.constValue))
+	}
+	if s.enum != nil && !contains(s.enum, value) {
+		fail("must be one of %s", encode(s.enum))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be at least %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be at most %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			fail("must be greater than %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			fail("must be less than %v", *s.exclusiveMaximum)
+		}
+	case string:
+		length := len([]rune(v))
+		if s.minLength != nil && length < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match the pattern %s", s.pattern.String())
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, FieldError{Field: join(path, name), Message: "is required"})
+			}
+		}
+		// * Sorted, so the same value always gives the errors in the same order
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.properties[name]; ok {
+				prop.validate(v[name], join(path, name), errs)
+			} else if s.noAdditional {
+				*errs = append(*errs, FieldError{Field: join(path, name), Message: "is not allowed"})
+			} else if s.additionalProperties != nil {
+				s.additionalProperties.validate(v[name], join(path, name), errs)
+			}
+		}
+	}
+}
+
+func (s *Schema) matchesType(value any) bool {
+	for _, t := range s.types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v) && !math.IsInf(v, 0)) {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func location(path string) string {
+	if path == "" {
+		return "schema"
+	}
+	return "schema of " + path
+}
+
+func stringList(value any) ([]string, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	list := make([]string, len(values))
+	for i, v := range values {
+		if list[i], ok = v.(string); !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+	}
+	return list, nil
+}
+
+func number(keyword string, value any) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", keyword)
+	}
+	return &n, nil
+}
+
+func count(keyword string, value any) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("%s must be a non-negative integer", keyword)
+	}
+	c := int(n)
+	return &c, nil
+}
+
+func contains(values []any, value any) bool {
+	for _, v := range values {
+		if equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// * equal compares JSON values, they are equal when they encode the same way (object keys are sorted by encoding/json) *
+func equal(a any, b any) bool {
+	return encode(a) == encode(b)
+}
+
+func encode(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,82 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const sensorSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["voltage", "interface"],
+	"additionalProperties": false,
+	"properties": {
+		"voltage": {"type": "number", "minimum": 1.8, "maximum": 5.5},
+		"interface": {"enum": ["i2c", "spi", "one-wire"]},
+		"address": {"type": "string", "pattern": "^0x[0-9a-f]{2}$"},
+		"pins": {"type": "array", "maxItems": 2, "items": {"type": "integer", "minimum": 0}}
+	}
+}`
+
+func validate(t *testing.T, schema string, value string) []FieldError {
+	t.Helper()
+	s, err := Compile([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v any
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		t.Fatal(err)
+	}
+	return s.Validate(v, "attributes")
+}
+
+func TestValidateValid(t *testing.T) {
+	errs := validate(t, sensorSchema, `{"voltage": 3.3, "interface": "i2c", "address": "0x76", "pins": [4, 5]}`)
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidateFieldErrors(t *testing.T) {
+	errs := validate(t, sensorSchema, `{"voltage": 12, "address": "76", "pins": [4, 1.5, -1], "colour": "red"}`)
+	expected := []FieldError{
+		{Field: "attributes.interface", Message: "is required"},
+		{Field: "attributes.address", Message: "must match the pattern ^0x[0-9a-f]{2}$"},
+		{Field: "attributes.colour", Message: "is not allowed"},
+		{Field: "attributes.pins", Message: "must have at most 2 items"},
+		{Field: "attributes.pins[1]", Message: "must be of type integer"},
+		{Field: "attributes.pins[2]", Message: "must be at least 0"},
+		{Field: "attributes.voltage", Message: "must be at most 5.5"},
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("unexpected errors:\n got %v\nwant %v", errs, expected)
+	}
+}
+
+func TestValidateType(t *testing.T) {
+	errs := validate(t, `{"type": "object"}`, `[1, 2]`)
+	if len(errs) != 1 || errs[0].Field != "attributes" || errs[0].Message != "must be of type object" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		"not JSON":            `{`,
+		"not an object":       `[]`,
+		"unknown type":        `{"type": "float"}`,
+		"unsupported keyword": `{"properties": {"a": {"$ref": "#/defs/a"}}}`,
+		"negative maxLength":  `{"maxLength": -1}`,
+		"invalid pattern":     `{"pattern": "("}`,
+		"empty enum":          `{"enum": []}`,
+	}
+	for name, schema := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Compile([]byte(schema)); err == nil {
+				t.Errorf("expected an error for %s", schema)
+			}
+		})
+	}
+}
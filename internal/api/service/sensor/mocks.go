@@ -0,0 +1,180 @@
+package sensor
+
+import (
+	"context"
+	"goapi/internal/api/repository/models"
+	"time"
+)
+
+// mockRegistry holds the builtin types of the mocks
+var mockRegistry, _ = NewRegistry(BuiltinTypes...)
+
+// MockSensorServiceSuccessful: Simulates a service with the builtin types and reading 1 of every type, at version 1
+type MockSensorServiceSuccessful struct{}
+
+func (m *MockSensorServiceSuccessful) Types() []*models.SensorType {
+	return mockRegistry.Types()
+}
+
+func (m *MockSensorServiceSuccessful) Type(name string) *models.SensorType {
+	return mockRegistry.Lookup(name)
+}
+
+func (m *MockSensorServiceSuccessful) Create(reading *models.SensorReading, ctx context.Context) error {
+	sensorType := mockRegistry.Lookup(reading.Type)
+	if sensorType == nil {
+		return ErrUnknownSensorType
+	}
+	if err := ValidateSensorReading(sensorType, reading); err != nil {
+		return err
+	}
+	reading.ID = 1
+	reading.Version = 1
+	return nil
+}
+
+func (m *MockSensorServiceSuccessful) ReadOne(sensorType string, id int, ctx context.Context) (*models.SensorReading, error) {
+	if mockRegistry.Lookup(sensorType) == nil {
+		return nil, ErrUnknownSensorType
+	}
+	if id != 1 {
+		return nil, nil
+	}
+	return &models.SensorReading{
+		ID:         1,
+		Type:       sensorType,
+		DeviceName: "greenhouse",
+		DateTime:   "2024-12-22T12:00:00Z",
+		Values:     map[string]float64{"temperature": 21.5},
+		Version:    1,
+		CreatedAt:  "2024-12-22T12:00:00.000000Z",
+		UpdatedAt:  "2024-12-22T12:00:00.000000Z",
+	}, nil
+}
+
+func (m *MockSensorServiceSuccessful) ReadMany(sensorType string, page, rowsPerPage int, ctx context.Context) ([]*models.SensorReading, error) {
+	if mockRegistry.Lookup(sensorType) == nil {
+		return nil, ErrUnknownSensorType
+	}
+	return []*models.SensorReading{
+		{ID: 1, Type: sensorType, DeviceName: "greenhouse", DateTime: "2024-12-22T12:00:00Z", Values: map[string]float64{"temperature": 21.5}, Version: 1},
+		{ID: 2, Type: sensorType, DeviceName: "greenhouse", DateTime: "2024-12-22T13:00:00Z", Values: map[string]float64{"temperature": 22}, Version: 1},
+	}, nil
+}
+
+func (m *MockSensorServiceSuccessful) Count(sensorType string, ctx context.Context) (int, error) {
+	if mockRegistry.Lookup(sensorType) == nil {
+		return 0, ErrUnknownSensorType
+	}
+	return 2, nil
+}
+
+func (m *MockSensorServiceSuccessful) Update(reading *models.SensorReading, ctx context.Context) (int64, error) {
+	sensorType := mockRegistry.Lookup(reading.Type)
+	if sensorType == nil {
+		return 0, ErrUnknownSensorType
+	}
+	if err := ValidateSensorReading(sensorType, reading); err != nil {
+		return 0, err
+	}
+	if reading.ID != 1 {
+		return 0, nil
+	}
+	if reading.Version != 0 && reading.Version != 1 {
+		return 0, models.ErrVersionMismatch
+	}
+	reading.Version = 2
+	return 1, nil
+}
+
+func (m *MockSensorServiceSuccessful) Delete(sensorType string, id int, version int, ctx context.Context) (int64, error) {
+	if mockRegistry.Lookup(sensorType) == nil {
+		return 0, ErrUnknownSensorType
+	}
+	if id != 1 {
+		return 0, nil
+	}
+	if version != 0 && version != 1 {
+		return 0, models.ErrVersionMismatch
+	}
+	return 1, nil
+}
+
+func (m *MockSensorServiceSuccessful) Restore(sensorType string, id int, ctx context.Context) (int64, error) {
+	if mockRegistry.Lookup(sensorType) == nil {
+		return 0, ErrUnknownSensorType
+	}
+	if id != 1 {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func (m *MockSensorServiceSuccessful) ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedSensorReading, error) {
+	return []*models.TrashedSensorReading{
+		{
+			SensorReading: models.SensorReading{ID: 2, Type: "ds18b20", DeviceName: "greenhouse", DateTime: "2024-12-22T13:00:00Z", Values: map[string]float64{"temperature": 22}, Version: 1},
+			DeletedAt:     "2024-12-23T09:00:00.000000Z",
+			DeletedBy:     "admin",
+		},
+	}, nil
+}
+
+func (m *MockSensorServiceSuccessful) CountTrash(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+func (m *MockSensorServiceSuccessful) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+// MockSensorServiceError: Simulates a service with the builtin types whose storage fails
+type MockSensorServiceError struct{}
+
+func (m *MockSensorServiceError) Types() []*models.SensorType {
+	return mockRegistry.Types()
+}
+
+func (m *MockSensorServiceError) Type(name string) *models.SensorType {
+	return mockRegistry.Lookup(name)
+}
+
+func (m *MockSensorServiceError) Create(reading *models.SensorReading, ctx context.Context) error {
+	return SensorError("Error creating reading")
+}
+
+func (m *MockSensorServiceError) ReadOne(sensorType string, id int, ctx context.Context) (*models.SensorReading, error) {
+	return nil, SensorError("Error reading reading")
+}
+
+func (m *MockSensorServiceError) ReadMany(sensorType string, page, rowsPerPage int, ctx context.Context) ([]*models.SensorReading, error) {
+	return nil, SensorError("Error reading readings")
+}
+
+func (m *MockSensorServiceError) Count(sensorType string, ctx context.Context) (int, error) {
+	return 0, SensorError("Error counting readings")
+}
+
+func (m *MockSensorServiceError) Update(reading *models.SensorReading, ctx context.Context) (int64, error) {
+	return 0, SensorError("Error updating reading")
+}
+
+func (m *MockSensorServiceError) Delete(sensorType string, id int, version int, ctx context.Context) (int64, error) {
+	return 0, SensorError("Error deleting reading")
+}
+
+func (m *MockSensorServiceError) Restore(sensorType string, id int, ctx context.Context) (int64, error) {
+	return 0, SensorError("Error restoring reading")
+}
+
+func (m *MockSensorServiceError) ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedSensorReading, error) {
+	return nil, SensorError("Error reading trash")
+}
+
+func (m *MockSensorServiceError) CountTrash(ctx context.Context) (int, error) {
+	return 0, SensorError("Error counting trash")
+}
+
+func (m *MockSensorServiceError) Purge(before time.Time, ctx context.Context) (int64, error) {
+	return 0, SensorError("Error purging readings")
+}
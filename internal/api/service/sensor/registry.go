@@ -0,0 +1,134 @@
+package sensor
+
+import (
+	"encoding/json"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// SensorError is returned when a sensor type can not be registered or used
+type SensorError string
+
+func (e SensorError) Error() string {
+	return string(e)
+}
+
+var ErrUnknownSensorType = SensorError("Unknown sensor type.")
+
+// namePattern is what the names of types and fields look like, a type name is a part of the URI of its readings
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// BuiltinTypes are the sensor types every server knows, more can be declared in a JSON file, see LoadTypes
+var BuiltinTypes = []models.SensorType{
+	{
+		Name:        "bme280",
+		Description: "Bosch BME280 temperature, humidity and barometric pressure sensor",
+		Fields: []models.SensorField{
+			{Name: "temperature", Unit: "°C", Min: -40, Max: 85},
+			{Name: "humidity", Unit: "%", Min: 0, Max: 100},
+			{Name: "pressure", Unit: "hPa", Min: 300, Max: 1100},
+		},
+	},
+	{
+		Name:        "ds18b20",
+		Description: "Maxim DS18B20 1-Wire temperature sensor",
+		Fields: []models.SensorField{
+			{Name: "temperature", Unit: "°C", Min: -55, Max: 125},
+		},
+	},
+	{
+		Name:        "scd30",
+		Description: "Sensirion SCD30 CO2, temperature and humidity sensor",
+		Fields: []models.SensorField{
+			{Name: "co2", Unit: "ppm", Min: 0, Max: 40000},
+			{Name: "temperature", Unit: "°C", Min: -40, Max: 70, Optional: true},
+			{Name: "humidity", Unit: "%", Min: 0, Max: 100, Optional: true},
+		},
+	},
+}
+
+// Registry holds the declared sensor types, it is filled when the server starts and only read afterwards
+type Registry struct {
+	types map[string]*models.SensorType
+}
+
+// NewRegistry checks and registers the types, a type declared twice is an error
+func NewRegistry(types ...models.SensorType) (*Registry, error) {
+	registry := &Registry{types: map[string]*models.SensorType{}}
+	for _, t := range types {
+		if err := registry.Register(t); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register declares a sensor type, its readings can then be stored under its name
+func (r *Registry) Register(sensorType models.SensorType) error {
+	if err := validateSensorType(&sensorType); err != nil {
+		return err
+	}
+	if _, ok := r.types[sensorType.Name]; ok {
+		return SensorError(fmt.Sprintf("Sensor type %s is declared twice.", sensorType.Name))
+	}
+	// * The fields are copied, the caller can not change a registered type
+	sensorType.Fields = append([]models.SensorField(nil), sensorType.Fields...)
+	r.types[sensorType.Name] = &sensorType
+	return nil
+}
+
+// Lookup returns a registered type, nil if no type has the name
+func (r *Registry) Lookup(name string) *models.SensorType {
+	return r.types[name]
+}
+
+// Types returns the registered types ordered by name
+func (r *Registry) Types() []*models.SensorType {
+	types := make([]*models.SensorType, 0, len(r.types))
+	for _, t := range r.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// LoadTypes reads the declarations of sensor types from a JSON file holding an array of types, e.g.
+// [{"name": "sht31", "description": "...", "fields": [{"name": "temperature", "unit": "°C", "min": -40, "max": 125}]}]
+func LoadTypes(path string) ([]models.SensorType, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var types []models.SensorType
+	if err := json.Unmarshal(content, &types); err != nil {
+		return nil, SensorError(fmt.Sprintf("Invalid sensor types in %s: %v.", path, err))
+	}
+	return types, nil
+}
+
+// validateSensorType checks a declaration, the readings of a type that passes can always be stored
+func validateSensorType(t *models.SensorType) error {
+	if len(t.Name) > 50 || !namePattern.MatchString(t.Name) {
+		return SensorError(fmt.Sprintf("Sensor type name %q must be lowercase letters, digits, - and _, and less than 50 characters.", t.Name))
+	}
+	if len(t.Fields) == 0 {
+		return SensorError(fmt.Sprintf("Sensor type %s must have at least one field.", t.Name))
+	}
+	seen := map[string]bool{}
+	for _, f := range t.Fields {
+		if len(f.Name) > 50 || !namePattern.MatchString(f.Name) {
+			return SensorError(fmt.Sprintf("Field name %q of sensor type %s must be lowercase letters, digits, - and _, and less than 50 characters.", f.Name, t.Name))
+		}
+		if seen[f.Name] {
+			return SensorError(fmt.Sprintf("Field %s of sensor type %s is declared twice.", f.Name, t.Name))
+		}
+		seen[f.Name] = true
+		if f.Min >= f.Max {
+			return SensorError(fmt.Sprintf("Field %s of sensor type %s must have a min lower than its max.", f.Name, t.Name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/service/jsonschema"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SensorService stores and validates the readings of the registered sensor types
+type SensorService interface {
+	Types() []*models.SensorType
+	Type(name string) *models.SensorType
+	Create(reading *models.SensorReading, ctx context.Context) error
+	ReadOne(sensorType string, id int, ctx context.Context) (*models.SensorReading, error)
+	ReadMany(sensorType string, page, rowsPerPage int, ctx context.Context) ([]*models.SensorReading, error)
+	Count(sensorType string, ctx context.Context) (int, error)
+	Update(reading *models.SensorReading, ctx context.Context) (int64, error)
+	Delete(sensorType string, id int, version int, ctx context.Context) (int64, error)
+	Restore(sensorType string, id int, ctx context.Context) (int64, error)
+	ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedSensorReading, error)
+	CountTrash(ctx context.Context) (int, error)
+	Purge(before time.Time, ctx context.Context) (int64, error)
+}
+
+// ValidationError lists every field of a reading that is not valid, it is a client error
+type ValidationError struct {
+	Errors []jsonschema.FieldError
+}
+
+func (ve ValidationError) Error() string {
+	msgs := make([]string, len(ve.Errors))
+	for i, e := range ve.Errors {
+		msgs[i] = e.Field + " " + e.Message
+	}
+	return "Invalid reading: " + strings.Join(msgs, ", ") + "."
+}
+
+// sensorService implements the SensorService interface
+type sensorService struct {
+	repository models.SensorRepository
+	registry   *Registry
+}
+
+func NewSensorService(repository models.SensorRepository, registry *Registry) SensorService {
+	return &sensorService{
+		repository: repository,
+		registry:   registry,
+	}
+}
+
+func (s *sensorService) Types() []*models.SensorType {
+	return s.registry.Types()
+}
+
+func (s *sensorService) Type(name string) *models.SensorType {
+	return s.registry.Lookup(name)
+}
+
+func (s *sensorService) Create(reading *models.SensorReading, ctx context.Context) error {
+	if err := s.validate(reading); err != nil {
+		return err
+	}
+	reading.DateTime = models.UTCDateTime(reading.DateTime)
+	return s.repository.Create(reading, ctx)
+}
+
+func (s *sensorService) ReadOne(sensorType string, id int, ctx context.Context) (*models.SensorReading, error) {
+	if s.registry.Lookup(sensorType) == nil {
+		return nil, ErrUnknownSensorType
+	}
+	return s.repository.ReadOne(sensorType, id, ctx)
+}
+
+func (s *sensorService) ReadMany(sensorType string, page, rowsPerPage int, ctx context.Context) ([]*models.SensorReading, error) {
+	if s.registry.Lookup(sensorType) == nil {
+		return nil, ErrUnknownSensorType
+	}
+	return s.repository.ReadMany(sensorType, page, rowsPerPage, ctx)
+}
+
+func (s *sensorService) Count(sensorType string, ctx context.Context) (int, error) {
+	if s.registry.Lookup(sensorType) == nil {
+		return 0, ErrUnknownSensorType
+	}
+	return s.repository.Count(sensorType, ctx)
+}
+
+// Update replaces a reading with a valid one, a version other than 0 must be the stored one
+func (s *sensorService) Update(reading *models.SensorReading, ctx context.Context) (int64, error) {
+	if err := s.validate(reading); err != nil {
+		return 0, err
+	}
+	reading.DateTime = models.UTCDateTime(reading.DateTime)
+	return s.repository.Update(reading, ctx)
+}
+
+func (s *sensorService) Delete(sensorType string, id int, version int, ctx context.Context) (int64, error) {
+	if s.registry.Lookup(sensorType) == nil {
+		return 0, ErrUnknownSensorType
+	}
+	return s.repository.Delete(sensorType, id, version, ctx)
+}
+
+func (s *sensorService) Restore(sensorType string, id int, ctx context.Context) (int64, error) {
+	if s.registry.Lookup(sensorType) == nil {
+		return 0, ErrUnknownSensorType
+	}
+	// Call repository to take the reading out of the trash, 0 if it is not in the trash
+	return s.repository.Restore(sensorType, id, ctx)
+}
+
+func (s *sensorService) ReadTrash(page, rowsPerPage int, ctx context.Context) ([]*models.TrashedSensorReading, error) {
+	// Call repository to fetch the deleted readings of every type
+	return s.repository.ReadTrash(page, rowsPerPage, ctx)
+}
+
+func (s *sensorService) CountTrash(ctx context.Context) (int, error) {
+	// Call repository to count the deleted readings
+	return s.repository.CountTrash(ctx)
+}
+
+func (s *sensorService) Purge(before time.Time, ctx context.Context) (int64, error) {
+	// Call repository to permanently remove the readings deleted before the given time
+	return s.repository.Purge(before, ctx)
+}
+
+// validate checks a reading against the declaration of its type
+func (s *sensorService) validate(reading *models.SensorReading) error {
+	sensorType := s.registry.Lookup(reading.Type)
+	if sensorType == nil {
+		return ErrUnknownSensorType
+	}
+	return ValidateSensorReading(sensorType, reading)
+}
+
+// ValidateSensorReading checks a reading against the column sizes and the fields, units and ranges of its type.
+// Every required field must have a value, and values of fields the type does not declare are rejected.
+func ValidateSensorReading(sensorType *models.SensorType, reading *models.SensorReading) error {
+	var errs []jsonschema.FieldError
+	fail := func(field, message string) {
+		errs = append(errs, jsonschema.FieldError{Field: field, Message: message})
+	}
+
+	if reading.DeviceName == "" || len(reading.DeviceName) > 50 {
+		fail("device_name", "is required and must be less than 50 characters")
+	}
+	if _, err := time.Parse(time.RFC3339, reading.DateTime); err != nil {
+		fail("date_time", "must be in the format: 2021-01-01T12:00:00Z")
+	}
+	for _, f := range sensorType.Fields {
+		value, ok := reading.Values[f.Name]
+		if !ok {
+			if !f.Optional {
+				fail("values."+f.Name, "is required")
+			}
+			continue
+		}
+		if value < f.Min || value > f.Max {
+			fail("values."+f.Name, fmt.Sprintf("must be between %s and %s %s", formatFloat(f.Min), formatFloat(f.Max), f.Unit))
+		}
+	}
+	// * Map order is random, the unknown fields are reported in name order
+	var unknown []string
+	for name := range reading.Values {
+		if sensorType.Field(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		fail("values."+name, "is not a field of "+sensorType.Name)
+	}
+
+	if len(errs) > 0 {
+		return ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,193 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"goapi/internal/api/repository/DAL/memory"
+	"goapi/internal/api/repository/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestService(t *testing.T) SensorService {
+	t.Helper()
+	registry, err := NewRegistry(BuiltinTypes...)
+	if err != nil {
+		t.Fatalf("NewRegistry of the builtin types failed: %v", err)
+	}
+	return NewSensorService(memory.NewSensorRepository(memory.NewDatabase()), registry)
+}
+
+func fieldsOf(err error) []string {
+	var ve ValidationError
+	if !errors.As(err, &ve) {
+		return nil
+	}
+	fields := []string{}
+	for _, e := range ve.Errors {
+		fields = append(fields, e.Field)
+	}
+	return fields
+}
+
+func TestNewRegistry_InvalidTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []models.SensorType
+	}{
+		{"no name", []models.SensorType{{Fields: []models.SensorField{{Name: "co2", Min: 0, Max: 1}}}}},
+		{"name not in a URI", []models.SensorType{{Name: "BME/280", Fields: []models.SensorField{{Name: "co2", Min: 0, Max: 1}}}}},
+		{"no fields", []models.SensorType{{Name: "empty"}}},
+		{"field declared twice", []models.SensorType{{Name: "twice", Fields: []models.SensorField{{Name: "co2", Min: 0, Max: 1}, {Name: "co2", Min: 0, Max: 1}}}}},
+		{"min above max", []models.SensorType{{Name: "upside-down", Fields: []models.SensorField{{Name: "co2", Min: 1, Max: 0}}}}},
+		{"type declared twice", append(append([]models.SensorType{}, BuiltinTypes...), BuiltinTypes[0])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRegistry(tt.types...); err == nil {
+				t.Errorf("Expected the types to be rejected")
+			}
+		})
+	}
+}
+
+func TestRegistry_Types(t *testing.T) {
+	registry, err := NewRegistry(models.SensorType{Name: "sht31", Fields: []models.SensorField{{Name: "temperature", Unit: "°C", Min: -40, Max: 125}}}, BuiltinTypes[0])
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+	var names []string
+	for _, st := range registry.Types() {
+		names = append(names, st.Name)
+	}
+	if strings.Join(names, ",") != "bme280,sht31" {
+		t.Errorf("Expected the types in name order, got %v", names)
+	}
+	if registry.Lookup("scd30") != nil {
+		t.Errorf("Expected a type that is not registered to be missing")
+	}
+}
+
+func TestLoadTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sensors.json")
+	content := `[{"name": "sht31", "description": "Sensirion SHT31", "fields": [{"name": "temperature", "unit": "°C", "min": -40, "max": 125}, {"name": "humidity", "unit": "%", "min": 0, "max": 100, "optional": true}]}]`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	types, err := LoadTypes(path)
+	if err != nil {
+		t.Fatalf("LoadTypes failed: %v", err)
+	}
+	registry, err := NewRegistry(append(types, BuiltinTypes...)...)
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+	if f := registry.Lookup("sht31").Field("humidity"); f == nil || !f.Optional || f.Max != 100 {
+		t.Errorf("Expected the humidity of sht31 to be loaded, got %+v", f)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"name": "sht31"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTypes(path); err == nil {
+		t.Errorf("Expected a file without an array of types to be rejected")
+	}
+}
+
+func TestSensorService_Validation(t *testing.T) {
+	service := newTestService(t)
+	tests := []struct {
+		name   string
+		values map[string]float64
+		want   string
+	}{
+		{"valid", map[string]float64{"temperature": 21.5, "humidity": 40, "pressure": 1013.25}, ""},
+		{"missing field", map[string]float64{"temperature": 21.5, "humidity": 40}, "values.pressure"},
+		{"out of range", map[string]float64{"temperature": 21.5, "humidity": 40, "pressure": 1200}, "values.pressure"},
+		{"unknown fields", map[string]float64{"temperature": 21.5, "humidity": 40, "pressure": 1013, "voc": 1, "co2": 400}, "values.co2,values.voc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading := &models.SensorReading{Type: "bme280", DeviceName: "greenhouse", DateTime: "2024-01-01T12:00:00Z", Values: tt.values}
+			err := service.Create(reading, context.Background())
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Create failed: %v", err)
+				}
+				return
+			}
+			if got := strings.Join(fieldsOf(err), ","); got != tt.want {
+				t.Errorf("Expected the invalid fields %s, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// * The optional fields of a SCD30 can be left out, CO2 can not
+	reading := &models.SensorReading{Type: "scd30", DeviceName: "office", DateTime: "2024-01-01T12:00:00Z", Values: map[string]float64{"co2": 850}}
+	if err := service.Create(reading, context.Background()); err != nil {
+		t.Errorf("Create of a reading without its optional fields failed: %v", err)
+	}
+	reading = &models.SensorReading{Type: "scd30", DeviceName: "", DateTime: "yesterday", Values: map[string]float64{"temperature": 20}}
+	if got := strings.Join(fieldsOf(service.Create(reading, context.Background())), ","); got != "device_name,date_time,values.co2" {
+		t.Errorf("Expected device_name, date_time and co2 to be invalid, got %s", got)
+	}
+}
+
+func TestSensorService_UnknownType(t *testing.T) {
+	service := newTestService(t)
+	reading := &models.SensorReading{Type: "dht11", DeviceName: "greenhouse", DateTime: "2024-01-01T12:00:00Z", Values: map[string]float64{"temperature": 20}}
+	if err := service.Create(reading, context.Background()); !errors.Is(err, ErrUnknownSensorType) {
+		t.Errorf("Create returned %v, want %v", err, ErrUnknownSensorType)
+	}
+	if _, err := service.ReadMany("dht11", 1, 10, context.Background()); !errors.Is(err, ErrUnknownSensorType) {
+		t.Errorf("ReadMany returned %v, want %v", err, ErrUnknownSensorType)
+	}
+	if _, err := service.Delete("dht11", 1, 0, context.Background()); !errors.Is(err, ErrUnknownSensorType) {
+		t.Errorf("Delete returned %v, want %v", err, ErrUnknownSensorType)
+	}
+}
+
+func TestSensorService_Update(t *testing.T) {
+	service := newTestService(t)
+	reading := &models.SensorReading{Type: "ds18b20", DeviceName: "boiler", DateTime: "2024-01-01T12:00:00Z", Values: map[string]float64{"temperature": 60}}
+	if err := service.Create(reading, context.Background()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	invalid := *reading
+	invalid.Values = map[string]float64{"temperature": 130}
+	if _, err := service.Update(&invalid, context.Background()); strings.Join(fieldsOf(err), ",") != "values.temperature" {
+		t.Errorf("Expected a temperature above the range of a DS18B20 to be rejected, got %v", err)
+	}
+
+	reading.Values = map[string]float64{"temperature": 65.5}
+	if n, err := service.Update(reading, context.Background()); err != nil || n != 1 {
+		t.Fatalf("Update returned %v, %v", n, err)
+	}
+	stored, err := service.ReadOne("ds18b20", reading.ID, context.Background())
+	if err != nil || stored == nil || stored.Values["temperature"] != 65.5 || stored.Version != 2 {
+		t.Errorf("Expected the updated reading at version 2, got %+v, %v", stored, err)
+	}
+}
+
+func TestSensorService_DateTimeInUTC(t *testing.T) {
+	service := newTestService(t)
+	reading := &models.SensorReading{Type: "ds18b20", DeviceName: "boiler", DateTime: "2024-01-01T14:00:00.5+02:00", Values: map[string]float64{"temperature": 60}}
+	if err := service.Create(reading, context.Background()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if reading.DateTime != "2024-01-01T12:00:00.5Z" {
+		t.Errorf("Create stored date_time %v, want 2024-01-01T12:00:00.5Z", reading.DateTime)
+	}
+
+	reading.DateTime = "2024-01-01T08:00:00-05:00"
+	if _, err := service.Update(reading, context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	stored, err := service.ReadOne("ds18b20", reading.ID, context.Background())
+	if err != nil || stored == nil || stored.DateTime != "2024-01-01T13:00:00Z" {
+		t.Errorf("ReadOne returned %+v, %v, want date_time 2024-01-01T13:00:00Z", stored, err)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	service "goapi/internal/api/service/data"
+	"goapi/internal/api/service/dht22"
+	"goapi/internal/api/service/sensor"
+	"log"
+	"time"
+)
+
+// * TrashPurger permanently removes the records and readings that have been in the trash longer than the retention *
+type TrashPurger struct {
+	ds           service.DataService
+	dht22Service dht22.DHT22Service
+	ss           sensor.SensorService
+	logger       *log.Logger
+}
+
+func NewTrashPurger(ds service.DataService, dht22Service dht22.DHT22Service, ss sensor.SensorService, logger *log.Logger) *TrashPurger {
+	return &TrashPurger{
+		ds:           ds,
+		dht22Service: dht22Service,
+		ss:           ss,
+		logger:       logger,
+	}
+}
+
+// Run purges the trash right away and then every interval, until the context is canceled.
+func (p *TrashPurger) Run(ctx context.Context, retention time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.Purge(time.Now().Add(-retention), ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes everything deleted before the given time, errors are logged so the next run tries again.
+func (p *TrashPurger) Purge(before time.Time, ctx context.Context) {
+	if n, err := p.ds.Purge(before, ctx); err != nil {
+		p.logger.Println("Could not purge data trash:", err)
+	} else if n > 0 {
+		p.logger.Printf("Purged %d data records deleted before %s", n, before.UTC().Format(time.RFC3339))
+	}
+
+	if n, err := p.dht22Service.Purge(before, ctx); err != nil {
+		p.logger.Println("Could not purge DHT22 trash:", err)
+	} else if n > 0 {
+		p.logger.Printf("Purged %d DHT22 readings deleted before %s", n, before.UTC().Format(time.RFC3339))
+	}
+
+	if n, err := p.ss.Purge(before, ctx); err != nil {
+		p.logger.Println("Could not purge sensor trash:", err)
+	} else if n > 0 {
+		p.logger.Printf("Purged %d sensor readings deleted before %s", n, before.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"goapi/internal/api/repository/models"
+	"goapi/internal/api/service"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrashPurger_Purge(t *testing.T) {
+	var logs bytes.Buffer
+	factory := service.NewServiceFactory(nil, log.New(&logs, "", 0), context.Background(), service.Config{})
+	ds, err := factory.CreateDataService(service.MemoryDataService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dht22Service, err := factory.CreateDHT22Service(service.MemoryDHT22Service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sensorService, err := factory.CreateSensorService(service.MemoryDHT22Service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// * One record and one reading of each kind are deleted before the cutoff, one of each after it and one of each stays live
+	var records []*models.Data
+	var readings []*models.DHT22Data
+	var sensorReadings []*models.SensorReading
+	for i, serial := range []string{"SN-1", "SN-2", "SN-3"} {
+		data := &models.Data{DeviceID: "d1", SerialNumber: serial, DateTime: "2024-01-01T10:00:00Z", Currency: models.BaseCurrency}
+		if err := ds.Create(data, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		reading := &models.DHT22Data{DeviceName: "greenhouse-1", Temperature: 20 + float64(i), Humidity: 50, DateTime: "2024-01-01T10:00:00Z"}
+		if err := dht22Service.Create(reading, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		sensorReading := &models.SensorReading{Type: "ds18b20", DeviceName: "greenhouse-1", DateTime: "2024-01-01T10:00:00Z", Values: map[string]float64{"temperature": 20 + float64(i)}}
+		if err := sensorService.Create(sensorReading, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		records, readings, sensorReadings = append(records, data), append(readings, reading), append(sensorReadings, sensorReading)
+	}
+	trash := func(i int) {
+		t.Helper()
+		if _, err := ds.Delete(&models.Data{ID: records[i].ID}, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := dht22Service.Delete(&models.DHT22Data{ID: readings[i].ID}, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sensorService.Delete("ds18b20", sensorReadings[i].ID, 0, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trash(0)
+	time.Sleep(2 * time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	trash(1)
+
+	purger := service.NewTrashPurger(ds, dht22Service, sensorService, log.New(&logs, "", 0))
+	purger.Purge(cutoff, context.Background())
+
+	if count, err := ds.CountTrash(context.Background()); err != nil || count != 1 {
+		t.Errorf("The data trash has %d records (%v) after the purge, want the one deleted after the cutoff", count, err)
+	}
+	if count, err := dht22Service.CountTrash(context.Background()); err != nil || count != 1 {
+		t.Errorf("The DHT22 trash has %d readings (%v) after the purge, want the one deleted after the cutoff", count, err)
+	}
+	if count, err := sensorService.CountTrash(context.Background()); err != nil || count != 1 {
+		t.Errorf("The sensor trash has %d readings (%v) after the purge, want the one deleted after the cutoff", count, err)
+	}
+	if n, err := ds.Restore(records[0].ID, context.Background()); err != nil || n != 0 {
+		t.Errorf("Restore of a purged record returned %v, %v, want 0, nil", n, err)
+	}
+	if n, err := dht22Service.Restore(readings[0].ID, context.Background()); err != nil || n != 0 {
+		t.Errorf("Restore of a purged reading returned %v, %v, want 0, nil", n, err)
+	}
+	if n, err := sensorService.Restore("ds18b20", sensorReadings[0].ID, context.Background()); err != nil || n != 0 {
+		t.Errorf("Restore of a purged sensor reading returned %v, %v, want 0, nil", n, err)
+	}
+	if data, err := ds.ReadOne(records[2].ID, context.Background()); err != nil || data == nil {
+		t.Errorf("The live record was read as %v, %v after the purge", data, err)
+	}
+	if reading, err := dht22Service.ReadOne(readings[2].ID, context.Background()); err != nil || reading == nil {
+		t.Errorf("The live reading was read as %v, %v after the purge", reading, err)
+	}
+	if reading, err := sensorService.ReadOne("ds18b20", sensorReadings[2].ID, context.Background()); err != nil || reading == nil {
+		t.Errorf("The live sensor reading was read as %v, %v after the purge", reading, err)
+	}
+	for _, want := range []string{"Purged 1 data records deleted before", "Purged 1 DHT22 readings deleted before", "Purged 1 sensor readings deleted before"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("The purge logged %q, want %q", logs.String(), want)
+		}
+	}
+
+	// * Nothing left before the cutoff, a second run purges and logs nothing
+	logs.Reset()
+	purger.Purge(cutoff, context.Background())
+	if logs.Len() != 0 {
+		t.Errorf("The second purge logged %q, want nothing", logs.String())
+	}
+}